@@ -3,12 +3,16 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/audit"
 	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
 )
 
 func desktopBridgeInitJS(prefs desktopPreferences) string {
@@ -128,11 +132,13 @@ window.setTimeout(window.__ATM_REPORT_DESKTOP_PATHNAME__, 0);
 type desktopBridge struct {
 	collector   *monitor.Collector
 	auth        *api.AuthManager
+	session     desktopBridgeSession
 	view        desktopBridgeView
 	provider    string
 	preferences *desktopPreferencesController
 	tray        *desktopTray
 	windows     *desktopNativeWindows
+	auditLog    *audit.Logger
 }
 
 func newDesktopBridge(collector *monitor.Collector, auth *api.AuthManager, provider string, preferences *desktopPreferencesController, tray *desktopTray, windows *desktopNativeWindows) *desktopBridge {
@@ -167,6 +173,18 @@ func (b *desktopBridge) bind(w desktopBridgeView) error {
 	if err := w.Bind("atmDesktopSendAgentMessage", b.sendAgentMessage); err != nil {
 		return fmt.Errorf("bind atmDesktopSendAgentMessage: %w", err)
 	}
+	if err := w.Bind("atmDesktopSignalProcess", b.signalProcess); err != nil {
+		return fmt.Errorf("bind atmDesktopSignalProcess: %w", err)
+	}
+	if err := w.Bind("atmDesktopSignalTeam", b.signalTeam); err != nil {
+		return fmt.Errorf("bind atmDesktopSignalTeam: %w", err)
+	}
+	if err := w.Bind("atmDesktopLogin", b.login); err != nil {
+		return fmt.Errorf("bind atmDesktopLogin: %w", err)
+	}
+	if err := w.Bind("atmDesktopLogout", b.logout); err != nil {
+		return fmt.Errorf("bind atmDesktopLogout: %w", err)
+	}
 	if err := w.Bind("atmDesktopGetContext", b.getContext); err != nil {
 		return fmt.Errorf("bind atmDesktopGetContext: %w", err)
 	}
@@ -210,6 +228,9 @@ func (b *desktopBridge) getState() (json.RawMessage, error) {
 	if b == nil || b.collector == nil {
 		return nil, fmt.Errorf("desktop bridge collector unavailable")
 	}
+	if err := b.requirePermission(api.PermissionRead); err != nil {
+		return nil, err
+	}
 
 	state := b.collector.GetState()
 	payload, err := json.Marshal(state)
@@ -220,22 +241,116 @@ func (b *desktopBridge) getState() (json.RawMessage, error) {
 	return json.RawMessage(payload), nil
 }
 
-func (b *desktopBridge) requireAdmin() error {
+// desktopBridgeSession holds the admin session token for bridge calls, which
+// arrive without an HTTP request and so cannot see the webview's cookie.
+type desktopBridgeSession struct {
+	mu    sync.Mutex
+	token string
+}
+
+func (s *desktopBridgeSession) get() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+func (s *desktopBridgeSession) set(token string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.token
+	s.token = token
+	return previous
+}
+
+func (b *desktopBridge) login(username, password string) (api.AuthStatus, error) {
+	if b == nil || b.auth == nil {
+		return api.AuthStatus{}, fmt.Errorf("admin login not configured")
+	}
+
+	session, err := b.auth.Login(username, password)
+	entry := audit.Entry{Actor: strings.TrimSpace(username), Action: audit.ActionLogin}
+	if err != nil {
+		entry.Result = audit.ResultDenied
+		b.recordAudit(entry, err)
+		return api.AuthStatus{}, err
+	}
+	if previous := b.session.set(session.Token); previous != "" {
+		b.auth.Logout(previous)
+	}
+	entry.Role = string(session.Role)
+	b.recordAudit(entry, nil)
+
+	return session.Status(), nil
+}
+
+func (b *desktopBridge) logout() error {
 	if b == nil || b.auth == nil {
+		return nil
+	}
+	if session, err := b.auth.Authenticate(b.session.get()); err == nil {
+		b.recordAudit(audit.Entry{Actor: session.Username, Role: string(session.Role), Action: audit.ActionLogout}, nil)
+	}
+	b.auth.Logout(b.session.set(""))
+	return nil
+}
+
+// requirePermission checks the bridge session's role. Window and navigation
+// helpers are not gated; only calls that read or change monitor data are.
+// Without an auth manager the bridge behaves like an anonymous viewer.
+func (b *desktopBridge) requirePermission(permission api.Permission) error {
+	if b == nil || b.auth == nil {
+		if api.RoleViewer.Allows(permission) {
+			return nil
+		}
 		return fmt.Errorf("admin login not configured")
 	}
-	return b.auth.RequireAuthenticated()
+	return b.auth.CheckPermission(b.auth.SessionPrincipal(b.session.get()), permission)
+}
+
+// authorize is requirePermission for mutating calls; denials are audited.
+func (b *desktopBridge) authorize(permission api.Permission, entry audit.Entry) error {
+	err := b.requirePermission(permission)
+	if err != nil {
+		entry.Result = audit.ResultDenied
+		b.recordAudit(entry, err)
+	}
+	return err
+}
+
+// recordAudit appends entry under the bridge session's identity.
+func (b *desktopBridge) recordAudit(entry audit.Entry, err error) {
+	if b == nil || b.auditLog == nil {
+		return
+	}
+	if entry.Actor == "" {
+		principal := b.auth.SessionPrincipal(b.session.get())
+		entry.Actor = principal.Name()
+		entry.Role = string(principal.Role)
+	}
+	entry.Client = audit.ClientDesktop
+	if err != nil {
+		entry.Error = err.Error()
+		if entry.Result == "" {
+			entry.Result = audit.ResultError
+		}
+	}
+	if recordErr := b.auditLog.Record(entry); recordErr != nil {
+		log.Printf("Error writing audit log: %v", recordErr)
+	}
 }
 
 func (b *desktopBridge) deleteTeam(teamName string) (map[string]interface{}, error) {
 	if b == nil || b.collector == nil {
 		return nil, fmt.Errorf("desktop bridge collector unavailable")
 	}
-	if err := b.requireAdmin(); err != nil {
+	entry := audit.Entry{Action: audit.ActionTeamDelete, Team: teamName}
+	if err := b.authorize(api.PermissionTeamsDelete, entry); err != nil {
 		return nil, err
 	}
 
-	if err := b.collector.DeleteTeam(teamName); err != nil {
+	err := b.collector.DeleteTeam(teamName)
+	b.recordAudit(entry, err)
+	if err != nil {
 		return nil, err
 	}
 
@@ -249,11 +364,14 @@ func (b *desktopBridge) sendAgentMessage(teamName, agentName, text string) (map[
 	if b == nil || b.collector == nil {
 		return nil, fmt.Errorf("desktop bridge collector unavailable")
 	}
-	if err := b.requireAdmin(); err != nil {
+	entry := audit.Entry{Action: audit.ActionAgentMessage, Team: teamName, Agent: agentName, Detail: audit.Summarize(text)}
+	if err := b.authorize(api.PermissionMessage, entry); err != nil {
 		return nil, err
 	}
 
-	if err := b.collector.SendAgentMessage(teamName, agentName, text); err != nil {
+	err := b.collector.SendAgentMessage(teamName, agentName, text)
+	b.recordAudit(entry, err)
+	if err != nil {
 		return nil, err
 	}
 
@@ -263,6 +381,43 @@ func (b *desktopBridge) sendAgentMessage(teamName, agentName, text string) (map[
 	}, nil
 }
 
+// signalProcess sends SIGINT, SIGTERM or SIGKILL to a monitored process and
+// waits for it to exit.
+func (b *desktopBridge) signalProcess(pid int32, signal string) ([]types.SignalResult, error) {
+	if b == nil || b.collector == nil {
+		return nil, fmt.Errorf("desktop bridge collector unavailable")
+	}
+	entry := audit.Entry{Action: audit.ActionProcessSignal, Detail: fmt.Sprintf("%s %d", signal, pid)}
+	if err := b.authorize(api.PermissionManagedControl, entry); err != nil {
+		return nil, err
+	}
+
+	results, err := b.collector.SignalProcesses([]int32{pid}, signal, monitor.DefaultSignalTimeout)
+	if len(results) > 0 {
+		entry.Detail = monitor.SummarizeSignalResults(results)
+	}
+	b.recordAudit(entry, err)
+	return results, err
+}
+
+// signalTeam signals every process linked to a team.
+func (b *desktopBridge) signalTeam(teamName, signal string) ([]types.SignalResult, error) {
+	if b == nil || b.collector == nil {
+		return nil, fmt.Errorf("desktop bridge collector unavailable")
+	}
+	entry := audit.Entry{Action: audit.ActionProcessSignal, Team: teamName, Detail: signal}
+	if err := b.authorize(api.PermissionManagedControl, entry); err != nil {
+		return nil, err
+	}
+
+	results, err := b.collector.SignalTeam(teamName, signal, monitor.DefaultSignalTimeout)
+	if len(results) > 0 {
+		entry.Detail = monitor.SummarizeSignalResults(results)
+	}
+	b.recordAudit(entry, err)
+	return results, err
+}
+
 func (b *desktopBridge) getContext() map[string]interface{} {
 	prefs := defaultDesktopPreferences()
 	if b != nil && b.preferences != nil {
@@ -333,11 +488,14 @@ func (b *desktopBridge) setPreferences(input desktopPreferences) (desktopPrefere
 	if b == nil || b.preferences == nil {
 		return defaultDesktopPreferences(), fmt.Errorf("desktop preferences unavailable")
 	}
-	if err := b.requireAdmin(); err != nil {
+	entry := audit.Entry{Action: audit.ActionPreferencesUpdate}
+	if err := b.authorize(api.PermissionAdmin, entry); err != nil {
 		return defaultDesktopPreferences(), err
 	}
 
-	return b.preferences.Set(input)
+	saved, err := b.preferences.Set(input)
+	b.recordAudit(entry, err)
+	return saved, err
 }
 
 func (b *desktopBridge) openExternal(target string) error {
@@ -412,7 +570,7 @@ func (b *desktopBridge) openPreferencesWindow() error {
 	if b == nil || b.windows == nil {
 		return fmt.Errorf("desktop native windows unavailable")
 	}
-	if err := b.requireAdmin(); err != nil {
+	if err := b.requirePermission(api.PermissionAdmin); err != nil {
 		return err
 	}
 
@@ -2,12 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"unsafe"
 
 	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/audit"
 	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
 	"github.com/liaoweijun/agent-team-monitor/pkg/types"
 )
@@ -38,15 +40,20 @@ func newTestDesktopPreferencesController() *desktopPreferencesController {
 	return newDesktopPreferencesController(newInMemoryDesktopPreferencesStore(), nil, nil)
 }
 
-func newConfiguredAuthenticatedTestAuthManager(t *testing.T) *api.AuthManager {
+func newConfiguredTestAuthManager(t *testing.T) *api.AuthManager {
 	t.Helper()
 	t.Setenv("ATM_ADMIN_USERNAME", "admin")
 	t.Setenv("ATM_ADMIN_PASSWORD", "secret")
-	manager := api.NewAuthManagerFromEnv()
-	if err := manager.Login("admin", "secret"); err != nil {
-		t.Fatalf("login test auth manager: %v", err)
+	return api.NewAuthManagerFromEnv()
+}
+
+func newAuthenticatedTestDesktopBridge(t *testing.T, collector *monitor.Collector) *desktopBridge {
+	t.Helper()
+	bridge := newDesktopBridge(collector, newConfiguredTestAuthManager(t), "both", newTestDesktopPreferencesController(), nil, nil)
+	if _, err := bridge.login("admin", "secret"); err != nil {
+		t.Fatalf("login test desktop bridge: %v", err)
 	}
-	return manager
+	return bridge
 }
 
 func TestDesktopBridgeGetState_ReturnsJSONPayload(t *testing.T) {
@@ -93,7 +100,7 @@ func TestDesktopBridgeDeleteTeam_RemovesClaudeTeamArtifacts(t *testing.T) {
 		_ = collector.Stop()
 	}()
 
-	bridge := newDesktopBridge(collector, newConfiguredAuthenticatedTestAuthManager(t), "both", newTestDesktopPreferencesController(), nil, nil)
+	bridge := newAuthenticatedTestDesktopBridge(t, collector)
 	result, err := bridge.deleteTeam(teamName)
 	if err != nil {
 		t.Fatalf("deleteTeam returned error: %v", err)
@@ -111,6 +118,122 @@ func TestDesktopBridgeDeleteTeam_RemovesClaudeTeamArtifacts(t *testing.T) {
 	}
 }
 
+func TestDesktopBridgeRequiresOwnSession(t *testing.T) {
+	auth := newConfiguredTestAuthManager(t)
+	bridge := newDesktopBridge(nil, auth, "both", newTestDesktopPreferencesController(), nil, nil)
+
+	if _, err := auth.Login("admin", "secret"); err != nil {
+		t.Fatalf("login another client: %v", err)
+	}
+	if err := bridge.requirePermission(api.PermissionAdmin); err == nil {
+		t.Fatal("expected another client's session not to unlock the bridge")
+	}
+
+	if _, err := bridge.login("admin", "wrong"); err == nil {
+		t.Fatal("expected invalid password to be rejected")
+	}
+	if _, err := bridge.login("admin", "secret"); err != nil {
+		t.Fatalf("bridge login: %v", err)
+	}
+	if err := bridge.requirePermission(api.PermissionAdmin); err != nil {
+		t.Fatalf("expected bridge session to be admin, got %v", err)
+	}
+
+	if err := bridge.logout(); err != nil {
+		t.Fatalf("bridge logout: %v", err)
+	}
+	if err := bridge.requirePermission(api.PermissionAdmin); err == nil {
+		t.Fatal("expected logout to end the bridge session")
+	}
+}
+
+func TestDesktopBridgeUsesSessionRole(t *testing.T) {
+	auth := newConfiguredTestAuthManager(t)
+	hash, err := api.HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if err := auth.LoadUsers(api.UsersConfig{Users: []api.UserConfig{{Username: "otto", PasswordHash: hash, Role: "operator"}}}); err != nil {
+		t.Fatalf("LoadUsers: %v", err)
+	}
+	bridge := newDesktopBridge(nil, auth, "both", newTestDesktopPreferencesController(), nil, nil)
+
+	if err := bridge.requirePermission(api.PermissionRead); err != nil {
+		t.Fatalf("expected anonymous bridge to read, got %v", err)
+	}
+	if _, err := bridge.login("otto", "secret"); err != nil {
+		t.Fatalf("bridge login: %v", err)
+	}
+	if err := bridge.requirePermission(api.PermissionMessage); err != nil {
+		t.Fatalf("expected operator to message, got %v", err)
+	}
+	if _, err := bridge.setPreferences(defaultDesktopPreferences()); err == nil {
+		t.Fatal("expected operator to be denied preference changes")
+	}
+	if err := bridge.requirePermission(api.PermissionTeamsDelete); err == nil {
+		t.Fatal("expected operator to be denied team deletion")
+	}
+}
+
+func TestDesktopBridgeAuditsActions(t *testing.T) {
+	logger, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.jsonl")})
+	if err != nil {
+		t.Fatalf("audit.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	bridge := newDesktopBridge(nil, newConfiguredTestAuthManager(t), "both", newTestDesktopPreferencesController(), nil, nil)
+	bridge.auditLog = logger
+
+	if _, err := bridge.setPreferences(defaultDesktopPreferences()); err == nil {
+		t.Fatal("expected anonymous preference change to be denied")
+	}
+	if _, err := bridge.login("admin", "secret"); err != nil {
+		t.Fatalf("bridge login: %v", err)
+	}
+	if _, err := bridge.setPreferences(defaultDesktopPreferences()); err != nil {
+		t.Fatalf("setPreferences: %v", err)
+	}
+
+	entries, err := logger.Query(audit.Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %+v", entries)
+	}
+	if entries[0].Actor != "admin" || entries[0].Action != audit.ActionPreferencesUpdate || entries[0].Result != audit.ResultOK || entries[0].Client != audit.ClientDesktop {
+		t.Fatalf("unexpected preferences entry: %+v", entries[0])
+	}
+	if entries[2].Actor != "anonymous" || entries[2].Result != audit.ResultDenied {
+		t.Fatalf("unexpected denied entry: %+v", entries[2])
+	}
+}
+
+func TestDesktopBridgeSignalsOnlyMonitoredProcesses(t *testing.T) {
+	collector, err := monitor.NewCollector()
+	if err != nil {
+		t.Fatalf("new collector: %v", err)
+	}
+	defer func() {
+		_ = collector.Stop()
+	}()
+	auth := newConfiguredTestAuthManager(t)
+	bridge := newDesktopBridge(collector, auth, "both", newTestDesktopPreferencesController(), nil, nil)
+
+	if _, err := bridge.signalProcess(int32(os.Getpid()), monitor.SignalTerminate); err == nil || errors.Is(err, monitor.ErrProcessNotMonitored) {
+		t.Fatalf("expected an anonymous signal to be denied, got %v", err)
+	}
+	if _, err := bridge.login("admin", "secret"); err != nil {
+		t.Fatalf("bridge login: %v", err)
+	}
+	if _, err := bridge.signalProcess(int32(os.Getpid()), monitor.SignalTerminate); !errors.Is(err, monitor.ErrProcessNotMonitored) {
+		t.Fatalf("expected an unmonitored PID to be refused, got %v", err)
+	}
+	if _, err := bridge.signalTeam("nobody", monitor.SignalKill); !errors.Is(err, monitor.ErrProcessNotMonitored) {
+		t.Fatalf("expected a team without processes to be refused, got %v", err)
+	}
+}
+
 func TestDesktopBridgeGetContext_ReturnsDesktopMetadata(t *testing.T) {
 	collector, err := monitor.NewCollector()
 	if err != nil {
@@ -132,7 +255,7 @@ func TestDesktopBridgeGetContext_ReturnsDesktopMetadata(t *testing.T) {
 }
 
 func TestDesktopBridgePreferencesRoundTrip(t *testing.T) {
-	bridge := newDesktopBridge(nil, newConfiguredAuthenticatedTestAuthManager(t), "both", newTestDesktopPreferencesController(), nil, nil)
+	bridge := newAuthenticatedTestDesktopBridge(t, nil)
 
 	prefs := bridge.getPreferences()
 	if prefs != defaultDesktopPreferences() {
@@ -168,7 +291,7 @@ func TestDesktopBridgeOpenExternal_RejectsUnsupportedSchemes(t *testing.T) {
 }
 
 func TestDesktopBridgeNativeWindowActionsRequireNativeWindows(t *testing.T) {
-	bridge := newDesktopBridge(nil, newConfiguredAuthenticatedTestAuthManager(t), "both", newTestDesktopPreferencesController(), nil, nil)
+	bridge := newAuthenticatedTestDesktopBridge(t, nil)
 
 	if err := bridge.openPreferencesWindow(); err == nil {
 		t.Fatal("expected openPreferencesWindow to fail without native window support")
@@ -103,6 +103,7 @@ func main() {
 	}
 
 	bridge := newDesktopBridge(session.Collector, session.Auth, *provider, preferencesController, tray, nativeWindows)
+	bridge.auditLog = session.Audit
 	if err := mainWindow.attachBridge(bridge); err != nil {
 		log.Fatalf("attach desktop bridge: %v", err)
 	}
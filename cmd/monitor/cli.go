@@ -0,0 +1,920 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	agentapp "github.com/liaoweijun/agent-team-monitor/internal/app"
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/audit"
+	"github.com/liaoweijun/agent-team-monitor/pkg/client"
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/mcp"
+	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/report"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+const cliUsage = `Usage:
+  agent-team-monitor teams  [-team NAME] [-provider NAME] [-status STATUS]
+  agent-team-monitor agents [-team NAME] [-provider NAME] [-status STATUS]
+  agent-team-monitor tasks  [-team NAME] [-provider NAME] [-status STATUS]
+  agent-team-monitor state
+  agent-team-monitor send TEAM AGENT TEXT
+  agent-team-monitor tail TEAM AGENT [-n 20] [-f]
+  agent-team-monitor managed list
+  agent-team-monitor managed create -name NAME [-workspace DIR] [-agents lead,reviewer] [-model M] [-permission P]
+  agent-team-monitor managed start|stop TEAM
+  agent-team-monitor managed logs TEAM [-agent ID] [-n 200]
+  agent-team-monitor report [-team NAME] [-format markdown|html|json] [-o FILE] [-from STATE.json] [-events 10]
+  agent-team-monitor mcp
+
+Every subcommand also accepts:
+  -url URL        Server to talk to (env ATM_URL); without it, local files are read directly
+  -token SECRET   API token for -url (env ATM_TOKEN)
+  -provider NAME  claude, codex or openclaw; also limits what is read locally
+  -format FORMAT  table (default), json or ndjson
+  -json           Same as -format json
+
+TEAM in managed subcommands is a managed team id or name. report -from reads
+a saved "state --json" snapshot instead of collecting. mcp serves the Model
+Context Protocol on stdin/stdout; tools need the same permissions as the HTTP
+API, granted locally by -token or the anonymous role.
+`
+
+// localWatchInterval is how often tail -f re-reads local files.
+const localWatchInterval = 2 * time.Second
+
+// cliCommands are the scripting subcommands dispatched by main.
+var cliCommands = []string{"teams", "agents", "tasks", "state", "send", "tail", "managed", "report", "mcp"}
+
+func isCLICommand(name string) bool {
+	return slices.Contains(cliCommands, name)
+}
+
+// cliBackend is what the subcommands need from either a running server or
+// the local files.
+type cliBackend interface {
+	State(ctx context.Context) (types.MonitorState, error)
+	Watch(ctx context.Context, handle func(types.MonitorState) error) error
+	SendAgentMessage(ctx context.Context, team, agent, text string) error
+	ManagedTeams(ctx context.Context) ([]managed.ManagedTeam, error)
+	CreateManagedTeam(ctx context.Context, input managed.CreateTeamInput) (managed.TeamSpec, error)
+	StartTeam(ctx context.Context, id string) (managed.RunState, error)
+	StopTeam(ctx context.Context, id string) (managed.RunState, error)
+	ManagedLogs(ctx context.Context, id, agent string, lines int) (managed.LogTail, error)
+	Close() error
+}
+
+// remoteBackend talks to a running server through the Go client.
+type remoteBackend struct {
+	*client.Client
+}
+
+func (remoteBackend) Close() error { return nil }
+
+// localBackend reads a one-shot snapshot of the local files. Managed
+// teams can be created and inspected, but starting and stopping them
+// needs the server that owns their terminals.
+type localBackend struct {
+	collector *monitor.Collector
+	managed   *managed.Manager
+	// server merges managed teams into the collected state the same way
+	// the web API does.
+	server    *api.Server
+	refreshed time.Time
+}
+
+var errNeedsServer = errors.New("starting and stopping managed teams needs a running server; pass -url or set ATM_URL")
+
+func newLocalBackend(provider string) (*localBackend, error) {
+	cfg, _, err := agentapp.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	collector, err := agentapp.CollectOnce(provider, cfg)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := managed.NewManagerInDir(cfg.ManagedDir())
+	if err != nil {
+		_ = collector.Stop()
+		return nil, err
+	}
+	return &localBackend{
+		collector: collector,
+		managed:   manager,
+		server:    api.NewServer(collector, "", nil, nil, manager),
+		refreshed: time.Now(),
+	}, nil
+}
+
+// State re-reads the local files when the snapshot is older than
+// localWatchInterval, so long-running commands stay current.
+func (l *localBackend) State(context.Context) (types.MonitorState, error) {
+	if time.Since(l.refreshed) >= localWatchInterval {
+		l.collector.Refresh()
+		l.refreshed = time.Now()
+	}
+	return l.server.State(), nil
+}
+
+func (l *localBackend) Watch(ctx context.Context, handle func(types.MonitorState) error) error {
+	ticker := time.NewTicker(localWatchInterval)
+	defer ticker.Stop()
+	for {
+		state, _ := l.State(ctx)
+		if err := handle(state); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *localBackend) SendAgentMessage(_ context.Context, team, agent, text string) error {
+	return l.collector.SendAgentMessage(team, agent, text)
+}
+
+func (l *localBackend) ManagedTeams(context.Context) ([]managed.ManagedTeam, error) {
+	return l.managed.ListTeams()
+}
+
+func (l *localBackend) CreateManagedTeam(_ context.Context, input managed.CreateTeamInput) (managed.TeamSpec, error) {
+	return l.managed.CreateTeam(input)
+}
+
+func (l *localBackend) StartTeam(context.Context, string) (managed.RunState, error) {
+	return managed.RunState{}, errNeedsServer
+}
+
+func (l *localBackend) StopTeam(context.Context, string) (managed.RunState, error) {
+	return managed.RunState{}, errNeedsServer
+}
+
+func (l *localBackend) ManagedLogs(_ context.Context, id, agent string, lines int) (managed.LogTail, error) {
+	if lines <= 0 {
+		lines = 200
+	}
+	return l.managed.ReadLog(id, agent, lines)
+}
+
+func (l *localBackend) Close() error {
+	return l.collector.Stop()
+}
+
+// cliOptions are the flags shared by every subcommand.
+type cliOptions struct {
+	url      string
+	token    string
+	provider string
+	format   string
+	json     bool
+	team     string
+	status   string
+}
+
+func newCLIFlagSet(name string, stdout io.Writer, listing bool) (*flag.FlagSet, *cliOptions) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	fs.Usage = func() { fmt.Fprint(stdout, cliUsage) }
+	options := &cliOptions{}
+	fs.StringVar(&options.url, "url", os.Getenv("ATM_URL"), "Server URL; empty reads local files")
+	fs.StringVar(&options.token, "token", os.Getenv("ATM_TOKEN"), "API token for -url")
+	fs.StringVar(&options.provider, "provider", "", "claude, codex or openclaw")
+	fs.StringVar(&options.format, "format", "table", "table, json or ndjson")
+	fs.BoolVar(&options.json, "json", false, "Same as -format json")
+	if listing {
+		fs.StringVar(&options.team, "team", "", "Only this team")
+		fs.StringVar(&options.status, "status", "", "Only this status")
+	}
+	return fs, options
+}
+
+// parseArgs lets flags appear before, between or after positional
+// arguments; everything after "--" is positional.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func (o *cliOptions) backend() (cliBackend, error) {
+	if o.url == "" {
+		return newLocalBackend(strings.ToLower(o.provider))
+	}
+	c, err := client.New(o.url, client.Options{Token: o.token})
+	if err != nil {
+		return nil, err
+	}
+	return remoteBackend{c}, nil
+}
+
+// runCLICommand runs one scripting subcommand against a server or, without
+// -url, the local files.
+func runCLICommand(ctx context.Context, name string, args []string, stdout io.Writer) error {
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		fmt.Fprint(stdout, cliUsage)
+		return nil
+	}
+	switch name {
+	case "teams", "agents", "tasks", "state":
+		return runListCommand(ctx, name, args, stdout)
+	case "send":
+		return runSendCommand(ctx, args, stdout)
+	case "tail":
+		return runTailCommand(ctx, args, stdout)
+	case "managed":
+		return runManagedCommand(ctx, args, stdout)
+	case "report":
+		return runReportCommand(ctx, args, stdout)
+	case "mcp":
+		return runMCPCommand(ctx, args, stdout)
+	default:
+		return fmt.Errorf("unknown subcommand %q\n%s", name, cliUsage)
+	}
+}
+
+// cliAgent and cliTask flatten an agent or task with its team, like the
+// /api/v1 list items.
+type cliAgent struct {
+	Team string `json:"team"`
+	types.AgentInfo
+}
+
+type cliTask struct {
+	Team string `json:"team"`
+	types.TaskInfo
+}
+
+func runListCommand(ctx context.Context, name string, args []string, stdout io.Writer) error {
+	fs, options := newCLIFlagSet(name, stdout, name != "state")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("%s takes no arguments\n%s", name, cliUsage)
+	}
+	format, err := parseOutputFormat(options.format, options.json)
+	if err != nil {
+		return err
+	}
+	backend, err := options.backend()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	state, err := backend.State(ctx)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "state":
+		return writeItem(stdout, format, state, func(w io.Writer) error {
+			return writeStateSummary(w, state)
+		})
+	case "teams":
+		var teams []types.TeamInfo
+		for _, team := range state.Teams {
+			if options.matchTeam(team) && (options.status == "" || teamHasStatus(team, options.status)) {
+				teams = append(teams, team)
+			}
+		}
+		return writeList(stdout, format, teams, []string{"NAME", "PROVIDER", "STATUS", "AGENTS", "WORKING", "TASKS"}, teamRow)
+	case "agents":
+		var agents []cliAgent
+		for _, team := range state.Teams {
+			if !options.matchTeam(team) {
+				continue
+			}
+			for _, agent := range team.Members {
+				if options.matchProvider(firstNonEmpty(agent.Provider, team.Provider)) && options.matchStatus(agent.Status) {
+					agents = append(agents, cliAgent{Team: team.Name, AgentInfo: agent})
+				}
+			}
+		}
+		return writeList(stdout, format, agents, []string{"TEAM", "NAME", "PROVIDER", "STATUS", "CURRENT TASK", "LAST ACTIVE"}, func(agent cliAgent) []string {
+			return []string{agent.Team, agent.Name, cell(agent.Provider, 0), agent.Status, cell(agent.CurrentTask, 48), formatCLITime(agent.LastActivity)}
+		})
+	default:
+		var tasks []cliTask
+		for _, team := range state.Teams {
+			if !options.matchTeam(team) {
+				continue
+			}
+			for _, task := range team.Tasks {
+				if options.matchStatus(task.Status) {
+					tasks = append(tasks, cliTask{Team: team.Name, TaskInfo: task})
+				}
+			}
+		}
+		return writeList(stdout, format, tasks, []string{"TEAM", "ID", "STATUS", "OWNER", "SUBJECT"}, func(task cliTask) []string {
+			return []string{task.Team, task.ID, task.Status, cell(task.Owner, 0), cell(task.Subject, 60)}
+		})
+	}
+}
+
+// matchTeam applies -team and -provider; a team matches a provider when
+// it or any member uses it.
+func (o *cliOptions) matchTeam(team types.TeamInfo) bool {
+	if o.team != "" && !strings.EqualFold(team.Name, o.team) {
+		return false
+	}
+	if o.matchProvider(team.Provider) {
+		return true
+	}
+	for _, member := range team.Members {
+		if o.matchProvider(member.Provider) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *cliOptions) matchProvider(provider string) bool {
+	return o.provider == "" || strings.EqualFold(provider, o.provider)
+}
+
+func (o *cliOptions) matchStatus(status string) bool {
+	return o.status == "" || strings.EqualFold(status, o.status)
+}
+
+func teamHasStatus(team types.TeamInfo, status string) bool {
+	if strings.EqualFold(team.ManagedStatus, status) {
+		return true
+	}
+	for _, member := range team.Members {
+		if strings.EqualFold(member.Status, status) {
+			return true
+		}
+	}
+	return false
+}
+
+func teamRow(team types.TeamInfo) []string {
+	working, done := 0, 0
+	for _, member := range team.Members {
+		if member.Status == "working" {
+			working++
+		}
+	}
+	for _, task := range team.Tasks {
+		if task.Status == "completed" {
+			done++
+		}
+	}
+	return []string{
+		team.Name,
+		cell(team.Provider, 0),
+		cell(team.ManagedStatus, 0),
+		strconv.Itoa(len(team.Members)),
+		strconv.Itoa(working),
+		fmt.Sprintf("%d/%d", done, len(team.Tasks)),
+	}
+}
+
+func writeStateSummary(w io.Writer, state types.MonitorState) error {
+	agents, working, tasks, open := 0, 0, 0, 0
+	for _, team := range state.Teams {
+		agents += len(team.Members)
+		tasks += len(team.Tasks)
+		for _, member := range team.Members {
+			if member.Status == "working" {
+				working++
+			}
+		}
+		for _, task := range team.Tasks {
+			if task.Status != "completed" {
+				open++
+			}
+		}
+	}
+	if state.Version > 0 {
+		fmt.Fprintf(w, "Version:   %d\n", state.Version)
+	}
+	fmt.Fprintf(w, "Updated:   %s\n", formatCLITime(state.UpdatedAt))
+	fmt.Fprintf(w, "Teams:     %d\n", len(state.Teams))
+	fmt.Fprintf(w, "Agents:    %d (%d working)\n", agents, working)
+	fmt.Fprintf(w, "Tasks:     %d (%d open)\n", tasks, open)
+	_, err := fmt.Fprintf(w, "Processes: %d\n", len(state.Processes))
+	return err
+}
+
+// sentMessage is the json output of send.
+type sentMessage struct {
+	Team  string `json:"team"`
+	Agent string `json:"agent"`
+	Text  string `json:"text"`
+}
+
+func runSendCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, options := newCLIFlagSet("send", stdout, false)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 3 {
+		return fmt.Errorf("usage: agent-team-monitor send TEAM AGENT TEXT")
+	}
+	format, err := parseOutputFormat(options.format, options.json)
+	if err != nil {
+		return err
+	}
+	message := sentMessage{Team: positional[0], Agent: positional[1], Text: strings.Join(positional[2:], " ")}
+	backend, err := options.backend()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	if err := backend.SendAgentMessage(ctx, message.Team, message.Agent, message.Text); err != nil {
+		return err
+	}
+	return writeItem(stdout, format, message, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Sent to %s/%s\n", message.Team, message.Agent)
+		return err
+	})
+}
+
+func runTailCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, options := newCLIFlagSet("tail", stdout, false)
+	count := fs.Int("n", 20, "Events to print first")
+	follow := fs.Bool("f", false, "Keep printing new events until interrupted")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("usage: agent-team-monitor tail TEAM AGENT [-n 20] [-f]")
+	}
+	format, err := parseOutputFormat(options.format, options.json)
+	if err != nil {
+		return err
+	}
+	if *follow && format == formatJSON {
+		// A JSON array never ends while following.
+		format = formatNDJSON
+	}
+	teamName, agentName := positional[0], positional[1]
+	backend, err := options.backend()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	seen := map[string]struct{}{}
+	// fresh returns the agent's events not printed yet, oldest first.
+	fresh := func(state types.MonitorState) ([]types.AgentEvent, error) {
+		agent, ok := findStateAgent(state, teamName, agentName)
+		if !ok {
+			return nil, fmt.Errorf("agent %q not found in team %q", agentName, teamName)
+		}
+		var events []types.AgentEvent
+		for i := len(agent.RecentEvents) - 1; i >= 0; i-- {
+			event := agent.RecentEvents[i]
+			key := event.Timestamp.Format(time.RFC3339Nano) + "\x00" + event.Kind + "\x00" + event.Text
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			events = append(events, event)
+		}
+		return events, nil
+	}
+
+	if !*follow {
+		state, err := backend.State(ctx)
+		if err != nil {
+			return err
+		}
+		events, err := fresh(state)
+		if err != nil {
+			return err
+		}
+		return writeEvents(stdout, format, lastEvents(events, *count))
+	}
+
+	first := true
+	err = backend.Watch(ctx, func(state types.MonitorState) error {
+		events, err := fresh(state)
+		if err != nil {
+			return err
+		}
+		if first {
+			events, first = lastEvents(events, *count), false
+		}
+		return writeEvents(stdout, format, events)
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func findStateAgent(state types.MonitorState, team, agent string) (types.AgentInfo, bool) {
+	for _, candidate := range state.Teams {
+		if !strings.EqualFold(candidate.Name, team) {
+			continue
+		}
+		for _, member := range candidate.Members {
+			if strings.EqualFold(member.Name, agent) || member.AgentID == agent {
+				return member, true
+			}
+		}
+	}
+	return types.AgentInfo{}, false
+}
+
+func lastEvents(events []types.AgentEvent, n int) []types.AgentEvent {
+	if n >= 0 && len(events) > n {
+		return events[len(events)-n:]
+	}
+	return events
+}
+
+// writeEvents prints tail output; table lines are written one by one so
+// followed output is not held back for alignment.
+func writeEvents(w io.Writer, format outputFormat, events []types.AgentEvent) error {
+	if format != formatTable {
+		return writeList(w, format, events, nil, nil)
+	}
+	for _, event := range events {
+		label := event.Kind
+		if event.Title != "" {
+			label += " " + cell(event.Title, 40)
+		}
+		if _, err := fmt.Fprintf(w, "%s  [%s]  %s\n", formatCLITime(event.Timestamp), label, cell(event.Text, 200)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runManagedCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing managed subcommand\n%s", cliUsage)
+	}
+	action := args[0]
+	fs, options := newCLIFlagSet("managed "+action, stdout, false)
+	var (
+		name, workspace, agents, model, permission string
+		agentID                                    string
+		lines                                      int
+	)
+	switch action {
+	case "create":
+		fs.StringVar(&name, "name", "", "Team name")
+		fs.StringVar(&workspace, "workspace", "", "Workspace directory (default: current directory)")
+		fs.StringVar(&agents, "agents", "", "Comma-separated agent names (default: one lead)")
+		fs.StringVar(&model, "model", "", "Model for every agent")
+		fs.StringVar(&permission, "permission", "", "Permission mode for every agent")
+	case "logs":
+		fs.StringVar(&agentID, "agent", "", "Agent id (default: the team's primary agent)")
+		fs.IntVar(&lines, "n", 200, "Trailing lines to print")
+	case "list", "start", "stop":
+	default:
+		return fmt.Errorf("unknown managed subcommand %q\n%s", action, cliUsage)
+	}
+	positional, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	wantArgs := 1
+	if action == "list" || action == "create" {
+		wantArgs = 0
+	}
+	if len(positional) != wantArgs {
+		return fmt.Errorf("wrong number of arguments for managed %s\n%s", action, cliUsage)
+	}
+	format, err := parseOutputFormat(options.format, options.json)
+	if err != nil {
+		return err
+	}
+	backend, err := options.backend()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	switch action {
+	case "list":
+		teams, err := backend.ManagedTeams(ctx)
+		if err != nil {
+			return err
+		}
+		return writeList(stdout, format, teams, []string{"ID", "NAME", "PROVIDER", "STATUS", "AGENTS", "WORKSPACE"}, func(team managed.ManagedTeam) []string {
+			return []string{team.Spec.ID, team.Spec.Name, team.Spec.Provider, managedStatus(team.Run), strconv.Itoa(len(team.Spec.Agents)), team.Spec.Workspace}
+		})
+	case "create":
+		if workspace == "" {
+			if workspace, err = os.Getwd(); err != nil {
+				return err
+			}
+		}
+		input := managed.CreateTeamInput{
+			Name:       name,
+			Provider:   firstNonEmpty(options.provider, "claude"),
+			Workspace:  workspace,
+			Model:      model,
+			Permission: permission,
+		}
+		for _, agent := range splitList(agents) {
+			input.Agents = append(input.Agents, managed.AgentInput{Name: agent, Provider: input.Provider, Model: model, Permission: permission})
+		}
+		spec, err := backend.CreateManagedTeam(ctx, input)
+		if err != nil {
+			return err
+		}
+		return writeItem(stdout, format, spec, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "Created managed team %s (%s) with %d agents\n", spec.ID, spec.Name, len(spec.Agents))
+			return err
+		})
+	}
+
+	team, err := resolveManagedTeam(ctx, backend, positional[0])
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "logs":
+		tail, err := backend.ManagedLogs(ctx, team.Spec.ID, agentID, lines)
+		if err != nil {
+			return err
+		}
+		if format == formatNDJSON {
+			return writeList(stdout, format, tail.Lines, nil, nil)
+		}
+		return writeItem(stdout, format, tail, func(w io.Writer) error {
+			for _, line := range tail.Lines {
+				if _, err := fmt.Fprintln(w, line); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	default:
+		control := backend.StartTeam
+		if action == "stop" {
+			control = backend.StopTeam
+		}
+		run, err := control(ctx, team.Spec.ID)
+		if err != nil {
+			return err
+		}
+		return writeItem(stdout, format, run, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "Managed team %s (%s) is %s\n", team.Spec.ID, team.Spec.Name, run.Status)
+			return err
+		})
+	}
+}
+
+// resolveManagedTeam finds a managed team by id, or by name ignoring case.
+func resolveManagedTeam(ctx context.Context, backend cliBackend, ref string) (managed.ManagedTeam, error) {
+	teams, err := backend.ManagedTeams(ctx)
+	if err != nil {
+		return managed.ManagedTeam{}, err
+	}
+	for _, team := range teams {
+		if team.Spec.ID == ref {
+			return team, nil
+		}
+	}
+	for _, team := range teams {
+		if strings.EqualFold(team.Spec.Name, ref) {
+			return team, nil
+		}
+	}
+	return managed.ManagedTeam{}, fmt.Errorf("managed team %q not found", ref)
+}
+
+func managedStatus(run *managed.RunState) string {
+	if run == nil {
+		return string(managed.RunStatusStopped)
+	}
+	return string(run.Status)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func runReportCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, options := newCLIFlagSet("report", stdout, false)
+	formatFlag := fs.Lookup("format")
+	formatFlag.Usage, formatFlag.DefValue = "markdown, html or json", string(report.FormatMarkdown)
+	_ = formatFlag.Value.Set(formatFlag.DefValue)
+	fs.StringVar(&options.team, "team", "", "Only this team")
+	output := fs.String("o", "", "Write the report to this file instead of stdout")
+	from := fs.String("from", "", `Render a saved "state --json" snapshot ("-" for stdin) instead of collecting`)
+	events := fs.Int("events", report.DefaultEvents, "Recent events per agent, 0 for none")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("report takes no arguments\n%s", cliUsage)
+	}
+	format := report.FormatJSON
+	if !options.json {
+		if format, err = report.ParseFormat(options.format); err != nil {
+			return err
+		}
+	}
+
+	var state types.MonitorState
+	if *from != "" {
+		if state, err = readStateFile(*from); err != nil {
+			return err
+		}
+	} else {
+		backend, err := options.backend()
+		if err != nil {
+			return err
+		}
+		state, err = backend.State(ctx)
+		backend.Close()
+		if err != nil {
+			return err
+		}
+	}
+	teams := state.Teams[:0:0]
+	for _, team := range state.Teams {
+		if options.matchTeam(team) {
+			teams = append(teams, team)
+		}
+	}
+	state.Teams = teams
+
+	eventLimit := *events
+	if eventLimit == 0 {
+		eventLimit = -1
+	}
+	built, err := report.Build(state, report.Options{Team: options.team, Events: eventLimit})
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return report.Render(stdout, format, built)
+	}
+	var buf bytes.Buffer
+	if err := report.Render(&buf, format, built); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "Wrote %s report to %s\n", format, *output)
+	return err
+}
+
+// readStateFile decodes a MonitorState saved by "state --json" or fetched
+// from /api/state; "-" reads stdin.
+func readStateFile(path string) (types.MonitorState, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return types.MonitorState{}, err
+	}
+	var state types.MonitorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return types.MonitorState{}, fmt.Errorf("decode state snapshot %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// runMCPCommand serves MCP on stdin and stdout until stdin closes. Against
+// a server, the server checks the token's permissions; locally, the same
+// token and role rules are applied here.
+func runMCPCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	fs, options := newCLIFlagSet("mcp", stdout, false)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("mcp takes no arguments\n%s", cliUsage)
+	}
+
+	var (
+		authorize func(api.Permission) error
+		recorder  *mcpAuditRecorder
+	)
+	if options.url == "" {
+		cfg, _, err := agentapp.LoadConfig()
+		if err != nil {
+			return err
+		}
+		auth, err := agentapp.NewAuthManager(cfg)
+		if err != nil {
+			return err
+		}
+		principal, err := auth.IdentifyToken(options.token)
+		if err != nil {
+			return err
+		}
+		auditConfig, err := audit.LoadConfigFromEnv()
+		if err != nil {
+			return fmt.Errorf("load audit config: %w", err)
+		}
+		var auditLog *audit.Logger
+		if auditConfig.Enabled() {
+			if auditLog, err = audit.Open(auditConfig); err != nil {
+				return fmt.Errorf("open audit log: %w", err)
+			}
+			defer auditLog.Close()
+		}
+		recorder = &mcpAuditRecorder{log: auditLog, principal: principal}
+		authorize = func(permission api.Permission) error {
+			err := auth.CheckPermission(principal, permission)
+			if err != nil && permission == api.PermissionMessage {
+				recorder.record(audit.Entry{Action: audit.ActionAgentMessage, Result: audit.ResultDenied}, err)
+			}
+			return err
+		}
+	}
+	backend, err := options.backend()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	var mcpBackend mcp.Backend = backend
+	if recorder != nil {
+		mcpBackend = auditedMCPBackend{Backend: backend, recorder: recorder}
+	}
+	server := mcp.NewServer(mcpBackend, mcp.Options{Version: appVersion, Authorize: authorize})
+	return server.Serve(ctx, os.Stdin, stdout)
+}
+
+// mcpAuditRecorder appends entries for a local MCP server under the
+// principal of its -token, as the HTTP API does for its callers.
+type mcpAuditRecorder struct {
+	log       *audit.Logger
+	principal api.Principal
+}
+
+func (r *mcpAuditRecorder) record(entry audit.Entry, err error) {
+	entry.Actor = r.principal.Name()
+	entry.Role = string(r.principal.Role)
+	entry.Client = audit.ClientMCP
+	if err != nil {
+		entry.Error = err.Error()
+		if entry.Result == "" {
+			entry.Result = audit.ResultError
+		}
+	}
+	if recordErr := r.log.Record(entry); recordErr != nil {
+		log.Printf("Error writing audit log: %v", recordErr)
+	}
+}
+
+// auditedMCPBackend records the messages a local MCP server sends; with
+// -url the server it talks to records them.
+type auditedMCPBackend struct {
+	mcp.Backend
+	recorder *mcpAuditRecorder
+}
+
+func (b auditedMCPBackend) SendAgentMessage(ctx context.Context, team, agent, text string) error {
+	err := b.Backend.SendAgentMessage(ctx, team, agent, text)
+	b.recorder.record(audit.Entry{Action: audit.ActionAgentMessage, Team: team, Agent: agent, Detail: audit.Summarize(text)}, err)
+	return err
+}
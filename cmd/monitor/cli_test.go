@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/audit"
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// newCLITestServer serves a real api.Server with a managed team store and
+// returns its URL, an admin token and a workspace directory.
+func newCLITestServer(t *testing.T) (string, string, string) {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("ATM_TOKENS_FILE", filepath.Join(root, "api-tokens.json"))
+	t.Setenv("ATM_MANAGED_DIR", filepath.Join(root, "managed"))
+	t.Setenv("ATM_URL", "")
+	t.Setenv("ATM_TOKEN", "")
+	workspace := filepath.Join(root, "workspace")
+	if err := os.MkdirAll(workspace, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := managed.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	auth := api.NewAuthManagerFromEnv()
+	_, secret, err := auth.Tokens().Create("cli", []string{"admin"}, 0)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	server := api.NewServer(nil, ":0", fstest.MapFS{"index.html": {Data: []byte("ok")}}, auth, manager)
+	security := api.DefaultSecurityConfig()
+	security.AllowedHosts = []string{"*"}
+	server.SetSecurity(security)
+
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return ts.URL, secret, workspace
+}
+
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := runCLICommand(context.Background(), args[0], args[1:], &out)
+	return out.String(), err
+}
+
+func TestCLIAgainstServer(t *testing.T) {
+	url, token, workspace := newCLITestServer(t)
+	remote := []string{"-url", url, "-token", token}
+
+	out, err := runCLI(t, append([]string{"managed", "create", "-name", "Script Crew", "-workspace", workspace, "-agents", "lead,reviewer", "-json"}, remote...)...)
+	if err != nil {
+		t.Fatalf("managed create: %v", err)
+	}
+	var spec managed.TeamSpec
+	if err := json.Unmarshal([]byte(out), &spec); err != nil || spec.ID == "" || len(spec.Agents) != 2 {
+		t.Fatalf("unexpected create output %q (%v)", out, err)
+	}
+
+	out, err = runCLI(t, append([]string{"teams"}, remote...)...)
+	if err != nil || !strings.Contains(out, "NAME") || !strings.Contains(out, "Script Crew") {
+		t.Fatalf("teams table: %q %v", out, err)
+	}
+	if out, err := runCLI(t, append([]string{"teams", "-provider", "codex", "-json"}, remote...)...); err != nil || strings.TrimSpace(out) != "[]" {
+		t.Fatalf("expected the provider filter to leave an empty array, got %q %v", out, err)
+	}
+
+	out, err = runCLI(t, append([]string{"agents", "-team", "script crew", "-format", "ndjson"}, remote...)...)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if err != nil || len(lines) != 2 {
+		t.Fatalf("agents ndjson: %q %v", out, err)
+	}
+	var agent cliAgent
+	if err := json.Unmarshal([]byte(lines[1]), &agent); err != nil || agent.Team != "Script Crew" || agent.Name != "reviewer" {
+		t.Fatalf("unexpected agent line %q (%v)", lines[1], err)
+	}
+
+	// Go flags accept --json, so "state --json" prints the whole state.
+	out, err = runCLI(t, append([]string{"state", "--json"}, remote...)...)
+	var state types.MonitorState
+	if err != nil || json.Unmarshal([]byte(out), &state) != nil || len(state.Teams) != 1 || state.Version == 0 {
+		t.Fatalf("state --json: %q %v", out, err)
+	}
+
+	out, err = runCLI(t, append([]string{"managed", "logs", "SCRIPT CREW", "-agent", "reviewer", "-json"}, remote...)...)
+	var tail managed.LogTail
+	if err != nil || json.Unmarshal([]byte(out), &tail) != nil || tail.TeamID != spec.ID || tail.AgentID != "reviewer" {
+		t.Fatalf("managed logs: %q %v", out, err)
+	}
+
+	if _, err := runCLI(t, append([]string{"managed", "stop", spec.ID}, remote...)...); err == nil {
+		t.Fatal("expected stopping a stopped team to fail")
+	}
+	if _, err := runCLI(t, append([]string{"managed", "start", "ghost"}, remote...)...); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected an unknown team to fail, got %v", err)
+	}
+
+	out, err = runCLI(t, append([]string{"tail", "Script Crew", "lead", "-n", "5"}, remote...)...)
+	if err != nil || out != "" {
+		t.Fatalf("expected an agent without events to print nothing, got %q %v", out, err)
+	}
+	if _, err := runCLI(t, append([]string{"tail", "Script Crew", "ghost"}, remote...)...); err == nil {
+		t.Fatal("expected tail of an unknown agent to fail")
+	}
+
+	// Without a token the server refuses to create teams.
+	if _, err := runCLI(t, "managed", "create", "-name", "Anon", "-workspace", workspace, "-url", url); err == nil {
+		t.Fatal("expected an anonymous create to be refused")
+	}
+}
+
+func TestCLILocalManagedControlNeedsServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ATM_MANAGED_DIR", filepath.Join(t.TempDir(), "managed"))
+	t.Setenv("ATM_URL", "")
+	workspace := t.TempDir()
+
+	if _, err := runCLI(t, "managed", "create", "-name", "Local Crew", "-workspace", workspace); err != nil {
+		t.Fatalf("local create: %v", err)
+	}
+	out, err := runCLI(t, "managed", "list")
+	if err != nil || !strings.Contains(out, "Local Crew") || !strings.Contains(out, "stopped") {
+		t.Fatalf("local list: %q %v", out, err)
+	}
+	if _, err := runCLI(t, "managed", "start", "local crew"); err != errNeedsServer {
+		t.Fatalf("expected local start to ask for a server, got %v", err)
+	}
+}
+
+func TestParseArgsAllowsFlagsAnywhere(t *testing.T) {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	url := fs.String("url", "", "")
+	positional, err := parseArgs(fs, []string{"team", "-url", "http://x", "lead", "--", "-not-a-flag"})
+	if err != nil || *url != "http://x" || strings.Join(positional, "|") != "team|lead|-not-a-flag" {
+		t.Fatalf("unexpected parse %q %q %v", positional, *url, err)
+	}
+}
+
+func TestWriteListFormats(t *testing.T) {
+	items := []sentMessage{{Team: "a", Agent: "x", Text: "hi\nthere"}, {Team: "b", Agent: "y"}}
+	row := func(m sentMessage) []string { return []string{m.Team, m.Agent, cell(m.Text, 5)} }
+
+	var table bytes.Buffer
+	if err := writeList(&table, formatTable, items, []string{"TEAM", "AGENT", "TEXT"}, row); err != nil {
+		t.Fatal(err)
+	}
+	if want := "TEAM  AGENT  TEXT\na     x      hi t…\nb     y      -\n"; table.String() != want {
+		t.Fatalf("unexpected table %q", table.String())
+	}
+
+	var ndjson bytes.Buffer
+	if err := writeList(&ndjson, formatNDJSON, items, nil, nil); err != nil || strings.Count(ndjson.String(), "\n") != 2 {
+		t.Fatalf("unexpected ndjson %q %v", ndjson.String(), err)
+	}
+	var empty bytes.Buffer
+	if err := writeList[sentMessage](&empty, formatJSON, nil, nil, nil); err != nil || strings.TrimSpace(empty.String()) != "[]" {
+		t.Fatalf("expected an empty JSON array, got %q %v", empty.String(), err)
+	}
+
+	if _, err := parseOutputFormat("yaml", false); err == nil {
+		t.Fatal("expected an unknown format to fail")
+	}
+	if format, _ := parseOutputFormat("table", true); format != formatJSON {
+		t.Fatalf("expected -json to win, got %q", format)
+	}
+}
+
+func TestReportFromSavedState(t *testing.T) {
+	dir := t.TempDir()
+	state := types.MonitorState{Version: 3, Teams: []types.TeamInfo{
+		{Name: "Docs Crew", Provider: "claude", Members: []types.AgentInfo{{Name: "writer", Status: "working"}}},
+		{Name: "Codex Crew", Provider: "codex", Members: []types.AgentInfo{{Name: "coder", Status: "idle", Provider: "codex"}}},
+	}}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(snapshot, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCLI(t, "report", "-from", snapshot, "-provider", "claude")
+	if err != nil || !strings.Contains(out, "## Docs Crew") || strings.Contains(out, "Codex Crew") {
+		t.Fatalf("markdown report: %q %v", out, err)
+	}
+
+	page := filepath.Join(dir, "report.html")
+	if out, err := runCLI(t, "report", "-from", snapshot, "-team", "codex crew", "-format", "html", "-o", page); err != nil || !strings.Contains(out, page) {
+		t.Fatalf("html report: %q %v", out, err)
+	}
+	if html, err := os.ReadFile(page); err != nil || !strings.Contains(string(html), "<h2>Codex Crew</h2>") {
+		t.Fatalf("unexpected report file %q %v", html, err)
+	}
+
+	if _, err := runCLI(t, "report", "-from", snapshot, "-team", "ghost"); err == nil {
+		t.Fatal("expected an unknown team to fail")
+	}
+}
+
+type stubMCPBackend struct{ err error }
+
+func (stubMCPBackend) State(context.Context) (types.MonitorState, error) {
+	return types.MonitorState{}, nil
+}
+
+func (b stubMCPBackend) SendAgentMessage(context.Context, string, string, string) error {
+	return b.err
+}
+
+func TestLocalMCPSendIsAudited(t *testing.T) {
+	logger, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.jsonl"), MaxSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("audit.Open: %v", err)
+	}
+	defer logger.Close()
+	recorder := &mcpAuditRecorder{log: logger, principal: api.Principal{TokenName: "agent", Permissions: []api.Permission{api.PermissionMessage}}}
+
+	backend := auditedMCPBackend{Backend: stubMCPBackend{}, recorder: recorder}
+	if err := backend.SendAgentMessage(context.Background(), "alpha", "dev", "hello"); err != nil {
+		t.Fatalf("SendAgentMessage: %v", err)
+	}
+	failing := auditedMCPBackend{Backend: stubMCPBackend{err: errors.New("inbox missing")}, recorder: recorder}
+	if err := failing.SendAgentMessage(context.Background(), "alpha", "dev", "again"); err == nil {
+		t.Fatal("expected the backend error")
+	}
+
+	entries, err := logger.Query(audit.Filter{Action: audit.ActionAgentMessage})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected both sends to be recorded, got %+v", entries)
+	}
+	failed, sent := entries[0], entries[1]
+	if sent.Actor != "token:agent" || sent.Client != audit.ClientMCP || sent.Team != "alpha" || sent.Agent != "dev" || sent.Detail != "hello" || sent.Result != audit.ResultOK {
+		t.Fatalf("unexpected entry %+v", sent)
+	}
+	if failed.Result != audit.ResultError || failed.Error != "inbox missing" {
+		t.Fatalf("unexpected failed entry %+v", failed)
+	}
+}
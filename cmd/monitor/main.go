@@ -1,32 +1,70 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	agentapp "github.com/liaoweijun/agent-team-monitor/internal/app"
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/federation"
 )
 
 var (
 	webMode    = flag.Bool("web", false, "Run in web mode (HTTP server)")
-	webAddr    = flag.String("addr", ":8080", "Web server address")
-	provider   = flag.String("provider", "both", "Data source provider: claude, codex, openclaw, both")
+	webAddr    = flag.String("addr", ":8080", "Web server address; overrides web.addr in the config file")
+	provider   = flag.String("provider", "both", "Data source provider: claude, codex, openclaw, both; overrides provider in the config file")
 	version    = flag.Bool("version", false, "Show version information")
+	hashPasswd = flag.Bool("hash-password", false, "Read a password from stdin and print an ATM_ADMIN_PASSWORD_HASH value")
+	tlsMode    = flag.Bool("tls", false, "Serve the web dashboard over HTTPS")
+	tlsCert    = flag.String("tls-cert", "", "TLS certificate file (default: generated local CA under ~/.agent-team-monitor/tls)")
+	tlsKey     = flag.String("tls-key", "", "TLS private key file, required with -tls-cert")
+	tlsHosts   = flag.String("tls-hosts", "", "Extra comma-separated host names or IPs for the generated certificate")
+	tlsRedir   = flag.String("tls-redirect", "", "Also listen on this address and redirect HTTP to HTTPS, e.g. :80")
+	readOnly   = flag.Bool("read-only", false, "Serve a read-only kiosk dashboard (implies -web); all controls are refused")
+	privacy    = flag.String("privacy", "", "Details served in read-only mode: full, standard (no thinking, tool details or responses) or strict (default: privacy.read_only_profile in the config file, else standard)")
+	kioskEvery = flag.Duration("kiosk-rotate", 30*time.Second, "How long each team stays in focus in read-only mode, 0 to disable")
+	hubMode    = flag.Bool("hub", false, "Accept state pushes from other monitors and show their teams (implies -web)")
+	pushTo     = flag.String("push-to", "", "Push this monitor's state to the hub at this URL (implies -web); the token comes from "+hubTokenEnv)
+	nodeName   = flag.String("node", "", "Name this monitor's teams carry on the hub (default: the host name)")
+	advertise  = flag.String("advertise", "", "URL the hub reaches this monitor at to relay actions (default: the local dashboard URL)")
 	appVersion = "dev"
 )
 
 const appName = "Agent Team Monitor"
 
+// hubTokenEnv holds the hub API token, created there with the federate scope.
+const hubTokenEnv = "ATM_HUB_TOKEN"
+
 func main() {
 	if err := agentapp.LoadEnvFromExecutableDir(); err != nil {
 		log.Fatalf("Error loading .env from executable directory: %v", err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		if err := runTokenCommand(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && isCLICommand(os.Args[1]) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runCLICommand(ctx, os.Args[1], os.Args[2:], os.Stdout)
+		stop()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
 	flag.Parse()
 
 	if *version {
@@ -34,10 +72,15 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *hashPasswd {
+		runHashPassword()
+		return
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	if *webMode {
+	if *webMode || *readOnly || *hubMode || *pushTo != "" {
 		runWebMode(ctx)
 		return
 	}
@@ -45,20 +88,87 @@ func main() {
 	runTUIMode(ctx)
 }
 
+func runHashPassword() {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("Error reading password from stdin: %v", err)
+	}
+	hash, err := api.HashPassword(strings.TrimRight(line, "\r\n"))
+	if err != nil {
+		log.Fatalf("Error hashing password: %v", err)
+	}
+	fmt.Println(hash)
+}
+
 func runTUIMode(ctx context.Context) {
-	if err := agentapp.RunTUI(ctx, *provider); err != nil {
+	if err := agentapp.RunTUI(ctx, ifFlagSet("provider", *provider)); err != nil {
 		log.Fatalf("Error running TUI: %v", err)
 	}
 }
 
 func runWebMode(ctx context.Context) {
-	session, err := agentapp.StartWeb(*provider, *webAddr)
+	var readOnlyOptions *api.ReadOnlyOptions
+	if *readOnly {
+		readOnlyOptions = &api.ReadOnlyOptions{Rotate: *kioskEvery}
+		if *privacy != "" {
+			profile, err := api.ParsePrivacyProfile(*privacy)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			readOnlyOptions.Privacy = profile
+		}
+	}
+
+	var push *federation.AgentOptions
+	if *pushTo != "" {
+		node := *nodeName
+		if node == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				log.Fatalf("Error: -node is required: %v", err)
+			}
+			node = strings.SplitN(hostname, ".", 2)[0]
+		}
+		push = &federation.AgentOptions{
+			HubURL: *pushTo,
+			Token:  strings.TrimSpace(os.Getenv(hubTokenEnv)),
+			Node:   node,
+			URL:    *advertise,
+		}
+	}
+
+	session, err := agentapp.StartWebWithOptions(agentapp.WebOptions{
+		Provider: ifFlagSet("provider", *provider),
+		Addr:     ifFlagSet("addr", *webAddr),
+		TLS: api.TLSOptions{
+			Enabled:      *tlsMode,
+			CertFile:     *tlsCert,
+			KeyFile:      *tlsKey,
+			Hosts:        splitList(*tlsHosts),
+			RedirectAddr: *tlsRedir,
+		},
+		ReadOnly: readOnlyOptions,
+		Hub:      *hubMode,
+		Push:     push,
+	})
 	if err != nil {
 		log.Fatalf("Error starting web server: %v", err)
 	}
 	defer session.Stop()
 
 	fmt.Printf("Web dashboard available at %s\n", session.BaseURL)
+	if *hubMode {
+		fmt.Println("Federation hub: accepting pushes at /api/federation/push")
+	}
+	if push != nil {
+		fmt.Printf("Pushing state to %s as node %s\n", push.HubURL, push.Node)
+	}
+	if readOnlyOptions != nil {
+		fmt.Printf("Read-only mode: controls disabled, privacy profile %s\n", session.Server.PrivacyProfile())
+	}
+	if session.CAFile != "" {
+		fmt.Printf("Trust the local CA %s in your browser to avoid certificate warnings\n", session.CAFile)
+	}
 	fmt.Println("Press Ctrl+C to stop")
 
 	<-ctx.Done()
@@ -67,3 +177,26 @@ func runWebMode(ctx context.Context) {
 		log.Printf("Error stopping server: %v", err)
 	}
 }
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ifFlagSet returns value when the flag was given on the command line, and
+// "" otherwise so the config file applies.
+func ifFlagSet(name, value string) string {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	if !set {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// outputFormat selects how CLI subcommands print results.
+type outputFormat string
+
+const (
+	formatTable  outputFormat = "table"
+	formatJSON   outputFormat = "json"
+	formatNDJSON outputFormat = "ndjson"
+)
+
+func parseOutputFormat(raw string, jsonShortcut bool) (outputFormat, error) {
+	if jsonShortcut {
+		return formatJSON, nil
+	}
+	switch format := outputFormat(strings.ToLower(strings.TrimSpace(raw))); format {
+	case "":
+		return formatTable, nil
+	case formatTable, formatJSON, formatNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (want table, json or ndjson)", raw)
+	}
+}
+
+// writeList prints items as an aligned table, one indented JSON array, or
+// one JSON object per line.
+func writeList[T any](w io.Writer, format outputFormat, items []T, header []string, row func(T) []string) error {
+	switch format {
+	case formatJSON:
+		if items == nil {
+			items = []T{}
+		}
+		return writeJSON(w, items)
+	case formatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, item := range items {
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, item := range items {
+		fmt.Fprintln(tw, strings.Join(row(item), "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeItem prints one result; table output is left to the caller's text
+// function.
+func writeItem(w io.Writer, format outputFormat, item any, text func(io.Writer) error) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, item)
+	case formatNDJSON:
+		return json.NewEncoder(w).Encode(item)
+	}
+	return text(w)
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// cell keeps table columns on one line and readable.
+func cell(value string, limit int) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return "-"
+	}
+	if limit > 0 {
+		if runes := []rune(value); len(runes) > limit {
+			return string(runes[:limit-1]) + "…"
+		}
+	}
+	return value
+}
+
+func formatCLITime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+)
+
+const tokenUsage = `Usage:
+  agent-team-monitor token create -name NAME -scopes read,message[,...] [-expires 720h]
+  agent-team-monitor token list
+  agent-team-monitor token revoke ID
+
+Scopes: read, message, tasks:write, teams:delete, managed:control, federate, admin
+`
+
+// runTokenCommand manages API tokens directly in the token file, so it works
+// whether or not a web server is running.
+func runTokenCommand(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing token subcommand\n%s", tokenUsage)
+	}
+
+	path, err := api.DefaultTokenStorePath()
+	if err != nil {
+		return err
+	}
+	store := api.NewTokenStore(path)
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+		fs.SetOutput(stdout)
+		name := fs.String("name", "", "Token name, e.g. ci")
+		scopes := fs.String("scopes", "", "Comma-separated scopes")
+		expires := fs.Duration("expires", 0, "Token lifetime (0 = never expires)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		token, secret, err := store.Create(*name, []string{*scopes}, *expires)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Created token %s (%s) with scopes %s\n", token.ID, token.Name, formatScopes(token.Scopes))
+		if !token.ExpiresAt.IsZero() {
+			fmt.Fprintf(stdout, "Expires at %s\n", token.ExpiresAt.Format(time.RFC3339))
+		}
+		fmt.Fprintln(stdout, "Store this secret now; it cannot be shown again:")
+		fmt.Fprintln(stdout, secret)
+		return nil
+	case "list":
+		tokens, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			fmt.Fprintf(stdout, "No API tokens in %s\n", store.Path())
+			return nil
+		}
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSCOPES\tPREFIX\tCREATED\tEXPIRES\tLAST USED")
+		for _, token := range tokens {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				token.ID,
+				token.Name,
+				formatScopes(token.Scopes),
+				token.Prefix+"...",
+				formatTokenTime(token.CreatedAt, "-"),
+				formatTokenTime(token.ExpiresAt, "never"),
+				formatTokenTime(token.LastUsedAt, "never"),
+			)
+		}
+		return tw.Flush()
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: agent-team-monitor token revoke ID")
+		}
+		token, err := store.Revoke(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Revoked token %s (%s)\n", token.ID, token.Name)
+		return nil
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, tokenUsage)
+		return nil
+	default:
+		return fmt.Errorf("unknown token subcommand %q\n%s", args[0], tokenUsage)
+	}
+}
+
+func formatTokenTime(t time.Time, empty string) string {
+	if t.IsZero() {
+		return empty
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func formatScopes(scopes []api.Permission) string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+	return strings.Join(names, ",")
+}
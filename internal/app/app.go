@@ -5,13 +5,21 @@ import (
 	"fmt"
 	"io/fs"
 	"net"
+	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/audit"
+	"github.com/liaoweijun/agent-team-monitor/pkg/config"
+	"github.com/liaoweijun/agent-team-monitor/pkg/federation"
 	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
 	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/trace"
 	"github.com/liaoweijun/agent-team-monitor/pkg/ui"
+	"github.com/liaoweijun/agent-team-monitor/pkg/webhook"
 	"github.com/liaoweijun/agent-team-monitor/web"
 )
 
@@ -33,21 +41,46 @@ type WebSession struct {
 	Server    *api.Server
 	Auth      *api.AuthManager
 	Managed   *managed.Manager
+	Webhooks  *webhook.Dispatcher
+	Audit     *audit.Logger
 	Addr      string
 	BaseURL   string
-
-	stopOnce sync.Once
+	CAFile    string // generated local CA when TLS uses the built-in certificate
+
+	redirect     *http.Server
+	watchCtx     context.Context
+	stopWatchers context.CancelFunc
+	stopOnce     sync.Once
+	hub          *federation.Hub // nil unless the session is a federation hub
+
+	// Reload state: what the config file is layered over.
+	configMu       sync.Mutex
+	provider       string
+	configAddr     string
+	baseSecurity   api.SecurityConfig
+	boundHosts     []string
+	baseSessionTTL time.Duration
+	webhookConfig  webhook.Config
+	stopWebhooks   context.CancelFunc
+	basePrivacy    api.PrivacyProfile // from -privacy; empty defers to the file
 }
 
+// defaultWebAddr is used when neither the caller nor the config file names
+// a listen address.
+const defaultWebAddr = ":8080"
+
+// StartCollector starts a collector configured by the config file; a
+// non-empty provider overrides the file.
 func StartCollector(provider string) (*monitor.Collector, error) {
-	providerMode, err := monitor.ParseProviderMode(provider)
+	cfg, _, err := LoadConfig()
 	if err != nil {
-		return nil, fmt.Errorf("invalid provider mode: %w", err)
+		return nil, err
 	}
+	return startCollector(provider, cfg)
+}
 
-	collector, err := monitor.NewCollectorWithOptions(monitor.CollectorOptions{
-		Provider: providerMode,
-	})
+func startCollector(provider string, cfg config.Config) (*monitor.Collector, error) {
+	collector, err := newCollector(provider, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -59,11 +92,48 @@ func StartCollector(provider string) (*monitor.Collector, error) {
 	return collector, nil
 }
 
+// CollectOnce returns a collector holding one snapshot of local state,
+// with no watchers running. Callers Refresh it for newer snapshots and
+// Stop it when done.
+func CollectOnce(provider string, cfg config.Config) (*monitor.Collector, error) {
+	collector, err := newCollector(provider, cfg)
+	if err != nil {
+		return nil, err
+	}
+	collector.Refresh()
+	return collector, nil
+}
+
+func newCollector(provider string, cfg config.Config) (*monitor.Collector, error) {
+	settings, err := collectorSettings(cfg, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return monitor.NewCollectorWithOptions(monitor.CollectorOptions{
+		Settings: &settings,
+		Redactor: redactor,
+	})
+}
+
+// RunTUI runs the terminal UI, applying config file changes while it runs.
 func RunTUI(ctx context.Context, provider string) error {
-	collector, err := StartCollector(provider)
+	cfg, configPath, err := LoadConfig()
 	if err != nil {
 		return err
 	}
+	collector, err := startCollector(provider, cfg)
+	if err != nil {
+		return err
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watchCollectorConfig(watchCtx, configPath, collector, provider)
 
 	return runTUIWithCollector(ctx, collector, collector.Stop, ui.RunWithContext)
 }
@@ -80,8 +150,33 @@ func runTUIWithCollector(ctx context.Context, collector *monitor.Collector, stop
 	return run(ctx, collector)
 }
 
+// WebOptions configures StartWebWithOptions.
+type WebOptions struct {
+	// Provider and Addr, when empty, come from the config file.
+	Provider string
+	Addr     string
+	TLS      api.TLSOptions
+	// ReadOnly, when set, serves a kiosk dashboard with every control
+	// refused. An empty privacy profile comes from the config file.
+	ReadOnly *api.ReadOnlyOptions
+	// Hub accepts state pushes from federated nodes and merges their teams
+	// into its own.
+	Hub bool
+	// Push, when set, pushes this instance's state to a hub. An empty URL
+	// advertises the local base URL.
+	Push *federation.AgentOptions
+}
+
 func StartWeb(provider, requestedAddr string) (*WebSession, error) {
-	collector, err := StartCollector(provider)
+	return StartWebWithOptions(WebOptions{Provider: provider, Addr: requestedAddr})
+}
+
+func StartWebWithOptions(opts WebOptions) (*WebSession, error) {
+	cfg, configPath, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	collector, err := startCollector(opts.Provider, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -92,33 +187,173 @@ func StartWeb(provider, requestedAddr string) (*WebSession, error) {
 		return nil, fmt.Errorf("load embedded static files: %w", err)
 	}
 
-	resolvedAddr, err := resolveWebAddr(requestedAddr)
+	resolvedAddr, err := resolveWebAddr(firstNonEmpty(opts.Addr, cfg.Web.Addr, defaultWebAddr))
 	if err != nil {
 		collector.Stop()
 		return nil, err
 	}
 
+	baseSecurity, err := api.LoadSecurityConfigFromEnv()
+	if err != nil {
+		collector.Stop()
+		return nil, fmt.Errorf("load security config: %w", err)
+	}
+	// Names the server was explicitly bound to or issued a certificate for
+	// are trusted Host headers.
+	var boundHosts []string
+	if host, _, err := net.SplitHostPort(resolvedAddr); err == nil && host != "" {
+		boundHosts = append(boundHosts, host)
+	}
+	boundHosts = append(boundHosts, opts.TLS.Hosts...)
+	if opts.Push != nil {
+		if advertised, err := url.Parse(opts.Push.URL); err == nil && advertised.Hostname() != "" {
+			boundHosts = append(boundHosts, advertised.Hostname())
+		}
+	}
+
+	var localCert api.LocalCertificate
+	if opts.TLS.Enabled {
+		if host, _, err := net.SplitHostPort(resolvedAddr); err == nil {
+			opts.TLS.Hosts = append(opts.TLS.Hosts, host)
+		}
+		if localCert, err = api.LoadTLSConfig(opts.TLS); err != nil {
+			collector.Stop()
+			return nil, fmt.Errorf("init TLS: %w", err)
+		}
+	}
+
 	auth := api.NewAuthManagerFromEnv()
-	managedManager, err := managed.NewManager()
+	baseSessionTTL := auth.SessionTTL()
+	if err := applyAuthConfig(auth, cfg, baseSessionTTL); err != nil {
+		collector.Stop()
+		return nil, err
+	}
+	managedManager, err := managed.NewManagerInDir(cfg.ManagedDir())
 	if err != nil {
 		collector.Stop()
 		return nil, fmt.Errorf("init managed manager: %w", err)
 	}
+	collector.SetManagedRemedies(managedManager)
+	webhookConfig, err := webhook.LoadConfigFromEnv()
+	if err != nil {
+		collector.Stop()
+		return nil, fmt.Errorf("load webhook config: %w", err)
+	}
+	webhookConfig = cfg.WebhookConfig(webhookConfig)
+	traceConfig, err := trace.LoadConfigFromEnv()
+	if err != nil {
+		collector.Stop()
+		return nil, fmt.Errorf("load trace export config: %w", err)
+	}
+	auditConfig, err := audit.LoadConfigFromEnv()
+	if err != nil {
+		collector.Stop()
+		return nil, fmt.Errorf("load audit config: %w", err)
+	}
+	var auditLog *audit.Logger
+	if auditConfig.Enabled() {
+		if auditLog, err = audit.Open(auditConfig); err != nil {
+			collector.Stop()
+			return nil, fmt.Errorf("open audit log: %w", err)
+		}
+	}
+	var traceExporter *trace.Exporter
+	if traceConfig.Enabled() {
+		if traceExporter, err = trace.NewExporter(traceConfig); err != nil {
+			collector.Stop()
+			return nil, fmt.Errorf("init trace export: %w", err)
+		}
+	}
 	server := api.NewServer(collector, resolvedAddr, staticFS, auth, managedManager)
+	server.SetAuditLog(auditLog)
+	var hub *federation.Hub
+	if opts.Hub {
+		hub = federation.NewHub()
+		hub.SetReservedNames(func(name string) bool { return collector.Settings().HasRootLabel(name) })
+		server.SetFederation(hub)
+	}
+	session := &WebSession{
+		Collector:      collector,
+		Server:         server,
+		Auth:           auth,
+		Managed:        managedManager,
+		Audit:          auditLog,
+		CAFile:         localCert.CAFile,
+		hub:            hub,
+		provider:       opts.Provider,
+		configAddr:     cfg.Web.Addr,
+		baseSecurity:   baseSecurity,
+		boundHosts:     boundHosts,
+		baseSessionTTL: baseSessionTTL,
+	}
+	server.SetSecurity(session.securityConfig(cfg))
+	if opts.ReadOnly != nil {
+		readOnly := *opts.ReadOnly
+		session.basePrivacy = readOnly.Privacy
+		readOnly.Privacy = session.privacyProfile(cfg)
+		server.SetReadOnly(readOnly)
+	}
+	if localCert.Config != nil {
+		server.EnableTLS(localCert.Config)
+	}
 	listener, err := net.Listen("tcp", resolvedAddr)
 	if err != nil {
+		_ = auditLog.Close()
 		collector.Stop()
 		return nil, fmt.Errorf("listen on %s: %w", resolvedAddr, err)
 	}
 
 	actualAddr := listener.Addr().String()
-	session := &WebSession{
-		Collector: collector,
-		Server:    server,
-		Auth:      auth,
-		Managed:   managedManager,
-		Addr:      actualAddr,
-		BaseURL:   buildLocalhostURL(actualAddr),
+	session.Addr = actualAddr
+	session.BaseURL = buildLocalhostURL(actualAddr, server.TLSEnabled())
+
+	var pushAgent *federation.Agent
+	if opts.Push != nil {
+		pushOptions := *opts.Push
+		pushOptions.URL = firstNonEmpty(pushOptions.URL, session.BaseURL)
+		if pushAgent, err = federation.NewAgent(pushOptions, server.StateDelta); err != nil {
+			_ = listener.Close()
+			_ = auditLog.Close()
+			collector.Stop()
+			return nil, fmt.Errorf("init federation push: %w", err)
+		}
+		auth.SetHubSecret(pushAgent.Secret())
+	}
+
+	if opts.TLS.Enabled && strings.TrimSpace(opts.TLS.RedirectAddr) != "" {
+		redirectListener, err := net.Listen("tcp", strings.TrimSpace(opts.TLS.RedirectAddr))
+		if err != nil {
+			_ = listener.Close()
+			_ = auditLog.Close()
+			collector.Stop()
+			return nil, fmt.Errorf("listen on %s for HTTPS redirect: %w", opts.TLS.RedirectAddr, err)
+		}
+		_, httpsPort, _ := net.SplitHostPort(actualAddr)
+		session.redirect = &http.Server{
+			Handler:           api.NewHTTPSRedirectHandler(httpsPort),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			_ = session.redirect.Serve(redirectListener)
+		}()
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	session.watchCtx = watchCtx
+	session.stopWatchers = cancel
+	if err := session.startWebhooksLocked(webhookConfig); err != nil {
+		cancel()
+		_ = listener.Close()
+		_ = auditLog.Close()
+		collector.Stop()
+		return nil, fmt.Errorf("init webhooks: %w", err)
+	}
+	if traceExporter != nil {
+		go traceExporter.Run(watchCtx, server.State)
+	}
+	go config.NewWatcher(configPath, session.applyConfig).Run(watchCtx)
+	if pushAgent != nil {
+		go pushAgent.Run(watchCtx)
 	}
 
 	go func() {
@@ -135,12 +370,24 @@ func StartWeb(provider, requestedAddr string) (*WebSession, error) {
 func (s *WebSession) Stop() error {
 	var stopErr error
 	s.stopOnce.Do(func() {
+		if s.stopWatchers != nil {
+			s.stopWatchers()
+		}
+		s.configMu.Lock()
+		if s.Webhooks != nil {
+			_ = s.Webhooks.Close()
+		}
+		s.configMu.Unlock()
+		if s.redirect != nil {
+			_ = s.redirect.Close()
+		}
 		if s.Server != nil {
 			stopErr = s.Server.Stop()
 		}
 		if s.Collector != nil {
 			_ = s.Collector.Stop()
 		}
+		_ = s.Audit.Close()
 	})
 	return stopErr
 }
@@ -177,13 +424,18 @@ func pickRandomAddr(host string) (string, error) {
 	return net.JoinHostPort(host, "0"), nil
 }
 
-func buildLocalhostURL(addr string) string {
+func buildLocalhostURL(addr string, secure bool) string {
+	scheme := "http://"
+	if secure {
+		scheme = "https://"
+	}
+
 	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		if strings.HasPrefix(addr, ":") {
-			return "http://localhost" + addr
+			return scheme + "localhost" + addr
 		}
-		return "http://" + addr
+		return scheme + addr
 	}
 
 	normalizedHost := host
@@ -192,7 +444,16 @@ func buildLocalhostURL(addr string) string {
 		normalizedHost = "localhost"
 	}
 
-	return "http://" + net.JoinHostPort(normalizedHost, port)
+	return scheme + net.JoinHostPort(normalizedHost, port)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
 }
 
 func isServerClosed(err error) bool {
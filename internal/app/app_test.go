@@ -2,9 +2,15 @@ package app
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/config"
 	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
 )
 
@@ -44,18 +50,21 @@ func TestResolveWebAddr(t *testing.T) {
 
 func TestBuildLocalhostURL(t *testing.T) {
 	tests := []struct {
-		addr string
-		want string
+		addr   string
+		secure bool
+		want   string
 	}{
 		{addr: ":8000", want: "http://localhost:8000"},
 		{addr: "0.0.0.0:8123", want: "http://localhost:8123"},
 		{addr: "127.0.0.1:9000", want: "http://127.0.0.1:9000"},
 		{addr: "[::]:4567", want: "http://localhost:4567"},
+		{addr: "0.0.0.0:8443", secure: true, want: "https://localhost:8443"},
+		{addr: ":8443", secure: true, want: "https://localhost:8443"},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.addr, func(t *testing.T) {
-			got := buildLocalhostURL(tc.addr)
+			got := buildLocalhostURL(tc.addr, tc.secure)
 			if got != tc.want {
 				t.Fatalf("expected %q, got %q", tc.want, got)
 			}
@@ -111,3 +120,97 @@ func TestRunTUIWithCollector_ReturnsAfterContextCancellation(t *testing.T) {
 		t.Fatalf("expected stop to be called once, got %d", stopped)
 	}
 }
+
+func TestWebSessionAppliesReloadedConfig(t *testing.T) {
+	home := t.TempDir()
+	configPath := filepath.Join(home, "config.yaml")
+	t.Setenv("HOME", home)
+	t.Setenv("ATM_CONFIG", configPath)
+	t.Setenv("ATM_MANAGED_DIR", "")
+	t.Setenv("ATM_SESSION_TTL", "")
+	if err := os.WriteFile(configPath, []byte("provider: claude\nweb:\n  allowed_hosts: [first.example.com]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := StartWebWithOptions(WebOptions{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("StartWebWithOptions: %v", err)
+	}
+	defer session.Stop()
+	if got := session.Collector.Settings().Provider; got != monitor.ProviderClaude {
+		t.Fatalf("expected the provider from the file, got %q", got)
+	}
+
+	cfg, err := config.Parse([]byte(`
+provider: codex
+thresholds:
+  poll_interval: 1s
+auth:
+  session_ttl: 90m
+alerts:
+  webhooks:
+    - url: http://127.0.0.1:1/hook
+web:
+  allowed_hosts: [second.example.com]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.applyConfig(cfg)
+
+	if settings := session.Collector.Settings(); settings.Provider != monitor.ProviderCodex || settings.PollInterval != time.Second {
+		t.Fatalf("expected the collector to switch, got %+v", settings)
+	}
+	if ttl := session.Auth.SessionTTL(); ttl != 90*time.Minute {
+		t.Fatalf("expected the new session TTL, got %s", ttl)
+	}
+	if session.Webhooks == nil || session.Webhooks.Endpoints() != 1 {
+		t.Fatal("expected webhooks to start")
+	}
+
+	for host, want := range map[string]bool{"second.example.com": true, "first.example.com": false} {
+		req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+		req.Host = host
+		res := httptest.NewRecorder()
+		session.Server.Handler().ServeHTTP(res, req)
+		if accepted := res.Code != http.StatusMisdirectedRequest; accepted != want {
+			t.Fatalf("host %s: expected accepted=%v, got status %d", host, want, res.Code)
+		}
+	}
+
+	session.applyConfig(config.Config{})
+	if session.Webhooks != nil || session.Auth.SessionTTL() != 12*time.Hour {
+		t.Fatalf("expected an empty config to restore the defaults, got %v %s", session.Webhooks, session.Auth.SessionTTL())
+	}
+}
+
+func TestWebSessionReloadsPrivacyProfile(t *testing.T) {
+	home := t.TempDir()
+	configPath := filepath.Join(home, "config.yaml")
+	t.Setenv("HOME", home)
+	t.Setenv("ATM_CONFIG", configPath)
+	t.Setenv("ATM_MANAGED_DIR", "")
+	if err := os.WriteFile(configPath, []byte("privacy:\n  read_only_profile: strict\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := StartWebWithOptions(WebOptions{Addr: "127.0.0.1:0", ReadOnly: &api.ReadOnlyOptions{}})
+	if err != nil {
+		t.Fatalf("StartWebWithOptions: %v", err)
+	}
+	defer session.Stop()
+	if got := session.Server.PrivacyProfile(); got != api.PrivacyStrict {
+		t.Fatalf("expected the profile from the file, got %q", got)
+	}
+
+	session.applyConfig(config.Config{Privacy: config.Privacy{ReadOnlyProfile: "full"}})
+	if got := session.Server.PrivacyProfile(); got != api.PrivacyFull {
+		t.Fatalf("expected the reloaded profile, got %q", got)
+	}
+
+	session.basePrivacy = api.PrivacyStandard
+	session.applyConfig(config.Config{Privacy: config.Privacy{ReadOnlyProfile: "strict"}})
+	if got := session.Server.PrivacyProfile(); got != api.PrivacyStandard {
+		t.Fatalf("expected -privacy to win over the file, got %q", got)
+	}
+}
@@ -0,0 +1,215 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/config"
+	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/redact"
+	"github.com/liaoweijun/agent-team-monitor/pkg/webhook"
+)
+
+// LoadConfig reads the config file at config.DefaultPath and returns it
+// with its path.
+func LoadConfig() (config.Config, string, error) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return config.Config{}, "", err
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return config.Config{}, "", err
+	}
+	return cfg, path, nil
+}
+
+// NewAuthManager builds the auth manager from the environment and the
+// auth section of cfg.
+func NewAuthManager(cfg config.Config) (*api.AuthManager, error) {
+	auth := api.NewAuthManagerFromEnv()
+	if err := applyAuthConfig(auth, cfg, auth.SessionTTL()); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// applyAuthConfig loads the users file and sets the session lifetime,
+// falling back to baseTTL from the environment.
+func applyAuthConfig(auth *api.AuthManager, cfg config.Config, baseTTL time.Duration) error {
+	users, err := cfg.UsersConfig()
+	if err != nil {
+		return fmt.Errorf("load users config: %w", err)
+	}
+	if err := auth.LoadUsers(users); err != nil {
+		return fmt.Errorf("load users config: %w", err)
+	}
+	ttl := cfg.Auth.SessionTTL
+	if ttl <= 0 {
+		ttl = baseTTL
+	}
+	auth.SetSessionTTL(ttl)
+	return nil
+}
+
+// collectorSettings layers cfg over the environment defaults; a provider
+// given on the command line wins over the file.
+func collectorSettings(cfg config.Config, provider string) (monitor.Settings, error) {
+	settings := cfg.CollectorSettings(monitor.DefaultSettings())
+	if strings.TrimSpace(provider) != "" {
+		mode, err := monitor.ParseProviderMode(provider)
+		if err != nil {
+			return monitor.Settings{}, fmt.Errorf("invalid provider mode: %w", err)
+		}
+		settings.Provider = mode
+	}
+	return settings, nil
+}
+
+// watchCollectorConfig re-applies the config file to collector whenever it
+// changes, until ctx ends.
+func watchCollectorConfig(ctx context.Context, path string, collector *monitor.Collector, provider string) {
+	go config.NewWatcher(path, func(cfg config.Config) {
+		if err := applyCollectorConfig(collector, cfg, provider); err != nil {
+			log.Printf("Config reload: collector: %v", err)
+		}
+	}).Run(ctx)
+}
+
+func applyCollectorConfig(collector *monitor.Collector, cfg config.Config, provider string) error {
+	settings, err := collectorSettings(cfg, provider)
+	if err != nil {
+		return err
+	}
+	if err := collector.ApplySettings(settings); err != nil {
+		return err
+	}
+	return applyRedactConfig(collector, cfg)
+}
+
+// newRedactor builds the redactor from the redaction file with the
+// privacy section applied over it.
+func newRedactor(cfg config.Config) (*redact.Redactor, error) {
+	base, err := redact.LoadConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("load redact config: %w", err)
+	}
+	redactor, err := redact.New(cfg.RedactConfig(base))
+	if err != nil {
+		return nil, fmt.Errorf("init redaction: %w", err)
+	}
+	return redactor, nil
+}
+
+func applyRedactConfig(collector *monitor.Collector, cfg config.Config) error {
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return err
+	}
+	collector.SetRedactor(redactor)
+	return nil
+}
+
+// applyConfig applies a reloaded config file to the running session. The
+// listen address and the managed teams directory change on restart only.
+func (s *WebSession) applyConfig(cfg config.Config) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	settings, err := collectorSettings(cfg, s.provider)
+	if err == nil {
+		err = s.checkRootLabels(settings)
+	}
+	if err == nil {
+		err = s.Collector.ApplySettings(settings)
+	}
+	if err != nil {
+		log.Printf("Config reload: collector: %v", err)
+	}
+	if err := applyRedactConfig(s.Collector, cfg); err != nil {
+		log.Printf("Config reload: %v", err)
+	}
+	s.Server.SetSecurity(s.securityConfig(cfg))
+	s.Server.SetPrivacyProfile(s.privacyProfile(cfg))
+
+	if err := applyAuthConfig(s.Auth, cfg, s.baseSessionTTL); err != nil {
+		log.Printf("Config reload: %v", err)
+	}
+
+	if base, err := webhook.LoadConfigFromEnv(); err != nil {
+		log.Printf("Config reload: %v", err)
+	} else if next := cfg.WebhookConfig(base); !reflect.DeepEqual(next, s.webhookConfig) {
+		if err := s.startWebhooksLocked(next); err != nil {
+			log.Printf("Config reload: webhooks: %v", err)
+		}
+	}
+
+	if cfg.Web.Addr != "" && cfg.Web.Addr != s.configAddr {
+		log.Printf("Config reload: web.addr changes take effect after a restart")
+	}
+}
+
+// privacyProfile is the read-only profile from -privacy, or else from the
+// file.
+func (s *WebSession) privacyProfile(cfg config.Config) api.PrivacyProfile {
+	if s.basePrivacy != "" {
+		return s.basePrivacy
+	}
+	return cfg.PrivacyProfile(api.PrivacyStandard)
+}
+
+// checkRootLabels refuses root labels that name a node pushing to this
+// hub: the node's teams are qualified with its name the way the root's
+// teams are with the label.
+func (s *WebSession) checkRootLabels(settings monitor.Settings) error {
+	if s.hub == nil {
+		return nil
+	}
+	for _, node := range s.hub.Nodes() {
+		if settings.HasRootLabel(node.Name) {
+			return fmt.Errorf("root label %q is the name of a federated node", node.Name)
+		}
+	}
+	return nil
+}
+
+// securityConfig applies the web section over the environment, trusting
+// the names the server is bound to or has certificates for.
+func (s *WebSession) securityConfig(cfg config.Config) api.SecurityConfig {
+	security := cfg.SecurityConfig(s.baseSecurity)
+	security.AllowedHosts = append(append([]string(nil), security.AllowedHosts...), s.boundHosts...)
+	return security
+}
+
+// startWebhooksLocked replaces the webhook dispatcher; no endpoints turns
+// webhooks off. The caller holds configMu.
+func (s *WebSession) startWebhooksLocked(cfg webhook.Config) error {
+	var dispatcher *webhook.Dispatcher
+	if len(cfg.Endpoints) > 0 {
+		var err error
+		if dispatcher, err = webhook.NewDispatcher(cfg); err != nil {
+			return err
+		}
+	}
+
+	if s.stopWebhooks != nil {
+		s.stopWebhooks()
+		s.stopWebhooks = nil
+	}
+	if s.Webhooks != nil {
+		_ = s.Webhooks.Close()
+	}
+	s.Webhooks = dispatcher
+	s.webhookConfig = cfg
+	if dispatcher != nil {
+		ctx, cancel := context.WithCancel(s.watchCtx)
+		s.stopWebhooks = cancel
+		go webhook.NewWatcher(s.Server.State, dispatcher).Run(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/audit"
+)
+
+// SetAuditLog enables audit logging of mutating requests and /api/audit.
+func (s *Server) SetAuditLog(logger *audit.Logger) {
+	s.auditLog = logger
+}
+
+// AuditLog returns the audit logger, or nil when auditing is disabled.
+func (s *Server) AuditLog() *audit.Logger {
+	return s.auditLog
+}
+
+// authorize checks permission for a mutating request, recording and
+// rejecting callers that lack it.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, permission Permission, entry audit.Entry) bool {
+	if err := s.auth.RequirePermission(r, permission); err != nil {
+		entry.Result = audit.ResultDenied
+		s.recordAudit(r, entry, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// recordAudit fills in who and from where, then appends entry. A nil err
+// with no preset result records success.
+func (s *Server) recordAudit(r *http.Request, entry audit.Entry, err error) {
+	if s.auditLog == nil {
+		return
+	}
+	if entry.Actor == "" {
+		principal, identifyErr := s.auth.Identify(r)
+		if identifyErr != nil {
+			entry.Actor = "invalid-token"
+		} else {
+			entry.Actor = principal.Name()
+			entry.Role = string(principal.Role)
+		}
+	}
+	entry.Client = clientAddress(r)
+	entry.UserAgent = r.UserAgent()
+	if err != nil {
+		entry.Error = err.Error()
+		if entry.Result == "" {
+			entry.Result = audit.ResultError
+		}
+	}
+	if recordErr := s.auditLog.Record(entry); recordErr != nil {
+		log.Printf("Error writing audit log: %v", recordErr)
+	}
+}
+
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.auth.RequirePermission(r, PermissionAdmin); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if s.auditLog == nil {
+		http.Error(w, "Audit log disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter, err := audit.ParseFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := s.auditLog.Query(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	respondJSON(w, entries)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/audit"
+)
+
+func newTestAuditServer(t *testing.T) (*Server, *audit.Logger) {
+	t.Helper()
+	server, _ := newTestRBACServer(t, "")
+	logger, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.jsonl")})
+	if err != nil {
+		t.Fatalf("audit.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	server.SetAuditLog(logger)
+	return server, logger
+}
+
+func TestMutatingRequestsAreAudited(t *testing.T) {
+	server, logger := newTestAuditServer(t)
+
+	serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"vera","password":"wrong"}`)
+	viewer := loginAs(t, server, "vera")
+	serveAuthRequest(server, http.MethodPost, "/api/agents/message", `{"team_name":"alpha","agent_name":"lead","text":"hi"}`, viewer)
+	admin := loginAs(t, server, "ada")
+	serveAuthRequest(server, http.MethodPost, "/api/tokens", `{"name":"ci","scopes":["read"]}`, admin)
+
+	entries, err := logger.Query(audit.Filter{Limit: 100})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	want := []struct {
+		actor, action, result string
+	}{
+		{"ada", audit.ActionTokenCreate, audit.ResultOK},
+		{"ada", audit.ActionLogin, audit.ResultOK},
+		{"vera", audit.ActionAgentMessage, audit.ResultDenied},
+		{"vera", audit.ActionLogin, audit.ResultOK},
+		{"vera", audit.ActionLogin, audit.ResultDenied},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), entries)
+	}
+	for i, w := range want {
+		got := entries[i]
+		if got.Actor != w.actor || got.Action != w.action || got.Result != w.result || got.Client == "" {
+			t.Errorf("entry %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+	if entries[2].Role != string(RoleViewer) || entries[2].Error == "" {
+		t.Fatalf("expected denied message to record role and reason, got %+v", entries[2])
+	}
+}
+
+func TestAuditEndpointFiltersAndRequiresAdmin(t *testing.T) {
+	server, _ := newTestAuditServer(t)
+	operator := loginAs(t, server, "otto")
+	admin := loginAs(t, server, "ada")
+
+	if res := serveAuthRequest(server, http.MethodGet, "/api/audit", "", operator); res.Code != http.StatusForbidden {
+		t.Fatalf("expected operator to be denied audit log, got %d", res.Code)
+	}
+
+	res := serveAuthRequest(server, http.MethodGet, "/api/audit?actor=otto&action=auth", "", admin)
+	if res.Code != http.StatusOK {
+		t.Fatalf("audit query: %d %s", res.Code, res.Body.String())
+	}
+	var entries []audit.Entry
+	if err := json.Unmarshal(res.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("decode entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Actor != "otto" || entries[0].Action != audit.ActionLogin {
+		t.Fatalf("unexpected filtered entries: %+v", entries)
+	}
+
+	if res := serveAuthRequest(server, http.MethodGet, "/api/audit?limit=abc", "", admin); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid filter to be rejected, got %d", res.Code)
+	}
+}
@@ -1,7 +1,11 @@
 package api
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
@@ -9,93 +13,554 @@ import (
 )
 
 const (
-	adminUsernameEnv = "ATM_ADMIN_USERNAME"
-	adminPasswordEnv = "ATM_ADMIN_PASSWORD"
+	adminUsernameEnv     = "ATM_ADMIN_USERNAME"
+	adminPasswordEnv     = "ATM_ADMIN_PASSWORD"
+	adminPasswordHashEnv = "ATM_ADMIN_PASSWORD_HASH"
+	sessionTTLEnv        = "ATM_SESSION_TTL"
+
+	// SessionCookieName is the HTTP-only cookie carrying the session token.
+	SessionCookieName = "atm_session"
+	// CSRFHeaderName carries the CSRF token on state-changing requests.
+	CSRFHeaderName = "X-CSRF-Token"
+
+	defaultSessionTTL = 12 * time.Hour
 )
 
 type AuthStatus struct {
-	Configured    bool      `json:"configured"`
-	Authenticated bool      `json:"authenticated"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	Configured    bool         `json:"configured"`
+	Authenticated bool         `json:"authenticated"`
+	Username      string       `json:"username,omitempty"`
+	Role          Role         `json:"role,omitempty"`
+	Permissions   []Permission `json:"permissions"`
+	CSRFToken     string       `json:"csrf_token,omitempty"` // echo in X-CSRF-Token on state-changing requests
+	ExpiresAt     time.Time    `json:"expires_at,omitempty"`
+	ReadOnly      bool         `json:"read_only,omitempty"` // kiosk mode: only read is ever granted
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// Session is one logged-in client. Token is only known to the client; the
+// manager keeps a hash of it.
+type Session struct {
+	Token     string    `json:"-"`
+	CSRFToken string    `json:"-"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Status describes the session as seen by its own client.
+func (s Session) Status() AuthStatus {
+	return AuthStatus{
+		Configured:    true,
+		Authenticated: true,
+		Username:      s.Username,
+		Role:          s.Role,
+		Permissions:   s.Role.Permissions(),
+		CSRFToken:     s.CSRFToken,
+		ExpiresAt:     s.ExpiresAt,
+		UpdatedAt:     s.CreatedAt,
+	}
+}
+
+// Principal is whoever is behind a request: a logged-in user, an API token
+// or an anonymous client.
+type Principal struct {
+	Username    string
+	Role        Role
+	TokenName   string
+	Permissions []Permission
 }
 
-// AuthManager keeps a simple process-local admin lock state.
+// Anonymous reports whether the principal presented no credentials.
+func (p Principal) Anonymous() bool {
+	return p.Username == "" && p.TokenName == ""
+}
+
+// Name identifies the principal in logs: the username, "token:<name>" or
+// "anonymous".
+func (p Principal) Name() string {
+	switch {
+	case p.Username != "":
+		return p.Username
+	case p.TokenName != "":
+		return "token:" + p.TokenName
+	default:
+		return "anonymous"
+	}
+}
+
+// Allows reports whether the principal holds permission.
+func (p Principal) Allows(permission Permission) bool {
+	return permissionsAllow(p.Permissions, permission)
+}
+
+// AuthManager issues per-client sessions for local users and checks scoped
+// API tokens. Logging in on one client never grants access to another, and
+// logout only ends the caller's session.
 type AuthManager struct {
-	username string
-	password string
+	envUsers      []userAccount // from ATM_ADMIN_*, always present
+	users         []userAccount
+	anonymousRole Role
+	sessionTTL    time.Duration
+	now           func() time.Time
+	tokens        *TokenStore
 
-	mu            sync.RWMutex
-	authenticated bool
-	updatedAt     time.Time
+	mu            sync.Mutex
+	hubSecret     string             // bearer the federation hub forwards actions with
+	sessions      map[string]Session // keyed by sessionKey(token)
+	anonymousCSRF string
 }
 
+// NewAuthManagerFromEnv reads the admin account from ATM_ADMIN_USERNAME and
+// either ATM_ADMIN_PASSWORD_HASH or ATM_ADMIN_PASSWORD. A plaintext password
+// is hashed immediately and never kept in memory. Further users come from
+// LoadUsers.
 func NewAuthManagerFromEnv() *AuthManager {
-	return &AuthManager{
+	manager := &AuthManager{
+		anonymousRole: RoleViewer,
+		sessionTTL:    defaultSessionTTL,
+		now:           time.Now,
+		sessions:      make(map[string]Session),
+	}
+
+	admin := userAccount{
 		username: strings.TrimSpace(os.Getenv(adminUsernameEnv)),
-		password: strings.TrimSpace(os.Getenv(adminPasswordEnv)),
+		role:     RoleAdmin,
+	}
+	if encoded := strings.TrimSpace(os.Getenv(adminPasswordHashEnv)); encoded != "" {
+		admin.passwordHash = encoded
+	} else if password := strings.TrimSpace(os.Getenv(adminPasswordEnv)); password != "" {
+		if hash, err := HashPassword(password); err == nil {
+			admin.passwordHash = hash
+		}
 	}
+	if admin.username != "" && admin.passwordHash != "" {
+		manager.envUsers = []userAccount{admin}
+		manager.users = []userAccount{admin}
+	}
+
+	if raw := strings.TrimSpace(os.Getenv(sessionTTLEnv)); raw != "" {
+		if ttl, err := time.ParseDuration(raw); err == nil && ttl > 0 {
+			manager.sessionTTL = ttl
+		}
+	}
+
+	if path, err := DefaultTokenStorePath(); err == nil {
+		manager.tokens = NewTokenStore(path)
+	}
+
+	return manager
+}
+
+// LoadUsersFromEnv loads the users config from DefaultUsersConfigPath.
+func (m *AuthManager) LoadUsersFromEnv() error {
+	path, err := DefaultUsersConfigPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := LoadUsersConfig(path)
+	if err != nil {
+		return err
+	}
+	return m.LoadUsers(cfg)
 }
 
+// LoadUsers adds the configured users next to the environment admin and
+// applies the anonymous role. Existing sessions are kept.
+func (m *AuthManager) LoadUsers(cfg UsersConfig) error {
+	if m == nil {
+		return fmt.Errorf("auth manager unavailable")
+	}
+	anonymous, accounts, err := cfg.compile()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := append([]userAccount(nil), m.envUsers...)
+	for _, account := range accounts {
+		if _, exists := findUser(users, account.username); exists {
+			return fmt.Errorf("user %q is already defined by %s", account.username, adminUsernameEnv)
+		}
+		users = append(users, account)
+	}
+	m.users = users
+	m.anonymousRole = anonymous
+	return nil
+}
+
+func findUser(users []userAccount, username string) (userAccount, bool) {
+	for _, user := range users {
+		if subtle.ConstantTimeCompare([]byte(user.username), []byte(username)) == 1 {
+			return user, true
+		}
+	}
+	return userAccount{}, false
+}
+
+// Tokens returns the API token store, or nil when none is available.
+func (m *AuthManager) Tokens() *TokenStore {
+	if m == nil {
+		return nil
+	}
+	return m.tokens
+}
+
+// IsConfigured reports whether any local user can log in.
 func (m *AuthManager) IsConfigured() bool {
 	if m == nil {
 		return false
 	}
-	return m.username != "" && m.password != ""
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.users) > 0
 }
 
-func (m *AuthManager) IsAuthenticated() bool {
+// Status reports whether the request carries a live session and what it may do.
+func (m *AuthManager) Status(r *http.Request) AuthStatus {
 	if m == nil {
-		return false
+		return AuthStatus{Permissions: []Permission{}}
+	}
+	if session, err := m.Authenticate(SessionToken(r)); err == nil {
+		status := session.Status()
+		status.UpdatedAt = m.clock()
+		return status
 	}
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.authenticated
+	return m.AnonymousStatus()
 }
 
-func (m *AuthManager) Status() AuthStatus {
+// AnonymousStatus describes a client without a session.
+func (m *AuthManager) AnonymousStatus() AuthStatus {
 	if m == nil {
-		return AuthStatus{}
+		return AuthStatus{Permissions: []Permission{}}
 	}
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	anonymous := m.anonymous()
 	return AuthStatus{
-		Configured:    m.IsConfigured(),
-		Authenticated: m.authenticated,
-		UpdatedAt:     m.updatedAt,
+		Configured:  m.IsConfigured(),
+		Role:        anonymous,
+		Permissions: anonymous.Permissions(),
+		CSRFToken:   m.anonymousCSRFToken(),
+		UpdatedAt:   m.clock(),
 	}
 }
 
-func (m *AuthManager) Login(username, password string) error {
+// Login verifies the credentials and starts a new session.
+func (m *AuthManager) Login(username, password string) (Session, error) {
 	if m == nil || !m.IsConfigured() {
-		return fmt.Errorf("admin login not configured")
+		return Session{}, fmt.Errorf("admin login not configured")
+	}
+
+	m.mu.Lock()
+	account, found := findUser(m.users, strings.TrimSpace(username))
+	m.mu.Unlock()
+
+	hash := account.passwordHash
+	if !found {
+		// Spend the same hashing time for unknown users.
+		hash = unknownUserPasswordHash()
+	}
+	if !VerifyPassword(hash, password) || !found || account.role == RoleNone {
+		return Session{}, fmt.Errorf("invalid username or password")
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return Session{}, err
+	}
+	csrfToken, err := randomHex(16)
+	if err != nil {
+		return Session{}, err
 	}
-	if strings.TrimSpace(username) != m.username || password != m.password {
-		return fmt.Errorf("invalid username or password")
+
+	now := m.clock()
+	session := Session{
+		Token:     token,
+		CSRFToken: csrfToken,
+		Username:  account.username,
+		Role:      account.role,
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.ttl()),
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.authenticated = true
-	m.updatedAt = time.Now()
-	return nil
+	m.pruneExpiredLocked(now)
+	if m.sessions == nil {
+		m.sessions = make(map[string]Session)
+	}
+	m.sessions[sessionKey(token)] = session
+	return session, nil
 }
 
-func (m *AuthManager) Logout() {
-	if m == nil {
+// Logout ends the session identified by token; other sessions are untouched.
+func (m *AuthManager) Logout(token string) {
+	if m == nil || token == "" {
 		return
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.authenticated = false
-	m.updatedAt = time.Now()
+	delete(m.sessions, sessionKey(token))
 }
 
-func (m *AuthManager) RequireAuthenticated() error {
+// Authenticate resolves a session token.
+func (m *AuthManager) Authenticate(token string) (Session, error) {
 	if m == nil || !m.IsConfigured() {
+		return Session{}, fmt.Errorf("admin login not configured")
+	}
+	if token == "" {
+		return Session{}, fmt.Errorf("admin login required")
+	}
+
+	key := sessionKey(token)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[key]
+	if !ok {
+		return Session{}, fmt.Errorf("admin login required")
+	}
+	if !m.clock().Before(session.ExpiresAt) {
+		delete(m.sessions, key)
+		return Session{}, fmt.Errorf("admin session expired")
+	}
+	return session, nil
+}
+
+// Identify resolves the caller from an "Authorization: Bearer" API token, a
+// session cookie or bearer session token, falling back to the anonymous role.
+// Only an invalid API token is an error; a stale session reads as anonymous.
+func (m *AuthManager) Identify(r *http.Request) (Principal, error) {
+	return m.IdentifyToken(SessionToken(r))
+}
+
+// IdentifyToken resolves an API token or session token the way Identify
+// does, for callers outside HTTP; an empty token is the anonymous role.
+func (m *AuthManager) IdentifyToken(token string) (Principal, error) {
+	if m == nil {
+		return Principal{}, fmt.Errorf("admin login not configured")
+	}
+	if principal, ok := m.hubPrincipal(token); ok {
+		return principal, nil
+	}
+	if strings.HasPrefix(token, apiTokenPrefix) && m.tokens != nil {
+		apiToken, err := m.tokens.Authenticate(token)
+		if err != nil {
+			return Principal{}, err
+		}
+		return apiToken.Principal(), nil
+	}
+	return m.SessionPrincipal(token), nil
+}
+
+// SetHubSecret accepts secret as a bearer token from the federation hub
+// this node pushes to, granting hubPermissions so the hub can relay
+// operator actions. An empty secret turns it off.
+func (m *AuthManager) SetHubSecret(secret string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hubSecret = secret
+}
+
+// hubPermissions are what the hub may do on a node on behalf of its users.
+// The secret travels in every push, so it never grants team deletion.
+var hubPermissions = []Permission{PermissionRead, PermissionMessage, PermissionManagedControl}
+
+func (m *AuthManager) hubPrincipal(token string) (Principal, bool) {
+	m.mu.Lock()
+	secret := m.hubSecret
+	m.mu.Unlock()
+	if secret == "" || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return Principal{}, false
+	}
+	return Principal{TokenName: "hub", Permissions: append([]Permission{}, hubPermissions...)}, true
+}
+
+// SessionPrincipal resolves a session token, falling back to the anonymous role.
+func (m *AuthManager) SessionPrincipal(token string) Principal {
+	if m == nil {
+		return Principal{Role: RoleNone}
+	}
+	if session, err := m.Authenticate(token); err == nil {
+		return Principal{
+			Username:    session.Username,
+			Role:        session.Role,
+			Permissions: session.Role.Permissions(),
+		}
+	}
+	anonymous := m.anonymous()
+	return Principal{Role: anonymous, Permissions: anonymous.Permissions()}
+}
+
+// RequirePermission checks that the caller holds permission.
+func (m *AuthManager) RequirePermission(r *http.Request, permission Permission) error {
+	principal, err := m.Identify(r)
+	if err != nil {
+		return err
+	}
+	return m.CheckPermission(principal, permission)
+}
+
+// CheckPermission explains why principal may not use permission, if so.
+func (m *AuthManager) CheckPermission(principal Principal, permission Permission) error {
+	if principal.Allows(permission) {
+		return nil
+	}
+	switch {
+	case principal.TokenName != "":
+		return fmt.Errorf("API token %q lacks scope %q", principal.TokenName, permission)
+	case !principal.Anonymous():
+		return fmt.Errorf("user %q (role %s) lacks permission %q", principal.Username, principal.Role, permission)
+	case !m.IsConfigured():
 		return fmt.Errorf("admin login not configured")
+	default:
+		return fmt.Errorf("login required for permission %q", permission)
+	}
+}
+
+func (m *AuthManager) anonymous() Role {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.anonymousRole == "" {
+		return RoleViewer
+	}
+	return m.anonymousRole
+}
+
+// SessionToken extracts the session token from the cookie or an
+// "Authorization: Bearer" header.
+func SessionToken(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if header := strings.TrimSpace(r.Header.Get("Authorization")); len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+func setSessionCookie(w http.ResponseWriter, r *http.Request, session Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (m *AuthManager) pruneExpiredLocked(now time.Time) {
+	for key, session := range m.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(m.sessions, key)
+		}
+	}
+}
+
+func (m *AuthManager) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+func (m *AuthManager) ttl() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessionTTL > 0 {
+		return m.sessionTTL
+	}
+	return defaultSessionTTL
+}
+
+// SessionTTL returns how long new sessions last.
+func (m *AuthManager) SessionTTL() time.Duration {
+	if m == nil {
+		return defaultSessionTTL
+	}
+	return m.ttl()
+}
+
+// SetSessionTTL changes how long new sessions last; existing sessions keep
+// their expiry. Zero restores the default.
+func (m *AuthManager) SetSessionTTL(ttl time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessionTTL = ttl
+}
+
+func sessionKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	unknownUserHashOnce sync.Once
+	unknownUserHash     string
+)
+
+func unknownUserPasswordHash() string {
+	unknownUserHashOnce.Do(func() {
+		unknownUserHash, _ = HashPassword("unknown-user")
+	})
+	return unknownUserHash
+}
+
+// anonymousCSRFToken is shared by clients without a session. Cross-origin
+// pages cannot read it, which is all CSRF protection needs.
+func (m *AuthManager) anonymousCSRFToken() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.anonymousCSRF == "" {
+		token, err := randomHex(16)
+		if err != nil {
+			return ""
+		}
+		m.anonymousCSRF = token
+	}
+	return m.anonymousCSRF
+}
+
+// VerifyCSRF checks the X-CSRF-Token header of a state-changing request
+// against the caller's session, or the anonymous token without one.
+// Requests carrying an Authorization header are exempt: browsers never
+// attach it on their own.
+func (m *AuthManager) VerifyCSRF(r *http.Request) error {
+	if m == nil || strings.TrimSpace(r.Header.Get("Authorization")) != "" {
+		return nil
+	}
+	expected := m.anonymousCSRFToken()
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		if session, err := m.Authenticate(cookie.Value); err == nil {
+			expected = session.CSRFToken
+		}
 	}
-	if !m.IsAuthenticated() {
-		return fmt.Errorf("admin login required")
+	got := strings.TrimSpace(r.Header.Get(CSRFHeaderName))
+	if expected == "" || subtle.ConstantTimeCompare([]byte(got), []byte(expected)) != 1 {
+		return fmt.Errorf("missing or invalid %s header", CSRFHeaderName)
 	}
 	return nil
 }
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newTestAuthServer(t *testing.T) (*Server, *AuthManager) {
+	t.Helper()
+	t.Setenv("ATM_ADMIN_USERNAME", "admin")
+	t.Setenv("ATM_ADMIN_PASSWORD", "secret")
+	auth := NewAuthManagerFromEnv()
+	server := NewServer(nil, ":0", fstest.MapFS{
+		"index.html": {Data: []byte("dark-dashboard")},
+	}, auth, nil)
+	return server, auth
+}
+
+func serveAuthRequest(server *Server, method, path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	res := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(res, req)
+	return res
+}
+
+func sessionCookie(t *testing.T, res *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range res.Result().Cookies() {
+		if cookie.Name == SessionCookieName {
+			return cookie
+		}
+	}
+	t.Fatalf("expected %s cookie, got %v", SessionCookieName, res.Result().Cookies())
+	return nil
+}
+
+func TestLoginIssuesHTTPOnlySessionCookie(t *testing.T) {
+	server, _ := newTestAuthServer(t)
+
+	res := serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"admin","password":"secret"}`)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	cookie := sessionCookie(t, res)
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode || cookie.Value == "" {
+		t.Fatalf("unexpected session cookie: %+v", cookie)
+	}
+
+	var status AuthStatus
+	if err := json.Unmarshal(res.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if !status.Authenticated || status.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected login status: %+v", status)
+	}
+	if strings.Contains(res.Body.String(), cookie.Value) {
+		t.Fatal("session token must not appear in the response body")
+	}
+}
+
+func TestSessionsAreIsolatedPerClient(t *testing.T) {
+	server, _ := newTestAuthServer(t)
+
+	first := sessionCookie(t, serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"admin","password":"secret"}`))
+	second := sessionCookie(t, serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"admin","password":"secret"}`))
+
+	var anonymous AuthStatus
+	_ = json.Unmarshal(serveAuthRequest(server, http.MethodGet, "/api/auth/status", "").Body.Bytes(), &anonymous)
+	if anonymous.Authenticated {
+		t.Fatal("a login on one client must not authenticate other clients")
+	}
+
+	if res := serveAuthRequest(server, http.MethodPost, "/api/auth/logout", "", first); res.Code != http.StatusOK {
+		t.Fatalf("logout: %d", res.Code)
+	}
+	if res := serveAuthRequest(server, http.MethodPost, "/api/agents/message", `{}`, first); res.Code != http.StatusForbidden {
+		t.Fatalf("expected logged-out session to be rejected, got %d", res.Code)
+	}
+	if res := serveAuthRequest(server, http.MethodPost, "/api/agents/message", `{}`, second); res.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected other session to stay valid (503 without collector), got %d", res.Code)
+	}
+}
+
+func TestAuthenticateRejectsExpiredSessions(t *testing.T) {
+	_, auth := newTestAuthServer(t)
+	now := time.Date(2026, 4, 10, 9, 0, 0, 0, time.UTC)
+	auth.now = func() time.Time { return now }
+	auth.sessionTTL = time.Hour
+
+	session, err := auth.Login("admin", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, err := auth.Authenticate(session.Token); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := auth.Authenticate(session.Token); err == nil {
+		t.Fatal("expected expired session to be rejected")
+	}
+}
+
+func TestLoginRejectsWrongCredentials(t *testing.T) {
+	_, auth := newTestAuthServer(t)
+	for _, creds := range [][2]string{{"admin", "wrong"}, {"root", "secret"}, {"", ""}} {
+		if _, err := auth.Login(creds[0], creds[1]); err == nil {
+			t.Fatalf("expected login %v to fail", creds)
+		}
+	}
+}
+
+func TestBearerSessionTokenIsAccepted(t *testing.T) {
+	_, auth := newTestAuthServer(t)
+	session, err := auth.Login("admin", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/status", nil)
+	req.Header.Set("Authorization", "Bearer "+session.Token)
+	if err := auth.RequirePermission(req, PermissionAdmin); err != nil {
+		t.Fatalf("expected bearer session to authenticate, got %v", err)
+	}
+}
+
+func TestIdentifyTokenOutsideHTTP(t *testing.T) {
+	t.Setenv("ATM_TOKENS_FILE", filepath.Join(t.TempDir(), "api-tokens.json"))
+	_, auth := newTestAuthServer(t)
+	_, secret, err := auth.Tokens().Create("agent", []string{"read", "message"}, 0)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+
+	principal, err := auth.IdentifyToken(secret)
+	if err != nil || auth.CheckPermission(principal, PermissionMessage) != nil {
+		t.Fatalf("expected the API token to allow messages, got %v %+v", err, principal)
+	}
+	anonymous, err := auth.IdentifyToken("")
+	if err != nil || auth.CheckPermission(anonymous, PermissionMessage) == nil {
+		t.Fatalf("expected the anonymous role to be refused messages, got %v", err)
+	}
+	if _, err := auth.IdentifyToken("atm_not-a-token"); err == nil {
+		t.Fatal("expected an unknown API token to fail")
+	}
+}
+
+func TestPasswordHashRoundTrip(t *testing.T) {
+	encoded, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if strings.Contains(encoded, "secret") || !strings.HasPrefix(encoded, "pbkdf2-sha256$") {
+		t.Fatalf("unexpected encoded hash %q", encoded)
+	}
+	if !VerifyPassword(encoded, "secret") || VerifyPassword(encoded, "Secret") {
+		t.Fatal("password verification mismatch")
+	}
+
+	t.Setenv("ATM_ADMIN_USERNAME", "admin")
+	t.Setenv("ATM_ADMIN_PASSWORD", "")
+	t.Setenv("ATM_ADMIN_PASSWORD_HASH", encoded)
+	if _, err := NewAuthManagerFromEnv().Login("admin", "secret"); err != nil {
+		t.Fatalf("expected ATM_ADMIN_PASSWORD_HASH login to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/client"
+	"github.com/liaoweijun/agent-team-monitor/pkg/federation"
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// errDeleteNotRelayed refuses deleting a node's team through the hub.
+var errDeleteNotRelayed = errors.New("teams on a federated node can only be deleted on the node")
+
+// maxNodeReportBytes bounds a pushed report; a full state of a busy node
+// stays well below it.
+const maxNodeReportBytes = 32 << 20
+
+// SetFederation makes the server a hub: nodes push to it, their teams and
+// processes are served alongside the local ones and actions on them are
+// relayed to the owning node.
+func (s *Server) SetFederation(hub *federation.Hub) {
+	s.hub = hub
+}
+
+// StateDelta is what /api/state/delta would serve for since; a node pushes
+// it to its hub.
+func (s *Server) StateDelta(since uint64) types.StateDelta {
+	return s.journal.delta(s.servedState(), since)
+}
+
+func (s *Server) handleFederationPush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.hub == nil {
+		http.Error(w, "Federation hub not enabled", http.StatusNotFound)
+		return
+	}
+	// Not audited: nodes push every few seconds.
+	principal, err := s.auth.Identify(r)
+	if err == nil {
+		err = s.auth.CheckPermission(principal, PermissionFederate)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var report types.NodeReport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxNodeReportBytes)).Decode(&report); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	ack, err := s.hub.Receive(principal.Name(), report)
+	if errors.Is(err, federation.ErrNodeClaimed) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	respondJSON(w, ack)
+}
+
+func (s *Server) handleFederationNodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.auth.RequirePermission(r, PermissionRead); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	nodes := []types.NodeInfo{}
+	if s.hub != nil {
+		nodes = s.hub.Nodes()
+	}
+	respondJSON(w, nodes)
+}
+
+// forward relays an action on a node-qualified name to its node. handled
+// is false when the target is local or the server is not a hub.
+func (s *Server) forward(ctx context.Context, qualified string, call func(ctx context.Context, c *client.Client, name string) error) (handled bool, err error) {
+	return s.hub.Forward(ctx, qualified, call)
+}
+
+// federated reports whether qualified names something on a hub node.
+func (s *Server) federated(qualified string) bool {
+	_, _, ok := s.hub.Owner(qualified)
+	return ok
+}
+
+// federatedTeam reports whether team names a team on a hub node. Local
+// teams from a labeled root are named <team>@<label> as well, so a team
+// the hub serves without a host stays local whatever its suffix.
+func (s *Server) federatedTeam(team string) bool {
+	if !s.federated(team) {
+		return false
+	}
+	for _, info := range s.buildState().Teams {
+		if info.Name == team && info.Host == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// forwardTeam relays an action on a team to its node, as forward does,
+// unless the team is local.
+func (s *Server) forwardTeam(ctx context.Context, team string, call func(ctx context.Context, c *client.Client, name string) error) (handled bool, err error) {
+	if !s.federatedTeam(team) {
+		return false, nil
+	}
+	return s.forward(ctx, team, call)
+}
+
+// The helpers below run an action here, or on the node owning the team.
+
+func (s *Server) sendAgentMessage(ctx context.Context, team, agent, text string) error {
+	handled, err := s.forwardTeam(ctx, team, func(ctx context.Context, c *client.Client, name string) error {
+		return c.SendAgentMessage(ctx, name, agent, text)
+	})
+	if handled {
+		return err
+	}
+	return s.collector.SendAgentMessage(team, agent, text)
+}
+
+// deleteTeam deletes a local team. Teams on nodes are refused: the hub
+// secret does not grant deletion.
+func (s *Server) deleteTeam(ctx context.Context, team string) error {
+	if s.federatedTeam(team) {
+		return errDeleteNotRelayed
+	}
+	return s.collector.DeleteTeam(team)
+}
+
+// startManaged starts a managed team, or one agent when agentID is set.
+func (s *Server) startManaged(ctx context.Context, teamID, agentID string) (run managed.RunState, err error) {
+	handled, err := s.forward(ctx, teamID, func(ctx context.Context, c *client.Client, id string) error {
+		if agentID != "" {
+			run, err = c.StartAgent(ctx, id, agentID)
+		} else {
+			run, err = c.StartTeam(ctx, id)
+		}
+		return err
+	})
+	switch {
+	case handled:
+		return run, err
+	case agentID != "":
+		return s.managed.StartAgent(teamID, agentID)
+	default:
+		return s.managed.StartTeam(teamID)
+	}
+}
+
+// stopManaged stops a managed team, or one agent when agentID is set.
+func (s *Server) stopManaged(ctx context.Context, teamID, agentID string) (run managed.RunState, err error) {
+	handled, err := s.forward(ctx, teamID, func(ctx context.Context, c *client.Client, id string) error {
+		if agentID != "" {
+			run, err = c.StopAgent(ctx, id, agentID)
+		} else {
+			run, err = c.StopTeam(ctx, id)
+		}
+		return err
+	})
+	switch {
+	case handled:
+		return run, err
+	case agentID != "":
+		return s.managed.StopAgent(teamID, agentID)
+	default:
+		return s.managed.StopTeam(teamID)
+	}
+}
+
+// sendManagedMessage messages a managed team's lead, or agentID when set.
+func (s *Server) sendManagedMessage(ctx context.Context, teamID, agentID, text string) error {
+	handled, err := s.forward(ctx, teamID, func(ctx context.Context, c *client.Client, id string) error {
+		if agentID != "" {
+			return c.SendManagedAgentMessage(ctx, id, agentID, text)
+		}
+		return c.SendManagedMessage(ctx, id, text)
+	})
+	switch {
+	case handled:
+		return err
+	case agentID != "":
+		return s.managed.SendMessageToAgent(teamID, agentID, text)
+	default:
+		return s.managed.SendMessage(teamID, text)
+	}
+}
+
+// signalProcess signals a process here, or on its node when pid is
+// qualified as <pid>@<node>.
+func (s *Server) signalProcess(ctx context.Context, pid, signal string, timeout time.Duration) (results []types.SignalResult, err error) {
+	local := pid
+	if name, _, ok := s.hub.Owner(pid); ok {
+		local = name
+	}
+	id, err := parsePID(local)
+	if err != nil {
+		return nil, err
+	}
+	handled, err := s.forward(ctx, pid, func(ctx context.Context, c *client.Client, _ string) error {
+		results, err = c.SignalProcess(ctx, id, signal, timeout)
+		return err
+	})
+	if handled {
+		return results, err
+	}
+	return s.collector.SignalProcesses([]int32{id}, signal, timeout)
+}
+
+// signalTeam signals every process linked to a team, including processes
+// of managed runs, which the collector alone cannot attribute.
+func (s *Server) signalTeam(ctx context.Context, team, signal string, timeout time.Duration) (results []types.SignalResult, err error) {
+	handled, err := s.forwardTeam(ctx, team, func(ctx context.Context, c *client.Client, name string) error {
+		results, err = c.SignalTeam(ctx, name, signal, timeout)
+		return err
+	})
+	if handled {
+		return results, err
+	}
+	var pids []int32
+	for _, process := range s.buildState().Processes {
+		if process.Team == team && process.Host == "" {
+			pids = append(pids, process.PID)
+		}
+	}
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("%w: none linked to team %q", monitor.ErrProcessNotMonitored, team)
+	}
+	return s.collector.SignalProcesses(pids, signal, timeout)
+}
+
+func parsePID(value string) (int32, error) {
+	pid, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid process ID %q", value)
+	}
+	return int32(pid), nil
+}
+
+// actionError picks the response for a failed action: the node's own
+// answer for a relayed one, 502 when the node could not be reached, and
+// status with err for a local one.
+func actionError(err error, status int) (int, string) {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode, apiErr.Message
+	}
+	if errors.Is(err, federation.ErrNodeUnreachable) {
+		return http.StatusBadGateway, err.Error()
+	}
+	if errors.Is(err, errDeleteNotRelayed) {
+		return http.StatusForbidden, err.Error()
+	}
+	return status, err.Error()
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/federation"
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func TestFederationPushMergesAndRelays(t *testing.T) {
+	hub, _ := newTestV1Server(t)
+	hub.SetFederation(federation.NewHub())
+	_, hubToken, err := hub.auth.Tokens().Create("devbox", []string{string(PermissionFederate)}, 0)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	hubHTTP := httptest.NewServer(hub.Handler())
+	defer hubHTTP.Close()
+
+	node, workspace := newTestV1Server(t)
+	team, err := node.managed.CreateTeam(managed.CreateTeamInput{Name: "Release Crew", Provider: "claude", Workspace: workspace})
+	if err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	nodeHTTP := httptest.NewServer(node.Handler())
+	defer nodeHTTP.Close()
+
+	agent, err := federation.NewAgent(federation.AgentOptions{HubURL: hubHTTP.URL, Token: hubToken, Node: "devbox", URL: nodeHTTP.URL}, node.StateDelta)
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	node.auth.SetHubSecret(agent.Secret())
+	if err := agent.Push(context.Background()); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	var state types.MonitorState
+	res := serveAuthRequest(hub, http.MethodGet, "/api/state", "")
+	if err := json.Unmarshal(res.Body.Bytes(), &state); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+	if len(state.Teams) != 1 || state.Teams[0].Name != "Release Crew@devbox" || state.Teams[0].ManagedTeamID != team.ID+"@devbox" {
+		t.Fatalf("expected the node's team on the hub, got %+v", state.Teams)
+	}
+	if len(state.Nodes) != 1 || state.Nodes[0].Name != "devbox" || !state.Nodes[0].Online {
+		t.Fatalf("expected the node to be listed, got %+v", state.Nodes)
+	}
+	if err := agent.Push(context.Background()); err != nil {
+		t.Fatalf("heartbeat push: %v", err)
+	}
+
+	_, intruderToken, err := hub.auth.Tokens().Create("intruder", []string{string(PermissionFederate)}, 0)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	intruder, err := federation.NewAgent(federation.AgentOptions{HubURL: hubHTTP.URL, Token: intruderToken, Node: "devbox", URL: "http://intruder.example"}, node.StateDelta)
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	if err := intruder.Push(context.Background()); err == nil {
+		t.Fatal("expected a push under another token's node name to be refused")
+	}
+	if nodes := hub.hub.Nodes(); nodes[0].URL != nodeHTTP.URL {
+		t.Fatalf("expected the node to keep its URL, got %+v", nodes)
+	}
+
+	operator := loginAs(t, hub, "otto")
+	res = serveAuthRequest(hub, http.MethodPost, "/api/v1/managed/teams/"+team.ID+"@devbox/messages", `{"text":"hi"}`, operator)
+	if res.Code != http.StatusBadRequest || decodeAPIError(t, res).Code != "bad_request" {
+		t.Fatalf("expected the node's refusal of a stopped team to come back, got %d %s", res.Code, res.Body.String())
+	}
+	res = serveAuthRequest(hub, http.MethodPost, "/api/v1/processes/4242@devbox/signals", `{"signal":"TERM"}`, operator)
+	if res.Code != http.StatusServiceUnavailable || decodeAPIError(t, res).Message != "collector unavailable" {
+		t.Fatalf("expected the signal to reach the node, which has no collector, got %d %s", res.Code, res.Body.String())
+	}
+	res = serveAuthRequest(hub, http.MethodPost, "/api/v1/processes/first@devbox/signals", `{"signal":"TERM"}`, operator)
+	if res.Code != http.StatusBadRequest || decodeAPIError(t, res).Message != `invalid process ID "first"` {
+		t.Fatalf("expected the node-local PID to be checked, got %d %s", res.Code, res.Body.String())
+	}
+	res = serveAuthRequest(hub, http.MethodPost, "/api/v1/processes/4242@elsewhere/signals", `{"signal":"TERM"}`, operator)
+	if res.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected an unknown node to stay local, got %d %s", res.Code, res.Body.String())
+	}
+	res = serveAuthRequest(hub, http.MethodPost, "/api/v1/managed/teams/"+team.ID+"@elsewhere/messages", `{"text":"hi"}`, operator)
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown node to be a local miss, got %d %s", res.Code, res.Body.String())
+	}
+
+	// A local team may end in @devbox too, for example a managed team
+	// named so; it must not be routed to the node.
+	if _, err := hub.managed.CreateTeam(managed.CreateTeamInput{Name: "Ops@devbox", Provider: "claude", Workspace: t.TempDir()}); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	res = serveAuthRequest(hub, http.MethodPost, "/api/v1/teams/Ops@devbox/signals", `{"signal":"TERM"}`, operator)
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("expected the local team to be signalled locally, got %d %s", res.Code, res.Body.String())
+	}
+
+	admin := loginAs(t, hub, "ada")
+	res = serveAuthRequest(hub, http.MethodDelete, "/api/teams/Release%20Crew@devbox", "", admin)
+	if res.Code != http.StatusForbidden {
+		t.Fatalf("expected team deletion not to be relayed to the node, got %d %s", res.Code, res.Body.String())
+	}
+
+	res = serveAuthRequest(hub, http.MethodPost, "/api/federation/push", `{}`, operator)
+	if res.Code != http.StatusForbidden {
+		t.Fatalf("expected a push without the federate scope to be refused, got %d", res.Code)
+	}
+}
+
+func TestHubSecretGrantsOperatorPermissions(t *testing.T) {
+	_, auth := newTestRBACServer(t, "none")
+	auth.SetHubSecret("relay-secret")
+
+	principal, err := auth.IdentifyToken("relay-secret")
+	if err != nil || !principal.Allows(PermissionManagedControl) || principal.Allows(PermissionAdmin) || principal.Allows(PermissionTeamsDelete) || principal.Name() != "token:hub" {
+		t.Fatalf("unexpected hub principal %+v (%v)", principal, err)
+	}
+	if principal, _ := auth.IdentifyToken("other"); principal.Allows(PermissionRead) {
+		t.Fatal("expected a wrong secret to stay anonymous")
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/parser"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsWriter renders the Prometheus text exposition format.
+type metricsWriter struct {
+	buf bytes.Buffer
+}
+
+type metricSample struct {
+	labels []string // alternating name/value pairs
+	value  float64
+}
+
+func (m *metricsWriter) family(name, metricType, help string, samples ...metricSample) {
+	fmt.Fprintf(&m.buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(&m.buf, "# TYPE %s %s\n", name, metricType)
+	for _, sample := range samples {
+		m.buf.WriteString(name)
+		if len(sample.labels) > 0 {
+			m.buf.WriteByte('{')
+			for i := 0; i+1 < len(sample.labels); i += 2 {
+				if i > 0 {
+					m.buf.WriteByte(',')
+				}
+				fmt.Fprintf(&m.buf, "%s=\"%s\"", sample.labels[i], escapeLabelValue(sample.labels[i+1]))
+			}
+			m.buf.WriteByte('}')
+		}
+		m.buf.WriteByte(' ')
+		m.buf.WriteString(strconv.FormatFloat(sample.value, 'g', -1, 64))
+		m.buf.WriteByte('\n')
+	}
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+// countSamples turns a label-key → count map into samples sorted by key.
+func countSamples(counts map[string]float64, labelNames ...string) []metricSample {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	samples := make([]metricSample, 0, len(keys))
+	for _, key := range keys {
+		values := strings.Split(key, "\x00")
+		labels := make([]string, 0, len(labelNames)*2)
+		for i, name := range labelNames {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			labels = append(labels, name, value)
+		}
+		samples = append(samples, metricSample{labels: labels, value: counts[key]})
+	}
+	return samples
+}
+
+func renderMetrics(state types.MonitorState, health monitor.CollectorHealth, discovery parser.DiscoveryMetrics) []byte {
+	teams := make(map[string]float64)
+	agents := make(map[string]float64)
+	tasks := make(map[string]float64)
+	processes := make(map[string]float64)
+
+	for _, team := range state.Teams {
+		provider := firstNonEmpty(team.Provider, "unknown")
+		teams[provider]++
+		for _, agent := range team.Members {
+			agentProvider := firstNonEmpty(agent.Provider, provider)
+			status := firstNonEmpty(agent.Status, "unknown")
+			agents[agentProvider+"\x00"+status]++
+		}
+		for _, task := range team.Tasks {
+			tasks[firstNonEmpty(task.Status, "unknown")]++
+		}
+	}
+	for _, process := range state.Processes {
+		processes[firstNonEmpty(process.Provider, "unknown")]++
+	}
+
+	var m metricsWriter
+	m.family("atm_teams", "gauge", "Number of monitored teams by provider.", countSamples(teams, "provider")...)
+	m.family("atm_agents", "gauge", "Number of agents by provider and status.", countSamples(agents, "provider", "status")...)
+	m.family("atm_tasks", "gauge", "Number of tasks by status.", countSamples(tasks, "status")...)
+	m.family("atm_processes", "gauge", "Number of monitored agent processes by provider.", countSamples(processes, "provider")...)
+	if !state.UpdatedAt.IsZero() {
+		m.family("atm_state_updated_timestamp_seconds", "gauge", "Unix time of the last collector state update.",
+			metricSample{value: float64(state.UpdatedAt.UnixNano()) / 1e9})
+	}
+
+	m.family("atm_collector_updates_total", "counter", "Number of completed collector updateState passes.",
+		metricSample{value: float64(health.Updates)})
+	m.family("atm_collector_update_duration_seconds_total", "counter", "Total time spent in collector updateState.",
+		metricSample{value: health.TotalUpdateTime.Seconds()})
+	m.family("atm_collector_last_update_duration_seconds", "gauge", "Duration of the most recent collector updateState pass.",
+		metricSample{value: health.LastUpdateDuration.Seconds()})
+
+	parseErrors := make([]metricSample, 0, len(health.ParseErrors))
+	for _, source := range health.ParseErrorSources() {
+		parseErrors = append(parseErrors, metricSample{labels: []string{"source", source}, value: float64(health.ParseErrors[source])})
+	}
+	m.family("atm_collector_parse_errors_total", "counter", "Number of collector parse errors by data source.", parseErrors...)
+
+	m.family("atm_discovery_runs_total", "counter", "Number of project team discovery passes.",
+		metricSample{value: float64(discovery.Runs)})
+	m.family("atm_discovery_duration_seconds_total", "counter", "Total time spent in project team discovery.",
+		metricSample{value: discovery.TotalDuration.Seconds()})
+	m.family("atm_discovery_cache_hits_total", "counter", "Discovery parse cache hits by cache.",
+		metricSample{labels: []string{"cache", "root"}, value: float64(discovery.RootCacheHits)},
+		metricSample{labels: []string{"cache", "subagent"}, value: float64(discovery.SubCacheHits)})
+	m.family("atm_discovery_cache_misses_total", "counter", "Discovery parse cache misses by cache.",
+		metricSample{labels: []string{"cache", "root"}, value: float64(discovery.RootCacheMisses)},
+		metricSample{labels: []string{"cache", "subagent"}, value: float64(discovery.SubCacheMisses)})
+
+	return m.buf.Bytes()
+}
+
+// handleMetrics serves Prometheus metrics for state and collector health
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.auth.RequirePermission(r, PermissionRead); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	state := s.buildState()
+	health := s.collector.Health()
+	payload := renderMetrics(state, health, parser.SnapshotDiscoveryMetrics())
+
+	w.Header().Set("Content-Type", metricsContentType)
+	w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate")
+	_, _ = w.Write(payload)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/parser"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func TestRenderMetricsCountsStateAndHealth(t *testing.T) {
+	state := types.MonitorState{
+		UpdatedAt: time.Unix(1700000000, 0),
+		Teams: []types.TeamInfo{
+			{
+				Name:     "alpha",
+				Provider: "claude",
+				Members: []types.AgentInfo{
+					{Name: "lead", Status: "working"},
+					{Name: "dev", Status: "working"},
+					{Name: "qa", Provider: "codex", Status: "idle"},
+				},
+				Tasks: []types.TaskInfo{
+					{ID: "1", Status: "completed"},
+					{ID: "2", Status: "in_progress"},
+				},
+			},
+			{Name: "codex-demo", Provider: "codex"},
+		},
+		Processes: []types.ProcessInfo{{PID: 1, Provider: "claude"}},
+	}
+	health := monitor.CollectorHealth{
+		Updates:            3,
+		LastUpdateDuration: 250 * time.Millisecond,
+		TotalUpdateTime:    time.Second,
+		ParseErrors:        map[string]int64{"activity": 2, "inbox": 1},
+	}
+	discovery := parser.DiscoveryMetrics{Runs: 4, RootCacheHits: 7, RootCacheMisses: 1, SubCacheHits: 5, SubCacheMisses: 2}
+
+	body := string(renderMetrics(state, health, discovery))
+
+	for _, want := range []string{
+		"# TYPE atm_teams gauge",
+		`atm_teams{provider="claude"} 1`,
+		`atm_teams{provider="codex"} 1`,
+		`atm_agents{provider="claude",status="working"} 2`,
+		`atm_agents{provider="codex",status="idle"} 1`,
+		`atm_tasks{status="completed"} 1`,
+		`atm_processes{provider="claude"} 1`,
+		"atm_state_updated_timestamp_seconds 1.7e+09",
+		"atm_collector_updates_total 3",
+		"atm_collector_last_update_duration_seconds 0.25",
+		"# TYPE atm_collector_update_duration_seconds_total counter",
+		"atm_collector_update_duration_seconds_total 1",
+		`atm_collector_parse_errors_total{source="activity"} 2`,
+		`atm_discovery_cache_hits_total{cache="root"} 7`,
+		`atm_discovery_cache_misses_total{cache="subagent"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsEndpointServesPrometheusText(t *testing.T) {
+	server := NewServer(nil, ":0", fstest.MapFS{
+		"index.html": {Data: []byte("dark-dashboard")},
+	}, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	res := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	if got := res.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	if !strings.Contains(res.Body.String(), "atm_collector_updates_total 0") {
+		t.Fatalf("expected collector health metrics, got:\n%s", res.Body.String())
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	if got := escapeLabelValue("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Fatalf("unexpected escape result %q", got)
+	}
+}
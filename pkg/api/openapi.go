@@ -0,0 +1,268 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// apiV1QueryParams documents the shared list query parameters.
+var apiV1QueryParams = map[string]map[string]any{
+	"team":          {"description": "Only items of this team (case-insensitive)", "schema": map[string]any{"type": "string"}},
+	"provider":      {"description": "claude, codex or openclaw", "schema": map[string]any{"type": "string"}},
+	"status":        {"description": "Agent, task or run status; for teams, the managed status or any member's status", "schema": map[string]any{"type": "string"}},
+	"updated_since": {"description": "RFC 3339 time, or a duration before now such as 15m", "schema": map[string]any{"type": "string"}},
+	"limit":         {"description": "Page size, default 100, at most 1000", "schema": map[string]any{"type": "integer", "minimum": 1, "maximum": apiV1MaxLimit}},
+	"offset":        {"description": "Items to skip; use page.next_offset for the next page", "schema": map[string]any{"type": "integer", "minimum": 0}},
+	"agent":         {"description": "Managed agent id; defaults to the team's primary agent", "schema": map[string]any{"type": "string"}},
+	"lines":         {"description": "Trailing log lines, default 200, at most 5000", "schema": map[string]any{"type": "integer", "minimum": 1, "maximum": apiV1MaxLogLines}},
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (s *Server) handleV1OpenAPI(w http.ResponseWriter, r *http.Request) {
+	respondAPIStatus(w, http.StatusOK, buildOpenAPIDocument(s.apiV1Routes()))
+}
+
+// buildOpenAPIDocument describes routes as OpenAPI 3.1, deriving schemas
+// from the Go types they exchange.
+func buildOpenAPIDocument(routes []apiV1Route) map[string]any {
+	schemas := newSchemaRegistry()
+	errorRef := schemas.ref(reflect.TypeOf(apiV1Error{}))
+	pageRef := schemas.ref(reflect.TypeOf(apiV1Page{}))
+	errorResponse := func(description string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content":     map[string]any{"application/json": map[string]any{"schema": errorRef}},
+		}
+	}
+
+	paths := map[string]any{}
+	for _, route := range routes {
+		operation := map[string]any{
+			"summary":     route.Summary,
+			"tags":        []string{route.Tag},
+			"operationId": operationID(route),
+		}
+
+		var params []map[string]any
+		for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			params = append(params, map[string]any{
+				"name": match[1], "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, name := range route.Query {
+			param := map[string]any{"name": name, "in": "query"}
+			for key, value := range apiV1QueryParams[name] {
+				param[key] = value
+			}
+			params = append(params, param)
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if route.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{"application/json": map[string]any{
+					"schema": schemas.ref(reflect.TypeOf(route.Request)),
+				}},
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		if route.Response != nil {
+			var body map[string]any
+			switch {
+			case route.Tag == "meta":
+				body = map[string]any{"type": "object"}
+			case route.List:
+				body = objectSchema(map[string]any{
+					"data": map[string]any{"type": "array", "items": schemas.ref(reflect.TypeOf(route.Response))},
+					"page": pageRef,
+				}, "data", "page")
+			default:
+				body = objectSchema(map[string]any{"data": schemas.ref(reflect.TypeOf(route.Response))}, "data")
+			}
+			success["content"] = map[string]any{"application/json": map[string]any{"schema": body}}
+		}
+		responses := map[string]any{strconv.Itoa(status): success}
+		if len(params) > 0 || route.Request != nil {
+			responses["400"] = errorResponse("Invalid parameters or body")
+		}
+		if route.Permission != "" {
+			responses["403"] = errorResponse("Missing permission")
+			operation["security"] = []map[string]any{{"bearerAuth": []string{}}, {"cookieAuth": []string{}}}
+			operation["x-permission"] = string(route.Permission)
+		} else {
+			operation["security"] = []map[string]any{}
+		}
+		if strings.Contains(route.Path, "{") {
+			responses["404"] = errorResponse("Not found")
+		}
+		operation["responses"] = responses
+
+		path := apiV1Prefix + route.Path
+		item, _ := paths[path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "Agent Team Monitor API",
+			"version":     "1",
+			"description": "Read and control monitored agent teams. State-changing requests authenticated by the session cookie must send X-CSRF-Token.",
+		},
+		"servers": []map[string]any{{"url": "/"}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas.defs,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "description": "API token created with `agent-team-monitor token create`"},
+				"cookieAuth": map[string]any{"type": "apiKey", "in": "cookie", "name": SessionCookieName},
+			},
+		},
+	}
+}
+
+// operationID turns "GET /teams/{team}/agents" into "getTeamsTeamAgents".
+func operationID(route apiV1Route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '.' || r == '_'
+	}) {
+		b.WriteString(exportName(part))
+	}
+	return b.String()
+}
+
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schemaRegistry derives JSON schemas from Go types, following encoding/json
+// rules: json tags name fields, omitempty fields are optional and embedded
+// structs are flattened.
+type schemaRegistry struct {
+	defs map[string]any
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{defs: map[string]any{}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ref returns a $ref to a named struct, registering it on first use, or
+// an inline schema for anything else.
+func (g *schemaRegistry) ref(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || t.Name() == "" {
+		return g.schema(t)
+	}
+	name := exportName(strings.TrimPrefix(t.Name(), "apiV1"))
+	if _, ok := g.defs[name]; !ok {
+		g.defs[name] = map[string]any{} // placeholder breaks recursion
+		g.defs[name] = g.structSchema(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (g *schemaRegistry) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.ref(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t == reflect.TypeOf(time.Duration(0)) {
+			return map[string]any{"type": "integer", "description": "nanoseconds"}
+		}
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.ref(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.ref(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		return g.structSchema(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+func (g *schemaRegistry) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	g.collectFields(t, properties, &required)
+	return objectSchema(properties, required...)
+}
+
+func (g *schemaRegistry) collectFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.collectFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.ref(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func exportName(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	passwordHashScheme     = "pbkdf2-sha256"
+	passwordHashIterations = 600000
+	passwordSaltBytes      = 16
+	passwordKeyBytes       = 32
+)
+
+// HashPassword returns an encoded PBKDF2-SHA256 hash of the form
+// "pbkdf2-sha256$<iterations>$<salt>$<key>" suitable for ATM_ADMIN_PASSWORD_HASH.
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", fmt.Errorf("password must not be empty")
+	}
+	salt := make([]byte, passwordSaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordHashIterations, passwordKeyBytes)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		passwordHashScheme,
+		strconv.Itoa(passwordHashIterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// VerifyPassword reports whether password matches an encoded hash.
+func VerifyPassword(encoded, password string) bool {
+	parts := strings.Split(strings.TrimSpace(encoded), "$")
+	if len(parts) != 4 || parts[0] != passwordHashScheme {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Permission is a single capability checked by handlers. API token scopes
+// use the same names.
+type Permission string
+
+const (
+	PermissionRead           Permission = "read"
+	PermissionMessage        Permission = "message"
+	PermissionTasksWrite     Permission = "tasks:write"     // writes team task data; implies teams:delete
+	PermissionTeamsDelete    Permission = "teams:delete"    // removes a team's config and task directories
+	PermissionManagedControl Permission = "managed:control" // managed runs and signalling agent processes
+	PermissionAdmin          Permission = "admin"           // implies every other permission
+	PermissionFederate       Permission = "federate"        // lets a node push its state to a hub
+)
+
+var knownPermissions = []Permission{PermissionRead, PermissionMessage, PermissionTasksWrite, PermissionTeamsDelete, PermissionManagedControl, PermissionFederate, PermissionAdmin}
+
+// Role is a named set of permissions assigned to a user.
+type Role string
+
+const (
+	RoleNone     Role = "none"
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleNone:     nil,
+	RoleViewer:   {PermissionRead},
+	RoleOperator: {PermissionRead, PermissionMessage, PermissionManagedControl},
+	RoleAdmin:    {PermissionAdmin},
+}
+
+// ParseRole validates a role name.
+func ParseRole(value string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role %q (valid: viewer, operator, admin, none)", value)
+	}
+	return role, nil
+}
+
+// Permissions lists what the role grants.
+func (r Role) Permissions() []Permission {
+	return append([]Permission{}, rolePermissions[r]...)
+}
+
+// Allows reports whether the role grants permission.
+func (r Role) Allows(permission Permission) bool {
+	return permissionsAllow(rolePermissions[r], permission)
+}
+
+func permissionsAllow(granted []Permission, permission Permission) bool {
+	for _, candidate := range granted {
+		if candidate == permission || candidate == PermissionAdmin {
+			return true
+		}
+		// Team deletion was gated by tasks:write before it got its own
+		// scope, so tokens issued with tasks:write keep it.
+		if candidate == PermissionTasksWrite && permission == PermissionTeamsDelete {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	usersConfigEnv         = "ATM_USERS_CONFIG"
+	defaultUsersConfigName = "users.json"
+)
+
+// UsersConfig lists local accounts. AnonymousRole applies to requests
+// without a session or token; it defaults to viewer so the dashboard stays
+// readable without login. Set it to "none" to require login for reads.
+type UsersConfig struct {
+	AnonymousRole string       `json:"anonymous_role,omitempty"`
+	Users         []UserConfig `json:"users"`
+}
+
+// UserConfig is one local account. Passwords are stored as hashes produced
+// by HashPassword (see the -hash-password flag).
+type UserConfig struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Role         string `json:"role"`
+}
+
+type userAccount struct {
+	username     string
+	passwordHash string
+	role         Role
+}
+
+// DefaultUsersConfigPath returns the users config location, honoring ATM_USERS_CONFIG.
+func DefaultUsersConfigPath() (string, error) {
+	if custom := strings.TrimSpace(os.Getenv(usersConfigEnv)); custom != "" {
+		return custom, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".agent-team-monitor", defaultUsersConfigName), nil
+}
+
+// LoadUsersConfig reads a users config file. A missing file yields an empty config.
+func LoadUsersConfig(path string) (UsersConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return UsersConfig{}, nil
+		}
+		return UsersConfig{}, err
+	}
+	var cfg UsersConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return UsersConfig{}, fmt.Errorf("parse users config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c UsersConfig) compile() (Role, []userAccount, error) {
+	anonymous := RoleViewer
+	if strings.TrimSpace(c.AnonymousRole) != "" {
+		role, err := ParseRole(c.AnonymousRole)
+		if err != nil {
+			return "", nil, fmt.Errorf("anonymous_role: %w", err)
+		}
+		if role == RoleAdmin {
+			return "", nil, fmt.Errorf("anonymous_role must not be admin")
+		}
+		anonymous = role
+	}
+
+	seen := make(map[string]bool)
+	accounts := make([]userAccount, 0, len(c.Users))
+	for i, user := range c.Users {
+		username := strings.TrimSpace(user.Username)
+		if username == "" {
+			return "", nil, fmt.Errorf("users[%d]: username is required", i)
+		}
+		if seen[username] {
+			return "", nil, fmt.Errorf("users[%d]: duplicate username %q", i, username)
+		}
+		seen[username] = true
+		if !strings.HasPrefix(strings.TrimSpace(user.PasswordHash), passwordHashScheme+"$") {
+			return "", nil, fmt.Errorf("users[%d]: password_hash must be a %s hash", i, passwordHashScheme)
+		}
+		role, err := ParseRole(user.Role)
+		if err != nil {
+			return "", nil, fmt.Errorf("users[%d]: %w", i, err)
+		}
+		accounts = append(accounts, userAccount{
+			username:     username,
+			passwordHash: strings.TrimSpace(user.PasswordHash),
+			role:         role,
+		})
+	}
+	return anonymous, accounts, nil
+}
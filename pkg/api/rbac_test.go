@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	testPasswordHashOnce sync.Once
+	testPasswordHash     string
+)
+
+// hashedTestPassword hashes "secret" once; PBKDF2 is deliberately slow.
+func hashedTestPassword(t *testing.T) string {
+	t.Helper()
+	testPasswordHashOnce.Do(func() {
+		encoded, err := HashPassword("secret")
+		if err != nil {
+			t.Fatalf("HashPassword: %v", err)
+		}
+		testPasswordHash = encoded
+	})
+	return testPasswordHash
+}
+
+func newTestRBACServer(t *testing.T, anonymousRole string) (*Server, *AuthManager) {
+	t.Helper()
+	t.Setenv("ATM_TOKENS_FILE", filepath.Join(t.TempDir(), "api-tokens.json"))
+	server, auth := newTestAuthServer(t)
+	hash := hashedTestPassword(t)
+	err := auth.LoadUsers(UsersConfig{
+		AnonymousRole: anonymousRole,
+		Users: []UserConfig{
+			{Username: "vera", PasswordHash: hash, Role: "viewer"},
+			{Username: "otto", PasswordHash: hash, Role: "operator"},
+			{Username: "ada", PasswordHash: hash, Role: "admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("LoadUsers: %v", err)
+	}
+	return server, auth
+}
+
+func loginAs(t *testing.T, server *Server, username string) *http.Cookie {
+	t.Helper()
+	res := serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"`+username+`","password":"secret"}`)
+	if res.Code != http.StatusOK {
+		t.Fatalf("login %s: %d %s", username, res.Code, res.Body.String())
+	}
+	return sessionCookie(t, res)
+}
+
+func TestRolesGateRoutes(t *testing.T) {
+	server, _ := newTestRBACServer(t, "")
+
+	cases := []struct {
+		user   string
+		method string
+		path   string
+		want   int
+	}{
+		{"vera", http.MethodGet, "/api/state", http.StatusOK},
+		{"vera", http.MethodPost, "/api/agents/message", http.StatusForbidden},
+		{"vera", http.MethodDelete, "/api/teams/demo", http.StatusForbidden},
+		// 503 means the request got past auth to the missing collector.
+		{"otto", http.MethodPost, "/api/agents/message", http.StatusServiceUnavailable},
+		{"otto", http.MethodDelete, "/api/teams/demo", http.StatusForbidden},
+		{"otto", http.MethodGet, "/api/tokens", http.StatusForbidden},
+		{"vera", http.MethodPost, "/api/v1/processes/123/signals", http.StatusForbidden},
+		{"otto", http.MethodPost, "/api/v1/processes/123/signals", http.StatusServiceUnavailable},
+		{"ada", http.MethodPost, "/api/agents/message", http.StatusServiceUnavailable},
+		{"ada", http.MethodGet, "/api/tokens", http.StatusOK},
+	}
+
+	cookies := make(map[string]*http.Cookie)
+	for _, tc := range cases {
+		cookie, ok := cookies[tc.user]
+		if !ok {
+			cookie = loginAs(t, server, tc.user)
+			cookies[tc.user] = cookie
+		}
+		res := serveAuthRequest(server, tc.method, tc.path, "", cookie)
+		if res.Code != tc.want {
+			t.Errorf("%s %s %s: expected %d, got %d: %s", tc.user, tc.method, tc.path, tc.want, res.Code, res.Body.String())
+		}
+	}
+}
+
+func TestLoginReportsRolePermissions(t *testing.T) {
+	server, _ := newTestRBACServer(t, "")
+
+	res := serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"otto","password":"secret"}`)
+	if res.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", res.Code, res.Body.String())
+	}
+	var status AuthStatus
+	if err := json.Unmarshal(res.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if status.Role != RoleOperator || !permissionsAllow(status.Permissions, PermissionManagedControl) || permissionsAllow(status.Permissions, PermissionTeamsDelete) {
+		t.Fatalf("unexpected operator status: %+v", status)
+	}
+}
+
+func TestAnonymousRoleNoneRequiresLogin(t *testing.T) {
+	server, _ := newTestRBACServer(t, "none")
+
+	if res := serveAuthRequest(server, http.MethodGet, "/api/state", ""); res.Code != http.StatusForbidden {
+		t.Fatalf("expected anonymous state read to be denied, got %d", res.Code)
+	}
+	if res := serveAuthRequest(server, http.MethodGet, "/api/health", ""); res.Code != http.StatusOK {
+		t.Fatalf("expected health to stay open, got %d", res.Code)
+	}
+
+	res := serveAuthRequest(server, http.MethodGet, "/api/auth/status", "")
+	var status AuthStatus
+	if err := json.Unmarshal(res.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if status.Authenticated || status.Role != RoleNone || len(status.Permissions) != 0 {
+		t.Fatalf("unexpected anonymous status: %+v", status)
+	}
+
+	cookie := loginAs(t, server, "vera")
+	if res := serveAuthRequest(server, http.MethodGet, "/api/state", "", cookie); res.Code != http.StatusOK {
+		t.Fatalf("expected viewer state read to succeed, got %d", res.Code)
+	}
+}
+
+func TestLoadUsersRejectsInvalidConfig(t *testing.T) {
+	_, auth := newTestAuthServer(t)
+	hash := hashedTestPassword(t)
+
+	cases := map[string]UsersConfig{
+		"admin anonymous role": {AnonymousRole: "admin"},
+		"unknown anonymous":    {AnonymousRole: "guest"},
+		"missing username":     {Users: []UserConfig{{PasswordHash: hash, Role: "viewer"}}},
+		"plaintext password":   {Users: []UserConfig{{Username: "vera", PasswordHash: "secret", Role: "viewer"}}},
+		"unknown role":         {Users: []UserConfig{{Username: "vera", PasswordHash: hash, Role: "owner"}}},
+		"duplicate username": {Users: []UserConfig{
+			{Username: "vera", PasswordHash: hash, Role: "viewer"},
+			{Username: "vera", PasswordHash: hash, Role: "admin"},
+		}},
+		"shadows env admin": {Users: []UserConfig{{Username: "admin", PasswordHash: hash, Role: "viewer"}}},
+	}
+	for name, cfg := range cases {
+		if err := auth.LoadUsers(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := auth.Login("admin", "secret"); err != nil {
+		t.Fatalf("expected env admin to survive rejected configs, got %v", err)
+	}
+}
+
+func TestLoadUsersFromEnvReadsConfigFile(t *testing.T) {
+	_, auth := newTestAuthServer(t)
+	path := filepath.Join(t.TempDir(), "users.json")
+	t.Setenv("ATM_USERS_CONFIG", path)
+
+	if err := auth.LoadUsersFromEnv(); err != nil {
+		t.Fatalf("expected missing users file to be ignored, got %v", err)
+	}
+
+	data := `{"anonymous_role":"none","users":[{"username":"otto","password_hash":"` + hashedTestPassword(t) + `","role":"operator"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write users config: %v", err)
+	}
+	if err := auth.LoadUsersFromEnv(); err != nil {
+		t.Fatalf("LoadUsersFromEnv: %v", err)
+	}
+	session, err := auth.Login("otto", "secret")
+	if err != nil || session.Role != RoleOperator {
+		t.Fatalf("expected operator login, got %+v (%v)", session, err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatalf("write users config: %v", err)
+	}
+	if err := auth.LoadUsersFromEnv(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("expected parse error naming %s, got %v", path, err)
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// PrivacyProfile selects which agent details a read-only server still
+// serves.
+type PrivacyProfile string
+
+const (
+	// PrivacyFull serves everything the dashboard normally shows.
+	PrivacyFull PrivacyProfile = "full"
+	// PrivacyStandard drops thinking, tool details and full responses.
+	PrivacyStandard PrivacyProfile = "standard"
+	// PrivacyStrict also drops messages, todos, task descriptions, paths,
+	// errors and process command lines, leaving names, statuses and counts.
+	PrivacyStrict PrivacyProfile = "strict"
+)
+
+// ParsePrivacyProfile accepts full, standard or strict; empty means
+// standard.
+func ParsePrivacyProfile(value string) (PrivacyProfile, error) {
+	switch profile := PrivacyProfile(strings.ToLower(strings.TrimSpace(value))); profile {
+	case "":
+		return PrivacyStandard, nil
+	case PrivacyFull, PrivacyStandard, PrivacyStrict:
+		return profile, nil
+	default:
+		return "", fmt.Errorf("unknown privacy profile %q (want full, standard or strict)", value)
+	}
+}
+
+// ReadOnlyOptions configures kiosk mode.
+type ReadOnlyOptions struct {
+	Privacy PrivacyProfile
+	// Rotate is how long each team stays in the spotlight; 0 disables
+	// rotation.
+	Rotate time.Duration
+}
+
+// SetReadOnly turns the server into a kiosk: every mutating route and the
+// managed team controls are refused, sessions report read permission only,
+// and served state is filtered by opts.Privacy.
+func (s *Server) SetReadOnly(opts ReadOnlyOptions) {
+	if opts.Privacy == "" {
+		opts.Privacy = PrivacyStandard
+	}
+	s.readOnly = &opts
+}
+
+// SetPrivacyProfile changes what a read-only server serves while it runs.
+// It does nothing when the server is not read-only.
+func (s *Server) SetPrivacyProfile(profile PrivacyProfile) {
+	if !s.ReadOnly() {
+		return
+	}
+	if profile == "" {
+		profile = PrivacyStandard
+	}
+	s.readOnlyMu.Lock()
+	defer s.readOnlyMu.Unlock()
+	s.readOnly.Privacy = profile
+}
+
+// PrivacyProfile returns the profile a read-only server serves, or "" when
+// the server is not read-only.
+func (s *Server) PrivacyProfile() PrivacyProfile {
+	if !s.ReadOnly() {
+		return ""
+	}
+	s.readOnlyMu.RLock()
+	defer s.readOnlyMu.RUnlock()
+	return s.readOnly.Privacy
+}
+
+// ReadOnly reports whether the server runs in kiosk mode.
+func (s *Server) ReadOnly() bool {
+	return s != nil && s.readOnly != nil
+}
+
+// readOnlyAllows keeps reads and sign-in working. Managed team routes are
+// refused outright since they only exist to drive the control panel.
+func readOnlyAllows(r *http.Request) bool {
+	path := r.URL.Path
+	if rest, ok := strings.CutPrefix(path, apiV1Prefix+"/"); ok {
+		path = "/api/" + rest
+	}
+	if strings.HasPrefix(path, "/api/managed") || strings.HasPrefix(path, "/api/tokens") {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return path == "/api/auth/login" || path == "/api/auth/logout"
+}
+
+// readOnlyStatus caps what the dashboard offers to read access.
+func (s *Server) readOnlyStatus(status AuthStatus) AuthStatus {
+	if !s.ReadOnly() {
+		return status
+	}
+	status.ReadOnly = true
+	if permissionsAllow(status.Permissions, PermissionRead) {
+		status.Permissions = []Permission{PermissionRead}
+	} else {
+		status.Permissions = []Permission{}
+	}
+	return status
+}
+
+// kioskState applies the privacy profile and picks the spotlight team.
+func (s *Server) kioskState(state types.MonitorState, now time.Time) types.MonitorState {
+	if !s.ReadOnly() {
+		return state
+	}
+	s.readOnlyMu.RLock()
+	opts := *s.readOnly
+	s.readOnlyMu.RUnlock()
+	applyPrivacy(&state, opts.Privacy)
+
+	kiosk := &types.KioskInfo{Privacy: string(opts.Privacy)}
+	if len(state.Teams) > 0 {
+		if opts.Rotate > 0 {
+			// Derive the slot from wall-clock time so every screen shows the
+			// same team without the server tracking viewers.
+			slot := now.UnixNano() / int64(opts.Rotate)
+			kiosk.FocusIndex = int(slot % int64(len(state.Teams)))
+			kiosk.RotateSeconds = int(opts.Rotate / time.Second)
+			kiosk.NextRotationAt = time.Unix(0, (slot+1)*int64(opts.Rotate))
+		}
+		kiosk.FocusTeam = state.Teams[kiosk.FocusIndex].Name
+	}
+	state.Kiosk = kiosk
+	return state
+}
+
+// applyPrivacy strips state in place; callers pass a copy.
+func applyPrivacy(state *types.MonitorState, profile PrivacyProfile) {
+	if profile == PrivacyFull {
+		return
+	}
+	strict := profile == PrivacyStrict
+
+	for i := range state.Teams {
+		team := &state.Teams[i]
+		if strict {
+			team.ProjectCwd = ""
+			team.ConfigPath = ""
+			team.LogPath = ""
+			team.LastError = ""
+			for j := range team.Tasks {
+				team.Tasks[j].Description = ""
+			}
+		}
+		for j := range team.Members {
+			agent := &team.Members[j]
+			agent.LastThinking = ""
+			agent.LastToolDetail = ""
+			agent.LatestResponse = ""
+			if strict {
+				agent.Cwd = ""
+				agent.LatestMessage = ""
+				agent.MessageSummary = ""
+				agent.Todos = nil
+				agent.RecentEvents = nil
+				continue
+			}
+			events := agent.RecentEvents[:0]
+			for _, event := range agent.RecentEvents {
+				switch event.Kind {
+				case "message", "task", "status":
+					events = append(events, event)
+				}
+			}
+			agent.RecentEvents = events
+		}
+	}
+
+	if strict {
+		for i := range state.Processes {
+			process := &state.Processes[i]
+			process.Command = ""
+			process.Cwd = ""
+			process.Children = strippedChildren(process.Children)
+			if process.Container != nil && process.Container.DataDir != "" {
+				container := *process.Container
+				container.DataDir = ""
+				process.Container = &container
+			}
+		}
+	}
+}
+
+// strippedChildren copies a process tree without command lines. The
+// children are shared with the collector's state, so they are not
+// blanked in place.
+func strippedChildren(children []types.ChildProcess) []types.ChildProcess {
+	if children == nil {
+		return nil
+	}
+	stripped := make([]types.ChildProcess, len(children))
+	for i, child := range children {
+		child.Command = ""
+		child.Children = strippedChildren(child.Children)
+		stripped[i] = child
+	}
+	return stripped
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func TestReadOnlyRefusesMutatingRoutes(t *testing.T) {
+	server, _ := newTestRBACServer(t, "")
+	server.SetReadOnly(ReadOnlyOptions{Privacy: PrivacyStandard})
+	admin := loginAs(t, server, "ada")
+
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodPost, "/api/agents/message", http.StatusForbidden},
+		{http.MethodDelete, "/api/teams/alpha", http.StatusForbidden},
+		{http.MethodPost, "/api/managed/teams/abc/start", http.StatusForbidden},
+		{http.MethodGet, "/api/managed/teams", http.StatusForbidden},
+		{http.MethodPost, "/api/tokens", http.StatusForbidden},
+		{http.MethodGet, "/api/state", http.StatusOK},
+		{http.MethodGet, "/api/health", http.StatusOK},
+	}
+	for _, tc := range cases {
+		res := serveAuthRequest(server, tc.method, tc.path, `{}`, admin)
+		if res.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d %s", tc.method, tc.path, tc.want, res.Code, res.Body.String())
+		}
+	}
+
+	res := serveAuthRequest(server, http.MethodGet, "/api/auth/status", "", admin)
+	var status AuthStatus
+	if err := json.Unmarshal(res.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if !status.ReadOnly || len(status.Permissions) != 1 || status.Permissions[0] != PermissionRead {
+		t.Fatalf("expected admin to be capped to read, got %+v", status)
+	}
+}
+
+func kioskTestState() types.MonitorState {
+	return types.MonitorState{
+		Teams: []types.TeamInfo{
+			{
+				Name:       "alpha",
+				ProjectCwd: "~/src/alpha",
+				Tasks:      []types.TaskInfo{{ID: "1", Subject: "ship", Description: "internal details"}},
+				Members: []types.AgentInfo{{
+					Name:           "lead",
+					Status:         "working",
+					LatestMessage:  "please review",
+					LatestResponse: "full answer",
+					LastThinking:   "hmm",
+					LastToolUse:    "Bash",
+					LastToolDetail: "cat secrets.txt",
+					RecentEvents: []types.AgentEvent{
+						{Kind: "thinking", Text: "hmm"},
+						{Kind: "message", Text: "please review"},
+						{Kind: "tool", Text: "cat secrets.txt"},
+					},
+				}},
+			},
+			{Name: "beta"},
+			{Name: "gamma"},
+		},
+		Processes: []types.ProcessInfo{{PID: 42, Command: "claude --api-key x"}},
+	}
+}
+
+func TestKioskStateAppliesPrivacyProfiles(t *testing.T) {
+	server, _ := newTestAuthServer(t)
+	server.SetReadOnly(ReadOnlyOptions{Privacy: PrivacyStandard})
+
+	state := server.kioskState(kioskTestState(), time.Now())
+	agent := state.Teams[0].Members[0]
+	if agent.LastThinking != "" || agent.LastToolDetail != "" || agent.LatestResponse != "" {
+		t.Fatalf("expected standard profile to strip thinking, tool details and responses: %+v", agent)
+	}
+	if agent.LastToolUse != "Bash" || agent.LatestMessage == "" {
+		t.Fatalf("expected standard profile to keep tool names and messages: %+v", agent)
+	}
+	if len(agent.RecentEvents) != 1 || agent.RecentEvents[0].Kind != "message" {
+		t.Fatalf("expected only message events to remain, got %+v", agent.RecentEvents)
+	}
+	if state.Kiosk == nil || state.Kiosk.Privacy != "standard" {
+		t.Fatalf("expected kiosk info, got %+v", state.Kiosk)
+	}
+
+	server.SetReadOnly(ReadOnlyOptions{Privacy: PrivacyStrict})
+	state = server.kioskState(kioskTestState(), time.Now())
+	agent = state.Teams[0].Members[0]
+	if agent.LatestMessage != "" || agent.RecentEvents != nil || state.Teams[0].ProjectCwd != "" {
+		t.Fatalf("expected strict profile to strip messages and paths: %+v", state.Teams[0])
+	}
+	if state.Teams[0].Tasks[0].Description != "" || state.Teams[0].Tasks[0].Subject != "ship" {
+		t.Fatalf("expected strict profile to keep only task subjects: %+v", state.Teams[0].Tasks)
+	}
+	if state.Processes[0].Command != "" || state.Processes[0].PID != 42 {
+		t.Fatalf("expected strict profile to strip command lines: %+v", state.Processes)
+	}
+
+	server.SetReadOnly(ReadOnlyOptions{Privacy: PrivacyFull})
+	state = server.kioskState(kioskTestState(), time.Now())
+	if state.Teams[0].Members[0].LastThinking != "hmm" {
+		t.Fatal("expected full profile to keep everything")
+	}
+}
+
+func TestKioskStrictProfileStripsProcesses(t *testing.T) {
+	server, _ := newTestAuthServer(t)
+	server.SetReadOnly(ReadOnlyOptions{Privacy: PrivacyStrict})
+
+	source := kioskTestState()
+	source.Processes[0].Cwd = "~/src/alpha"
+	children := []types.ChildProcess{{
+		PID:      43,
+		Command:  "bash -c 'deploy --token y'",
+		Children: []types.ChildProcess{{PID: 44, Command: "curl -H 'Authorization: z'"}},
+	}}
+	source.Processes[0].Children = children
+	container := &types.ContainerInfo{ID: "abc123", Name: "devbox", DataDir: "/home/ops/.claude", Root: "devbox"}
+	source.Processes[0].Container = container
+	state := server.kioskState(source, time.Now())
+
+	process := state.Processes[0]
+	if process.Cwd != "" || process.Command != "" {
+		t.Fatalf("expected strict profile to strip the process's cwd and command: %+v", process)
+	}
+	if len(process.Children) != 1 || process.Children[0].PID != 43 || process.Children[0].Command != "" {
+		t.Fatalf("expected children to keep only their PIDs: %+v", process.Children)
+	}
+	if grandchild := process.Children[0].Children; len(grandchild) != 1 || grandchild[0].PID != 44 || grandchild[0].Command != "" {
+		t.Fatalf("expected grandchildren to be stripped too: %+v", grandchild)
+	}
+	if process.Container == nil || process.Container.DataDir != "" || process.Container.Name != "devbox" {
+		t.Fatalf("expected strict profile to strip the container's data directory: %+v", process.Container)
+	}
+	if children[0].Command == "" || children[0].Children[0].Command == "" || container.DataDir == "" {
+		t.Fatal("expected the collector's process tree to stay intact")
+	}
+}
+
+func TestKioskStateRotatesTeams(t *testing.T) {
+	server, _ := newTestAuthServer(t)
+	server.SetReadOnly(ReadOnlyOptions{Privacy: PrivacyFull, Rotate: 10 * time.Second})
+
+	start := time.Unix(1_700_000_000, 0) // a multiple of 10s
+	seen := make([]string, 0, 4)
+	for i := 0; i < 4; i++ {
+		state := server.kioskState(kioskTestState(), start.Add(time.Duration(i)*10*time.Second))
+		if state.Teams[state.Kiosk.FocusIndex].Name != state.Kiosk.FocusTeam {
+			t.Fatalf("focus index and team disagree: %+v", state.Kiosk)
+		}
+		seen = append(seen, state.Kiosk.FocusTeam)
+	}
+	if seen[0] == seen[1] || seen[1] == seen[2] || seen[0] != seen[3] {
+		t.Fatalf("expected teams to rotate with period 3, got %v", seen)
+	}
+
+	state := server.kioskState(kioskTestState(), start.Add(3*time.Second))
+	if state.Kiosk.RotateSeconds != 10 || !state.Kiosk.NextRotationAt.Equal(start.Add(10*time.Second)) {
+		t.Fatalf("unexpected rotation schedule %+v", state.Kiosk)
+	}
+
+	server.SetReadOnly(ReadOnlyOptions{Privacy: PrivacyFull})
+	state = server.kioskState(kioskTestState(), start.Add(25*time.Second))
+	if state.Kiosk.FocusTeam != "alpha" || state.Kiosk.RotateSeconds != 0 {
+		t.Fatalf("expected rotation off to pin the first team, got %+v", state.Kiosk)
+	}
+}
+
+func TestParsePrivacyProfile(t *testing.T) {
+	if profile, err := ParsePrivacyProfile(""); err != nil || profile != PrivacyStandard {
+		t.Fatalf("expected empty profile to default to standard, got %q %v", profile, err)
+	}
+	if profile, err := ParsePrivacyProfile(" Strict "); err != nil || profile != PrivacyStrict {
+		t.Fatalf("expected strict, got %q %v", profile, err)
+	}
+	if _, err := ParsePrivacyProfile("secret"); err == nil {
+		t.Fatal("expected unknown profile to be rejected")
+	}
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	allowedHostsEnv   = "ATM_ALLOWED_HOSTS"
+	corsOriginsEnv    = "ATM_CORS_ORIGINS"
+	loginRateLimitEnv = "ATM_LOGIN_RATE_LIMIT"
+
+	defaultLoginRateLimit = 10
+)
+
+// defaultCORSOrigins match any port on loopback.
+var defaultCORSOrigins = []string{
+	"http://localhost", "http://127.0.0.1", "http://[::1]",
+	"https://localhost", "https://127.0.0.1", "https://[::1]",
+}
+
+// SecurityConfig hardens the web API against DNS rebinding, cross-site
+// requests and password guessing.
+type SecurityConfig struct {
+	// AllowedHosts lists Host header names accepted besides loopback names,
+	// IP literals and this machine's hostname. "*.example.com" matches
+	// subdomains; "*" disables the check.
+	AllowedHosts []string
+	// AllowedOrigins lists CORS origins. An origin without a port matches
+	// any port.
+	AllowedOrigins []string
+	// CSRF requires X-CSRF-Token on state-changing /api requests.
+	CSRF bool
+	// LoginRateLimit caps login attempts per client per minute; 0 disables.
+	LoginRateLimit int
+}
+
+// DefaultSecurityConfig is what the web server runs with unless overridden
+// by the environment.
+func DefaultSecurityConfig() SecurityConfig {
+	return SecurityConfig{
+		AllowedOrigins: append([]string(nil), defaultCORSOrigins...),
+		CSRF:           true,
+		LoginRateLimit: defaultLoginRateLimit,
+	}
+}
+
+// permissiveSecurityConfig keeps servers built with NewServer alone (tests,
+// embedding) working without Host or CSRF checks until SetSecurity is called.
+func permissiveSecurityConfig() SecurityConfig {
+	return SecurityConfig{
+		AllowedHosts:   []string{"*"},
+		AllowedOrigins: append([]string(nil), defaultCORSOrigins...),
+	}
+}
+
+// LoadSecurityConfigFromEnv reads ATM_ALLOWED_HOSTS and ATM_CORS_ORIGINS
+// (comma-separated) and ATM_LOGIN_RATE_LIMIT on top of the defaults.
+func LoadSecurityConfigFromEnv() (SecurityConfig, error) {
+	cfg := DefaultSecurityConfig()
+	cfg.AllowedHosts = splitCommaList(os.Getenv(allowedHostsEnv))
+
+	if origins := splitCommaList(os.Getenv(corsOriginsEnv)); len(origins) > 0 {
+		for _, origin := range origins {
+			if origin == "*" {
+				return SecurityConfig{}, fmt.Errorf("%s must list explicit origins; credentials rule out *", corsOriginsEnv)
+			}
+			if _, err := parseOrigin(origin); err != nil {
+				return SecurityConfig{}, fmt.Errorf("invalid %s entry %q: %w", corsOriginsEnv, origin, err)
+			}
+		}
+		cfg.AllowedOrigins = origins
+	}
+
+	if raw := strings.TrimSpace(os.Getenv(loginRateLimitEnv)); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			return SecurityConfig{}, fmt.Errorf("invalid %s %q", loginRateLimitEnv, raw)
+		}
+		cfg.LoginRateLimit = limit
+	}
+	return cfg, nil
+}
+
+// SetSecurity replaces the server's host, CORS, CSRF and login limits.
+func (s *Server) SetSecurity(cfg SecurityConfig) {
+	s.securityMu.Lock()
+	defer s.securityMu.Unlock()
+	s.security = cfg
+	s.loginLimiter = newRateLimiter(cfg.LoginRateLimit, time.Minute)
+}
+
+func (s *Server) securityConfig() (SecurityConfig, *rateLimiter) {
+	s.securityMu.RLock()
+	defer s.securityMu.RUnlock()
+	return s.security, s.loginLimiter
+}
+
+func (s *Server) securityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg, _ := s.securityConfig()
+
+		if !isAllowedHost(r.Host, cfg.AllowedHosts) {
+			http.Error(w, "Host not allowed", http.StatusMisdirectedRequest)
+			return
+		}
+
+		origin := r.Header.Get("Origin")
+		if isAllowedOrigin(origin, cfg.AllowedOrigins) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+CSRFHeaderName)
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if s.ReadOnly() && !readOnlyAllows(r) {
+			http.Error(w, "Server is in read-only mode", http.StatusForbidden)
+			return
+		}
+
+		if cfg.CSRF && requiresCSRF(r) {
+			if err := s.auth.VerifyCSRF(r); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requiresCSRF selects state-changing API calls. Login has no session to
+// bind to yet; it is rate limited instead.
+func requiresCSRF(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, "/api/") && r.URL.Path != "/api/auth/login"
+}
+
+func isAllowedHost(hostHeader string, allowed []string) bool {
+	host := hostHeader
+	if h, _, err := net.SplitHostPort(hostHeader); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if host == "" {
+		return false
+	}
+
+	// IP literals cannot be rebound, and loopback names never leave the machine.
+	if net.ParseIP(host) != nil || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		hostname = strings.ToLower(hostname)
+		if host == hostname || host == strings.SplitN(hostname, ".", 2)[0] {
+			return true
+		}
+	}
+	for _, pattern := range allowed {
+		pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+		switch {
+		case pattern == "*" || pattern == host:
+			return true
+		case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowedOrigin compares scheme and host exactly, so "http://localhost"
+// does not admit "http://localhost.evil.example".
+func isAllowedOrigin(origin string, allowed []string) bool {
+	if origin == "" {
+		return false
+	}
+	got, err := parseOrigin(origin)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range allowed {
+		want, err := parseOrigin(candidate)
+		if err != nil {
+			continue
+		}
+		if got.Scheme == want.Scheme && got.Hostname() == want.Hostname() && (want.Port() == "" || got.Port() == want.Port()) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseOrigin(origin string) (*url.URL, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+	if err != nil {
+		return nil, err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") {
+		return nil, fmt.Errorf("expected scheme://host[:port]")
+	}
+	return parsed, nil
+}
+
+func splitCommaList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// rateLimiter is a per-key token bucket: limit attempts per window, refilled
+// continuously.
+type rateLimiter struct {
+	limit  float64
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		limit:   float64(limit),
+		window:  window,
+		now:     time.Now,
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// Allow takes one attempt for key. When refused it returns how long until
+// the next attempt is allowed.
+func (l *rateLimiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	rate := l.limit / l.window.Seconds()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &rateBucket{tokens: l.limit, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(l.limit, bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+
+	// Forget clients whose buckets have refilled, keeping the map small.
+	if len(l.buckets) > 1024 {
+		for k, b := range l.buckets {
+			if k != key && now.Sub(b.last) >= l.window {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
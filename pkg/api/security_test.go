@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestSecureServer applies the production defaults; httptest requests
+// use Host example.com, which is allowed explicitly.
+func newTestSecureServer(t *testing.T, limit int) (*Server, *AuthManager) {
+	t.Helper()
+	server, auth := newTestRBACServer(t, "")
+	cfg := DefaultSecurityConfig()
+	cfg.AllowedHosts = []string{"example.com"}
+	cfg.LoginRateLimit = limit
+	server.SetSecurity(cfg)
+	return server, auth
+}
+
+func TestSecurityMiddlewareRejectsUnknownHosts(t *testing.T) {
+	server, _ := newTestSecureServer(t, 0)
+
+	cases := map[string]int{
+		"evil.example":        http.StatusMisdirectedRequest,
+		"localhost.evil.test": http.StatusMisdirectedRequest,
+		"example.com":         http.StatusOK,
+		"127.0.0.1:8080":      http.StatusOK,
+		"[::1]:8080":          http.StatusOK,
+		"localhost:8080":      http.StatusOK,
+		"app.localhost":       http.StatusOK,
+	}
+	for host, want := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+		req.Host = host
+		res := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(res, req)
+		if res.Code != want {
+			t.Errorf("host %q: expected %d, got %d", host, want, res.Code)
+		}
+	}
+}
+
+func TestIsAllowedHostWildcards(t *testing.T) {
+	allowed := []string{"*.corp.example", "monitor.lan"}
+	for host, want := range map[string]bool{
+		"a.corp.example:443": true,
+		"corp.example":       false,
+		"monitor.lan":        true,
+		"MONITOR.LAN.":       true,
+		"other.lan":          false,
+		"":                   false,
+	} {
+		if got := isAllowedHost(host, allowed); got != want {
+			t.Errorf("isAllowedHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestCSRFTokenRequiredForCookieSessions(t *testing.T) {
+	server, _ := newTestSecureServer(t, 0)
+	res := serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"otto","password":"secret"}`)
+	if res.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", res.Code, res.Body.String())
+	}
+	cookie := sessionCookie(t, res)
+	var status AuthStatus
+	if err := json.Unmarshal(res.Body.Bytes(), &status); err != nil || status.CSRFToken == "" {
+		t.Fatalf("expected login to return a CSRF token, got %s (%v)", res.Body.String(), err)
+	}
+
+	logout := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil)
+		req.AddCookie(cookie)
+		if token != "" {
+			req.Header.Set(CSRFHeaderName, token)
+		}
+		res := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(res, req)
+		return res.Code
+	}
+	if code := logout(""); code != http.StatusForbidden {
+		t.Fatalf("expected missing CSRF token to be rejected, got %d", code)
+	}
+	anonymous := server.auth.AnonymousStatus().CSRFToken
+	if code := logout(anonymous); code != http.StatusForbidden {
+		t.Fatalf("expected anonymous token to be rejected for a session, got %d", code)
+	}
+	if code := logout(status.CSRFToken); code != http.StatusOK {
+		t.Fatalf("expected session CSRF token to be accepted, got %d", code)
+	}
+}
+
+func TestCSRFExemptsBearerTokens(t *testing.T) {
+	server, auth := newTestSecureServer(t, 0)
+	_, secret, err := auth.Tokens().Create("ci", []string{string(PermissionMessage)}, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/agents/message", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	res := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(res, req)
+	if res.Code == http.StatusForbidden {
+		t.Fatalf("expected bearer request to skip CSRF, got %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	server, _ := newTestSecureServer(t, 2)
+
+	for i := 0; i < 2; i++ {
+		res := serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"vera","password":"wrong"}`)
+		if res.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, res.Code)
+		}
+	}
+	res := serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"vera","password":"secret"}`)
+	if res.Code != http.StatusTooManyRequests || res.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", res.Code, res.Header())
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	limiter := newRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("a")
+	limiter.Allow("a")
+	if ok, wait := limiter.Allow("a"); ok || wait <= 0 || wait > 30*time.Second {
+		t.Fatalf("expected third attempt to wait up to 30s, got %v %v", ok, wait)
+	}
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Fatal("expected other clients to have their own bucket")
+	}
+	now = now.Add(30 * time.Second)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatal("expected bucket to refill")
+	}
+	if newRateLimiter(0, time.Minute) != nil {
+		t.Fatal("expected limit 0 to disable rate limiting")
+	}
+}
+
+func TestIsAllowedOriginMatchesExactly(t *testing.T) {
+	allowed := []string{"http://localhost", "https://dash.example:8443"}
+	for origin, want := range map[string]bool{
+		"http://localhost:5173":         true,
+		"http://localhost.evil.example": false,
+		"https://localhost":             false,
+		"https://dash.example:8443":     true,
+		"https://dash.example":          false,
+		"null":                          false,
+	} {
+		if got := isAllowedOrigin(origin, allowed); got != want {
+			t.Errorf("isAllowedOrigin(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
+
+func TestLoadSecurityConfigFromEnv(t *testing.T) {
+	t.Setenv("ATM_ALLOWED_HOSTS", "monitor.lan, *.corp.example")
+	t.Setenv("ATM_CORS_ORIGINS", "https://dash.example")
+	t.Setenv("ATM_LOGIN_RATE_LIMIT", "0")
+
+	cfg, err := LoadSecurityConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadSecurityConfigFromEnv: %v", err)
+	}
+	if len(cfg.AllowedHosts) != 2 || cfg.AllowedHosts[1] != "*.corp.example" {
+		t.Fatalf("unexpected hosts %v", cfg.AllowedHosts)
+	}
+	if len(cfg.AllowedOrigins) != 1 || !cfg.CSRF || cfg.LoginRateLimit != 0 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	t.Setenv("ATM_CORS_ORIGINS", "*")
+	if _, err := LoadSecurityConfigFromEnv(); err == nil {
+		t.Fatal("expected wildcard origin to be rejected")
+	}
+	t.Setenv("ATM_CORS_ORIGINS", "")
+	t.Setenv("ATM_LOGIN_RATE_LIMIT", "lots")
+	if _, err := LoadSecurityConfigFromEnv(); err == nil {
+		t.Fatal("expected invalid rate limit to be rejected")
+	}
+}
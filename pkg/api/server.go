@@ -2,14 +2,19 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/liaoweijun/agent-team-monitor/pkg/audit"
+	"github.com/liaoweijun/agent-team-monitor/pkg/federation"
 	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
 	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
 	"github.com/liaoweijun/agent-team-monitor/pkg/types"
@@ -20,7 +25,17 @@ type Server struct {
 	collector  *monitor.Collector
 	auth       *AuthManager
 	managed    *managed.Manager
+	auditLog   *audit.Logger
+	readOnly   *ReadOnlyOptions // Privacy guarded by readOnlyMu
+	journal    *stateJournal
+	hub        *federation.Hub
 	httpServer *http.Server
+
+	securityMu   sync.RWMutex
+	security     SecurityConfig
+	loginLimiter *rateLimiter
+
+	readOnlyMu sync.RWMutex
 }
 
 // NewServer creates a new API server
@@ -33,12 +48,17 @@ func NewServer(collector *monitor.Collector, addr string, staticFS fs.FS, auth *
 		collector: collector,
 		auth:      auth,
 		managed:   managedManager,
+		journal:   newStateJournal(),
+		security:  permissiveSecurityConfig(),
 	}
 
 	mux := http.NewServeMux()
 
 	// API endpoints
+	mux.Handle(apiV1Prefix+"/", s.newAPIV1Handler())
 	mux.HandleFunc("/api/state", s.handleGetState)
+	mux.HandleFunc("/api/state/delta", s.handleStateDelta)
+	mux.HandleFunc("/api/events", s.handleStateEvents)
 	mux.HandleFunc("/api/teams", s.handleGetTeams)
 	mux.HandleFunc("/api/teams/", s.handleTeamAction)
 	mux.HandleFunc("/api/agents/message", s.handleSendAgentMessage)
@@ -47,8 +67,14 @@ func NewServer(collector *monitor.Collector, addr string, staticFS fs.FS, auth *
 	mux.HandleFunc("/api/auth/status", s.handleAuthStatus)
 	mux.HandleFunc("/api/auth/login", s.handleAuthLogin)
 	mux.HandleFunc("/api/auth/logout", s.handleAuthLogout)
+	mux.HandleFunc("/api/tokens", s.handleAPITokens)
+	mux.HandleFunc("/api/tokens/", s.handleAPITokenAction)
+	mux.HandleFunc("/api/audit", s.handleAudit)
 	mux.HandleFunc("/api/processes", s.handleGetProcesses)
+	mux.HandleFunc("/api/federation/push", s.handleFederationPush)
+	mux.HandleFunc("/api/federation/nodes", s.handleFederationNodes)
 	mux.HandleFunc("/api/health", s.handleHealth)
+	mux.HandleFunc("/metrics", s.handleMetrics)
 
 	// Embedded static files
 	fileServer := http.FileServer(http.FS(staticFS))
@@ -56,7 +82,7 @@ func NewServer(collector *monitor.Collector, addr string, staticFS fs.FS, auth *
 
 	s.httpServer = &http.Server{
 		Addr:         addr,
-		Handler:      corsMiddleware(mux),
+		Handler:      s.securityMiddleware(mux),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -64,16 +90,22 @@ func NewServer(collector *monitor.Collector, addr string, staticFS fs.FS, auth *
 	return s
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server, or HTTPS after EnableTLS.
 func (s *Server) Start() error {
 	log.Printf("Starting web server on %s", s.httpServer.Addr)
+	if s.TLSEnabled() {
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
 	return s.httpServer.ListenAndServe()
 }
 
-// StartListener starts the HTTP server on an existing listener.
+// StartListener starts the server on an existing listener.
 func (s *Server) StartListener(listener net.Listener) error {
 	s.httpServer.Addr = listener.Addr().String()
 	log.Printf("Starting web server on %s", s.httpServer.Addr)
+	if s.TLSEnabled() {
+		return s.httpServer.ServeTLS(listener, "", "")
+	}
 	return s.httpServer.Serve(listener)
 }
 
@@ -82,6 +114,12 @@ func (s *Server) Stop() error {
 	return s.httpServer.Close()
 }
 
+// Handler returns the server's routes with its middleware applied, for
+// serving from another http.Server or httptest.
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
 func (s *Server) AuthManager() *AuthManager {
 	if s == nil {
 		return nil
@@ -89,6 +127,15 @@ func (s *Server) AuthManager() *AuthManager {
 	return s.auth
 }
 
+// State returns the collector snapshot merged with managed teams, exactly as
+// served by /api/state.
+func (s *Server) State() types.MonitorState {
+	if s == nil {
+		return types.MonitorState{}
+	}
+	return s.buildState()
+}
+
 func (s *Server) buildState() types.MonitorState {
 	state := types.MonitorState{}
 	if s.collector != nil {
@@ -100,9 +147,38 @@ func (s *Server) buildState() types.MonitorState {
 			state.Teams = append(state.Teams, convertManagedTeams(managedTeams)...)
 		}
 	}
+	linkManagedProcesses(state.Processes, state.Teams)
+	s.hub.Merge(&state)
 	return state
 }
 
+// linkManagedProcesses names the team of processes the collector found
+// running for a managed team, and marks the members whose process the
+// watchdog flagged as hung.
+func linkManagedProcesses(processes []types.ProcessInfo, teams []types.TeamInfo) {
+	for i := range processes {
+		if processes[i].ManagedTeamID == "" || processes[i].Team != "" {
+			continue
+		}
+		for t := range teams {
+			if teams[t].ManagedTeamID != processes[i].ManagedTeamID {
+				continue
+			}
+			processes[i].Team = teams[t].Name
+			if processes[i].Hung == nil {
+				break
+			}
+			for m := range teams[t].Members {
+				if member := &teams[t].Members[m]; member.AgentID == processes[i].ManagedAgentID {
+					evidence := *processes[i].Hung
+					member.Status, member.Hung = "hung", &evidence
+				}
+			}
+			break
+		}
+	}
+}
+
 func convertManagedTeams(items []managed.ManagedTeam) []types.TeamInfo {
 	result := make([]types.TeamInfo, 0, len(items))
 	for _, item := range items {
@@ -151,6 +227,7 @@ func convertManagedTeams(items []managed.ManagedTeam) []types.TeamInfo {
 					agent.LastActivity = run.StartedAt
 					agent.LastActiveTime = run.StartedAt
 				}
+				agent.ManagedStatus = string(run.Status)
 				if run.Status == managed.RunStatusRunning || run.Status == managed.RunStatusRunningDetached {
 					agent.Status = "working"
 				}
@@ -173,14 +250,25 @@ func convertManagedTeams(items []managed.ManagedTeam) []types.TeamInfo {
 	return result
 }
 
-// handleGetState returns the complete monitoring state
+// handleGetState returns the complete monitoring state, or 304 when the
+// client's ETag still names the current version.
 func (s *Server) handleGetState(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if err := s.auth.RequirePermission(r, PermissionRead); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 
-	state := s.buildState()
+	state := s.versionedState()
+	etag := stateETag(state.Version, state.Kiosk)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	respondJSON(w, state)
 }
 
@@ -190,8 +278,12 @@ func (s *Server) handleGetTeams(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if err := s.auth.RequirePermission(r, PermissionRead); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 
-	state := s.buildState()
+	state := s.servedState()
 	respondJSON(w, state.Teams)
 }
 
@@ -201,8 +293,12 @@ func (s *Server) handleGetProcesses(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if err := s.auth.RequirePermission(r, PermissionRead); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 
-	state := s.buildState()
+	state := s.servedState()
 	respondJSON(w, state.Processes)
 }
 
@@ -224,7 +320,7 @@ func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	respondJSON(w, s.auth.Status())
+	respondJSON(w, s.readOnlyStatus(s.auth.Status(r)))
 }
 
 func (s *Server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
@@ -238,12 +334,28 @@ func (s *Server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
-	if err := s.auth.Login(req.Username, req.Password); err != nil {
+	entry := audit.Entry{Actor: strings.TrimSpace(req.Username), Action: audit.ActionLogin}
+	if _, limiter := s.securityConfig(); limiter != nil {
+		if ok, wait := limiter.Allow(clientAddress(r)); !ok {
+			entry.Result = audit.ResultDenied
+			s.recordAudit(r, entry, fmt.Errorf("too many login attempts"))
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			http.Error(w, "Too many login attempts, try again later", http.StatusTooManyRequests)
+			return
+		}
+	}
+	session, err := s.auth.Login(req.Username, req.Password)
+	if err != nil {
+		entry.Result = audit.ResultDenied
+		s.recordAudit(r, entry, err)
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
+	entry.Role = string(session.Role)
+	s.recordAudit(r, entry, nil)
 
-	respondJSON(w, s.auth.Status())
+	setSessionCookie(w, r, session)
+	respondJSON(w, s.readOnlyStatus(session.Status()))
 }
 
 func (s *Server) handleAuthLogout(w http.ResponseWriter, r *http.Request) {
@@ -251,8 +363,98 @@ func (s *Server) handleAuthLogout(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	s.auth.Logout()
-	respondJSON(w, s.auth.Status())
+	if session, err := s.auth.Authenticate(SessionToken(r)); err == nil {
+		s.recordAudit(r, audit.Entry{Actor: session.Username, Role: string(session.Role), Action: audit.ActionLogout}, nil)
+	}
+	s.auth.Logout(SessionToken(r))
+	clearSessionCookie(w, r)
+	respondJSON(w, s.readOnlyStatus(s.auth.AnonymousStatus()))
+}
+
+type createAPITokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn string   `json:"expires_in,omitempty"` // Go duration, empty for no expiry
+}
+
+type createAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+func (s *Server) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	if err := s.auth.RequirePermission(r, PermissionAdmin); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	store := s.auth.Tokens()
+	if store == nil {
+		http.Error(w, "API token store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := store.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, tokens)
+	case http.MethodPost:
+		var req createAPITokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		var ttl time.Duration
+		if strings.TrimSpace(req.ExpiresIn) != "" {
+			parsed, err := time.ParseDuration(strings.TrimSpace(req.ExpiresIn))
+			if err != nil || parsed <= 0 {
+				http.Error(w, "Invalid expires_in duration", http.StatusBadRequest)
+				return
+			}
+			ttl = parsed
+		}
+		token, secret, err := store.Create(req.Name, req.Scopes, ttl)
+		s.recordAudit(r, audit.Entry{Action: audit.ActionTokenCreate, Detail: strings.TrimSpace(req.Name)}, err)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		respondJSON(w, createAPITokenResponse{APIToken: token, Token: secret})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAPITokenAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.auth.RequirePermission(r, PermissionAdmin); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	store := s.auth.Tokens()
+	if store == nil {
+		http.Error(w, "API token store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tokens/"), "/")
+	token, err := store.Revoke(id)
+	s.recordAudit(r, audit.Entry{Action: audit.ActionTokenRevoke, Detail: firstNonEmpty(token.Name, id)}, err)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	respondJSON(w, map[string]interface{}{
+		"status":  "ok",
+		"message": "Token revoked",
+		"id":      token.ID,
+	})
 }
 
 type sendAgentMessageRequest struct {
@@ -266,8 +468,7 @@ func (s *Server) handleSendAgentMessage(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if err := s.auth.RequireAuthenticated(); err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+	if !s.authorize(w, r, PermissionMessage, audit.Entry{Action: audit.ActionAgentMessage}) {
 		return
 	}
 	if s.collector == nil {
@@ -281,8 +482,16 @@ func (s *Server) handleSendAgentMessage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := s.collector.SendAgentMessage(req.TeamName, req.AgentName, req.Text); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	err := s.sendAgentMessage(r.Context(), req.TeamName, req.AgentName, req.Text)
+	s.recordAudit(r, audit.Entry{
+		Action: audit.ActionAgentMessage,
+		Team:   req.TeamName,
+		Agent:  req.AgentName,
+		Detail: audit.Summarize(req.Text),
+	}, err)
+	if err != nil {
+		status, message := actionError(err, http.StatusBadRequest)
+		http.Error(w, message, status)
 		return
 	}
 
@@ -314,6 +523,10 @@ func (s *Server) handleManagedTeams(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
+		if err := s.auth.RequirePermission(r, PermissionRead); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		teams, err := s.managed.ListTeams()
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -321,8 +534,7 @@ func (s *Server) handleManagedTeams(w http.ResponseWriter, r *http.Request) {
 		}
 		respondJSON(w, teams)
 	case http.MethodPost:
-		if err := s.auth.RequireAuthenticated(); err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
+		if !s.authorize(w, r, PermissionManagedControl, audit.Entry{Action: audit.ActionManagedCreate}) {
 			return
 		}
 
@@ -340,6 +552,7 @@ func (s *Server) handleManagedTeams(w http.ResponseWriter, r *http.Request) {
 			Permission: req.Permission,
 			Agents:     req.Agents,
 		})
+		s.recordAudit(r, audit.Entry{Action: audit.ActionManagedCreate, Team: firstNonEmpty(team.ID, req.Name), Detail: req.Name}, err)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -355,12 +568,15 @@ func (s *Server) handleManagedTeamAction(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Managed team manager unavailable", http.StatusServiceUnavailable)
 		return
 	}
-	if err := s.auth.RequireAuthenticated(); err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+	teamID, pathAgentID, action, ok := parseManagedTeamActionPath(strings.TrimPrefix(r.URL.Path, "/api/managed/teams/"))
+	permission := PermissionManagedControl
+	if action == "message" {
+		permission = PermissionMessage
+	}
+	entry := audit.Entry{Action: "managed." + action, Team: teamID, Agent: pathAgentID}
+	if !s.authorize(w, r, permission, entry) {
 		return
 	}
-
-	teamID, pathAgentID, action, ok := parseManagedTeamActionPath(strings.TrimPrefix(r.URL.Path, "/api/managed/teams/"))
 	if !ok {
 		http.Error(w, "Managed team id required", http.StatusBadRequest)
 		return
@@ -368,32 +584,20 @@ func (s *Server) handleManagedTeamAction(w http.ResponseWriter, r *http.Request)
 
 	switch {
 	case r.Method == http.MethodPost && action == "start":
-		var (
-			run managed.RunState
-			err error
-		)
-		if pathAgentID != "" {
-			run, err = s.managed.StartAgent(teamID, pathAgentID)
-		} else {
-			run, err = s.managed.StartTeam(teamID)
-		}
+		run, err := s.startManaged(r.Context(), teamID, pathAgentID)
+		s.recordAudit(r, entry, err)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			status, message := actionError(err, http.StatusBadRequest)
+			http.Error(w, message, status)
 			return
 		}
 		respondJSON(w, run)
 	case r.Method == http.MethodPost && action == "stop":
-		var (
-			run managed.RunState
-			err error
-		)
-		if pathAgentID != "" {
-			run, err = s.managed.StopAgent(teamID, pathAgentID)
-		} else {
-			run, err = s.managed.StopTeam(teamID)
-		}
+		run, err := s.stopManaged(r.Context(), teamID, pathAgentID)
+		s.recordAudit(r, entry, err)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			status, message := actionError(err, http.StatusBadRequest)
+			http.Error(w, message, status)
 			return
 		}
 		respondJSON(w, run)
@@ -404,14 +608,13 @@ func (s *Server) handleManagedTeamAction(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		agentID := firstNonEmpty(pathAgentID, strings.TrimSpace(req.AgentID))
-		var err error
-		if agentID != "" {
-			err = s.managed.SendMessageToAgent(teamID, agentID, req.Text)
-		} else {
-			err = s.managed.SendMessage(teamID, req.Text)
-		}
+		err := s.sendManagedMessage(r.Context(), teamID, agentID, req.Text)
+		entry.Agent = agentID
+		entry.Detail = audit.Summarize(req.Text)
+		s.recordAudit(r, entry, err)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			status, message := actionError(err, http.StatusBadRequest)
+			http.Error(w, message, status)
 			return
 		}
 		respondJSON(w, map[string]interface{}{
@@ -459,13 +662,19 @@ func (s *Server) handleTeamAction(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodDelete:
-		if err := s.auth.RequireAuthenticated(); err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
+		entry := audit.Entry{Action: audit.ActionTeamDelete, Team: teamName}
+		if !s.authorize(w, r, PermissionTeamsDelete, entry) {
 			return
 		}
-		if err := s.collector.DeleteTeam(teamName); err != nil {
+		err := s.deleteTeam(r.Context(), teamName)
+		s.recordAudit(r, entry, err)
+		if err != nil {
 			log.Printf("Error deleting team %s: %v", teamName, err)
-			http.Error(w, "Failed to delete team", http.StatusInternalServerError)
+			status, message := actionError(err, http.StatusInternalServerError)
+			if !s.federatedTeam(teamName) {
+				message = "Failed to delete team"
+			}
+			http.Error(w, message, status)
 			return
 		}
 		respondJSON(w, map[string]interface{}{
@@ -558,44 +767,3 @@ func respondJSON(w http.ResponseWriter, data interface{}) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
-
-// corsMiddleware adds CORS headers, restricting to localhost origins
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if isAllowedOrigin(origin) {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Vary", "Origin")
-		}
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-// isAllowedOrigin checks if the origin is a localhost variant
-func isAllowedOrigin(origin string) bool {
-	if origin == "" {
-		return false
-	}
-	allowedPrefixes := []string{
-		"http://localhost",
-		"http://127.0.0.1",
-		"http://[::1]",
-		"https://localhost",
-		"https://127.0.0.1",
-		"https://[::1]",
-	}
-	for _, prefix := range allowedPrefixes {
-		if strings.HasPrefix(origin, prefix) {
-			return true
-		}
-	}
-	return false
-}
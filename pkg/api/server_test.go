@@ -14,6 +14,9 @@ import (
 
 	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
 	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/trace"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+	"github.com/liaoweijun/agent-team-monitor/pkg/webhook"
 )
 
 func TestStaticDashboardAndGameRoutesCoexist(t *testing.T) {
@@ -109,7 +112,8 @@ func TestSendAgentMessageRejectsInvalidBody(t *testing.T) {
 		t.Fatalf("NewCollector error: %v", err)
 	}
 	auth := NewAuthManagerFromEnv()
-	if err := auth.Login("admin", "secret"); err != nil {
+	session, err := auth.Login("admin", "secret")
+	if err != nil {
 		t.Fatalf("login auth: %v", err)
 	}
 	server := NewServer(collector, ":0", fstest.MapFS{
@@ -117,6 +121,7 @@ func TestSendAgentMessageRejectsInvalidBody(t *testing.T) {
 	}, auth, nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/agents/message", bytes.NewBufferString(`{`))
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: session.Token})
 	res := httptest.NewRecorder()
 	server.httpServer.Handler.ServeHTTP(res, req)
 
@@ -186,6 +191,54 @@ func TestConvertManagedTeamsIncludesAllManagedAgents(t *testing.T) {
 	}
 }
 
+func TestConvertManagedTeamsFailedRunRaisesAgentErrored(t *testing.T) {
+	running := convertManagedTeams(singleAgentManagedTeam(managed.RunStatusRunning, ""))
+	failed := convertManagedTeams(singleAgentManagedTeam(managed.RunStatusFailed, "exec: claude not found"))
+	if failed[0].Members[0].ManagedStatus != "failed" {
+		t.Fatalf("expected the agent to carry its run status, got %+v", failed[0].Members[0])
+	}
+
+	events := webhook.DiffStates(types.MonitorState{Teams: running}, types.MonitorState{Teams: failed}, time.Now())
+	var errored *webhook.Event
+	for i := range events {
+		if events[i].Type == webhook.EventAgentErrored {
+			errored = &events[i]
+		}
+	}
+	if errored == nil || errored.Agent != "team-lead" || errored.Error != "exec: claude not found" {
+		t.Fatalf("expected agent.errored for the failed run, got %+v", events)
+	}
+}
+
+func TestConvertManagedTeamsFailedRunMarksAgentSpan(t *testing.T) {
+	items := singleAgentManagedTeam(managed.RunStatusFailed, "exec: claude not found")
+	items[0].Spec.CreatedAt = time.Date(2026, 4, 9, 12, 0, 0, 0, time.UTC)
+
+	for _, span := range trace.BuildTraces(types.MonitorState{Teams: convertManagedTeams(items)}).Spans() {
+		if span.Name == "team-lead" {
+			if span.Status.Code != trace.StatusCodeError || span.Status.Message != "exec: claude not found" {
+				t.Fatalf("expected the failed run to mark the agent span, got %+v", span.Status)
+			}
+			return
+		}
+	}
+	t.Fatal("expected an agent span")
+}
+
+func singleAgentManagedTeam(status managed.RunStatus, lastError string) []managed.ManagedTeam {
+	run := managed.RunState{TeamID: "team-1", AgentID: "lead", Provider: "claude", Status: status, LastError: lastError}
+	return []managed.ManagedTeam{{
+		Spec: managed.TeamSpec{
+			ID:       "team-1",
+			Name:     "Managed Team",
+			Provider: "claude",
+			Agents:   []managed.AgentSpec{{ID: "lead", Name: "team-lead", Provider: "claude"}},
+		},
+		Run:  &run,
+		Runs: []managed.RunState{run},
+	}}
+}
+
 func TestParseManagedTeamActionPath(t *testing.T) {
 	tests := []struct {
 		path    string
@@ -239,7 +292,8 @@ func TestManagedAgentMessageRouteAcceptsAgentIDInBody(t *testing.T) {
 	}
 
 	auth := NewAuthManagerFromEnv()
-	if err := auth.Login("admin", "secret"); err != nil {
+	session, err := auth.Login("admin", "secret")
+	if err != nil {
 		t.Fatalf("login auth: %v", err)
 	}
 	server := NewServer(nil, ":0", fstest.MapFS{
@@ -247,6 +301,7 @@ func TestManagedAgentMessageRouteAcceptsAgentIDInBody(t *testing.T) {
 	}, auth, manager)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/managed/teams/"+spec.ID+"/message", bytes.NewBufferString(`{"agent_id":"reviewer","text":"hi"}`))
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: session.Token})
 	res := httptest.NewRecorder()
 	server.httpServer.Handler.ServeHTTP(res, req)
 
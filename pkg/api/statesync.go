@@ -0,0 +1,370 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// stateEventInterval is how often /api/events checks for a new version;
+// stateEventKeepAlive is how long a quiet stream waits before a comment
+// line keeps proxies from closing it.
+const (
+	stateEventInterval  = time.Second
+	stateEventKeepAlive = 15 * time.Second
+)
+
+// maxStateTombstones bounds how many removals the journal remembers; a
+// client that falls further behind gets a reset delta.
+const maxStateTombstones = 2048
+
+// stateJournal versions the served state so clients can poll with
+// If-None-Match or fetch only what changed since a version. It rehashes
+// teams, agents and tasks only when the collector version or the managed
+// teams move, so idle polls cost a string comparison.
+type stateJournal struct {
+	mu         sync.Mutex
+	key        string
+	version    uint64
+	floor      uint64 // Deltas from versions below this need a reset
+	teams      []types.TeamInfo
+	processes  []types.ProcessInfo
+	nodes      []types.NodeInfo
+	entries    map[journalKey]journalEntry
+	tombstones []types.Tombstone
+}
+
+// journalKey names a tracked entity; ID is the agent key or task ID.
+type journalKey struct {
+	Kind string
+	Team string
+	ID   string
+}
+
+type journalEntry struct {
+	hash    uint64
+	version uint64
+}
+
+var (
+	processesKey = journalKey{Kind: "processes"}
+	nodesKey     = journalKey{Kind: "nodes"}
+)
+
+func newStateJournal() *stateJournal {
+	return &stateJournal{entries: map[journalKey]journalEntry{}}
+}
+
+// observe stamps state with the journal version, recording it first when
+// the collector or managed teams moved since the last observation.
+func (j *stateJournal) observe(state *types.MonitorState) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.observeLocked(state)
+}
+
+func (j *stateJournal) observeLocked(state *types.MonitorState) {
+	key := strconv.FormatUint(state.Version, 10) + "/" + strconv.FormatUint(managedFingerprint(state.Teams), 16)
+	if key != j.key || j.version == 0 {
+		j.key = key
+		j.record(state.Teams, state.Processes, state.Nodes)
+	}
+	state.Version = j.version
+}
+
+// record diffs teams, processes and nodes against the previous
+// observation, bumping the version when anything changed.
+func (j *stateJournal) record(teams []types.TeamInfo, processes []types.ProcessInfo, nodes []types.NodeInfo) {
+	next := j.version + 1
+	changed := j.version == 0
+	seen := make(map[journalKey]bool, len(j.entries))
+	track := func(key journalKey, value any) {
+		seen[key] = true
+		hash := hashJSON(value)
+		if entry, ok := j.entries[key]; ok && entry.hash == hash {
+			return
+		}
+		j.entries[key] = journalEntry{hash: hash, version: next}
+		changed = true
+	}
+
+	for _, team := range teams {
+		track(journalKey{Kind: "team", Team: team.Name}, teamChange(team))
+		for _, agent := range team.Members {
+			track(journalKey{Kind: "agent", Team: team.Name, ID: types.AgentKey(agent)}, agent)
+		}
+		for _, task := range team.Tasks {
+			track(journalKey{Kind: "task", Team: team.Name, ID: task.ID}, task)
+		}
+	}
+	track(processesKey, processes)
+	track(nodesKey, nodeLiveness(nodes))
+
+	for key := range j.entries {
+		if seen[key] {
+			continue
+		}
+		delete(j.entries, key)
+		changed = true
+		j.tombstones = append(j.tombstones, types.Tombstone{Kind: key.Kind, Team: key.Team, ID: key.ID, Version: next})
+	}
+	if overflow := len(j.tombstones) - maxStateTombstones; overflow > 0 {
+		j.floor = j.tombstones[overflow-1].Version
+		j.tombstones = append([]types.Tombstone(nil), j.tombstones[overflow:]...)
+	}
+
+	if changed {
+		j.version = next
+	}
+	j.teams = teams
+	j.processes = processes
+	j.nodes = nodes
+}
+
+// delta observes state and lists changes after since, falling back to a
+// full reset when since is unknown to this journal.
+func (j *stateJournal) delta(state types.MonitorState, since uint64) types.StateDelta {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.observeLocked(&state)
+
+	delta := types.StateDelta{
+		Version:   j.version,
+		Since:     since,
+		UpdatedAt: state.UpdatedAt,
+		Kiosk:     state.Kiosk,
+	}
+	if since == 0 || since > j.version || since < j.floor {
+		delta.Reset = true
+		delta.State = &state
+		return delta
+	}
+	if since == j.version {
+		return delta
+	}
+
+	changedAfter := func(key journalKey) bool {
+		return j.entries[key].version > since
+	}
+	teamsChanged := false
+	for _, team := range j.teams {
+		if changedAfter(journalKey{Kind: "team", Team: team.Name}) {
+			teamsChanged = true
+			delta.Teams = append(delta.Teams, teamChange(team))
+		}
+		for _, agent := range team.Members {
+			if changedAfter(journalKey{Kind: "agent", Team: team.Name, ID: types.AgentKey(agent)}) {
+				delta.Agents = append(delta.Agents, types.AgentChange{Team: team.Name, AgentInfo: agent})
+			}
+		}
+		for _, task := range team.Tasks {
+			if changedAfter(journalKey{Kind: "task", Team: team.Name, ID: task.ID}) {
+				delta.Tasks = append(delta.Tasks, types.TaskChange{Team: team.Name, TaskInfo: task})
+			}
+		}
+	}
+	for _, tombstone := range j.tombstones {
+		if tombstone.Version > since {
+			delta.Removed = append(delta.Removed, tombstone)
+			if tombstone.Kind == "team" {
+				teamsChanged = true
+			}
+		}
+	}
+	if teamsChanged {
+		delta.TeamOrder = make([]string, 0, len(j.teams))
+		for _, team := range j.teams {
+			delta.TeamOrder = append(delta.TeamOrder, team.Name)
+		}
+	}
+	if changedAfter(processesKey) {
+		delta.Processes = append([]types.ProcessInfo{}, j.processes...)
+	}
+	if changedAfter(nodesKey) {
+		delta.Nodes = append([]types.NodeInfo{}, j.nodes...)
+	}
+	return delta
+}
+
+// teamChange strips members and tasks from team, keeping their order.
+func teamChange(team types.TeamInfo) types.TeamChange {
+	change := types.TeamChange{
+		TeamInfo:    team,
+		MemberOrder: make([]string, 0, len(team.Members)),
+		TaskOrder:   make([]string, 0, len(team.Tasks)),
+	}
+	for _, agent := range team.Members {
+		change.MemberOrder = append(change.MemberOrder, types.AgentKey(agent))
+	}
+	for _, task := range team.Tasks {
+		change.TaskOrder = append(change.TaskOrder, task.ID)
+	}
+	change.Members = nil
+	change.Tasks = nil
+	return change
+}
+
+// nodeLiveness drops the last-seen times, which move with every push, so
+// only nodes coming, going or changing size count as a change.
+func nodeLiveness(nodes []types.NodeInfo) []types.NodeInfo {
+	result := make([]types.NodeInfo, len(nodes))
+	for i, node := range nodes {
+		node.LastSeen = time.Time{}
+		result[i] = node
+	}
+	return result
+}
+
+// managedFingerprint hashes managed teams, which reach the served state
+// without passing through the collector version.
+func managedFingerprint(teams []types.TeamInfo) uint64 {
+	var managedTeams []types.TeamInfo
+	for _, team := range teams {
+		if team.Managed {
+			managedTeams = append(managedTeams, team)
+		}
+	}
+	return hashJSON(managedTeams)
+}
+
+func hashJSON(value any) uint64 {
+	h := fnv.New64a()
+	_ = json.NewEncoder(h).Encode(value)
+	return h.Sum64()
+}
+
+func stateETag(version uint64, kiosk *types.KioskInfo) string {
+	if kiosk != nil && kiosk.RotateSeconds > 0 {
+		// The rotating focus is part of the representation.
+		return fmt.Sprintf(`W/"%d-%d"`, version, kiosk.FocusIndex)
+	}
+	return fmt.Sprintf(`W/"%d"`, version)
+}
+
+// versionedState is servedState stamped with the journal version.
+func (s *Server) versionedState() types.MonitorState {
+	state := s.servedState()
+	s.journal.observe(&state)
+	return state
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing
+// weakly as RFC 9110 requires for GET.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStateVersion reads a ?since= or Last-Event-ID value; empty means 0.
+func parseStateVersion(raw string) (uint64, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(raw, 10, 64)
+}
+
+// handleStateDelta returns changes since the version in ?since=, or the
+// whole state with reset set when that version is unknown.
+func (s *Server) handleStateDelta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.auth.RequirePermission(r, PermissionRead); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	since, err := parseStateVersion(r.URL.Query().Get("since"))
+	if err != nil {
+		http.Error(w, "since must be a state version", http.StatusBadRequest)
+		return
+	}
+
+	delta := s.journal.delta(s.servedState(), since)
+	w.Header().Set("ETag", stateETag(delta.Version, delta.Kiosk))
+	respondJSON(w, delta)
+}
+
+// handleStateEvents streams state deltas as server-sent events. Each
+// "delta" event carries a StateDelta with the version as its id, so a
+// reconnecting EventSource resumes through Last-Event-ID.
+func (s *Server) handleStateEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.auth.RequirePermission(r, PermissionRead); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("since")
+	}
+	since, err := parseStateVersion(raw)
+	if err != nil {
+		http.Error(w, "since must be a state version", http.StatusBadRequest)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	// The server's write timeout is meant for ordinary responses.
+	_ = controller.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(stateEventInterval)
+	defer ticker.Stop()
+	quietSince := time.Now()
+	for {
+		delta := s.journal.delta(s.servedState(), since)
+		switch {
+		case delta.Reset || delta.Version != since:
+			data, err := json.Marshal(delta)
+			if err != nil {
+				log.Printf("Error encoding state event: %v", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: delta\ndata: %s\n\n", delta.Version, data); err != nil {
+				return
+			}
+			since = delta.Version
+			quietSince = time.Now()
+		case time.Since(quietSince) >= stateEventKeepAlive:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			quietSince = time.Now()
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func journalTestState(generation uint64) types.MonitorState {
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	return types.MonitorState{
+		Version:   generation,
+		UpdatedAt: at,
+		Processes: []types.ProcessInfo{{PID: 42, Command: "claude", Team: "alpha"}},
+		Teams: []types.TeamInfo{
+			{
+				Name:     "alpha",
+				Provider: "claude",
+				Members: []types.AgentInfo{
+					{Name: "lead", Status: "working", LastActivity: at},
+					{Name: "tester", Status: "idle", LastActivity: at},
+				},
+				Tasks: []types.TaskInfo{
+					{ID: "1", Subject: "Write parser", Status: "in_progress"},
+					{ID: "2", Subject: "Review", Status: "pending"},
+				},
+			},
+			{
+				Name:    "beta",
+				Members: []types.AgentInfo{{Name: "solo", Status: "idle", LastActivity: at}},
+				Tasks:   []types.TaskInfo{},
+			},
+		},
+	}
+}
+
+// roundTrip checks that applying the delta since old reproduces current.
+func roundTrip(t *testing.T, old types.MonitorState, delta types.StateDelta, current types.MonitorState) {
+	t.Helper()
+	// Compare through JSON, as clients see it.
+	want, _ := json.Marshal(current)
+	got, _ := json.Marshal(old.ApplyDelta(delta))
+	if string(got) != string(want) {
+		t.Fatalf("delta did not reproduce state\nwant %s\ngot  %s", want, got)
+	}
+}
+
+func TestStateJournalVersionsOnlyOnChange(t *testing.T) {
+	journal := newStateJournal()
+
+	first := journalTestState(1)
+	journal.observe(&first)
+	if first.Version != 1 {
+		t.Fatalf("expected first observation to be version 1, got %d", first.Version)
+	}
+
+	// A new collection with identical content keeps the version.
+	same := journalTestState(2)
+	journal.observe(&same)
+	if same.Version != 1 {
+		t.Fatalf("expected unchanged content to keep version 1, got %d", same.Version)
+	}
+
+	changed := journalTestState(3)
+	changed.Teams[0].Members[1].Status = "working"
+	journal.observe(&changed)
+	if changed.Version != 2 {
+		t.Fatalf("expected a member change to bump the version, got %d", changed.Version)
+	}
+
+	delta := journal.delta(journalTestState(3), 1)
+	if delta.Reset || delta.Version != 2 {
+		t.Fatalf("unexpected delta header %+v", delta)
+	}
+	if len(delta.Agents) != 1 || delta.Agents[0].Name != "tester" || delta.Agents[0].Team != "alpha" {
+		t.Fatalf("expected only tester to change, got %+v", delta.Agents)
+	}
+	if len(delta.Teams) != 0 || len(delta.Tasks) != 0 || delta.Processes != nil || delta.TeamOrder != nil {
+		t.Fatalf("expected unchanged teams, tasks and processes to be omitted, got %+v", delta)
+	}
+	roundTrip(t, first, delta, changed)
+
+	if empty := journal.delta(journalTestState(3), 2); empty.Reset || len(empty.Agents) != 0 || empty.Processes != nil {
+		t.Fatalf("expected an empty delta at the current version, got %+v", empty)
+	}
+}
+
+func TestStateJournalTombstonesAndReorders(t *testing.T) {
+	journal := newStateJournal()
+	first := journalTestState(1)
+	journal.observe(&first)
+
+	next := journalTestState(2)
+	next.Teams = next.Teams[:1]
+	alpha := &next.Teams[0]
+	alpha.Members = []types.AgentInfo{alpha.Members[1], {Name: "scout", Status: "working"}}
+	alpha.Tasks = alpha.Tasks[1:]
+	alpha.Tasks[0].Status = "completed"
+	next.Processes = []types.ProcessInfo{}
+	journal.observe(&next)
+
+	delta := journal.delta(journalTestState(2), first.Version)
+	removed := map[string]bool{}
+	for _, tombstone := range delta.Removed {
+		removed[tombstone.Kind+"/"+tombstone.Team+"/"+tombstone.ID] = true
+	}
+	for _, want := range []string{"team/beta/", "agent/beta/solo", "agent/alpha/lead", "task/alpha/1"} {
+		if !removed[want] {
+			t.Errorf("expected tombstone %s, got %+v", want, delta.Removed)
+		}
+	}
+	if !reflect.DeepEqual(delta.TeamOrder, []string{"alpha"}) {
+		t.Fatalf("expected team order after removal, got %v", delta.TeamOrder)
+	}
+	if len(delta.Teams) != 1 || !reflect.DeepEqual(delta.Teams[0].MemberOrder, []string{"tester", "scout"}) || delta.Teams[0].Members != nil {
+		t.Fatalf("expected alpha to carry its new member order only, got %+v", delta.Teams)
+	}
+	if delta.Processes == nil || len(delta.Processes) != 0 {
+		t.Fatalf("expected processes to be sent as an empty list, got %#v", delta.Processes)
+	}
+	roundTrip(t, first, delta, next)
+}
+
+func TestStateJournalResets(t *testing.T) {
+	journal := newStateJournal()
+	state := journalTestState(1)
+	journal.observe(&state)
+
+	for _, since := range []uint64{0, 99} {
+		delta := journal.delta(journalTestState(1), since)
+		if !delta.Reset || delta.State == nil || delta.State.Version != 1 || len(delta.State.Teams) != 2 {
+			t.Fatalf("since=%d: expected a full reset, got %+v", since, delta)
+		}
+		roundTrip(t, types.MonitorState{}, delta, state)
+	}
+
+	// Removals beyond the retained window force clients to resync.
+	for generation := uint64(2); generation < 2+maxStateTombstones; generation++ {
+		churn := journalTestState(generation)
+		churn.Teams[1].Name = "beta-" + strconv.FormatUint(generation, 10)
+		journal.observe(&churn)
+	}
+	if delta := journal.delta(journalTestState(1), 2); !delta.Reset {
+		t.Fatal("expected a reset once tombstones were trimmed")
+	}
+	if len(journal.tombstones) > maxStateTombstones {
+		t.Fatalf("expected tombstones to be capped, got %d", len(journal.tombstones))
+	}
+}
+
+func TestStateETagAndDeltaEndpoints(t *testing.T) {
+	server, workspace := newTestV1Server(t)
+	operator := loginAs(t, server, "otto")
+
+	res := serveAuthRequest(server, http.MethodGet, "/api/state", "")
+	etag := res.Header().Get("ETag")
+	if res.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected state with an ETag, got %d %q", res.Code, etag)
+	}
+	var state types.MonitorState
+	if err := json.Unmarshal(res.Body.Bytes(), &state); err != nil || state.Version == 0 {
+		t.Fatalf("expected a versioned state, got %s (%v)", res.Body.String(), err)
+	}
+
+	conditional := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/state", nil)
+		req.Header.Set("If-None-Match", etag)
+		res := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(res, req)
+		return res
+	}
+	if res := conditional(); res.Code != http.StatusNotModified || res.Body.Len() != 0 {
+		t.Fatalf("expected 304 for a matching ETag, got %d", res.Code)
+	}
+
+	body := `{"name":"Delta Crew","provider":"claude","workspace":"` + workspace + `","agents":[{"name":"lead","provider":"claude"}]}`
+	if res := serveAuthRequest(server, http.MethodPost, "/api/v1/managed/teams", body, operator); res.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", res.Code, res.Body.String())
+	}
+
+	if res := conditional(); res.Code != http.StatusOK || res.Header().Get("ETag") == etag {
+		t.Fatalf("expected a new managed team to change the ETag, got %d", res.Code)
+	}
+
+	res = serveAuthRequest(server, http.MethodGet, "/api/state/delta?since="+strconv.FormatUint(state.Version, 10), "")
+	var delta types.StateDelta
+	if err := json.Unmarshal(res.Body.Bytes(), &delta); err != nil || delta.Reset {
+		t.Fatalf("unexpected delta %s (%v)", res.Body.String(), err)
+	}
+	if len(delta.Teams) != 1 || delta.Teams[0].Name != "Delta Crew" || len(delta.Agents) != 1 {
+		t.Fatalf("expected the new team and its agent, got %s", res.Body.String())
+	}
+
+	if res := serveAuthRequest(server, http.MethodGet, "/api/state/delta?since=abc", ""); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid since to be rejected, got %d", res.Code)
+	}
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	localCAFile         = "ca.pem"
+	localCAKeyFile      = "ca-key.pem"
+	localServerCertFile = "server.pem"
+	localServerKeyFile  = "server-key.pem"
+
+	localCAValidity     = 10 * 365 * 24 * time.Hour
+	localServerValidity = 397 * 24 * time.Hour // browsers reject longer leaf lifetimes
+	localRenewBefore    = 30 * 24 * time.Hour
+)
+
+// TLSOptions configures HTTPS for the web server. With CertFile and KeyFile
+// empty, a local CA and server certificate are generated under Dir and
+// reused on later runs.
+type TLSOptions struct {
+	Enabled  bool
+	CertFile string
+	KeyFile  string
+	Dir      string   // defaults to ~/.agent-team-monitor/tls
+	Hosts    []string // extra DNS names or IPs for the generated certificate
+
+	// RedirectAddr, when set, serves plain HTTP on this address and
+	// redirects every request to the HTTPS listener.
+	RedirectAddr string
+}
+
+// LocalCertificate is the result of LoadTLSConfig.
+type LocalCertificate struct {
+	Config *tls.Config
+	CAFile string // generated CA to trust in the browser; empty for user certs
+}
+
+// DefaultTLSDir returns where generated certificates are kept.
+func DefaultTLSDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".agent-team-monitor", "tls"), nil
+}
+
+// LoadTLSConfig loads the user's certificate or creates the local one.
+func LoadTLSConfig(opts TLSOptions) (LocalCertificate, error) {
+	certFile := strings.TrimSpace(opts.CertFile)
+	keyFile := strings.TrimSpace(opts.KeyFile)
+	if (certFile == "") != (keyFile == "") {
+		return LocalCertificate{}, fmt.Errorf("TLS certificate and key must be provided together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return LocalCertificate{}, fmt.Errorf("load TLS certificate: %w", err)
+		}
+		return LocalCertificate{Config: newTLSConfig(cert)}, nil
+	}
+
+	dir := strings.TrimSpace(opts.Dir)
+	if dir == "" {
+		var err error
+		if dir, err = DefaultTLSDir(); err != nil {
+			return LocalCertificate{}, err
+		}
+	}
+	cert, err := ensureLocalCertificate(dir, localCertificateHosts(opts.Hosts), time.Now())
+	if err != nil {
+		return LocalCertificate{}, err
+	}
+	return LocalCertificate{Config: newTLSConfig(cert), CAFile: filepath.Join(dir, localCAFile)}, nil
+}
+
+func newTLSConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+}
+
+// localCertificateHosts always covers loopback and this machine's hostname,
+// so the dashboard works from the shared box's own name too.
+func localCertificateHosts(extra []string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hosts = append(hosts, hostname)
+	}
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(hosts)+len(extra))
+	for _, host := range append(hosts, extra...) {
+		host = strings.TrimSpace(host)
+		switch host {
+		case "", "0.0.0.0", "::":
+			continue
+		}
+		if !seen[host] {
+			seen[host] = true
+			result = append(result, host)
+		}
+	}
+	return result
+}
+
+func ensureLocalCertificate(dir string, hosts []string, now time.Time) (tls.Certificate, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return tls.Certificate{}, err
+	}
+
+	caCert, caKey, err := loadOrCreateLocalCA(dir, now)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	certPath := filepath.Join(dir, localServerCertFile)
+	keyPath := filepath.Join(dir, localServerKeyFile)
+	if cert, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && serverCertUsable(leaf, caCert, hosts, now) {
+			return cert, nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: "Agent Team Monitor"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(localServerValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("create server certificate: %w", err)
+	}
+	if err := writePEMPair(certPath, der, keyPath, key); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.LoadX509KeyPair(certPath, keyPath)
+}
+
+func serverCertUsable(leaf, ca *x509.Certificate, hosts []string, now time.Time) bool {
+	if now.Add(localRenewBefore).After(leaf.NotAfter) || leaf.CheckSignatureFrom(ca) != nil {
+		return false
+	}
+	for _, host := range hosts {
+		if leaf.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func loadOrCreateLocalCA(dir string, now time.Time) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPath := filepath.Join(dir, localCAFile)
+	keyPath := filepath.Join(dir, localCAKeyFile)
+	if pair, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		cert, certErr := x509.ParseCertificate(pair.Certificate[0])
+		key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+		if certErr == nil && ok && cert.IsCA && now.Before(cert.NotAfter) {
+			return cert, key, nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	hostname, _ := os.Hostname()
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: strings.TrimSpace("Agent Team Monitor Local CA " + hostname)},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(localCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create local CA: %w", err)
+	}
+	if err := writePEMPair(certPath, der, keyPath, key); err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A new CA invalidates any server certificate signed by the old one.
+	_ = os.Remove(filepath.Join(dir, localServerCertFile))
+	return cert, key, nil
+}
+
+func writePEMPair(certPath string, der []byte, keyPath string, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+func randomSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
+
+// EnableTLS makes Start and StartListener serve HTTPS.
+func (s *Server) EnableTLS(config *tls.Config) {
+	s.httpServer.TLSConfig = config
+}
+
+// TLSEnabled reports whether the server serves HTTPS.
+func (s *Server) TLSEnabled() bool {
+	return s != nil && s.httpServer.TLSConfig != nil
+}
+
+// NewHTTPSRedirectHandler redirects plain HTTP requests to the same host on
+// httpsPort.
+func NewHTTPSRedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			host = "localhost"
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
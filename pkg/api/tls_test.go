@@ -0,0 +1,128 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadTLSConfigGeneratesAndReusesLocalCA(t *testing.T) {
+	dir := t.TempDir()
+	first, err := LoadTLSConfig(TLSOptions{Enabled: true, Dir: dir, Hosts: []string{"devbox.internal", "10.1.2.3"}})
+	if err != nil {
+		t.Fatalf("LoadTLSConfig: %v", err)
+	}
+	if first.CAFile != filepath.Join(dir, "ca.pem") {
+		t.Fatalf("unexpected CA file %q", first.CAFile)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "ca-key.pem")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected private CA key with 0600, got %v (%v)", info, err)
+	}
+
+	caPEM, err := os.ReadFile(first.CAFile)
+	if err != nil {
+		t.Fatalf("read CA: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	leaf, err := x509.ParseCertificate(first.Config.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("parse leaf: %v", err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "devbox.internal", "10.1.2.3"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Errorf("expected certificate to verify for %s: %v", host, err)
+		}
+	}
+
+	second, err := LoadTLSConfig(TLSOptions{Enabled: true, Dir: dir, Hosts: []string{"devbox.internal"}})
+	if err != nil {
+		t.Fatalf("LoadTLSConfig again: %v", err)
+	}
+	reused, _ := x509.ParseCertificate(second.Config.Certificates[0].Certificate[0])
+	if reused.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+		t.Fatal("expected persisted server certificate to be reused")
+	}
+
+	third, err := LoadTLSConfig(TLSOptions{Enabled: true, Dir: dir, Hosts: []string{"other.internal"}})
+	if err != nil {
+		t.Fatalf("LoadTLSConfig with new host: %v", err)
+	}
+	reissued, _ := x509.ParseCertificate(third.Config.Certificates[0].Certificate[0])
+	if reissued.SerialNumber.Cmp(leaf.SerialNumber) == 0 || reissued.VerifyHostname("other.internal") != nil {
+		t.Fatal("expected a new certificate covering the new host")
+	}
+	if _, err := reissued.Verify(x509.VerifyOptions{DNSName: "other.internal", Roots: roots}); err != nil {
+		t.Fatalf("expected reissued certificate to chain to the same CA: %v", err)
+	}
+}
+
+func TestLoadTLSConfigUsesProvidedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ensureLocalCertificate(dir, []string{"localhost"}, time.Now()); err != nil {
+		t.Fatalf("ensureLocalCertificate: %v", err)
+	}
+
+	cert, err := LoadTLSConfig(TLSOptions{
+		Enabled:  true,
+		CertFile: filepath.Join(dir, "server.pem"),
+		KeyFile:  filepath.Join(dir, "server-key.pem"),
+	})
+	if err != nil {
+		t.Fatalf("LoadTLSConfig: %v", err)
+	}
+	if cert.CAFile != "" || len(cert.Config.Certificates) != 1 {
+		t.Fatalf("unexpected user certificate result: %+v", cert)
+	}
+
+	if _, err := LoadTLSConfig(TLSOptions{Enabled: true, CertFile: filepath.Join(dir, "server.pem")}); err == nil {
+		t.Fatal("expected certificate without key to be rejected")
+	}
+}
+
+func TestServerStartListenerServesTLS(t *testing.T) {
+	cert, err := LoadTLSConfig(TLSOptions{Enabled: true, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("LoadTLSConfig: %v", err)
+	}
+	server := NewServer(nil, "127.0.0.1:0", fstest.MapFS{"index.html": {Data: []byte("ok")}}, nil, nil)
+	server.EnableTLS(cert.Config)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = server.StartListener(listener) }()
+	t.Cleanup(func() { _ = server.Stop() })
+
+	caPEM, _ := os.ReadFile(cert.CAFile)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	res, err := client.Get("https://" + listener.Addr().String() + "/api/health")
+	if err != nil {
+		t.Fatalf("HTTPS request: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 over HTTPS, got %d", res.StatusCode)
+	}
+}
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	handler := NewHTTPSRedirectHandler("8443")
+	req := httptest.NewRequest(http.MethodGet, "http://devbox:8080/teams?view=all", nil)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Code != http.StatusPermanentRedirect || res.Header().Get("Location") != "https://devbox:8443/teams?view=all" {
+		t.Fatalf("unexpected redirect %d %q", res.Code, res.Header().Get("Location"))
+	}
+}
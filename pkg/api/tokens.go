@@ -0,0 +1,381 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tokensPathEnv         = "ATM_TOKENS_FILE"
+	defaultTokensFileName = "api-tokens.json"
+
+	apiTokenPrefix = "atm_"
+
+	// lastUsedPersistInterval throttles last-used writes so a busy script
+	// does not rewrite the token file on every request.
+	lastUsedPersistInterval = time.Minute
+)
+
+// APIToken describes a persistent token. The secret itself is only returned
+// once, at creation time. Scopes are permission names.
+type APIToken struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Scopes     []Permission `json:"scopes"`
+	Prefix     string       `json:"prefix"` // first characters of the secret, for recognition
+	CreatedAt  time.Time    `json:"created_at"`
+	ExpiresAt  time.Time    `json:"expires_at,omitempty"`
+	LastUsedAt time.Time    `json:"last_used_at,omitempty"`
+}
+
+// HasScope reports whether the token grants permission.
+func (t APIToken) HasScope(permission Permission) bool {
+	return permissionsAllow(t.Scopes, permission)
+}
+
+// Principal returns the caller identity for requests using this token.
+func (t APIToken) Principal() Principal {
+	return Principal{
+		TokenName:   t.Name,
+		Permissions: append([]Permission(nil), t.Scopes...),
+	}
+}
+
+// Expired reports whether the token is past its expiry.
+func (t APIToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+type storedAPIToken struct {
+	APIToken
+	Hash string `json:"hash"`
+}
+
+type tokenFile struct {
+	Tokens []storedAPIToken `json:"tokens"`
+}
+
+// TokenStore persists API tokens as hashes in a JSON file. The file is shared
+// with the CLI, so it is re-read whenever it changes on disk.
+type TokenStore struct {
+	path string
+	now  func() time.Time
+
+	mu        sync.Mutex
+	tokens    []storedAPIToken
+	modTime   time.Time
+	size      int64
+	persisted map[string]time.Time // token id -> last persisted LastUsedAt
+}
+
+// DefaultTokenStorePath returns the token file location, honoring ATM_TOKENS_FILE.
+func DefaultTokenStorePath() (string, error) {
+	if custom := strings.TrimSpace(os.Getenv(tokensPathEnv)); custom != "" {
+		return custom, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".agent-team-monitor", defaultTokensFileName), nil
+}
+
+// NewTokenStore returns a store backed by path. The file is read lazily.
+func NewTokenStore(path string) *TokenStore {
+	return &TokenStore{
+		path:      path,
+		now:       time.Now,
+		persisted: make(map[string]time.Time),
+	}
+}
+
+// Path returns the backing file.
+func (s *TokenStore) Path() string {
+	if s == nil {
+		return ""
+	}
+	return s.path
+}
+
+// ParseScopes validates a list of scopes, accepting comma-separated entries.
+func ParseScopes(values ...string) ([]Permission, error) {
+	seen := make(map[Permission]bool)
+	var scopes []Permission
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			scope := Permission(strings.ToLower(strings.TrimSpace(part)))
+			if scope == "" || seen[scope] {
+				continue
+			}
+			if !isKnownPermission(scope) {
+				return nil, fmt.Errorf("unknown scope %q (valid: %s)", scope, knownPermissionList())
+			}
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required (valid: %s)", knownPermissionList())
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i] < scopes[j] })
+	return scopes, nil
+}
+
+func isKnownPermission(permission Permission) bool {
+	for _, known := range knownPermissions {
+		if permission == known {
+			return true
+		}
+	}
+	return false
+}
+
+func knownPermissionList() string {
+	names := make([]string, 0, len(knownPermissions))
+	for _, permission := range knownPermissions {
+		names = append(names, string(permission))
+	}
+	return strings.Join(names, ", ")
+}
+
+// Create issues a new token and returns its metadata plus the secret. A zero
+// ttl means the token never expires.
+func (s *TokenStore) Create(name string, scopes []string, ttl time.Duration) (APIToken, string, error) {
+	if s == nil {
+		return APIToken{}, "", fmt.Errorf("token store unavailable")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return APIToken{}, "", fmt.Errorf("token name is required")
+	}
+	permissions, err := ParseScopes(scopes...)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+	if ttl < 0 {
+		return APIToken{}, "", fmt.Errorf("token expiry must not be negative")
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+	secret = apiTokenPrefix + secret
+	id, err := randomHex(6)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+
+	now := s.clock()
+	token := APIToken{
+		ID:        id,
+		Name:      name,
+		Scopes:    permissions,
+		Prefix:    secret[:len(apiTokenPrefix)+6],
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		token.ExpiresAt = now.Add(ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return APIToken{}, "", err
+	}
+	s.tokens = append(s.tokens, storedAPIToken{APIToken: token, Hash: hashAPIToken(secret)})
+	if err := s.saveLocked(); err != nil {
+		return APIToken{}, "", err
+	}
+	return token, secret, nil
+}
+
+// List returns all tokens, newest first.
+func (s *TokenStore) List() ([]APIToken, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return nil, err
+	}
+
+	result := make([]APIToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		result = append(result, token.APIToken)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// Revoke deletes the token with the given id (or unique id prefix).
+func (s *TokenStore) Revoke(id string) (APIToken, error) {
+	if s == nil {
+		return APIToken{}, fmt.Errorf("token store unavailable")
+	}
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return APIToken{}, fmt.Errorf("token id is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return APIToken{}, err
+	}
+
+	match := -1
+	for i, token := range s.tokens {
+		if token.ID == id {
+			match = i
+			break
+		}
+		if strings.HasPrefix(token.ID, id) {
+			if match >= 0 {
+				return APIToken{}, fmt.Errorf("token id %q is ambiguous", id)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return APIToken{}, fmt.Errorf("token %q not found", id)
+	}
+
+	revoked := s.tokens[match].APIToken
+	s.tokens = append(s.tokens[:match], s.tokens[match+1:]...)
+	delete(s.persisted, revoked.ID)
+	if err := s.saveLocked(); err != nil {
+		return APIToken{}, err
+	}
+	return revoked, nil
+}
+
+// Authenticate resolves a token secret and records its use.
+func (s *TokenStore) Authenticate(secret string) (APIToken, error) {
+	if s == nil || !strings.HasPrefix(secret, apiTokenPrefix) {
+		return APIToken{}, fmt.Errorf("invalid API token")
+	}
+	hash := hashAPIToken(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reloadLocked(); err != nil {
+		return APIToken{}, err
+	}
+
+	now := s.clock()
+	for i := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(s.tokens[i].Hash), []byte(hash)) != 1 {
+			continue
+		}
+		if s.tokens[i].Expired(now) {
+			return APIToken{}, fmt.Errorf("API token expired")
+		}
+		s.tokens[i].LastUsedAt = now
+		if now.Sub(s.persisted[s.tokens[i].ID]) >= lastUsedPersistInterval {
+			if err := s.saveLocked(); err == nil {
+				s.persisted[s.tokens[i].ID] = now
+			}
+		}
+		return s.tokens[i].APIToken, nil
+	}
+	return APIToken{}, fmt.Errorf("invalid API token")
+}
+
+func (s *TokenStore) reloadLocked() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.tokens = nil
+			s.modTime = time.Time{}
+			s.size = 0
+			return nil
+		}
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var file tokenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parse token file %s: %w", s.path, err)
+	}
+
+	// Keep in-memory last-used times that are newer than the file's.
+	lastUsed := make(map[string]time.Time, len(s.tokens))
+	for _, token := range s.tokens {
+		lastUsed[token.ID] = token.LastUsedAt
+	}
+	for i := range file.Tokens {
+		if used := lastUsed[file.Tokens[i].ID]; used.After(file.Tokens[i].LastUsedAt) {
+			file.Tokens[i].LastUsedAt = used
+		}
+	}
+
+	s.tokens = file.Tokens
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	return nil
+}
+
+func (s *TokenStore) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tokenFile{Tokens: s.tokens}, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+		s.size = info.Size()
+	}
+	return nil
+}
+
+func (s *TokenStore) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate random token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenStoreLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-tokens.json")
+	store := NewTokenStore(path)
+	now := time.Date(2026, 4, 10, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	token, secret, err := store.Create("ci", []string{"message,managed:control"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if token.Prefix == "" || secret[:len(token.Prefix)] != token.Prefix {
+		t.Fatalf("unexpected prefix %q for secret", token.Prefix)
+	}
+
+	// A second store on the same file (e.g. the CLI) sees the token.
+	other := NewTokenStore(path)
+	other.now = store.now
+	authenticated, err := other.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if !authenticated.HasScope(PermissionMessage) || authenticated.HasScope(PermissionTeamsDelete) {
+		t.Fatalf("unexpected scopes %v", authenticated.Scopes)
+	}
+	if !authenticated.LastUsedAt.Equal(now) {
+		t.Fatalf("expected last used to be recorded, got %v", authenticated.LastUsedAt)
+	}
+
+	listed, err := store.List()
+	if err != nil || len(listed) != 1 || !listed[0].LastUsedAt.Equal(now) {
+		t.Fatalf("expected persisted last-used time, got %+v (%v)", listed, err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := other.Authenticate(secret); err == nil {
+		t.Fatal("expected expired token to be rejected")
+	}
+
+	if _, err := store.Revoke(token.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := other.Authenticate(secret); err == nil {
+		t.Fatal("expected revoked token to be rejected")
+	}
+}
+
+func TestParseScopesRejectsUnknownScopes(t *testing.T) {
+	if _, err := ParseScopes("read,deploy"); err == nil {
+		t.Fatal("expected unknown scope error")
+	}
+	if _, err := ParseScopes(""); err == nil {
+		t.Fatal("expected empty scope error")
+	}
+	scopes, err := ParseScopes("message, admin", "message")
+	if err != nil || len(scopes) != 2 || scopes[0] != "admin" || scopes[1] != "message" {
+		t.Fatalf("unexpected scopes %v (%v)", scopes, err)
+	}
+}
+
+func TestBearerAPITokenScopesGateRoutes(t *testing.T) {
+	t.Setenv("ATM_TOKENS_FILE", filepath.Join(t.TempDir(), "api-tokens.json"))
+	server, auth := newTestAuthServer(t)
+
+	_, messageSecret, err := auth.Tokens().Create("ci", []string{string(PermissionMessage)}, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_, readSecret, err := auth.Tokens().Create("dashboard", []string{string(PermissionRead)}, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	send := func(secret string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/agents/message", nil)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		res := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	// 503 means the request got past auth to the missing collector.
+	if code := send(messageSecret); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected message token to pass auth, got %d", code)
+	}
+	if code := send(readSecret); code != http.StatusForbidden {
+		t.Fatalf("expected read-only token to be rejected, got %d", code)
+	}
+	if code := send("atm_not-a-token"); code != http.StatusForbidden {
+		t.Fatalf("expected unknown token to be rejected, got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tokens", nil)
+	req.Header.Set("Authorization", "Bearer "+messageSecret)
+	res := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(res, req)
+	if res.Code != http.StatusForbidden {
+		t.Fatalf("expected non-admin token to be denied token admin, got %d", res.Code)
+	}
+}
+
+func TestAPITokenAdminEndpoints(t *testing.T) {
+	t.Setenv("ATM_TOKENS_FILE", filepath.Join(t.TempDir(), "api-tokens.json"))
+	server, _ := newTestAuthServer(t)
+	cookie := sessionCookie(t, serveAuthRequest(server, http.MethodPost, "/api/auth/login", `{"username":"admin","password":"secret"}`))
+
+	res := serveAuthRequest(server, http.MethodPost, "/api/tokens", `{"name":"ci","scopes":["managed:control"],"expires_in":"24h"}`, cookie)
+	if res.Code != http.StatusOK {
+		t.Fatalf("create token: %d %s", res.Code, res.Body.String())
+	}
+	var created createAPITokenResponse
+	if err := json.Unmarshal(res.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created token: %v", err)
+	}
+	if created.Token == "" || created.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected created token: %+v", created)
+	}
+
+	res = serveAuthRequest(server, http.MethodGet, "/api/tokens", "", cookie)
+	var listed []APIToken
+	if err := json.Unmarshal(res.Body.Bytes(), &listed); err != nil || len(listed) != 1 || listed[0].ID != created.ID {
+		t.Fatalf("unexpected token list %s (%v)", res.Body.String(), err)
+	}
+	if strings.Contains(res.Body.String(), created.Token) {
+		t.Fatal("token list must not expose secrets")
+	}
+
+	if res := serveAuthRequest(server, http.MethodDelete, "/api/tokens/"+created.ID, "", cookie); res.Code != http.StatusOK {
+		t.Fatalf("revoke token: %d %s", res.Code, res.Body.String())
+	}
+	if res := serveAuthRequest(server, http.MethodDelete, "/api/tokens/"+created.ID, "", cookie); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for revoked token, got %d", res.Code)
+	}
+}
+
+func TestTeamDeletionScopes(t *testing.T) {
+	scopes, err := ParseScopes("tasks:write", "teams:delete")
+	if err != nil || len(scopes) != 2 {
+		t.Fatalf("expected both scopes to be valid, got %v %v", scopes, err)
+	}
+	if !(APIToken{Scopes: []Permission{PermissionTasksWrite}}).HasScope(PermissionTeamsDelete) {
+		t.Fatal("expected a tasks:write token to keep deleting teams")
+	}
+	deleter := APIToken{Scopes: []Permission{PermissionTeamsDelete}}
+	if !deleter.HasScope(PermissionTeamsDelete) || deleter.HasScope(PermissionTasksWrite) {
+		t.Fatalf("expected teams:delete to allow only team deletion, got %v", deleter.Scopes)
+	}
+}
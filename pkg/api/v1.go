@@ -0,0 +1,793 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/audit"
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+const (
+	apiV1Prefix = "/api/v1"
+
+	apiV1DefaultLimit = 100
+	apiV1MaxLimit     = 1000
+
+	apiV1DefaultLogLines = 200
+	apiV1MaxLogLines     = 5000
+)
+
+// apiV1Route is one /api/v1 endpoint. The same table drives the mux and
+// the OpenAPI document.
+type apiV1Route struct {
+	Method  string
+	Path    string // relative to /api/v1, with {name} wildcards
+	Summary string
+	Tag     string
+	// Permission is checked before the handler runs; empty means public.
+	Permission Permission
+	// AuditAction records denied calls for mutating routes.
+	AuditAction string
+	Query       []string // supported query parameters, see apiV1QueryParams
+	Request     any      // request body type, nil for none
+	Response    any      // response data type, nil for 204
+	List        bool     // Response is wrapped in a paginated list
+	Status      int      // success status, default 200
+	handler     http.HandlerFunc
+}
+
+var apiV1ListQuery = []string{"limit", "offset"}
+
+func (s *Server) apiV1Routes() []apiV1Route {
+	teamFilters := append([]string{"provider", "status", "updated_since"}, apiV1ListQuery...)
+	itemFilters := append([]string{"team", "provider", "status", "updated_since"}, apiV1ListQuery...)
+
+	return []apiV1Route{
+		{Method: http.MethodGet, Path: "/teams", Tag: "teams", Summary: "List teams", Permission: PermissionRead,
+			Query: teamFilters, Response: types.TeamInfo{}, List: true, handler: s.handleV1Teams},
+		{Method: http.MethodGet, Path: "/teams/{team}", Tag: "teams", Summary: "Get a team", Permission: PermissionRead,
+			Response: types.TeamInfo{}, handler: s.handleV1Team},
+		{Method: http.MethodDelete, Path: "/teams/{team}", Tag: "teams", Summary: "Delete a team's config and tasks", Permission: PermissionTeamsDelete,
+			AuditAction: audit.ActionTeamDelete, Status: http.StatusNoContent, handler: s.handleV1DeleteTeam},
+		{Method: http.MethodGet, Path: "/teams/{team}/agents", Tag: "agents", Summary: "List a team's agents", Permission: PermissionRead,
+			Query: append([]string{"provider", "status", "updated_since"}, apiV1ListQuery...), Response: apiV1Agent{}, List: true, handler: s.handleV1Agents},
+		{Method: http.MethodGet, Path: "/teams/{team}/agents/{agent}", Tag: "agents", Summary: "Get an agent by name or id", Permission: PermissionRead,
+			Response: apiV1Agent{}, handler: s.handleV1Agent},
+		{Method: http.MethodPost, Path: "/teams/{team}/agents/{agent}/messages", Tag: "agents", Summary: "Send a message to an agent's inbox", Permission: PermissionMessage,
+			AuditAction: audit.ActionAgentMessage, Request: apiV1MessageRequest{}, Response: apiV1Accepted{}, Status: http.StatusAccepted, handler: s.handleV1AgentMessage},
+		{Method: http.MethodGet, Path: "/teams/{team}/tasks", Tag: "tasks", Summary: "List a team's tasks", Permission: PermissionRead,
+			Query: append([]string{"status", "updated_since"}, apiV1ListQuery...), Response: apiV1Task{}, List: true, handler: s.handleV1Tasks},
+		{Method: http.MethodGet, Path: "/agents", Tag: "agents", Summary: "List agents across teams", Permission: PermissionRead,
+			Query: itemFilters, Response: apiV1Agent{}, List: true, handler: s.handleV1Agents},
+		{Method: http.MethodGet, Path: "/tasks", Tag: "tasks", Summary: "List tasks across teams", Permission: PermissionRead,
+			Query: itemFilters, Response: apiV1Task{}, List: true, handler: s.handleV1Tasks},
+		{Method: http.MethodGet, Path: "/processes", Tag: "processes", Summary: "List monitored agent processes", Permission: PermissionRead,
+			Query: append([]string{"team", "provider", "updated_since"}, apiV1ListQuery...), Response: types.ProcessInfo{}, List: true, handler: s.handleV1Processes},
+		{Method: http.MethodPost, Path: "/processes/{pid}/signals", Tag: "processes", Summary: "Signal a monitored agent process and wait for it to exit", Permission: PermissionManagedControl,
+			AuditAction: audit.ActionProcessSignal, Request: apiV1SignalRequest{}, Response: []types.SignalResult{}, handler: s.handleV1Signal},
+		{Method: http.MethodPost, Path: "/teams/{team}/signals", Tag: "processes", Summary: "Signal every process linked to a team", Permission: PermissionManagedControl,
+			AuditAction: audit.ActionProcessSignal, Request: apiV1SignalRequest{}, Response: []types.SignalResult{}, handler: s.handleV1Signal},
+		{Method: http.MethodGet, Path: "/managed/teams", Tag: "managed", Summary: "List managed teams", Permission: PermissionRead,
+			Query: append([]string{"provider", "status"}, apiV1ListQuery...), Response: managed.ManagedTeam{}, List: true, handler: s.handleV1ManagedTeams},
+		{Method: http.MethodPost, Path: "/managed/teams", Tag: "managed", Summary: "Create a managed team", Permission: PermissionManagedControl,
+			AuditAction: audit.ActionManagedCreate, Request: managed.CreateTeamInput{}, Response: managed.TeamSpec{}, Status: http.StatusCreated, handler: s.handleV1CreateManagedTeam},
+		{Method: http.MethodGet, Path: "/managed/teams/{id}", Tag: "managed", Summary: "Get a managed team", Permission: PermissionRead,
+			Response: managed.ManagedTeam{}, handler: s.handleV1ManagedTeam},
+		{Method: http.MethodGet, Path: "/managed/teams/{id}/runs", Tag: "managed", Summary: "List a managed team's runs", Permission: PermissionRead,
+			Query: apiV1ListQuery, Response: managed.RunState{}, List: true, handler: s.handleV1ManagedRuns},
+		{Method: http.MethodPost, Path: "/managed/teams/{id}/runs", Tag: "managed", Summary: "Start a managed team", Permission: PermissionManagedControl,
+			AuditAction: audit.ActionManagedStart, Response: managed.RunState{}, Status: http.StatusCreated, handler: s.handleV1ManagedRun},
+		{Method: http.MethodDelete, Path: "/managed/teams/{id}/runs", Tag: "managed", Summary: "Stop a managed team", Permission: PermissionManagedControl,
+			AuditAction: audit.ActionManagedStop, Response: managed.RunState{}, handler: s.handleV1ManagedRun},
+		{Method: http.MethodPost, Path: "/managed/teams/{id}/agents/{agent}/runs", Tag: "managed", Summary: "Start one managed agent", Permission: PermissionManagedControl,
+			AuditAction: audit.ActionManagedStart, Response: managed.RunState{}, Status: http.StatusCreated, handler: s.handleV1ManagedRun},
+		{Method: http.MethodDelete, Path: "/managed/teams/{id}/agents/{agent}/runs", Tag: "managed", Summary: "Stop one managed agent", Permission: PermissionManagedControl,
+			AuditAction: audit.ActionManagedStop, Response: managed.RunState{}, handler: s.handleV1ManagedRun},
+		{Method: http.MethodGet, Path: "/managed/teams/{id}/logs", Tag: "managed", Summary: "Read the end of a managed agent's terminal log", Permission: PermissionManagedControl,
+			Query: []string{"agent", "lines"}, Response: managed.LogTail{}, handler: s.handleV1ManagedLogs},
+		{Method: http.MethodPost, Path: "/managed/teams/{id}/messages", Tag: "managed", Summary: "Send a message to a managed team", Permission: PermissionMessage,
+			AuditAction: audit.ActionManagedMessage, Request: managedTeamMessageRequest{}, Response: apiV1Accepted{}, Status: http.StatusAccepted, handler: s.handleV1ManagedMessage},
+		{Method: http.MethodPost, Path: "/managed/teams/{id}/agents/{agent}/messages", Tag: "managed", Summary: "Send a message to one managed agent", Permission: PermissionMessage,
+			AuditAction: audit.ActionManagedMessage, Request: apiV1MessageRequest{}, Response: apiV1Accepted{}, Status: http.StatusAccepted, handler: s.handleV1ManagedMessage},
+		{Method: http.MethodGet, Path: "/openapi.json", Tag: "meta", Summary: "This OpenAPI document", handler: s.handleV1OpenAPI},
+	}
+}
+
+// newAPIV1Handler routes /api/v1 with method-aware patterns and answers
+// unknown routes with JSON errors too.
+func (s *Server) newAPIV1Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, route := range s.apiV1Routes() {
+		mux.Handle(route.Method+" "+apiV1Prefix+route.Path, s.apiV1Guard(route))
+	}
+
+	catchAll := apiV1Prefix + "/"
+	mux.HandleFunc(catchAll, func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != catchAll {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			respondAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		respondAPIError(w, http.StatusNotFound, "no such route: "+r.URL.Path)
+	})
+	return mux
+}
+
+// apiV1Guard checks the route's permission, auditing denied mutations.
+func (s *Server) apiV1Guard(route apiV1Route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route.Permission != "" {
+			if err := s.auth.RequirePermission(r, route.Permission); err != nil {
+				if route.AuditAction != "" {
+					s.recordAudit(r, apiV1AuditEntry(r, route.AuditAction, audit.ResultDenied), err)
+				}
+				respondAPIError(w, http.StatusForbidden, err.Error())
+				return
+			}
+		}
+		route.handler(w, r)
+	})
+}
+
+func apiV1AuditEntry(r *http.Request, action, result string) audit.Entry {
+	return audit.Entry{
+		Action: action,
+		Team:   firstNonEmpty(r.PathValue("team"), r.PathValue("id")),
+		Agent:  r.PathValue("agent"),
+		Result: result,
+	}
+}
+
+// apiV1Agent is an agent together with the team it belongs to.
+type apiV1Agent struct {
+	Team string `json:"team"`
+	types.AgentInfo
+}
+
+// apiV1Task is a task together with the team it belongs to.
+type apiV1Task struct {
+	Team string `json:"team"`
+	types.TaskInfo
+}
+
+type apiV1MessageRequest struct {
+	Text string `json:"text"`
+}
+
+// apiV1SignalRequest names SIGINT, SIGTERM or SIGKILL; TimeoutSeconds
+// bounds the wait for exit and defaults to monitor.DefaultSignalTimeout.
+type apiV1SignalRequest struct {
+	Signal         string `json:"signal"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
+// apiV1Accepted acknowledges queued work.
+type apiV1Accepted struct {
+	Status string `json:"status"`
+}
+
+type apiV1Item struct {
+	Data any `json:"data"`
+}
+
+type apiV1List struct {
+	Data any       `json:"data"`
+	Page apiV1Page `json:"page"`
+}
+
+type apiV1Page struct {
+	Total      int  `json:"total"`
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+// apiV1Error is the body of every /api/v1 error response.
+type apiV1Error struct {
+	Error apiV1ErrorBody `json:"error"`
+}
+
+type apiV1ErrorBody struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func apiErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusBadGateway:
+		return "bad_gateway"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	default:
+		if status >= 500 {
+			return "internal"
+		}
+		return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	}
+}
+
+func respondAPIError(w http.ResponseWriter, status int, message string) {
+	respondAPIStatus(w, status, apiV1Error{Error: apiV1ErrorBody{
+		Status:  status,
+		Code:    apiErrorCode(status),
+		Message: message,
+	}})
+}
+
+func respondAPIStatus(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error encoding JSON: %v", err)
+	}
+}
+
+func respondAPIItem(w http.ResponseWriter, status int, data any) {
+	respondAPIStatus(w, status, apiV1Item{Data: data})
+}
+
+// apiV1Filter holds the shared list query parameters.
+type apiV1Filter struct {
+	Team         string
+	Provider     string
+	Status       string
+	UpdatedSince time.Time
+	Limit        int
+	Offset       int
+}
+
+// parseAPIV1Filter reads team, provider, status, updated_since (RFC 3339 or
+// a duration before now such as 15m), limit and offset.
+func parseAPIV1Filter(values url.Values, now time.Time) (apiV1Filter, error) {
+	filter := apiV1Filter{
+		Team:     strings.TrimSpace(values.Get("team")),
+		Provider: strings.ToLower(strings.TrimSpace(values.Get("provider"))),
+		Status:   strings.ToLower(strings.TrimSpace(values.Get("status"))),
+		Limit:    apiV1DefaultLimit,
+	}
+	if raw := strings.TrimSpace(values.Get("updated_since")); raw != "" {
+		if ago, err := time.ParseDuration(raw); err == nil {
+			filter.UpdatedSince = now.Add(-ago)
+		} else if at, err := time.Parse(time.RFC3339, raw); err == nil {
+			filter.UpdatedSince = at
+		} else {
+			return apiV1Filter{}, fmt.Errorf("invalid updated_since %q: want RFC 3339 or a duration", raw)
+		}
+	}
+	if raw := strings.TrimSpace(values.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return apiV1Filter{}, fmt.Errorf("invalid limit %q", raw)
+		}
+		filter.Limit = min(limit, apiV1MaxLimit)
+	}
+	if raw := strings.TrimSpace(values.Get("offset")); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return apiV1Filter{}, fmt.Errorf("invalid offset %q", raw)
+		}
+		filter.Offset = offset
+	}
+	return filter, nil
+}
+
+func (f apiV1Filter) matchTeamName(name string) bool {
+	return f.Team == "" || strings.EqualFold(f.Team, name)
+}
+
+func (f apiV1Filter) matchProvider(provider string) bool {
+	return f.Provider == "" || strings.EqualFold(f.Provider, provider)
+}
+
+func (f apiV1Filter) matchStatus(status string) bool {
+	return f.Status == "" || strings.EqualFold(f.Status, status)
+}
+
+func (f apiV1Filter) matchUpdated(at time.Time) bool {
+	return f.UpdatedSince.IsZero() || !at.Before(f.UpdatedSince)
+}
+
+// paginate slices items and describes the page.
+func paginate[T any](items []T, filter apiV1Filter) apiV1List {
+	total := len(items)
+	start := min(filter.Offset, total)
+	end := min(start+filter.Limit, total)
+	page := apiV1Page{Total: total, Limit: filter.Limit, Offset: filter.Offset}
+	if end < total {
+		next := end
+		page.NextOffset = &next
+	}
+	data := items[start:end]
+	if data == nil {
+		data = []T{}
+	}
+	return apiV1List{Data: data, Page: page}
+}
+
+func agentUpdatedAt(agent types.AgentInfo) time.Time {
+	latest := agent.LastActivity
+	for _, at := range []time.Time{agent.LastActiveTime, agent.LastMessageTime} {
+		if at.After(latest) {
+			latest = at
+		}
+	}
+	return latest
+}
+
+func teamUpdatedAt(team types.TeamInfo) time.Time {
+	latest := team.CreatedAt
+	for _, agent := range team.Members {
+		if at := agentUpdatedAt(agent); at.After(latest) {
+			latest = at
+		}
+	}
+	for _, task := range team.Tasks {
+		if task.UpdatedAt.After(latest) {
+			latest = task.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// teamHasStatus matches a team's managed run status or any member status,
+// so status=working finds teams with a busy agent.
+func teamHasStatus(team types.TeamInfo, status string) bool {
+	if status == "" || strings.EqualFold(team.ManagedStatus, status) {
+		return true
+	}
+	for _, agent := range team.Members {
+		if strings.EqualFold(agent.Status, status) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterTeams(teams []types.TeamInfo, filter apiV1Filter) []types.TeamInfo {
+	result := make([]types.TeamInfo, 0, len(teams))
+	for _, team := range teams {
+		if filter.matchTeamName(team.Name) && filter.matchProvider(team.Provider) &&
+			teamHasStatus(team, filter.Status) && filter.matchUpdated(teamUpdatedAt(team)) {
+			result = append(result, team)
+		}
+	}
+	return result
+}
+
+func filterAgents(teams []types.TeamInfo, filter apiV1Filter) []apiV1Agent {
+	result := []apiV1Agent{}
+	for _, team := range teams {
+		if !filter.matchTeamName(team.Name) {
+			continue
+		}
+		for _, agent := range team.Members {
+			if filter.matchProvider(firstNonEmpty(agent.Provider, team.Provider)) && filter.matchStatus(agent.Status) &&
+				filter.matchUpdated(agentUpdatedAt(agent)) {
+				result = append(result, apiV1Agent{Team: team.Name, AgentInfo: agent})
+			}
+		}
+	}
+	return result
+}
+
+func filterTasks(teams []types.TeamInfo, filter apiV1Filter) []apiV1Task {
+	result := []apiV1Task{}
+	for _, team := range teams {
+		if !filter.matchTeamName(team.Name) || !filter.matchProvider(team.Provider) {
+			continue
+		}
+		for _, task := range team.Tasks {
+			if filter.matchStatus(task.Status) && filter.matchUpdated(task.UpdatedAt) {
+				result = append(result, apiV1Task{Team: team.Name, TaskInfo: task})
+			}
+		}
+	}
+	return result
+}
+
+func filterProcesses(processes []types.ProcessInfo, filter apiV1Filter) []types.ProcessInfo {
+	result := make([]types.ProcessInfo, 0, len(processes))
+	for _, process := range processes {
+		if filter.matchTeamName(process.Team) && filter.matchProvider(process.Provider) && filter.matchUpdated(process.StartedAt) {
+			result = append(result, process)
+		}
+	}
+	return result
+}
+
+func findTeam(teams []types.TeamInfo, name string) (types.TeamInfo, bool) {
+	for _, team := range teams {
+		if team.Name == name {
+			return team, true
+		}
+	}
+	return types.TeamInfo{}, false
+}
+
+func findAgent(team types.TeamInfo, nameOrID string) (types.AgentInfo, bool) {
+	for _, agent := range team.Members {
+		if agent.Name == nameOrID || (agent.AgentID != "" && agent.AgentID == nameOrID) {
+			return agent, true
+		}
+	}
+	return types.AgentInfo{}, false
+}
+
+// servedState is the state as served to this client, after kiosk filtering.
+func (s *Server) servedState() types.MonitorState {
+	return s.kioskState(s.buildState(), time.Now())
+}
+
+// apiV1ListFilter parses the query, answering 400 itself on failure.
+func apiV1ListFilter(w http.ResponseWriter, r *http.Request) (apiV1Filter, bool) {
+	filter, err := parseAPIV1Filter(r.URL.Query(), time.Now())
+	if err != nil {
+		respondAPIError(w, http.StatusBadRequest, err.Error())
+		return apiV1Filter{}, false
+	}
+	return filter, true
+}
+
+func (s *Server) handleV1Teams(w http.ResponseWriter, r *http.Request) {
+	filter, ok := apiV1ListFilter(w, r)
+	if !ok {
+		return
+	}
+	respondAPIStatus(w, http.StatusOK, paginate(filterTeams(s.servedState().Teams, filter), filter))
+}
+
+// lookupTeam resolves {team}, answering 404 itself when it is unknown.
+func (s *Server) lookupTeam(w http.ResponseWriter, r *http.Request) (types.TeamInfo, bool) {
+	team, ok := findTeam(s.servedState().Teams, r.PathValue("team"))
+	if !ok {
+		respondAPIError(w, http.StatusNotFound, fmt.Sprintf("team %q not found", r.PathValue("team")))
+	}
+	return team, ok
+}
+
+func (s *Server) handleV1Team(w http.ResponseWriter, r *http.Request) {
+	if team, ok := s.lookupTeam(w, r); ok {
+		respondAPIItem(w, http.StatusOK, team)
+	}
+}
+
+func (s *Server) handleV1DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	if s.collector == nil {
+		respondAPIError(w, http.StatusServiceUnavailable, "collector unavailable")
+		return
+	}
+	team, ok := s.lookupTeam(w, r)
+	if !ok {
+		return
+	}
+	if team.Managed {
+		respondAPIError(w, http.StatusBadRequest, "managed teams are removed through their manager")
+		return
+	}
+	err := s.deleteTeam(r.Context(), team.Name)
+	s.recordAudit(r, apiV1AuditEntry(r, audit.ActionTeamDelete, ""), err)
+	if err != nil {
+		log.Printf("Error deleting team %s: %v", team.Name, err)
+		if team.Host != "" {
+			status, message := actionError(err, http.StatusInternalServerError)
+			respondAPIError(w, status, message)
+			return
+		}
+		respondAPIError(w, http.StatusInternalServerError, "failed to delete team")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleV1Agents(w http.ResponseWriter, r *http.Request) {
+	filter, ok := apiV1ListFilter(w, r)
+	if !ok {
+		return
+	}
+	teams := s.servedState().Teams
+	if name := r.PathValue("team"); name != "" {
+		team, found := findTeam(teams, name)
+		if !found {
+			respondAPIError(w, http.StatusNotFound, fmt.Sprintf("team %q not found", name))
+			return
+		}
+		teams, filter.Team = []types.TeamInfo{team}, ""
+	}
+	respondAPIStatus(w, http.StatusOK, paginate(filterAgents(teams, filter), filter))
+}
+
+func (s *Server) handleV1Agent(w http.ResponseWriter, r *http.Request) {
+	team, ok := s.lookupTeam(w, r)
+	if !ok {
+		return
+	}
+	agent, found := findAgent(team, r.PathValue("agent"))
+	if !found {
+		respondAPIError(w, http.StatusNotFound, fmt.Sprintf("agent %q not found in team %q", r.PathValue("agent"), team.Name))
+		return
+	}
+	respondAPIItem(w, http.StatusOK, apiV1Agent{Team: team.Name, AgentInfo: agent})
+}
+
+func (s *Server) handleV1AgentMessage(w http.ResponseWriter, r *http.Request) {
+	if s.collector == nil {
+		respondAPIError(w, http.StatusServiceUnavailable, "collector unavailable")
+		return
+	}
+	var req apiV1MessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondAPIError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	entry := apiV1AuditEntry(r, audit.ActionAgentMessage, "")
+	entry.Detail = audit.Summarize(req.Text)
+	err := s.sendAgentMessage(r.Context(), r.PathValue("team"), r.PathValue("agent"), req.Text)
+	s.recordAudit(r, entry, err)
+	if err != nil {
+		status, message := actionError(err, http.StatusBadRequest)
+		respondAPIError(w, status, message)
+		return
+	}
+	respondAPIItem(w, http.StatusAccepted, apiV1Accepted{Status: "queued"})
+}
+
+func (s *Server) handleV1Tasks(w http.ResponseWriter, r *http.Request) {
+	filter, ok := apiV1ListFilter(w, r)
+	if !ok {
+		return
+	}
+	teams := s.servedState().Teams
+	if name := r.PathValue("team"); name != "" {
+		team, found := findTeam(teams, name)
+		if !found {
+			respondAPIError(w, http.StatusNotFound, fmt.Sprintf("team %q not found", name))
+			return
+		}
+		teams, filter.Team, filter.Provider = []types.TeamInfo{team}, "", ""
+	}
+	respondAPIStatus(w, http.StatusOK, paginate(filterTasks(teams, filter), filter))
+}
+
+func (s *Server) handleV1Processes(w http.ResponseWriter, r *http.Request) {
+	filter, ok := apiV1ListFilter(w, r)
+	if !ok {
+		return
+	}
+	respondAPIStatus(w, http.StatusOK, paginate(filterProcesses(s.servedState().Processes, filter), filter))
+}
+
+// handleV1Signal signals one process by {pid}, qualified with its node on
+// a hub, or every process linked to {team}.
+func (s *Server) handleV1Signal(w http.ResponseWriter, r *http.Request) {
+	if s.collector == nil && !s.federated(firstNonEmpty(r.PathValue("team"), r.PathValue("pid"))) {
+		respondAPIError(w, http.StatusServiceUnavailable, "collector unavailable")
+		return
+	}
+	var req apiV1SignalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondAPIError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	signal, err := monitor.ParseSignal(req.Signal)
+	if err != nil {
+		respondAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+
+	var results []types.SignalResult
+	if team := r.PathValue("team"); team != "" {
+		results, err = s.signalTeam(r.Context(), team, signal, timeout)
+	} else {
+		results, err = s.signalProcess(r.Context(), r.PathValue("pid"), signal, timeout)
+	}
+	entry := apiV1AuditEntry(r, audit.ActionProcessSignal, "")
+	entry.Detail = firstNonEmpty(monitor.SummarizeSignalResults(results), signal+" "+r.PathValue("pid"))
+	s.recordAudit(r, entry, err)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, monitor.ErrProcessNotMonitored) {
+			status = http.StatusNotFound
+		}
+		status, message := actionError(err, status)
+		respondAPIError(w, status, message)
+		return
+	}
+	respondAPIItem(w, http.StatusOK, results)
+}
+
+// managedTeams lists managed teams, answering 503 itself when managed
+// teams are unavailable.
+func (s *Server) managedTeams(w http.ResponseWriter) ([]managed.ManagedTeam, bool) {
+	if s.managed == nil {
+		respondAPIError(w, http.StatusServiceUnavailable, "managed team manager unavailable")
+		return nil, false
+	}
+	teams, err := s.managed.ListTeams()
+	if err != nil {
+		respondAPIError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return teams, true
+}
+
+func (s *Server) lookupManagedTeam(w http.ResponseWriter, r *http.Request) (managed.ManagedTeam, bool) {
+	teams, ok := s.managedTeams(w)
+	if !ok {
+		return managed.ManagedTeam{}, false
+	}
+	id := r.PathValue("id")
+	for _, team := range teams {
+		if team.Spec.ID == id {
+			return team, true
+		}
+	}
+	respondAPIError(w, http.StatusNotFound, fmt.Sprintf("managed team %q not found", id))
+	return managed.ManagedTeam{}, false
+}
+
+func (s *Server) handleV1ManagedTeams(w http.ResponseWriter, r *http.Request) {
+	filter, ok := apiV1ListFilter(w, r)
+	if !ok {
+		return
+	}
+	teams, ok := s.managedTeams(w)
+	if !ok {
+		return
+	}
+	result := make([]managed.ManagedTeam, 0, len(teams))
+	for _, team := range teams {
+		status := string(managed.RunStatusStopped)
+		if team.Run != nil {
+			status = string(team.Run.Status)
+		}
+		if filter.matchProvider(team.Spec.Provider) && filter.matchStatus(status) {
+			result = append(result, team)
+		}
+	}
+	respondAPIStatus(w, http.StatusOK, paginate(result, filter))
+}
+
+func (s *Server) handleV1CreateManagedTeam(w http.ResponseWriter, r *http.Request) {
+	if s.managed == nil {
+		respondAPIError(w, http.StatusServiceUnavailable, "managed team manager unavailable")
+		return
+	}
+	var input managed.CreateTeamInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondAPIError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	spec, err := s.managed.CreateTeam(input)
+	s.recordAudit(r, audit.Entry{Action: audit.ActionManagedCreate, Team: firstNonEmpty(spec.ID, input.Name), Detail: input.Name}, err)
+	if err != nil {
+		respondAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondAPIItem(w, http.StatusCreated, spec)
+}
+
+func (s *Server) handleV1ManagedTeam(w http.ResponseWriter, r *http.Request) {
+	if team, ok := s.lookupManagedTeam(w, r); ok {
+		respondAPIItem(w, http.StatusOK, team)
+	}
+}
+
+func (s *Server) handleV1ManagedRuns(w http.ResponseWriter, r *http.Request) {
+	filter, ok := apiV1ListFilter(w, r)
+	if !ok {
+		return
+	}
+	if team, ok := s.lookupManagedTeam(w, r); ok {
+		respondAPIStatus(w, http.StatusOK, paginate(team.Runs, filter))
+	}
+}
+
+// handleV1ManagedLogs returns the last ?lines= (default 200) lines of the
+// ?agent= log, or the primary agent's.
+func (s *Server) handleV1ManagedLogs(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.lookupManagedTeam(w, r); !ok {
+		return
+	}
+	lines := apiV1DefaultLogLines
+	if raw := r.URL.Query().Get("lines"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			respondAPIError(w, http.StatusBadRequest, "lines must be a positive integer")
+			return
+		}
+		lines = min(parsed, apiV1MaxLogLines)
+	}
+	tail, err := s.managed.ReadLog(r.PathValue("id"), r.URL.Query().Get("agent"), lines)
+	if err != nil {
+		respondAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondAPIItem(w, http.StatusOK, tail)
+}
+
+// handleV1ManagedRun starts (POST) or stops (DELETE) a team or one agent.
+func (s *Server) handleV1ManagedRun(w http.ResponseWriter, r *http.Request) {
+	if !s.federated(r.PathValue("id")) {
+		if _, ok := s.lookupManagedTeam(w, r); !ok {
+			return
+		}
+	}
+	teamID, agentID := r.PathValue("id"), r.PathValue("agent")
+
+	var (
+		run    managed.RunState
+		err    error
+		action = audit.ActionManagedStart
+		status = http.StatusCreated
+	)
+	if r.Method == http.MethodPost {
+		run, err = s.startManaged(r.Context(), teamID, agentID)
+	} else {
+		run, err = s.stopManaged(r.Context(), teamID, agentID)
+		action, status = audit.ActionManagedStop, http.StatusOK
+	}
+	s.recordAudit(r, apiV1AuditEntry(r, action, ""), err)
+	if err != nil {
+		errStatus, message := actionError(err, http.StatusBadRequest)
+		respondAPIError(w, errStatus, message)
+		return
+	}
+	respondAPIItem(w, status, run)
+}
+
+func (s *Server) handleV1ManagedMessage(w http.ResponseWriter, r *http.Request) {
+	if !s.federated(r.PathValue("id")) {
+		if _, ok := s.lookupManagedTeam(w, r); !ok {
+			return
+		}
+	}
+	var req managedTeamMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondAPIError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	teamID := r.PathValue("id")
+	agentID := firstNonEmpty(r.PathValue("agent"), strings.TrimSpace(req.AgentID))
+	err := s.sendManagedMessage(r.Context(), teamID, agentID, req.Text)
+	entry := apiV1AuditEntry(r, audit.ActionManagedMessage, "")
+	entry.Agent = agentID
+	entry.Detail = audit.Summarize(req.Text)
+	s.recordAudit(r, entry, err)
+	if err != nil {
+		status, message := actionError(err, http.StatusBadRequest)
+		respondAPIError(w, status, message)
+		return
+	}
+	respondAPIItem(w, http.StatusAccepted, apiV1Accepted{Status: "queued"})
+}
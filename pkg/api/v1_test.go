@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func decodeAPIError(t *testing.T, res *httptest.ResponseRecorder) apiV1ErrorBody {
+	t.Helper()
+	var body apiV1Error
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil || body.Error.Code == "" {
+		t.Fatalf("expected JSON error envelope, got %q (%v)", res.Body.String(), err)
+	}
+	return body.Error
+}
+
+// newTestV1Server adds a managed team manager to the RBAC test server.
+func newTestV1Server(t *testing.T) (*Server, string) {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("ATM_MANAGED_DIR", filepath.Join(root, "managed"))
+	workspace := filepath.Join(root, "workspace")
+	if err := os.MkdirAll(workspace, 0o755); err != nil {
+		t.Fatalf("mkdir workspace: %v", err)
+	}
+	manager, err := managed.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	server, _ := newTestRBACServer(t, "")
+	server.managed = manager
+	return server, workspace
+}
+
+func TestAPIV1ManagedTeamLifecycle(t *testing.T) {
+	server, workspace := newTestV1Server(t)
+	operator := loginAs(t, server, "otto")
+
+	body := `{"name":"Release Crew","provider":"claude","workspace":"` + workspace + `","agents":[{"name":"lead","provider":"claude"},{"name":"reviewer","provider":"claude"}]}`
+	res := serveAuthRequest(server, http.MethodPost, "/api/v1/managed/teams", body, operator)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", res.Code, res.Body.String())
+	}
+	var created struct {
+		Data managed.TeamSpec `json:"data"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &created); err != nil || created.Data.ID == "" {
+		t.Fatalf("decode created team: %s (%v)", res.Body.String(), err)
+	}
+
+	res = serveAuthRequest(server, http.MethodGet, "/api/v1/teams?provider=claude", "")
+	var teams struct {
+		Data []types.TeamInfo `json:"data"`
+		Page apiV1Page        `json:"page"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &teams); err != nil || teams.Page.Total != 1 || teams.Data[0].Name != "Release Crew" {
+		t.Fatalf("unexpected team list %s (%v)", res.Body.String(), err)
+	}
+	if res := serveAuthRequest(server, http.MethodGet, "/api/v1/teams?provider=codex", ""); !jsonContains(t, res, `"total":0`) {
+		t.Fatalf("expected provider filter to exclude the team, got %s", res.Body.String())
+	}
+
+	res = serveAuthRequest(server, http.MethodGet, "/api/v1/teams/Release%20Crew/agents?limit=1", "")
+	var agents struct {
+		Data []apiV1Agent `json:"data"`
+		Page apiV1Page    `json:"page"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &agents); err != nil || len(agents.Data) != 1 || agents.Page.Total != 2 ||
+		agents.Page.NextOffset == nil || *agents.Page.NextOffset != 1 || agents.Data[0].Team != "Release Crew" {
+		t.Fatalf("unexpected agent page %s (%v)", res.Body.String(), err)
+	}
+
+	res = serveAuthRequest(server, http.MethodGet, "/api/v1/teams/Release%20Crew/agents/reviewer", "")
+	if res.Code != http.StatusOK || !jsonContains(t, res, `"name":"reviewer"`) {
+		t.Fatalf("get agent: %d %s", res.Code, res.Body.String())
+	}
+
+	res = serveAuthRequest(server, http.MethodGet, "/api/v1/managed/teams/"+created.Data.ID+"/runs", "")
+	if res.Code != http.StatusOK || !jsonContains(t, res, `"data":[`) {
+		t.Fatalf("list runs: %d %s", res.Code, res.Body.String())
+	}
+
+	res = serveAuthRequest(server, http.MethodGet, "/api/v1/managed/teams/"+created.Data.ID+"/logs?agent=reviewer&lines=5", "", operator)
+	if res.Code != http.StatusOK || !jsonContains(t, res, `"agent_id":"reviewer"`) || !jsonContains(t, res, `"lines":[]`) {
+		t.Fatalf("read logs: %d %s", res.Code, res.Body.String())
+	}
+	if res := serveAuthRequest(server, http.MethodGet, "/api/v1/managed/teams/"+created.Data.ID+"/logs?lines=0", "", operator); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected a bad lines value to be rejected, got %d", res.Code)
+	}
+	if res := serveAuthRequest(server, http.MethodGet, "/api/v1/managed/teams/"+created.Data.ID+"/logs", ""); res.Code != http.StatusForbidden && res.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous log reads to be refused, got %d", res.Code)
+	}
+
+	res = serveAuthRequest(server, http.MethodPost, "/api/v1/managed/teams/"+created.Data.ID+"/agents/reviewer/messages", `{"text":"hi"}`, operator)
+	if res.Code != http.StatusBadRequest || decodeAPIError(t, res).Code != "bad_request" {
+		t.Fatalf("expected stopped agent message to fail with an envelope, got %d %s", res.Code, res.Body.String())
+	}
+}
+
+func jsonContains(t *testing.T, res *httptest.ResponseRecorder, fragment string) bool {
+	t.Helper()
+	return res.Code == http.StatusOK && json.Valid(res.Body.Bytes()) && strings.Contains(res.Body.String(), fragment)
+}
+
+func TestAPIV1ErrorsAreJSONEnvelopes(t *testing.T) {
+	server, _ := newTestV1Server(t)
+	viewer := loginAs(t, server, "vera")
+
+	cases := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/api/v1/nope", http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/api/v1/teams/ghost", http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/api/v1/managed/teams/ghost", http.StatusNotFound, "not_found"},
+		{http.MethodPut, "/api/v1/teams", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodGet, "/api/v1/agents?limit=zero", http.StatusBadRequest, "bad_request"},
+		{http.MethodDelete, "/api/v1/teams/alpha", http.StatusForbidden, "forbidden"},
+	}
+	for _, tc := range cases {
+		res := serveAuthRequest(server, tc.method, tc.path, "", viewer)
+		if res.Code != tc.status {
+			t.Errorf("%s %s: expected %d, got %d %s", tc.method, tc.path, tc.status, res.Code, res.Body.String())
+			continue
+		}
+		if got := decodeAPIError(t, res); got.Code != tc.code || got.Status != tc.status {
+			t.Errorf("%s %s: unexpected error %+v", tc.method, tc.path, got)
+		}
+	}
+
+	res := serveAuthRequest(server, http.MethodPut, "/api/v1/teams/alpha", "")
+	if res.Header().Get("Allow") != "GET, DELETE" {
+		t.Fatalf("expected Allow header listing GET and DELETE, got %q", res.Header().Get("Allow"))
+	}
+}
+
+func TestAPIV1OpenAPIDocument(t *testing.T) {
+	server, _ := newTestV1Server(t)
+	res := serveAuthRequest(server, http.MethodGet, "/api/v1/openapi.json", "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("openapi: %d", res.Code)
+	}
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+
+	for _, route := range server.apiV1Routes() {
+		methods, ok := doc.Paths[apiV1Prefix+route.Path]
+		if _, found := methods[strings.ToLower(route.Method)]; !ok || !found {
+			t.Errorf("document misses %s %s", route.Method, route.Path)
+		}
+	}
+	agent := doc.Components.Schemas["Agent"]
+	if _, ok := agent.Properties["team"]; !ok {
+		t.Fatal("expected Agent schema to include the team field")
+	}
+	if _, ok := agent.Properties["last_tool_use"]; !ok {
+		t.Fatal("expected embedded AgentInfo fields to be flattened into Agent")
+	}
+	var deletion struct {
+		Permission string `json:"x-permission"`
+	}
+	if err := json.Unmarshal(doc.Paths[apiV1Prefix+"/teams/{team}"]["delete"], &deletion); err != nil || deletion.Permission != string(PermissionTeamsDelete) {
+		t.Fatalf("expected team deletion to need teams:delete, got %q (%v)", deletion.Permission, err)
+	}
+	team := doc.Components.Schemas["TeamInfo"]
+	if !containsString(team.Required, "name") || containsString(team.Required, "provider") {
+		t.Fatalf("expected required fields to follow omitempty, got %v", team.Required)
+	}
+}
+
+func containsString(items []string, want string) bool {
+	for _, item := range items {
+		if item == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAPIV1Filters(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	teams := []types.TeamInfo{
+		{
+			Name:     "alpha",
+			Provider: "claude",
+			Members: []types.AgentInfo{
+				{Name: "lead", Status: "working", LastActivity: now.Add(-time.Minute)},
+				{Name: "tester", Status: "idle", LastActivity: now.Add(-2 * time.Hour)},
+			},
+			Tasks: []types.TaskInfo{
+				{ID: "1", Status: "completed", UpdatedAt: now.Add(-3 * time.Hour)},
+				{ID: "2", Status: "in_progress", UpdatedAt: now.Add(-5 * time.Minute)},
+			},
+		},
+		{
+			Name:     "beta",
+			Provider: "codex",
+			Members:  []types.AgentInfo{{Name: "solo", Status: "idle", LastActivity: now.Add(-10 * time.Hour)}},
+		},
+	}
+
+	filter, err := parseAPIV1Filter(url.Values{"updated_since": {"30m"}}, now)
+	if err != nil {
+		t.Fatalf("parseAPIV1Filter: %v", err)
+	}
+	if got := filterAgents(teams, filter); len(got) != 1 || got[0].Name != "lead" || got[0].Team != "alpha" {
+		t.Fatalf("unexpected recent agents %+v", got)
+	}
+	if got := filterTeams(teams, filter); len(got) != 1 || got[0].Name != "alpha" {
+		t.Fatalf("unexpected recent teams %+v", got)
+	}
+	if got := filterTasks(teams, filter); len(got) != 1 || got[0].ID != "2" {
+		t.Fatalf("unexpected recent tasks %+v", got)
+	}
+
+	filter, _ = parseAPIV1Filter(url.Values{"status": {"WORKING"}}, now)
+	if got := filterTeams(teams, filter); len(got) != 1 || got[0].Name != "alpha" {
+		t.Fatalf("expected status to match teams with a working member, got %+v", got)
+	}
+	filter, _ = parseAPIV1Filter(url.Values{"provider": {"codex"}, "status": {"idle"}}, now)
+	if got := filterAgents(teams, filter); len(got) != 1 || got[0].Name != "solo" {
+		t.Fatalf("unexpected codex idle agents %+v", got)
+	}
+
+	filter, _ = parseAPIV1Filter(url.Values{"limit": {"2"}, "offset": {"2"}}, now)
+	page := paginate([]int{1, 2, 3}, filter)
+	if data := page.Data.([]int); len(data) != 1 || data[0] != 3 || page.Page.NextOffset != nil {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+	filter.Offset = 10
+	if data := paginate([]int{1}, filter).Data.([]int); data == nil || len(data) != 0 {
+		t.Fatal("expected an empty, non-nil page past the end")
+	}
+
+	if filter, _ := parseAPIV1Filter(url.Values{"limit": {"5000"}}, now); filter.Limit != apiV1MaxLimit {
+		t.Fatalf("expected limit to be capped, got %d", filter.Limit)
+	}
+	for _, bad := range []url.Values{{"updated_since": {"yesterday"}}, {"offset": {"-1"}}, {"limit": {"0"}}} {
+		if _, err := parseAPIV1Filter(bad, now); err == nil {
+			t.Errorf("expected %v to be rejected", bad)
+		}
+	}
+}
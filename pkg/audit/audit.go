@@ -0,0 +1,283 @@
+// Package audit keeps an append-only JSONL record of mutating actions such
+// as messages, team deletions, managed runs, process signals and logins.
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fileEnv       = "ATM_AUDIT_FILE"
+	maxSizeEnv    = "ATM_AUDIT_MAX_SIZE_MB"
+	maxBackupsEnv = "ATM_AUDIT_MAX_BACKUPS"
+
+	defaultFileName   = "audit.jsonl"
+	defaultMaxSize    = 10 << 20
+	defaultMaxBackups = 5
+)
+
+// Actions recorded by the web server and the desktop bridge.
+const (
+	ActionLogin             = "auth.login"
+	ActionLogout            = "auth.logout"
+	ActionAgentMessage      = "agent.message"
+	ActionTeamDelete        = "team.delete"
+	ActionManagedCreate     = "managed.create"
+	ActionManagedStart      = "managed.start"
+	ActionManagedStop       = "managed.stop"
+	ActionManagedMessage    = "managed.message"
+	ActionProcessSignal     = "process.signal"
+	ActionTokenCreate       = "token.create"
+	ActionTokenRevoke       = "token.revoke"
+	ActionPreferencesUpdate = "desktop.preferences"
+)
+
+// Results of an audited action.
+const (
+	ResultOK     = "ok"
+	ResultDenied = "denied" // failed credential or permission check
+	ResultError  = "error"
+)
+
+// Clients that are not a remote address.
+const (
+	ClientDesktop = "desktop" // the desktop bridge
+	ClientMCP     = "mcp"     // a local MCP stdio server
+)
+
+// Entry is one audited action.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"` // username, token:<name> or anonymous
+	Role      string    `json:"role,omitempty"`
+	Client    string    `json:"client"` // remote address, "desktop" or "mcp"
+	UserAgent string    `json:"user_agent,omitempty"`
+	Action    string    `json:"action"`
+	Team      string    `json:"team,omitempty"`
+	Agent     string    `json:"agent,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Config controls where the log lives and how it rotates. The active file
+// is rotated to <path>.1 once it would exceed MaxSize; older files shift up
+// to <path>.<MaxBackups> and anything beyond is deleted.
+type Config struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+}
+
+// Enabled reports whether audit logging is on.
+func (c Config) Enabled() bool {
+	return strings.TrimSpace(c.Path) != ""
+}
+
+// LoadConfigFromEnv reads ATM_AUDIT_FILE (default
+// ~/.agent-team-monitor/audit.jsonl, "off" disables), ATM_AUDIT_MAX_SIZE_MB
+// and ATM_AUDIT_MAX_BACKUPS.
+func LoadConfigFromEnv() (Config, error) {
+	cfg := Config{MaxSize: defaultMaxSize, MaxBackups: defaultMaxBackups}
+
+	switch path := strings.TrimSpace(os.Getenv(fileEnv)); strings.ToLower(path) {
+	case "off", "false", "no", "0":
+		return Config{}, nil
+	case "":
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.Path = filepath.Join(homeDir, ".agent-team-monitor", defaultFileName)
+	default:
+		cfg.Path = path
+	}
+
+	if raw := strings.TrimSpace(os.Getenv(maxSizeEnv)); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size <= 0 {
+			return Config{}, fmt.Errorf("invalid %s %q", maxSizeEnv, raw)
+		}
+		cfg.MaxSize = int64(size) << 20
+	}
+	if raw := strings.TrimSpace(os.Getenv(maxBackupsEnv)); raw != "" {
+		backups, err := strconv.Atoi(raw)
+		if err != nil || backups < 0 {
+			return Config{}, fmt.Errorf("invalid %s %q", maxBackupsEnv, raw)
+		}
+		cfg.MaxBackups = backups
+	}
+	return cfg, nil
+}
+
+// Logger appends entries to the audit file. A nil *Logger discards entries,
+// so callers need not check whether auditing is enabled.
+type Logger struct {
+	cfg Config
+	now func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open creates the log directory and opens the active file for appending.
+func Open(cfg Config) (*Logger, error) {
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("audit log path is required")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultMaxSize
+	}
+	if cfg.MaxBackups < 0 {
+		cfg.MaxBackups = 0
+	}
+
+	logger := &Logger{cfg: cfg, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := logger.openLocked(); err != nil {
+		return nil, err
+	}
+	return logger, nil
+}
+
+// Path returns the active log file.
+func (l *Logger) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.cfg.Path
+}
+
+// Record appends entry, stamping the time when unset.
+func (l *Logger) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = l.now()
+	}
+	if entry.Result == "" {
+		entry.Result = ResultOK
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("audit log closed")
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.cfg.MaxSize {
+		if err := l.rotateLocked(); err != nil {
+			if l.file == nil {
+				return fmt.Errorf("rotate audit log: %w", err)
+			}
+			log.Printf("Error rotating audit log: %v", err)
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// Close closes the active file. Later Record calls fail.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *Logger) openLocked() error {
+	file, err := os.OpenFile(l.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+func (l *Logger) rotateLocked() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	if err := l.shiftFilesLocked(); err != nil {
+		// A file held open elsewhere, such as by a query on Windows, can
+		// refuse the rename: keep appending and rotate on a later write.
+		if openErr := l.openLocked(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+	return l.openLocked()
+}
+
+// shiftFilesLocked moves the active file to the first backup, dropping the
+// oldest, or removes it when no backups are kept.
+func (l *Logger) shiftFilesLocked() error {
+	if l.cfg.MaxBackups == 0 {
+		if err := os.Remove(l.cfg.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.Remove(backupPath(l.cfg.Path, l.cfg.MaxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := l.cfg.MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(l.cfg.Path, i), backupPath(l.cfg.Path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(l.cfg.Path, backupPath(l.cfg.Path, 1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func backupPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+const maxDetailRunes = 200
+
+// Summarize trims free text such as a message body to a single-line detail.
+func Summarize(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= maxDetailRunes {
+		return text
+	}
+	return string(runes[:maxDetailRunes]) + "…"
+}
@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestLogger(t *testing.T, cfg Config) *Logger {
+	t.Helper()
+	if cfg.Path == "" {
+		cfg.Path = filepath.Join(t.TempDir(), "audit.jsonl")
+	}
+	logger, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+func TestRecordAndQueryNewestFirst(t *testing.T) {
+	logger := newTestLogger(t, Config{})
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []Entry{
+		{Time: base, Actor: "ada", Action: ActionLogin},
+		{Time: base.Add(time.Minute), Actor: "ada", Action: ActionManagedStart, Team: "alpha"},
+		{Time: base.Add(2 * time.Minute), Actor: "otto", Action: ActionAgentMessage, Team: "alpha", Agent: "lead", Result: ResultDenied},
+		{Time: base.Add(3 * time.Minute), Actor: "ada", Action: ActionManagedStop, Team: "beta"},
+	}
+	for _, entry := range entries {
+		if err := logger.Record(entry); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	got, err := logger.Query(Filter{Action: "managed"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 2 || got[0].Action != ActionManagedStop || got[1].Action != ActionManagedStart {
+		t.Fatalf("unexpected managed entries: %+v", got)
+	}
+	if got[0].Result != ResultOK {
+		t.Fatalf("expected empty result to default to ok, got %q", got[0].Result)
+	}
+
+	got, _ = logger.Query(Filter{Team: "alpha", Result: ResultDenied})
+	if len(got) != 1 || got[0].Actor != "otto" || got[0].Agent != "lead" {
+		t.Fatalf("unexpected denied entries: %+v", got)
+	}
+
+	got, _ = logger.Query(Filter{Since: base.Add(90 * time.Second), Limit: 1})
+	if len(got) != 1 || got[0].Action != ActionManagedStop {
+		t.Fatalf("expected newest entry within limit, got %+v", got)
+	}
+}
+
+func TestRecordRotatesAndQueriesBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger := newTestLogger(t, Config{Path: path, MaxSize: 200, MaxBackups: 2})
+
+	for i := 0; i < 12; i++ {
+		if err := logger.Record(Entry{Actor: "ada", Action: ActionAgentMessage, Detail: "hello"}); err != nil {
+			t.Fatalf("Record %d: %v", i, err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+		if info.Size() > 200 {
+			t.Fatalf("%s exceeds max size: %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected backups beyond MaxBackups to be removed, stat err = %v", err)
+	}
+
+	got, err := logger.Query(Filter{Limit: 1000})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) < 3 || len(got) >= 12 {
+		t.Fatalf("expected entries from active and rotated files only, got %d", len(got))
+	}
+}
+
+func TestQueryWhileRecordingAndRotating(t *testing.T) {
+	logger := newTestLogger(t, Config{MaxSize: 400, MaxBackups: 3})
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 200; i++ {
+			if err := logger.Record(Entry{Actor: "ada", Action: ActionAgentMessage, Detail: fmt.Sprint("message ", i)}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for finished := false; !finished; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Record: %v", err)
+			}
+			finished = true
+		default:
+		}
+		got, err := logger.Query(Filter{Limit: 1000})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		seen := map[string]bool{}
+		for _, entry := range got {
+			if seen[entry.Detail] {
+				t.Fatalf("expected every entry once, got %q twice", entry.Detail)
+			}
+			seen[entry.Detail] = true
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	filter, err := ParseFilter(url.Values{
+		"actor": {"ada"},
+		"since": {"2h"},
+		"until": {"2026-03-01T11:30:00Z"},
+		"limit": {"5000"},
+	}, now)
+	if err != nil {
+		t.Fatalf("ParseFilter: %v", err)
+	}
+	if filter.Actor != "ada" || !filter.Since.Equal(now.Add(-2*time.Hour)) || filter.Until.Hour() != 11 || filter.Limit != maxQueryLimit {
+		t.Fatalf("unexpected filter: %+v", filter)
+	}
+
+	for _, values := range []url.Values{{"since": {"yesterday"}}, {"limit": {"-1"}}} {
+		if _, err := ParseFilter(values, now); err == nil {
+			t.Fatalf("expected %v to be rejected", values)
+		}
+	}
+}
+
+func TestNilLoggerDiscards(t *testing.T) {
+	var logger *Logger
+	if err := logger.Record(Entry{Action: ActionLogin}); err != nil {
+		t.Fatalf("expected nil logger to discard, got %v", err)
+	}
+	if _, err := logger.Query(Filter{}); err == nil {
+		t.Fatal("expected nil logger query to report disabled")
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("ATM_AUDIT_FILE", "off")
+	cfg, err := LoadConfigFromEnv()
+	if err != nil || cfg.Enabled() {
+		t.Fatalf("expected audit log to be disabled, got %+v (%v)", cfg, err)
+	}
+
+	t.Setenv("ATM_AUDIT_FILE", "/tmp/custom-audit.jsonl")
+	t.Setenv("ATM_AUDIT_MAX_SIZE_MB", "2")
+	t.Setenv("ATM_AUDIT_MAX_BACKUPS", "0")
+	cfg, err = LoadConfigFromEnv()
+	if err != nil || cfg.Path != "/tmp/custom-audit.jsonl" || cfg.MaxSize != 2<<20 || cfg.MaxBackups != 0 {
+		t.Fatalf("unexpected config %+v (%v)", cfg, err)
+	}
+
+	t.Setenv("ATM_AUDIT_MAX_SIZE_MB", "lots")
+	if _, err := LoadConfigFromEnv(); err == nil {
+		t.Fatal("expected invalid size to be rejected")
+	}
+}
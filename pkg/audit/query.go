@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// Filter selects entries. Empty fields match everything. Action matches
+// exactly or as a dotted prefix, so "managed" matches "managed.start".
+type Filter struct {
+	Actor  string
+	Action string
+	Team   string
+	Agent  string
+	Result string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// ParseFilter reads actor, action, team, agent, result, since, until and
+// limit query parameters. since/until accept RFC 3339 times or a duration
+// relative to now, e.g. since=24h.
+func ParseFilter(values url.Values, now time.Time) (Filter, error) {
+	filter := Filter{
+		Actor:  strings.TrimSpace(values.Get("actor")),
+		Action: strings.TrimSpace(values.Get("action")),
+		Team:   strings.TrimSpace(values.Get("team")),
+		Agent:  strings.TrimSpace(values.Get("agent")),
+		Result: strings.TrimSpace(values.Get("result")),
+		Limit:  defaultQueryLimit,
+	}
+
+	var err error
+	if filter.Since, err = parseQueryTime(values.Get("since"), now); err != nil {
+		return Filter{}, fmt.Errorf("invalid since: %w", err)
+	}
+	if filter.Until, err = parseQueryTime(values.Get("until"), now); err != nil {
+		return Filter{}, fmt.Errorf("invalid until: %w", err)
+	}
+	if raw := strings.TrimSpace(values.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return Filter{}, fmt.Errorf("invalid limit %q", raw)
+		}
+		filter.Limit = limit
+	}
+	if filter.Limit > maxQueryLimit {
+		filter.Limit = maxQueryLimit
+	}
+	return filter, nil
+}
+
+func parseQueryTime(raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(raw); err == nil {
+		return now.Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// Matches reports whether entry passes the filter, ignoring Limit.
+func (f Filter) Matches(entry Entry) bool {
+	switch {
+	case f.Actor != "" && entry.Actor != f.Actor:
+		return false
+	case f.Action != "" && entry.Action != f.Action && !strings.HasPrefix(entry.Action, f.Action+"."):
+		return false
+	case f.Team != "" && entry.Team != f.Team:
+		return false
+	case f.Agent != "" && entry.Agent != f.Agent:
+		return false
+	case f.Result != "" && entry.Result != f.Result:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && entry.Time.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// Query returns the newest matching entries first, searching rotated files
+// as well as the active one.
+func (l *Logger) Query(filter Filter) ([]Entry, error) {
+	if l == nil {
+		return nil, fmt.Errorf("audit log disabled")
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+
+	// Hold the lock only to open the files: an open file keeps its
+	// contents through a rotation, and the sizes leave out lines written
+	// while the scan runs.
+	l.mu.Lock()
+	files, err := l.openFilesLocked()
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer closeQueryFiles(files)
+
+	var matches []Entry
+	for _, file := range files {
+		if err := scanEntries(io.NewSectionReader(file, 0, file.size), func(entry Entry) {
+			if !filter.Matches(entry) {
+				return
+			}
+			matches = append(matches, entry)
+			if len(matches) > limit {
+				matches = matches[1:]
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	return matches, nil
+}
+
+// queryFile is a log file opened for a query, read up to size.
+type queryFile struct {
+	*os.File
+	size int64
+}
+
+// openFilesLocked opens the rotated files, oldest first, then the active
+// one, skipping those that do not exist.
+func (l *Logger) openFilesLocked() ([]queryFile, error) {
+	var files []queryFile
+	for i := l.cfg.MaxBackups; i >= 0; i-- {
+		path := l.cfg.Path
+		if i > 0 {
+			path = backupPath(path, i)
+		}
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			closeQueryFiles(files)
+			return nil, err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			closeQueryFiles(files)
+			return nil, err
+		}
+		files = append(files, queryFile{File: file, size: info.Size()})
+	}
+	return files, nil
+}
+
+func closeQueryFiles(files []queryFile) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+func scanEntries(r io.Reader, visit func(Entry)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		// Skip torn or hand-edited lines rather than failing the whole query.
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		visit(entry)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,427 @@
+// Package client is a Go SDK for the agent-team-monitor HTTP API. It speaks
+// the versioned /api/v1 resources where they exist and the state endpoints
+// otherwise, decoding into the same pkg/types and managed types the server
+// encodes.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+const (
+	sessionCookieName = "atm_session"
+	csrfHeaderName    = "X-CSRF-Token"
+
+	// pageSize is the largest page /api/v1 serves.
+	pageSize = 1000
+)
+
+// Options configures a Client.
+type Options struct {
+	// Token is an API token sent as a bearer credential. Leave empty to
+	// use Login or anonymous access.
+	Token string
+	// HTTPClient overrides http.DefaultClient. Its Timeout also bounds
+	// event streams, so prefer contexts for deadlines.
+	HTTPClient *http.Client
+}
+
+// Client calls a monitor server. It is safe for concurrent use.
+type Client struct {
+	baseURL *url.URL
+	token   string
+	http    *http.Client
+
+	mu      sync.RWMutex
+	session string
+	csrf    string
+}
+
+// AuthStatus mirrors the server's /api/auth/status response.
+type AuthStatus struct {
+	Configured    bool      `json:"configured"`
+	Authenticated bool      `json:"authenticated"`
+	Username      string    `json:"username,omitempty"`
+	Role          string    `json:"role,omitempty"`
+	Permissions   []string  `json:"permissions"`
+	CSRFToken     string    `json:"csrf_token,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at,omitempty"`
+	ReadOnly      bool      `json:"read_only,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ListOptions filters list calls; zero fields match everything.
+type ListOptions struct {
+	Team         string
+	Provider     string
+	Status       string
+	UpdatedSince time.Time
+}
+
+// Error is a non-2xx response. Code is the /api/v1 error code, empty for
+// endpoints that answer in plain text.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("monitor api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("monitor api: %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the server.
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://localhost:8080".
+func New(baseURL string, options Options) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid monitor URL %q: must be an http(s) URL", baseURL)
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+
+	httpClient := options.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: parsed, token: strings.TrimSpace(options.Token), http: httpClient}, nil
+}
+
+// Login starts a session as username; later calls carry its cookie and
+// CSRF token.
+func (c *Client) Login(ctx context.Context, username, password string) (AuthStatus, error) {
+	body := map[string]string{"username": username, "password": password}
+	var status AuthStatus
+	resp, err := c.send(ctx, http.MethodPost, "/api/auth/login", nil, body)
+	if err != nil {
+		return status, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return status, fmt.Errorf("decode login response: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookieName {
+			c.session = cookie.Value
+		}
+	}
+	c.csrf = status.CSRFToken
+	return status, nil
+}
+
+// Logout ends the session started by Login.
+func (c *Client) Logout(ctx context.Context) error {
+	err := c.call(ctx, http.MethodPost, "/api/auth/logout", nil, nil, nil)
+	c.mu.Lock()
+	c.session, c.csrf = "", ""
+	c.mu.Unlock()
+	return err
+}
+
+// AuthStatus reports who the server thinks the client is.
+func (c *Client) AuthStatus(ctx context.Context) (AuthStatus, error) {
+	var status AuthStatus
+	err := c.call(ctx, http.MethodGet, "/api/auth/status", nil, nil, &status)
+	return status, err
+}
+
+// State returns the complete monitoring state.
+func (c *Client) State(ctx context.Context) (types.MonitorState, error) {
+	var state types.MonitorState
+	err := c.call(ctx, http.MethodGet, "/api/state", nil, nil, &state)
+	return state, err
+}
+
+// StateDelta returns what changed after version since; apply it with
+// types.MonitorState.ApplyDelta.
+func (c *Client) StateDelta(ctx context.Context, since uint64) (types.StateDelta, error) {
+	var delta types.StateDelta
+	query := url.Values{"since": {strconv.FormatUint(since, 10)}}
+	err := c.call(ctx, http.MethodGet, "/api/state/delta", query, nil, &delta)
+	return delta, err
+}
+
+// Teams lists teams, following pages until all are read.
+func (c *Client) Teams(ctx context.Context, options ListOptions) ([]types.TeamInfo, error) {
+	return listAll[types.TeamInfo](ctx, c, "/api/v1/teams", options)
+}
+
+// Team returns one team by name.
+func (c *Client) Team(ctx context.Context, name string) (types.TeamInfo, error) {
+	var team types.TeamInfo
+	err := c.callData(ctx, http.MethodGet, "/api/v1/teams/"+url.PathEscape(name), nil, &team)
+	return team, err
+}
+
+// DeleteTeam removes a team's config and task directories.
+func (c *Client) DeleteTeam(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, "/api/v1/teams/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// SendAgentMessage queues text in an agent's inbox.
+func (c *Client) SendAgentMessage(ctx context.Context, team, agent, text string) error {
+	path := "/api/v1/teams/" + url.PathEscape(team) + "/agents/" + url.PathEscape(agent) + "/messages"
+	return c.call(ctx, http.MethodPost, path, nil, map[string]string{"text": text}, nil)
+}
+
+// SignalProcess sends SIGINT, SIGTERM or SIGKILL to a monitored agent
+// process and waits up to timeout (0 for the server default) for it to exit.
+func (c *Client) SignalProcess(ctx context.Context, pid int32, signal string, timeout time.Duration) ([]types.SignalResult, error) {
+	var results []types.SignalResult
+	err := c.callData(ctx, http.MethodPost, "/api/v1/processes/"+strconv.Itoa(int(pid))+"/signals", signalRequest(signal, timeout), &results)
+	return results, err
+}
+
+// SignalTeam signals every process linked to a team, like SignalProcess.
+func (c *Client) SignalTeam(ctx context.Context, team, signal string, timeout time.Duration) ([]types.SignalResult, error) {
+	var results []types.SignalResult
+	err := c.callData(ctx, http.MethodPost, "/api/v1/teams/"+url.PathEscape(team)+"/signals", signalRequest(signal, timeout), &results)
+	return results, err
+}
+
+func signalRequest(signal string, timeout time.Duration) map[string]any {
+	return map[string]any{"signal": signal, "timeout_seconds": int(timeout / time.Second)}
+}
+
+// ManagedTeams lists managed teams with their run state.
+func (c *Client) ManagedTeams(ctx context.Context) ([]managed.ManagedTeam, error) {
+	return listAll[managed.ManagedTeam](ctx, c, "/api/v1/managed/teams", ListOptions{})
+}
+
+// ManagedTeam returns one managed team by ID.
+func (c *Client) ManagedTeam(ctx context.Context, id string) (managed.ManagedTeam, error) {
+	var team managed.ManagedTeam
+	err := c.callData(ctx, http.MethodGet, managedTeamPath(id), nil, &team)
+	return team, err
+}
+
+// CreateManagedTeam creates a managed team without starting it.
+func (c *Client) CreateManagedTeam(ctx context.Context, input managed.CreateTeamInput) (managed.TeamSpec, error) {
+	var spec managed.TeamSpec
+	err := c.callData(ctx, http.MethodPost, "/api/v1/managed/teams", input, &spec)
+	return spec, err
+}
+
+// StartTeam starts every agent of a managed team.
+func (c *Client) StartTeam(ctx context.Context, id string) (managed.RunState, error) {
+	var run managed.RunState
+	err := c.callData(ctx, http.MethodPost, managedTeamPath(id)+"/runs", nil, &run)
+	return run, err
+}
+
+// StopTeam stops every agent of a managed team.
+func (c *Client) StopTeam(ctx context.Context, id string) (managed.RunState, error) {
+	var run managed.RunState
+	err := c.callData(ctx, http.MethodDelete, managedTeamPath(id)+"/runs", nil, &run)
+	return run, err
+}
+
+// StartAgent starts one agent of a managed team.
+func (c *Client) StartAgent(ctx context.Context, id, agent string) (managed.RunState, error) {
+	var run managed.RunState
+	err := c.callData(ctx, http.MethodPost, managedTeamPath(id)+"/agents/"+url.PathEscape(agent)+"/runs", nil, &run)
+	return run, err
+}
+
+// StopAgent stops one agent of a managed team.
+func (c *Client) StopAgent(ctx context.Context, id, agent string) (managed.RunState, error) {
+	var run managed.RunState
+	err := c.callData(ctx, http.MethodDelete, managedTeamPath(id)+"/agents/"+url.PathEscape(agent)+"/runs", nil, &run)
+	return run, err
+}
+
+// SendManagedMessage sends text to a running managed team's lead.
+func (c *Client) SendManagedMessage(ctx context.Context, id, text string) error {
+	return c.call(ctx, http.MethodPost, managedTeamPath(id)+"/messages", nil, map[string]string{"text": text}, nil)
+}
+
+// SendManagedAgentMessage sends text to one agent of a running managed team.
+func (c *Client) SendManagedAgentMessage(ctx context.Context, id, agent, text string) error {
+	return c.call(ctx, http.MethodPost, managedTeamPath(id)+"/agents/"+url.PathEscape(agent)+"/messages", nil, map[string]string{"text": text}, nil)
+}
+
+// ManagedLogs returns the last lines of a managed agent's terminal log; an
+// empty agent means the team's primary agent and lines <= 0 the server
+// default.
+func (c *Client) ManagedLogs(ctx context.Context, id, agent string, lines int) (managed.LogTail, error) {
+	query := url.Values{}
+	if agent != "" {
+		query.Set("agent", agent)
+	}
+	if lines > 0 {
+		query.Set("lines", strconv.Itoa(lines))
+	}
+	var tail managed.LogTail
+	envelope := struct {
+		Data *managed.LogTail `json:"data"`
+	}{Data: &tail}
+	err := c.call(ctx, http.MethodGet, managedTeamPath(id)+"/logs", query, nil, &envelope)
+	return tail, err
+}
+
+// PushNodeReport sends a node's state delta to a federation hub; the
+// token needs the federate scope.
+func (c *Client) PushNodeReport(ctx context.Context, report types.NodeReport) (types.NodeAck, error) {
+	var ack types.NodeAck
+	err := c.call(ctx, http.MethodPost, "/api/federation/push", nil, report, &ack)
+	return ack, err
+}
+
+func managedTeamPath(id string) string {
+	return "/api/v1/managed/teams/" + url.PathEscape(id)
+}
+
+// listAll reads every page of a /api/v1 list.
+func listAll[T any](ctx context.Context, c *Client, path string, options ListOptions) ([]T, error) {
+	query := url.Values{"limit": {strconv.Itoa(pageSize)}}
+	for key, value := range map[string]string{"team": options.Team, "provider": options.Provider, "status": options.Status} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if !options.UpdatedSince.IsZero() {
+		query.Set("updated_since", options.UpdatedSince.UTC().Format(time.RFC3339Nano))
+	}
+
+	items := []T{}
+	for {
+		var page struct {
+			Data []T `json:"data"`
+			Page struct {
+				NextOffset *int `json:"next_offset"`
+			} `json:"page"`
+		}
+		if err := c.call(ctx, http.MethodGet, path, query, nil, &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page.Data...)
+		if page.Page.NextOffset == nil {
+			return items, nil
+		}
+		query.Set("offset", strconv.Itoa(*page.Page.NextOffset))
+	}
+}
+
+// callData is call for /api/v1 item responses wrapped in {"data": ...}.
+func (c *Client) callData(ctx context.Context, method, path string, body, out any) error {
+	envelope := struct {
+		Data any `json:"data"`
+	}{Data: out}
+	return c.call(ctx, method, path, nil, body, &envelope)
+}
+
+// call sends a request and decodes a JSON response into out, if non-nil.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// send performs an authenticated request, turning non-2xx responses into
+// *Error. The caller closes the body.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	// path arrives escaped, so join strings rather than URL.Path.
+	target := c.baseURL.String() + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s %s: %w", method, path, err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authorize(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, readError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		return
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.session == "" {
+		return
+	}
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: c.session})
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		req.Header.Set(csrfHeaderName, c.csrf)
+	}
+}
+
+func readError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	var envelope struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &envelope) == nil && envelope.Error.Code != "" {
+		apiErr.Code = envelope.Error.Code
+		apiErr.Message = envelope.Error.Message
+		return apiErr
+	}
+	apiErr.Message = strings.TrimSpace(string(raw))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
@@ -0,0 +1,230 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/client"
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+type testEnv struct {
+	server    *httptest.Server
+	auth      *api.AuthManager
+	workspace string
+}
+
+// newTestEnv serves a real api.Server with CSRF on, an admin account
+// (admin/secret) and a managed team store in a temp dir.
+func newTestEnv(t *testing.T) testEnv {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("ATM_ADMIN_USERNAME", "admin")
+	t.Setenv("ATM_ADMIN_PASSWORD", "secret")
+	t.Setenv("ATM_TOKENS_FILE", filepath.Join(root, "api-tokens.json"))
+	t.Setenv("ATM_MANAGED_DIR", filepath.Join(root, "managed"))
+	workspace := filepath.Join(root, "workspace")
+	if err := os.MkdirAll(workspace, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := managed.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	auth := api.NewAuthManagerFromEnv()
+	server := api.NewServer(nil, ":0", fstest.MapFS{"index.html": {Data: []byte("ok")}}, auth, manager)
+	security := api.DefaultSecurityConfig()
+	security.AllowedHosts = []string{"*"}
+	security.LoginRateLimit = 0
+	server.SetSecurity(security)
+
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return testEnv{server: ts, auth: auth, workspace: workspace}
+}
+
+func newTestClient(t *testing.T, env testEnv, options client.Options) *client.Client {
+	t.Helper()
+	c, err := client.New(env.server.URL, options)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func (env testEnv) createTeam(t *testing.T, c *client.Client, name string) managed.TeamSpec {
+	t.Helper()
+	spec, err := c.CreateManagedTeam(context.Background(), managed.CreateTeamInput{
+		Name:      name,
+		Provider:  "claude",
+		Workspace: env.workspace,
+		Agents:    []managed.AgentInput{{Name: "lead", Provider: "claude"}, {Name: "reviewer", Provider: "claude"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateManagedTeam: %v", err)
+	}
+	return spec
+}
+
+func TestLoginSessionCarriesCSRF(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	c := newTestClient(t, env, client.Options{})
+
+	_, err := c.CreateManagedTeam(ctx, managed.CreateTeamInput{Name: "early", Provider: "claude", Workspace: env.workspace})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected anonymous create to be forbidden, got %v", err)
+	}
+
+	if _, err := c.Login(ctx, "admin", "wrong"); err == nil {
+		t.Fatal("expected a bad password to fail")
+	}
+	status, err := c.Login(ctx, "admin", "secret")
+	if err != nil || !status.Authenticated || status.Role != "admin" || status.CSRFToken == "" {
+		t.Fatalf("unexpected login %+v %v", status, err)
+	}
+
+	// Creating passes the CSRF check only if the client echoes the token.
+	spec := env.createTeam(t, c, "Session Crew")
+	team, err := c.ManagedTeam(ctx, spec.ID)
+	if err != nil || team.Spec.Name != "Session Crew" || len(team.Spec.Agents) != 2 {
+		t.Fatalf("unexpected managed team %+v %v", team, err)
+	}
+
+	if err := c.Logout(ctx); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if status, err := c.AuthStatus(ctx); err != nil || status.Authenticated {
+		t.Fatalf("expected to be logged out, got %+v %v", status, err)
+	}
+}
+
+func TestTokenAuthAndTypedCalls(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	_, secret, err := env.auth.Tokens().Create("ci", []string{"admin"}, 0)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	c := newTestClient(t, env, client.Options{Token: secret})
+
+	spec := env.createTeam(t, c, "Token Crew")
+	env.createTeam(t, c, "Other Crew")
+
+	teams, err := c.Teams(ctx, client.ListOptions{Provider: "claude"})
+	if err != nil || len(teams) != 2 {
+		t.Fatalf("Teams: %+v %v", teams, err)
+	}
+	if teams, err := c.Teams(ctx, client.ListOptions{Provider: "codex"}); err != nil || len(teams) != 0 {
+		t.Fatalf("expected the provider filter to apply, got %+v %v", teams, err)
+	}
+	team, err := c.Team(ctx, "Token Crew")
+	if err != nil || team.ManagedTeamID != spec.ID || len(team.Members) != 2 {
+		t.Fatalf("Team: %+v %v", team, err)
+	}
+	if _, err := c.Team(ctx, "ghost"); !client.IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	managedTeams, err := c.ManagedTeams(ctx)
+	if err != nil || len(managedTeams) != 2 {
+		t.Fatalf("ManagedTeams: %+v %v", managedTeams, err)
+	}
+
+	tail, err := c.ManagedLogs(ctx, spec.ID, "", 10)
+	if err != nil || tail.TeamID != spec.ID || tail.AgentID != spec.Agents[0].ID || len(tail.Lines) != 0 {
+		t.Fatalf("ManagedLogs: %+v %v", tail, err)
+	}
+
+	state, err := c.State(ctx)
+	if err != nil || len(state.Teams) != 2 || state.Version == 0 {
+		t.Fatalf("State: %+v %v", state, err)
+	}
+	delta, err := c.StateDelta(ctx, state.Version)
+	if err != nil || delta.Reset || delta.Version != state.Version {
+		t.Fatalf("expected an empty delta, got %+v %v", delta, err)
+	}
+
+	// Stopped agents refuse messages; the server's reason comes through.
+	err = c.SendManagedMessage(ctx, spec.ID, "hello")
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Message == "" {
+		t.Fatalf("expected a bad request, got %v", err)
+	}
+	// The test server has no collector, so deletion gets past auth and stops.
+	if err := c.DeleteTeam(ctx, "Token Crew"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected deletion to reach the missing collector, got %v", err)
+	}
+	if _, err := c.StopTeam(ctx, spec.ID); !errors.As(err, &apiErr) || apiErr.Code != "bad_request" {
+		t.Fatalf("expected a stopped team to refuse StopTeam, got %v", err)
+	}
+
+	readOnly := newTestClient(t, env, client.Options{})
+	if err := readOnly.SendAgentMessage(ctx, "Token Crew", "lead", "hi"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected anonymous message to be forbidden, got %v", err)
+	}
+}
+
+func TestWatchFollowsChanges(t *testing.T) {
+	env := newTestEnv(t)
+	_, secret, err := env.auth.Tokens().Create("watch", []string{"admin"}, 0)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	c := newTestClient(t, env, client.Options{Token: secret})
+	env.createTeam(t, c, "First Crew")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	seen := make(chan types.MonitorState, 8)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Watch(ctx, func(state types.MonitorState) error {
+			seen <- state
+			return nil
+		})
+	}()
+
+	next := func() types.MonitorState {
+		select {
+		case state := <-seen:
+			return state
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for a state event")
+			return types.MonitorState{}
+		}
+	}
+	if first := next(); len(first.Teams) != 1 || first.Teams[0].Name != "First Crew" {
+		t.Fatalf("unexpected initial state %+v", first.Teams)
+	}
+
+	env.createTeam(t, c, "Second Crew")
+	second := next()
+	if len(second.Teams) != 2 || len(second.Teams[1].Members) != 2 {
+		t.Fatalf("expected the delta to add the second team with members, got %+v", second.Teams)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Watch to stop with the context, got %v", err)
+	}
+}
+
+func TestNewRejectsBadURLs(t *testing.T) {
+	for _, raw := range []string{"", "localhost:8080", "ftp://host"} {
+		if _, err := client.New(raw, client.Options{}); err == nil {
+			t.Errorf("expected %q to be rejected", raw)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// maxEventSize bounds one server-sent event; a reset delta carries the
+// whole state.
+const maxEventSize = 64 << 20
+
+// watchRetryDelay is how long Watch waits before reconnecting a dropped
+// stream.
+var watchRetryDelay = 2 * time.Second
+
+// Subscribe streams state deltas from /api/events, starting after version
+// since (0 sends the full state first). handle runs for each delta in
+// order. Subscribe returns when ctx ends, handle fails or the stream drops.
+func (c *Client) Subscribe(ctx context.Context, since uint64, handle func(types.StateDelta) error) error {
+	query := url.Values{"since": {strconv.FormatUint(since, 10)}}
+	resp, err := c.send(ctx, http.MethodGet, "/api/events", query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxEventSize)
+	var event string
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if event == "delta" && data.Len() > 0 {
+				var delta types.StateDelta
+				if err := json.Unmarshal([]byte(data.String()), &delta); err != nil {
+					return fmt.Errorf("decode state event: %w", err)
+				}
+				if err := handle(delta); err != nil {
+					return err
+				}
+			}
+			event = ""
+			data.Reset()
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read state events: %w", err)
+	}
+	return errors.New("state event stream closed")
+}
+
+// handlerError marks an error returned by the caller's handler, which
+// Watch passes through instead of reconnecting.
+type handlerError struct{ err error }
+
+func (e handlerError) Error() string { return e.err.Error() }
+
+// Watch keeps a merged copy of the server state, calling handle with it
+// after every change. Dropped streams are resumed from the last version;
+// Watch returns when ctx ends, handle fails or the server rejects the
+// request.
+func (c *Client) Watch(ctx context.Context, handle func(types.MonitorState) error) error {
+	var state types.MonitorState
+	for {
+		err := c.Subscribe(ctx, state.Version, func(delta types.StateDelta) error {
+			state = state.ApplyDelta(delta)
+			if err := handle(state); err != nil {
+				return handlerError{err}
+			}
+			return nil
+		})
+		var apiErr *Error
+		var handled handlerError
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.As(err, &handled):
+			return handled.err
+		case errors.As(err, &apiErr):
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
@@ -0,0 +1,470 @@
+// Package config reads the monitor's YAML config file, which gathers the
+// settings otherwise spread over flags, environment variables and built-in
+// defaults, and watches it for changes.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/redact"
+	"github.com/liaoweijun/agent-team-monitor/pkg/webhook"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configPathEnv         = "ATM_CONFIG"
+	defaultConfigFileName = "config.yaml"
+	// defaultDeadLetterFileName matches the webhooks file default.
+	defaultDeadLetterFileName = "webhooks-dead-letter.jsonl"
+
+	minPollInterval = 500 * time.Millisecond
+)
+
+// Config is the config file. Fields left out keep the value from the
+// environment or the built-in default; command-line flags win over both.
+type Config struct {
+	// Provider is claude, codex, openclaw or both, like -provider.
+	Provider    string      `yaml:"provider"`
+	Roots       Roots       `yaml:"roots"`
+	Thresholds  Thresholds  `yaml:"thresholds"`
+	Privacy     Privacy     `yaml:"privacy"`
+	Auth        Auth        `yaml:"auth"`
+	Alerts      Alerts      `yaml:"alerts"`
+	Web         Web         `yaml:"web"`
+	Diagnostics Diagnostics `yaml:"diagnostics"`
+	Watchdog    Watchdog    `yaml:"watchdog"`
+	Cache       Cache       `yaml:"cache"`
+}
+
+// Roots are the data directories. A leading ~ is the home directory.
+type Roots struct {
+	Claude   RootList `yaml:"claude"`   // default $CLAUDE_CONFIG_DIR or ~/.claude
+	Codex    RootList `yaml:"codex"`    // default $CODEX_HOME or ~/.codex
+	OpenClaw RootList `yaml:"openclaw"` // default ~/.openclaw
+	// Managed holds managed teams, like ATM_MANAGED_DIR. It is read at
+	// startup only.
+	Managed string `yaml:"managed"`
+}
+
+// RootList is one provider's data directories. In the file it is a single
+// path, or a list of paths and {label, dir} entries.
+type RootList []Root
+
+// Root is a labeled data directory; see monitor.Root.
+type Root struct {
+	Label string `yaml:"label"`
+	Dir   string `yaml:"dir"`
+}
+
+// UnmarshalYAML accepts a path, a list of paths, or a list of entries.
+func (l *RootList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*l = RootList{{Dir: value.Value}}
+		return nil
+	case yaml.SequenceNode:
+		roots := make(RootList, 0, len(value.Content))
+		for _, item := range value.Content {
+			if item.Kind == yaml.ScalarNode {
+				roots = append(roots, Root{Dir: item.Value})
+				continue
+			}
+			var root Root
+			if err := item.Decode(&root); err != nil {
+				return err
+			}
+			roots = append(roots, root)
+		}
+		*l = roots
+		return nil
+	default:
+		return fmt.Errorf("line %d: expected a path or a list of roots", value.Line)
+	}
+}
+
+// monitorRoots converts the list, expanding ~.
+func (l RootList) monitorRoots() []monitor.Root {
+	if len(l) == 0 {
+		return nil
+	}
+	roots := make([]monitor.Root, len(l))
+	for i, root := range l {
+		roots[i] = monitor.Root{Label: strings.TrimSpace(root.Label), Dir: expandHome(root.Dir)}
+	}
+	return roots
+}
+
+// Thresholds tune collection; see monitor.Settings.
+type Thresholds struct {
+	PollInterval          time.Duration `yaml:"poll_interval"`
+	StaleTeam             time.Duration `yaml:"stale_team"`
+	ClaudeProjectMaxAge   time.Duration `yaml:"claude_project_max_age"`
+	CodexSessionMaxAge    time.Duration `yaml:"codex_session_max_age"`
+	OpenClawSessionMaxAge time.Duration `yaml:"openclaw_session_max_age"`
+	WorkingRecent         time.Duration `yaml:"working_recent"`
+}
+
+// Privacy controls what the collector publishes and what a read-only
+// dashboard serves.
+type Privacy struct {
+	// ExposeAbsolutePaths replaces ATM_EXPOSE_ABS_PATHS.
+	ExposeAbsolutePaths *bool     `yaml:"expose_absolute_paths"`
+	Redaction           Redaction `yaml:"redaction"`
+	// ReadOnlyProfile is full, standard or strict, like -privacy.
+	ReadOnlyProfile string `yaml:"read_only_profile"`
+}
+
+// Redaction overrides the redaction file (ATM_REDACT_CONFIG); see
+// redact.Config.
+type Redaction struct {
+	// Enabled set to false turns redaction off.
+	Enabled *bool `yaml:"enabled"`
+	// Entropy set to false skips the high-entropy string detector.
+	Entropy *bool `yaml:"entropy"`
+	// Patterns, when set, replace the file's custom detectors.
+	Patterns []redact.PatternConfig `yaml:"patterns"`
+}
+
+// Auth overrides the users file and session settings.
+type Auth struct {
+	// UsersFile replaces ATM_USERS_CONFIG.
+	UsersFile string `yaml:"users_file"`
+	// AnonymousRole overrides anonymous_role in the users file.
+	AnonymousRole string `yaml:"anonymous_role"`
+	// SessionTTL replaces ATM_SESSION_TTL.
+	SessionTTL time.Duration `yaml:"session_ttl"`
+}
+
+// Alerts lists webhook endpoints. When set they replace the webhooks file.
+type Alerts struct {
+	Webhooks       []webhook.EndpointConfig `yaml:"webhooks"`
+	DeadLetterPath string                   `yaml:"dead_letter_path"`
+}
+
+// Web configures the web server.
+type Web struct {
+	// Addr is the listen address, like -addr. It is read at startup only.
+	Addr string `yaml:"addr"`
+	// AllowedHosts, CORSOrigins and LoginRateLimit replace
+	// ATM_ALLOWED_HOSTS, ATM_CORS_ORIGINS and ATM_LOGIN_RATE_LIMIT.
+	AllowedHosts   []string `yaml:"allowed_hosts"`
+	CORSOrigins    []string `yaml:"cors_origins"`
+	LoginRateLimit *int     `yaml:"login_rate_limit"`
+}
+
+// Diagnostics enables extra logging.
+type Diagnostics struct {
+	// DiscoveryMetrics replaces ATM_DISCOVERY_METRICS.
+	DiscoveryMetrics *bool `yaml:"discovery_metrics"`
+}
+
+// Watchdog tunes hung agent detection; see monitor.WatchdogSettings.
+type Watchdog struct {
+	HungAfter      time.Duration `yaml:"hung_after"`
+	IdleCPUPercent float64       `yaml:"idle_cpu_percent"`
+	// Remedy replaces ATM_WATCHDOG_REMEDY.
+	Remedy    string        `yaml:"remedy"`
+	NudgeText string        `yaml:"nudge_text"`
+	Cooldown  time.Duration `yaml:"cooldown"`
+}
+
+// Cache keeps parsing work across restarts.
+type Cache struct {
+	// LogOffsets replaces ATM_LOG_OFFSETS_FILE. It is read at startup only.
+	LogOffsets string `yaml:"log_offsets"`
+}
+
+// DefaultPath returns the config file location, honoring ATM_CONFIG.
+func DefaultPath() (string, error) {
+	if custom := strings.TrimSpace(os.Getenv(configPathEnv)); custom != "" {
+		return custom, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".agent-team-monitor", defaultConfigFileName), nil
+}
+
+// Load reads and validates a config file. A missing or empty file yields
+// an empty config, so the file stays optional.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+	if len(cfg.Alerts.Webhooks) > 0 && strings.TrimSpace(cfg.Alerts.DeadLetterPath) == "" {
+		cfg.Alerts.DeadLetterPath = filepath.Join(filepath.Dir(path), defaultDeadLetterFileName)
+	}
+	return cfg, nil
+}
+
+// Parse decodes and validates config file contents. Unknown keys are
+// errors so typos do not go unnoticed.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting, one per line.
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Provider != "" {
+		if _, err := monitor.ParseProviderMode(c.Provider); err != nil {
+			fail("provider", "%v", err)
+		}
+	}
+
+	for _, roots := range []struct {
+		name string
+		list RootList
+	}{
+		{"roots.claude", c.Roots.Claude},
+		{"roots.codex", c.Roots.Codex},
+		{"roots.openclaw", c.Roots.OpenClaw},
+	} {
+		if err := monitor.ValidateRoots(roots.list.monitorRoots()); err != nil {
+			fail(roots.name, "%v", err)
+		}
+	}
+
+	for _, field := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"thresholds.poll_interval", c.Thresholds.PollInterval},
+		{"thresholds.stale_team", c.Thresholds.StaleTeam},
+		{"thresholds.claude_project_max_age", c.Thresholds.ClaudeProjectMaxAge},
+		{"thresholds.codex_session_max_age", c.Thresholds.CodexSessionMaxAge},
+		{"thresholds.openclaw_session_max_age", c.Thresholds.OpenClawSessionMaxAge},
+		{"thresholds.working_recent", c.Thresholds.WorkingRecent},
+		{"auth.session_ttl", c.Auth.SessionTTL},
+		{"watchdog.hung_after", c.Watchdog.HungAfter},
+		{"watchdog.cooldown", c.Watchdog.Cooldown},
+	} {
+		if field.value < 0 {
+			fail(field.name, "must not be negative")
+		}
+	}
+	if c.Thresholds.PollInterval > 0 && c.Thresholds.PollInterval < minPollInterval {
+		fail("thresholds.poll_interval", "must be at least %s", minPollInterval)
+	}
+
+	if c.Watchdog.IdleCPUPercent < 0 {
+		fail("watchdog.idle_cpu_percent", "must not be negative")
+	}
+	if _, err := monitor.ParseRemedy(c.Watchdog.Remedy); err != nil {
+		fail("watchdog.remedy", "%v", err)
+	}
+
+	if c.Auth.AnonymousRole != "" {
+		role, err := api.ParseRole(c.Auth.AnonymousRole)
+		switch {
+		case err != nil:
+			fail("auth.anonymous_role", "%v", err)
+		case role == api.RoleAdmin:
+			fail("auth.anonymous_role", "must not be admin")
+		}
+	}
+
+	if _, err := redact.New(c.RedactConfig(redact.Config{})); err != nil {
+		fail("privacy.redaction.patterns", "%v", err)
+	}
+	if _, err := api.ParsePrivacyProfile(c.Privacy.ReadOnlyProfile); err != nil {
+		fail("privacy.read_only_profile", "%v", err)
+	}
+
+	if err := (webhook.Config{Endpoints: c.Alerts.Webhooks}).Validate(); err != nil {
+		fail("alerts.webhooks", "%v", err)
+	}
+
+	if c.Web.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Web.Addr); err != nil {
+			fail("web.addr", "%v", err)
+		}
+	}
+	for _, origin := range c.Web.CORSOrigins {
+		if origin == "*" {
+			fail("web.cors_origins", "must list explicit origins; credentials rule out *")
+			continue
+		}
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			fail("web.cors_origins", "invalid origin %q", origin)
+		}
+	}
+	if c.Web.LoginRateLimit != nil && *c.Web.LoginRateLimit < 0 {
+		fail("web.login_rate_limit", "must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+// CollectorSettings applies the file on top of base.
+func (c Config) CollectorSettings(base monitor.Settings) monitor.Settings {
+	settings := base
+	if c.Provider != "" {
+		settings.Provider, _ = monitor.ParseProviderMode(c.Provider)
+	}
+	overrideRoots(&settings.ClaudeRoots, c.Roots.Claude)
+	overrideRoots(&settings.CodexRoots, c.Roots.Codex)
+	overrideRoots(&settings.OpenClawRoots, c.Roots.OpenClaw)
+	overrideDuration(&settings.PollInterval, c.Thresholds.PollInterval)
+	overrideDuration(&settings.StaleTeamThreshold, c.Thresholds.StaleTeam)
+	overrideDuration(&settings.ProjectDiscoveryMaxAge, c.Thresholds.ClaudeProjectMaxAge)
+	overrideDuration(&settings.CodexSessionMaxAge, c.Thresholds.CodexSessionMaxAge)
+	overrideDuration(&settings.OpenClawSessionMaxAge, c.Thresholds.OpenClawSessionMaxAge)
+	overrideDuration(&settings.WorkingRecentThreshold, c.Thresholds.WorkingRecent)
+	if c.Privacy.ExposeAbsolutePaths != nil {
+		settings.ExposeAbsolutePaths = *c.Privacy.ExposeAbsolutePaths
+	}
+	if c.Diagnostics.DiscoveryMetrics != nil {
+		settings.DiscoveryMetrics = *c.Diagnostics.DiscoveryMetrics
+	}
+	overrideDuration(&settings.Watchdog.HungAfter, c.Watchdog.HungAfter)
+	overrideDuration(&settings.Watchdog.Cooldown, c.Watchdog.Cooldown)
+	if c.Watchdog.IdleCPUPercent > 0 {
+		settings.Watchdog.IdleCPUPercent = c.Watchdog.IdleCPUPercent
+	}
+	if c.Watchdog.Remedy != "" {
+		settings.Watchdog.Remedy, _ = monitor.ParseRemedy(c.Watchdog.Remedy)
+	}
+	if strings.TrimSpace(c.Watchdog.NudgeText) != "" {
+		settings.Watchdog.NudgeText = c.Watchdog.NudgeText
+	}
+	if c.Cache.LogOffsets != "" {
+		settings.LogOffsetsFile = expandHome(c.Cache.LogOffsets)
+	}
+	return settings
+}
+
+// SecurityConfig applies the web section on top of base.
+func (c Config) SecurityConfig(base api.SecurityConfig) api.SecurityConfig {
+	cfg := base
+	if len(c.Web.AllowedHosts) > 0 {
+		cfg.AllowedHosts = append([]string(nil), c.Web.AllowedHosts...)
+	}
+	if len(c.Web.CORSOrigins) > 0 {
+		cfg.AllowedOrigins = append([]string(nil), c.Web.CORSOrigins...)
+	}
+	if c.Web.LoginRateLimit != nil {
+		cfg.LoginRateLimit = *c.Web.LoginRateLimit
+	}
+	return cfg
+}
+
+// RedactConfig applies the redaction section on top of base.
+func (c Config) RedactConfig(base redact.Config) redact.Config {
+	cfg := base
+	if c.Privacy.Redaction.Enabled != nil {
+		cfg.Disabled = !*c.Privacy.Redaction.Enabled
+	}
+	if c.Privacy.Redaction.Entropy != nil {
+		cfg.DisableEntropy = !*c.Privacy.Redaction.Entropy
+	}
+	if len(c.Privacy.Redaction.Patterns) > 0 {
+		cfg.Patterns = append([]redact.PatternConfig(nil), c.Privacy.Redaction.Patterns...)
+	}
+	return cfg
+}
+
+// PrivacyProfile returns the read-only privacy profile, or base when the
+// file names none.
+func (c Config) PrivacyProfile(base api.PrivacyProfile) api.PrivacyProfile {
+	if strings.TrimSpace(c.Privacy.ReadOnlyProfile) == "" {
+		return base
+	}
+	profile, _ := api.ParsePrivacyProfile(c.Privacy.ReadOnlyProfile)
+	return profile
+}
+
+// UsersConfig loads the users file named in the auth section, or the
+// default one, and applies the anonymous role override.
+func (c Config) UsersConfig() (api.UsersConfig, error) {
+	path := expandHome(c.Auth.UsersFile)
+	if path == "" {
+		var err error
+		if path, err = api.DefaultUsersConfigPath(); err != nil {
+			return api.UsersConfig{}, err
+		}
+	}
+	users, err := api.LoadUsersConfig(path)
+	if err != nil {
+		return api.UsersConfig{}, err
+	}
+	if c.Auth.AnonymousRole != "" {
+		users.AnonymousRole = c.Auth.AnonymousRole
+	}
+	return users, nil
+}
+
+// WebhookConfig returns the alerts section, or base when it lists no
+// webhooks.
+func (c Config) WebhookConfig(base webhook.Config) webhook.Config {
+	if len(c.Alerts.Webhooks) == 0 {
+		return base
+	}
+	return webhook.Config{
+		DeadLetterPath: expandHome(c.Alerts.DeadLetterPath),
+		Endpoints:      append([]webhook.EndpointConfig(nil), c.Alerts.Webhooks...),
+	}
+}
+
+// ManagedDir returns the managed teams directory, or "" for the default.
+func (c Config) ManagedDir() string {
+	return expandHome(c.Roots.Managed)
+}
+
+func overrideRoots(target *[]monitor.Root, value RootList) {
+	if len(value) > 0 {
+		*target = value.monitorRoots()
+	}
+}
+
+func overrideDuration(target *time.Duration, value time.Duration) {
+	if value > 0 {
+		*target = value
+	}
+}
+
+func expandHome(path string) string {
+	path = strings.TrimSpace(path)
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/monitor"
+	"github.com/liaoweijun/agent-team-monitor/pkg/redact"
+	"github.com/liaoweijun/agent-team-monitor/pkg/webhook"
+)
+
+const sampleConfig = `
+provider: codex
+roots:
+  codex: /srv/codex
+thresholds:
+  poll_interval: 10s
+  stale_team: 30m
+privacy:
+  expose_absolute_paths: true
+  read_only_profile: strict
+  redaction:
+    entropy: false
+    patterns:
+      - name: ticket
+        regex: 'TICKET-[0-9]+'
+auth:
+  anonymous_role: none
+  session_ttl: 2h
+alerts:
+  webhooks:
+    - name: ops
+      url: https://hooks.example.com/atm
+      events: [agent.errored]
+web:
+  addr: 127.0.0.1:9000
+  allowed_hosts: [monitor.example.com]
+  login_rate_limit: 0
+watchdog:
+  hung_after: 45m
+  remedy: Nudge
+cache:
+  log_offsets: /var/lib/atm/log-offsets.json
+`
+
+func TestLoadAppliesSections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(sampleConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	settings := cfg.CollectorSettings(monitor.Settings{CodexSessionMaxAge: time.Hour})
+	if settings.Provider != monitor.ProviderCodex || len(settings.CodexRoots) != 1 || settings.CodexRoots[0].Dir != "/srv/codex" || settings.PollInterval != 10*time.Second ||
+		settings.StaleTeamThreshold != 30*time.Minute || !settings.ExposeAbsolutePaths || settings.CodexSessionMaxAge != time.Hour {
+		t.Fatalf("unexpected collector settings %+v", settings)
+	}
+	if settings.Watchdog.HungAfter != 45*time.Minute || settings.Watchdog.Remedy != monitor.RemedyNudge {
+		t.Fatalf("unexpected watchdog settings %+v", settings.Watchdog)
+	}
+	if settings.LogOffsetsFile != "/var/lib/atm/log-offsets.json" {
+		t.Fatalf("unexpected log offsets file %q", settings.LogOffsetsFile)
+	}
+
+	security := cfg.SecurityConfig(api.DefaultSecurityConfig())
+	if len(security.AllowedHosts) != 1 || security.LoginRateLimit != 0 || !security.CSRF {
+		t.Fatalf("unexpected security config %+v", security)
+	}
+
+	redaction := cfg.RedactConfig(redact.Config{Patterns: []redact.PatternConfig{{Name: "env", Regex: "ENV-[0-9]+"}}})
+	if redaction.Disabled || !redaction.DisableEntropy || len(redaction.Patterns) != 1 || redaction.Patterns[0].Name != "ticket" {
+		t.Fatalf("unexpected redact config %+v", redaction)
+	}
+	if profile := cfg.PrivacyProfile(api.PrivacyStandard); profile != api.PrivacyStrict {
+		t.Fatalf("unexpected privacy profile %q", profile)
+	}
+
+	hooks := cfg.WebhookConfig(webhook.Config{DeadLetterPath: "/ignored"})
+	if len(hooks.Endpoints) != 1 || hooks.DeadLetterPath != filepath.Join(filepath.Dir(path), defaultDeadLetterFileName) {
+		t.Fatalf("unexpected webhook config %+v", hooks)
+	}
+	if cfg.Auth.SessionTTL != 2*time.Hour || cfg.Web.Addr != "127.0.0.1:9000" {
+		t.Fatalf("unexpected auth or web section %+v %+v", cfg.Auth, cfg.Web)
+	}
+}
+
+func TestLoadMissingFileIsEmpty(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "absent.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	base := monitor.Settings{PollInterval: 3 * time.Second}
+	if !reflect.DeepEqual(cfg.CollectorSettings(base), base) {
+		t.Fatal("expected an empty config to leave the settings alone")
+	}
+}
+
+func TestParseReportsEveryInvalidSetting(t *testing.T) {
+	_, err := Parse([]byte(`
+provider: gemini
+thresholds:
+  poll_interval: 10ms
+  stale_team: -1m
+auth:
+  anonymous_role: admin
+web:
+  cors_origins: ["*"]
+watchdog:
+  remedy: reboot
+privacy:
+  read_only_profile: secret
+  redaction:
+    patterns:
+      - name: broken
+        regex: '(['
+`))
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, field := range []string{"provider", "thresholds.poll_interval", "thresholds.stale_team", "auth.anonymous_role", "web.cors_origins", "watchdog.remedy", "privacy.read_only_profile", "privacy.redaction.patterns"} {
+		if !strings.Contains(err.Error(), field+":") {
+			t.Fatalf("expected an error for %s, got %v", field, err)
+		}
+	}
+
+	if _, err := Parse([]byte("thresholds:\n  poll_intervall: 5s\n")); err == nil || !strings.Contains(err.Error(), "poll_intervall") {
+		t.Fatalf("expected an unknown key to fail, got %v", err)
+	}
+	if _, err := Parse([]byte("thresholds:\n  stale_team: 3600\n")); err == nil {
+		t.Fatal("expected a duration without a unit to fail")
+	}
+}
+
+func TestWatcherAppliesValidChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("provider: claude\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var applied []Config
+	watcher := NewWatcher(path, func(cfg Config) { applied = append(applied, cfg) })
+
+	if watcher.Poll() {
+		t.Fatal("expected the contents at creation to count as applied")
+	}
+	if err := os.WriteFile(path, []byte("provider: [broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if watcher.Poll() || len(applied) != 0 {
+		t.Fatal("expected an invalid file to be skipped")
+	}
+	if err := os.WriteFile(path, []byte("provider: codex\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !watcher.Poll() || len(applied) != 1 || applied[0].Provider != "codex" {
+		t.Fatalf("expected the fixed file to apply, got %+v", applied)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if !watcher.Poll() || applied[1].Provider != "" {
+		t.Fatalf("expected a deleted file to restore the defaults, got %+v", applied)
+	}
+}
+
+func TestRootsAcceptPathsAndLabeledEntries(t *testing.T) {
+	cfg, err := Parse([]byte(`
+roots:
+  claude:
+    - ~/.claude
+    - label: devbox
+      dir: /mnt/devbox/.claude
+  codex: /srv/codex
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	settings := cfg.CollectorSettings(monitor.DefaultSettings())
+	if len(settings.ClaudeRoots) != 2 || settings.ClaudeRoots[1] != (monitor.Root{Label: "devbox", Dir: "/mnt/devbox/.claude"}) {
+		t.Fatalf("unexpected claude roots %+v", settings.ClaudeRoots)
+	}
+	if filepath.Base(settings.ClaudeRoots[0].Dir) != ".claude" || settings.ClaudeRoots[0].Label != "" {
+		t.Fatalf("expected an unlabeled home root, got %+v", settings.ClaudeRoots[0])
+	}
+
+	if _, err := Parse([]byte("roots:\n  claude: [/a, /b]\n")); err == nil || !strings.Contains(err.Error(), "roots.claude:") {
+		t.Fatalf("expected two unlabeled roots to fail, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+const defaultWatchInterval = 2 * time.Second
+
+// Watcher polls a config file and hands every valid new version to apply.
+// Polling rather than file events survives editors that replace the file.
+type Watcher struct {
+	path     string
+	apply    func(Config)
+	interval time.Duration
+	last     []byte
+}
+
+// NewWatcher creates a watcher for the file at path. The current contents
+// are taken as already applied, so create it right after loading.
+func NewWatcher(path string, apply func(Config)) *Watcher {
+	last, _ := os.ReadFile(path)
+	return &Watcher{path: path, apply: apply, interval: defaultWatchInterval, last: last}
+}
+
+// Run polls until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	if w == nil || w.apply == nil {
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Poll()
+		}
+	}
+}
+
+// Poll reloads the file if its contents changed and reports whether the
+// new config was applied. An invalid file is logged and the running config
+// is kept; a deleted file applies the defaults.
+func (w *Watcher) Poll() bool {
+	data, err := os.ReadFile(w.path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Config reload: %v", err)
+		return false
+	}
+	if bytes.Equal(data, w.last) {
+		return false
+	}
+	w.last = data
+
+	cfg, err := Load(w.path)
+	if err != nil {
+		log.Printf("Config reload: keeping the running config: %v", err)
+		return false
+	}
+	w.apply(cfg)
+	log.Printf("Config reload: applied %s", w.path)
+	return true
+}
@@ -0,0 +1,122 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/client"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// DefaultPushInterval is how often an agent pushes, well inside the hub's
+// DefaultNodeTimeout.
+const DefaultPushInterval = 2 * time.Second
+
+// AgentOptions configures a node pushing to a hub.
+type AgentOptions struct {
+	HubURL     string
+	Token      string // hub API token with the federate scope
+	Node       string // name the node's teams are qualified with
+	URL        string // where the hub reaches this node to relay actions
+	Interval   time.Duration
+	HTTPClient *http.Client
+}
+
+// Agent pushes a node's state deltas to a hub. Every push also serves as
+// a heartbeat, so it is sent even when nothing changed.
+type Agent struct {
+	options AgentOptions
+	hub     *client.Client
+	delta   func(since uint64) types.StateDelta
+	secret  string
+
+	mu    sync.Mutex
+	acked uint64 // version the hub holds; 0 asks for a full state
+}
+
+// NewAgent creates an agent that reads deltas from delta.
+func NewAgent(options AgentOptions, delta func(since uint64) types.StateDelta) (*Agent, error) {
+	if err := ValidateNodeName(options.Node); err != nil {
+		return nil, err
+	}
+	if options.URL == "" {
+		return nil, fmt.Errorf("node URL required")
+	}
+	if options.Interval <= 0 {
+		options.Interval = DefaultPushInterval
+	}
+	hub, err := client.New(options.HubURL, client.Options{Token: options.Token, HTTPClient: options.HTTPClient})
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &Agent{options: options, hub: hub, delta: delta, secret: hex.EncodeToString(secret)}, nil
+}
+
+// Secret is the bearer token the hub relays actions with; give it to
+// api.AuthManager.SetHubSecret.
+func (a *Agent) Secret() string {
+	return a.secret
+}
+
+// Run pushes every interval until ctx is cancelled, logging failures
+// without stopping.
+func (a *Agent) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.options.Interval)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		if err := a.Push(ctx); err != nil {
+			if !failing && ctx.Err() == nil {
+				log.Printf("Federation: push to %s failed: %v", a.options.HubURL, err)
+			}
+			failing = true
+		} else if failing {
+			log.Printf("Federation: pushing to %s again", a.options.HubURL)
+			failing = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Push sends what changed since the hub's last acknowledged version, and
+// the full state right away when the hub asks for it. A failed push is
+// resent in full, since the hub may or may not have applied it.
+func (a *Agent) Push(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		ack, err := a.hub.PushNodeReport(ctx, types.NodeReport{
+			Node:   a.options.Node,
+			URL:    a.options.URL,
+			Secret: a.secret,
+			Delta:  a.delta(a.acked),
+		})
+		if err != nil {
+			a.acked = 0
+			return err
+		}
+		if !ack.Reset {
+			a.acked = ack.Version
+			return nil
+		}
+		a.acked = 0
+	}
+	return fmt.Errorf("hub %s refused the full state", a.options.HubURL)
+}
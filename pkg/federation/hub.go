@@ -0,0 +1,281 @@
+// Package federation joins monitors on several machines into one view. Each
+// node pushes state deltas to a hub, which merges the teams and processes
+// of every live node into its own state under node-qualified names and
+// relays operator actions back to the node that owns the target.
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/client"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// DefaultNodeTimeout is how long a hub waits for a push before marking the
+// node offline and dropping its teams from the merged state.
+const DefaultNodeTimeout = 15 * time.Second
+
+// ErrNodeUnreachable wraps failures to reach a node, including a node that
+// stopped pushing.
+var ErrNodeUnreachable = errors.New("node unreachable")
+
+// ErrNodeClaimed refuses a push under a node name that another principal
+// registered.
+var ErrNodeClaimed = errors.New("node name is registered to another principal")
+
+var nodeNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidateNodeName checks that name can qualify team names, which rules
+// out "@" and "/".
+func ValidateNodeName(name string) error {
+	if !nodeNamePattern.MatchString(name) {
+		return fmt.Errorf("node name %q may only contain letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// Hub holds the last state pushed by every node.
+type Hub struct {
+	timeout    time.Duration
+	now        func() time.Time
+	httpClient *http.Client
+
+	// reserved reports names nodes may not take, such as the hub's own
+	// root labels, which qualify local team names the same way.
+	reserved func(name string) bool
+
+	mu      sync.Mutex
+	nodes   map[string]*hubNode
+	version uint64 // bumped whenever the merged view changes
+}
+
+type hubNode struct {
+	info types.NodeInfo
+	// owner is the principal whose push registered the node; only it may
+	// push for the node, and so change its URL and secret.
+	owner  string
+	secret string
+	state  types.MonitorState
+	client *client.Client
+}
+
+// NewHub creates an empty hub.
+func NewHub() *Hub {
+	return &Hub{
+		timeout: DefaultNodeTimeout,
+		now:     time.Now,
+		nodes:   map[string]*hubNode{},
+	}
+}
+
+// SetReservedNames refuses pushes from nodes whose name reserved reports,
+// such as the labels of the hub's own data roots: a local team from root
+// <label> is named <team>@<label>, like a team of node <label>.
+func (h *Hub) SetReservedNames(reserved func(name string) bool) {
+	h.reserved = reserved
+}
+
+// Receive applies a node's push on behalf of principal. The first push
+// under a node name binds the name to its principal, and pushes from any
+// other principal are refused with ErrNodeClaimed. A delta that does not
+// follow the version the hub holds for the node is refused with Reset so
+// the node resends everything.
+func (h *Hub) Receive(principal string, report types.NodeReport) (types.NodeAck, error) {
+	if err := ValidateNodeName(report.Node); err != nil {
+		return types.NodeAck{}, err
+	}
+	if h.reserved != nil && h.reserved(report.Node) {
+		return types.NodeAck{}, fmt.Errorf("node name %q is a data root label on the hub", report.Node)
+	}
+	callback, err := url.Parse(report.URL)
+	if err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || callback.Host == "" {
+		return types.NodeAck{}, fmt.Errorf("node URL %q must be an http or https URL", report.URL)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	node, ok := h.nodes[report.Node]
+	if ok && node.owner != principal {
+		return types.NodeAck{}, fmt.Errorf("%w: %s", ErrNodeClaimed, report.Node)
+	}
+	if !ok {
+		node = &hubNode{info: types.NodeInfo{Name: report.Node}, owner: principal}
+		h.nodes[report.Node] = node
+	}
+	delta := report.Delta
+	if !delta.Reset && delta.Since != node.state.Version {
+		// Unknown to us: ask for everything, but still count it as a sign of life.
+		node.info.LastSeen = h.now()
+		return types.NodeAck{Version: node.state.Version, Reset: true}, nil
+	}
+
+	if node.info.URL != report.URL || node.secret != report.Secret {
+		node.info.URL, node.secret, node.client = report.URL, report.Secret, nil
+	}
+	previous := node.state.Version
+	node.state = node.state.ApplyDelta(delta)
+	node.state.Version = delta.Version
+	node.info.LastSeen = h.now()
+	node.info.Teams, node.info.Processes = len(node.state.Teams), len(node.state.Processes)
+	if !node.info.Online || delta.Version != previous {
+		node.info.Online = true
+		h.version++
+	}
+	return types.NodeAck{Version: node.state.Version}, nil
+}
+
+// Nodes lists every node that ever pushed, by name.
+func (h *Hub) Nodes() []types.NodeInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expireLocked()
+	return h.nodesLocked()
+}
+
+func (h *Hub) nodesLocked() []types.NodeInfo {
+	result := make([]types.NodeInfo, 0, len(h.nodes))
+	for _, node := range h.nodes {
+		result = append(result, node.info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// expireLocked marks nodes that missed their pushes offline.
+func (h *Hub) expireLocked() {
+	cutoff := h.now().Add(-h.timeout)
+	for _, node := range h.nodes {
+		if node.info.Online && node.info.LastSeen.Before(cutoff) {
+			node.info.Online = false
+			h.version++
+		}
+	}
+}
+
+// Merge adds the teams and processes of every online node to state, with
+// names qualified as <name>@<node>, and lists the nodes. The hub version is
+// added to state.Version so that the served version moves when only a
+// node changed.
+func (h *Hub) Merge(state *types.MonitorState) {
+	if h == nil || state == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expireLocked()
+
+	for _, info := range h.nodesLocked() {
+		if !info.Online {
+			continue
+		}
+		node := h.nodes[info.Name]
+		for _, team := range node.state.Teams {
+			team = copyTeam(team)
+			team.Name = Qualify(team.Name, info.Name)
+			if team.ManagedTeamID != "" {
+				team.ManagedTeamID = Qualify(team.ManagedTeamID, info.Name)
+			}
+			team.Host = info.Name
+			state.Teams = append(state.Teams, team)
+		}
+		for _, process := range node.state.Processes {
+			if process.Team != "" {
+				process.Team = Qualify(process.Team, info.Name)
+			}
+			if process.ManagedTeamID != "" {
+				process.ManagedTeamID = Qualify(process.ManagedTeamID, info.Name)
+			}
+			process.Host = info.Name
+			state.Processes = append(state.Processes, process)
+		}
+	}
+	state.Nodes = h.nodesLocked()
+	state.Version += h.version
+}
+
+// copyTeam copies the slices the served state may rewrite in place, so
+// the hub's copy of a node's state stays intact.
+func copyTeam(team types.TeamInfo) types.TeamInfo {
+	team.Tasks = append([]types.TaskInfo(nil), team.Tasks...)
+	team.Members = append([]types.AgentInfo(nil), team.Members...)
+	for i, member := range team.Members {
+		member.OfficeDialogues = append([]string(nil), member.OfficeDialogues...)
+		member.Todos = append([]types.TodoItem(nil), member.Todos...)
+		member.RecentEvents = append([]types.AgentEvent(nil), member.RecentEvents...)
+		team.Members[i] = member
+	}
+	return team
+}
+
+// Qualify names a node's team, managed team or process team on the hub.
+func Qualify(name, node string) string {
+	return name + "@" + node
+}
+
+// Owner splits a qualified name into the name on its node and the node,
+// reporting whether the node is known to the hub.
+func (h *Hub) Owner(qualified string) (name, node string, ok bool) {
+	if h == nil {
+		return "", "", false
+	}
+	at := strings.LastIndex(qualified, "@")
+	if at <= 0 {
+		return "", "", false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, known := h.nodes[qualified[at+1:]]; !known {
+		return "", "", false
+	}
+	return qualified[:at], qualified[at+1:], true
+}
+
+// Forward runs call with a client for the node that qualified names, and
+// the name as the node knows it. handled is false when qualified does not
+// end in a known node, meaning the target is local.
+func (h *Hub) Forward(ctx context.Context, qualified string, call func(ctx context.Context, c *client.Client, name string) error) (handled bool, err error) {
+	if h == nil {
+		return false, nil
+	}
+	name, nodeName, ok := h.Owner(qualified)
+	if !ok {
+		return false, nil
+	}
+
+	h.mu.Lock()
+	h.expireLocked()
+	node := h.nodes[nodeName]
+	if !node.info.Online {
+		h.mu.Unlock()
+		return true, fmt.Errorf("%w: %s is offline", ErrNodeUnreachable, nodeName)
+	}
+	if node.client == nil {
+		c, err := client.New(node.info.URL, client.Options{Token: node.secret, HTTPClient: h.httpClient})
+		if err != nil {
+			h.mu.Unlock()
+			return true, fmt.Errorf("%w: %s: %v", ErrNodeUnreachable, nodeName, err)
+		}
+		node.client = c
+	}
+	c := node.client
+	h.mu.Unlock()
+
+	if err := call(ctx, c, name); err != nil {
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) {
+			return true, err
+		}
+		return true, fmt.Errorf("%w: %s: %v", ErrNodeUnreachable, nodeName, err)
+	}
+	return true, nil
+}
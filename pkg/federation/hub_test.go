@@ -0,0 +1,162 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/client"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func fullReport(node string, version uint64, teams ...types.TeamInfo) types.NodeReport {
+	return types.NodeReport{
+		Node: node,
+		URL:  "http://" + node + ".example:8080",
+		Delta: types.StateDelta{
+			Version: version,
+			Reset:   true,
+			State: &types.MonitorState{
+				Teams:     teams,
+				Processes: []types.ProcessInfo{{PID: 7, Command: "claude", Team: "alpha"}},
+			},
+		},
+	}
+}
+
+func TestHubMergesOnlineNodesUnderQualifiedNames(t *testing.T) {
+	hub := NewHub()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	hub.now = func() time.Time { return now }
+
+	ack, err := hub.Receive("token:devbox", fullReport("devbox", 4, types.TeamInfo{
+		Name:          "alpha",
+		ManagedTeamID: "team-1",
+		Members:       []types.AgentInfo{{Name: "lead", Todos: []types.TodoItem{{Content: "ship"}}}},
+	}))
+	if err != nil || ack.Reset || ack.Version != 4 {
+		t.Fatalf("unexpected ack %+v (%v)", ack, err)
+	}
+
+	state := types.MonitorState{Version: 10, Teams: []types.TeamInfo{{Name: "local"}}}
+	hub.Merge(&state)
+	if len(state.Teams) != 2 || state.Teams[1].Name != "alpha@devbox" || state.Teams[1].ManagedTeamID != "team-1@devbox" || state.Teams[1].Host != "devbox" {
+		t.Fatalf("unexpected merged teams %+v", state.Teams)
+	}
+	if len(state.Processes) != 1 || state.Processes[0].Team != "alpha@devbox" || state.Processes[0].Host != "devbox" {
+		t.Fatalf("unexpected merged processes %+v", state.Processes)
+	}
+	if len(state.Nodes) != 1 || !state.Nodes[0].Online || state.Nodes[0].Teams != 1 || state.Version <= 10 {
+		t.Fatalf("unexpected nodes %+v at version %d", state.Nodes, state.Version)
+	}
+	state.Teams[1].Members[0].Todos[0].Content = "changed"
+
+	again := types.MonitorState{Version: 10}
+	hub.Merge(&again)
+	if again.Teams[0].Members[0].Todos[0].Content != "ship" {
+		t.Fatal("expected merged teams to be copies")
+	}
+	if again.Version != state.Version {
+		t.Fatalf("expected an unchanged hub to keep its version, got %d then %d", state.Version, again.Version)
+	}
+
+	now = now.Add(DefaultNodeTimeout + time.Second)
+	expired := types.MonitorState{Version: 10}
+	hub.Merge(&expired)
+	if len(expired.Teams) != 0 || expired.Nodes[0].Online || expired.Version <= again.Version {
+		t.Fatalf("expected the silent node to drop out, got %+v", expired)
+	}
+}
+
+func TestHubAsksForResetOnVersionGap(t *testing.T) {
+	hub := NewHub()
+	if _, err := hub.Receive("token:devbox", fullReport("devbox", 4, types.TeamInfo{Name: "alpha"})); err != nil {
+		t.Fatal(err)
+	}
+
+	gap := types.NodeReport{Node: "devbox", URL: "http://devbox.example:8080", Delta: types.StateDelta{Version: 9, Since: 7}}
+	if ack, err := hub.Receive("token:devbox", gap); err != nil || !ack.Reset {
+		t.Fatalf("expected a reset request, got %+v (%v)", ack, err)
+	}
+
+	next := types.NodeReport{Node: "devbox", URL: "http://devbox.example:8080", Delta: types.StateDelta{
+		Version:   5,
+		Since:     4,
+		TeamOrder: []string{"alpha", "beta"},
+		Teams:     []types.TeamChange{{TeamInfo: types.TeamInfo{Name: "beta"}}},
+	}}
+	if ack, err := hub.Receive("token:devbox", next); err != nil || ack.Reset || ack.Version != 5 {
+		t.Fatalf("expected the delta to apply, got %+v (%v)", ack, err)
+	}
+	if nodes := hub.Nodes(); nodes[0].Teams != 2 {
+		t.Fatalf("expected both teams after the delta, got %+v", nodes)
+	}
+
+	for _, bad := range []types.NodeReport{
+		{Node: "dev@box", URL: "http://devbox.example"},
+		{Node: "devbox", URL: "ftp://devbox.example"},
+	} {
+		if _, err := hub.Receive("token:devbox", bad); err == nil {
+			t.Fatalf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestHubBindsNodeNameToFirstPrincipal(t *testing.T) {
+	hub := NewHub()
+	if _, err := hub.Receive("token:devbox", fullReport("devbox", 1)); err != nil {
+		t.Fatal(err)
+	}
+	takeover := fullReport("devbox", 2)
+	takeover.URL, takeover.Secret = "http://intruder.example", "stolen"
+	if _, err := hub.Receive("token:intruder", takeover); !errors.Is(err, ErrNodeClaimed) {
+		t.Fatalf("expected the takeover to be refused, got %v", err)
+	}
+	if nodes := hub.Nodes(); nodes[0].URL != "http://devbox.example:8080" || hub.nodes["devbox"].secret == "stolen" {
+		t.Fatalf("expected the node to keep its URL and secret, got %+v", nodes)
+	}
+	if _, err := hub.Receive("token:devbox", fullReport("devbox", 2)); err != nil {
+		t.Fatalf("expected the registering principal to keep pushing, got %v", err)
+	}
+}
+
+func TestHubRefusesReservedNodeNames(t *testing.T) {
+	hub := NewHub()
+	hub.SetReservedNames(func(name string) bool { return name == "vm1" })
+	if _, err := hub.Receive("token:vm1", fullReport("vm1", 1)); err == nil {
+		t.Fatal("expected a node named like a root label to be refused")
+	}
+	if _, _, ok := hub.Owner("alpha@vm1"); ok {
+		t.Fatal("expected a root-labeled team to stay local")
+	}
+	if _, err := hub.Receive("token:devbox", fullReport("devbox", 1)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHubForwardRoutesByNodeSuffix(t *testing.T) {
+	hub := NewHub()
+	now := time.Now()
+	hub.now = func() time.Time { return now }
+	if _, err := hub.Receive("token:devbox", fullReport("devbox", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	call := func(_ context.Context, _ *client.Client, name string) error {
+		got = name
+		return nil
+	}
+	if handled, err := hub.Forward(context.Background(), "alpha@home", call); handled || err != nil {
+		t.Fatalf("expected an unknown suffix to stay local, got %v %v", handled, err)
+	}
+	if handled, err := hub.Forward(context.Background(), "alpha@label@devbox", call); !handled || err != nil || got != "alpha@label" {
+		t.Fatalf("expected the node's own name, got %q %v %v", got, handled, err)
+	}
+
+	now = now.Add(DefaultNodeTimeout + time.Second)
+	if handled, err := hub.Forward(context.Background(), "alpha@devbox", call); !handled || !errors.Is(err, ErrNodeUnreachable) {
+		t.Fatalf("expected an offline node to be unreachable, got %v %v", handled, err)
+	}
+}
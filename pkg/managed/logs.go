@@ -0,0 +1,90 @@
+package managed
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxLogTailBytes bounds how much of a log ReadLog scans from the end.
+const maxLogTailBytes = 1 << 20
+
+// LogTail is the end of one managed agent's terminal log.
+type LogTail struct {
+	TeamID  string   `json:"team_id"`
+	AgentID string   `json:"agent_id"`
+	Path    string   `json:"path,omitempty"`
+	Lines   []string `json:"lines"`
+}
+
+// ReadLog returns up to lines trailing lines of an agent's log; an empty
+// agentID means the team's primary agent. An agent that never ran has no
+// lines.
+func (m *Manager) ReadLog(teamID, agentID string, lines int) (LogTail, error) {
+	spec, err := m.loadTeamSpec(teamID)
+	if err != nil {
+		return LogTail{}, fmt.Errorf("team %q not found", teamID)
+	}
+	agentID = firstNonEmpty(strings.TrimSpace(agentID), primaryAgentID(spec))
+	if _, ok := findAgentSpec(spec, agentID); !ok {
+		return LogTail{}, fmt.Errorf("agent %q not found in team %q", agentID, teamID)
+	}
+
+	tail := LogTail{TeamID: spec.ID, AgentID: agentID, Lines: []string{}}
+	path := filepath.Join(m.logsRoot(), spec.ID, agentID+".log")
+	if run, err := m.loadAgentRunState(spec, agentID); err == nil && run.LogPath != "" {
+		path = run.LogPath
+	}
+	data, err := readFileTail(path, maxLogTailBytes)
+	if os.IsNotExist(err) {
+		return tail, nil
+	}
+	if err != nil {
+		return LogTail{}, err
+	}
+	tail.Path = path
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	all := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(all) == 1 && all[0] == "" {
+		return tail, nil
+	}
+	if lines > 0 && len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	tail.Lines = all
+	return tail, nil
+}
+
+// readFileTail reads at most limit bytes from the end of path, dropping a
+// partial first line when it starts mid-file.
+func readFileTail(path string, limit int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - limit
+	if offset <= 0 {
+		return io.ReadAll(file)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if newline := bytes.IndexByte(data, '\n'); newline >= 0 {
+		data = data[newline+1:]
+	}
+	return data, nil
+}
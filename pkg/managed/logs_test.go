@@ -0,0 +1,60 @@
+package managed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadLogReturnsTrailingLines(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("ATM_MANAGED_DIR", filepath.Join(root, "managed"))
+	workspace := filepath.Join(root, "workspace")
+	if err := os.MkdirAll(workspace, 0o755); err != nil {
+		t.Fatalf("mkdir workspace: %v", err)
+	}
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	spec, err := manager.CreateTeam(CreateTeamInput{
+		Name:      "Log Team",
+		Provider:  "claude",
+		Workspace: workspace,
+		Agents:    []AgentInput{{Name: "lead", Provider: "claude"}, {Name: "helper", Provider: "claude"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	lead, helper := spec.Agents[0].ID, spec.Agents[1].ID
+
+	tail, err := manager.ReadLog(spec.ID, "", 10)
+	if err != nil || tail.AgentID != lead || len(tail.Lines) != 0 {
+		t.Fatalf("expected an empty log for the primary agent, got %+v %v", tail, err)
+	}
+
+	logPath := filepath.Join(manager.logsRoot(), spec.ID, helper+".log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logPath, []byte("one\r\ntwo\r\nthree\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tail, err = manager.ReadLog(spec.ID, helper, 2)
+	if err != nil || strings.Join(tail.Lines, "|") != "two|three" || tail.Path != logPath {
+		t.Fatalf("unexpected tail %+v %v", tail, err)
+	}
+
+	if _, err := manager.ReadLog(spec.ID, "ghost", 5); err == nil {
+		t.Fatal("expected an unknown agent to fail")
+	}
+	if _, err := manager.ReadLog("missing", "", 5); err == nil {
+		t.Fatal("expected an unknown team to fail")
+	}
+
+	data, err := readFileTail(logPath, 8)
+	if err != nil || string(data) != "three\r\n" {
+		t.Fatalf("expected the partial first line to be dropped, got %q %v", data, err)
+	}
+}
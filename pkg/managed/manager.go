@@ -110,10 +110,20 @@ type activeRun struct {
 
 var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
 
+const restartPollInterval = 50 * time.Millisecond
+
 func NewManager() (*Manager, error) {
-	rootDir, err := defaultRootDir()
-	if err != nil {
-		return nil, err
+	return NewManagerInDir("")
+}
+
+// NewManagerInDir keeps managed teams under rootDir; empty uses
+// ATM_MANAGED_DIR or ~/.agent-team-monitor.
+func NewManagerInDir(rootDir string) (*Manager, error) {
+	if strings.TrimSpace(rootDir) == "" {
+		var err error
+		if rootDir, err = defaultRootDir(); err != nil {
+			return nil, err
+		}
 	}
 
 	m := &Manager{
@@ -408,6 +418,50 @@ func (m *Manager) SendMessageToAgent(teamID, agentID, text string) error {
 	return m.sendMessageToAgentLocked(teamID, agentID, text)
 }
 
+// InterruptAgent presses Esc in a controllable agent's terminal, which
+// cancels its current turn and leaves the session running.
+func (m *Manager) InterruptAgent(teamID, agentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active, ok := m.active[activeKey(teamID, agentID)]
+	if !ok || active.ptmx == nil {
+		return fmt.Errorf("agent %q/%q is not controllable", teamID, agentID)
+	}
+	_, err := io.WriteString(active.ptmx, "\x1b")
+	return err
+}
+
+// RestartAgent stops a controllable agent, kills it if it has not exited
+// after timeout, and starts it again.
+func (m *Manager) RestartAgent(teamID, agentID string, timeout time.Duration) (RunState, error) {
+	if _, err := m.StopAgent(teamID, agentID); err != nil {
+		return RunState{}, err
+	}
+
+	key := activeKey(teamID, agentID)
+	deadline := time.Now().Add(timeout)
+	killed := false
+	for {
+		m.mu.Lock()
+		active, running := m.active[key]
+		m.mu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			if killed {
+				return RunState{}, fmt.Errorf("agent %q/%q did not exit", teamID, agentID)
+			}
+			_ = active.cmd.Process.Kill()
+			killed = true
+			deadline = time.Now().Add(timeout)
+		}
+		time.Sleep(restartPollInterval)
+	}
+	return m.StartAgent(teamID, agentID)
+}
+
 func (m *Manager) startAgentLocked(spec TeamSpec, agent AgentSpec) (RunState, error) {
 	key := activeKey(spec.ID, agent.ID)
 	if existing, ok := m.active[key]; ok && existing.cmd != nil && existing.cmd.Process != nil {
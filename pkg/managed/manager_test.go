@@ -206,3 +206,59 @@ func TestLoadTeamRunStatesMigratesLegacyRunFile(t *testing.T) {
 		t.Fatalf("expected legacy run file removed, got %v", err)
 	}
 }
+
+func TestRestartAgentStartsAFreshProcess(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("ATM_MANAGED_DIR", filepath.Join(root, "managed"))
+
+	binDir := filepath.Join(root, "bin")
+	workspace := filepath.Join(root, "workspace")
+	for _, dir := range []string{binDir, workspace} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	// Ignores SIGINT, so the restart has to fall back to killing it.
+	script := "#!/usr/bin/env bash\ntrap '' INT\nstty -echo\nprintf 'START:%s\\n' \"$$\"\nwhile IFS= read -r -n1 key; do\n  printf 'KEY:%q\\n' \"$key\"\ndone\n"
+	if err := os.WriteFile(filepath.Join(binDir, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake claude: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	spec, err := manager.CreateTeam(CreateTeamInput{Name: "Stuck Team", Provider: "claude", Workspace: workspace})
+	if err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	agentID := spec.Agents[0].ID
+	first, err := manager.StartAgent(spec.ID, agentID)
+	if err != nil {
+		t.Fatalf("StartAgent: %v", err)
+	}
+	t.Cleanup(func() { _, _ = manager.StopTeam(spec.ID) })
+
+	time.Sleep(200 * time.Millisecond)
+	if err := manager.InterruptAgent(spec.ID, agentID); err != nil {
+		t.Fatalf("InterruptAgent: %v", err)
+	}
+
+	second, err := manager.RestartAgent(spec.ID, agentID, 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("RestartAgent: %v", err)
+	}
+	if second.PID == first.PID || second.Status != RunStatusRunning || !second.Controllable {
+		t.Fatalf("expected a fresh running agent, got %+v after %+v", second, first)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	data, err := os.ReadFile(second.LogPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if !strings.Contains(string(data), `KEY:$'\E'`) || strings.Count(string(data), "START:") != 2 {
+		t.Fatalf("expected an Esc key and two starts in the log, got %q", data)
+	}
+}
@@ -0,0 +1,256 @@
+// Package mcp serves monitor data to agents over the Model Context Protocol:
+// JSON-RPC 2.0 messages, one per line, on stdin and stdout.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// LatestProtocolVersion is offered to clients asking for a version this
+// server does not know.
+const LatestProtocolVersion = "2025-06-18"
+
+var supportedProtocolVersions = []string{LatestProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Backend is where tools read state and send messages: a local collector
+// or a running server.
+type Backend interface {
+	State(ctx context.Context) (types.MonitorState, error)
+	SendAgentMessage(ctx context.Context, team, agent, text string) error
+}
+
+// Options configures a Server.
+type Options struct {
+	Name    string
+	Version string
+	// Authorize runs before every tool with the permission the matching
+	// HTTP API route needs; nil allows every tool, for backends that check
+	// permissions themselves.
+	Authorize func(api.Permission) error
+}
+
+// Server answers MCP requests.
+type Server struct {
+	backend Backend
+	options Options
+	tools   []tool
+}
+
+// NewServer returns a server exposing the monitor tools over backend.
+func NewServer(backend Backend, options Options) *Server {
+	if options.Name == "" {
+		options.Name = "agent-team-monitor"
+	}
+	return &Server{backend: backend, options: options, tools: monitorTools()}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// Serve reads requests from in and writes responses to out until in ends
+// or ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					readErr <- err
+				}
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				select {
+				case err := <-readErr:
+					return err
+				default:
+					return nil
+				}
+			}
+			if resp := s.handleMessage(ctx, line); resp != nil {
+				if err := s.write(out, resp); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) write(out io.Writer, resp *response) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(resp)
+}
+
+// handleMessage answers one line; notifications get no response.
+func (s *Server) handleMessage(ctx context.Context, line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "parse error: " + err.Error()}}
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return &response{JSONRPC: "2.0", ID: idOrNull(req.ID), Error: &rpcError{Code: codeInvalidRequest, Message: "invalid JSON-RPC 2.0 request"}}
+	}
+	if len(req.ID) == 0 {
+		return nil
+	}
+
+	result, err := s.dispatch(ctx, req)
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	var rpcErr *rpcError
+	switch {
+	case errors.As(err, &rpcErr):
+		resp.Error = rpcErr
+	case err != nil:
+		resp.Error = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	default:
+		resp.Result = result
+	}
+	return resp
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (any, error) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := LatestProtocolVersion
+		if slices.Contains(supportedProtocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]any{"name": s.options.Name, "version": s.options.Version},
+			"instructions":    "Read what teammates in local Claude Code, Codex and OpenClaw agent teams are doing, and message them.",
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		list := make([]map[string]any, 0, len(s.tools))
+		for _, tool := range s.tools {
+			list = append(list, map[string]any{
+				"name":        tool.Name,
+				"title":       tool.Title,
+				"description": tool.Description,
+				"inputSchema": tool.InputSchema,
+				"annotations": map[string]any{"readOnlyHint": tool.Permission == api.PermissionRead},
+			})
+		}
+		return map[string]any{"tools": list}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, fmt.Errorf("invalid tools/call params: %w", err)
+		}
+		return s.callTool(ctx, params.Name, params.Arguments)
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+	}
+}
+
+// callTool runs a tool. Unknown tools are protocol errors; failures inside
+// a tool are results with isError set, so the calling model sees them.
+func (s *Server) callTool(ctx context.Context, name string, arguments json.RawMessage) (any, error) {
+	index := slices.IndexFunc(s.tools, func(t tool) bool { return t.Name == name })
+	if index < 0 {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", name)}
+	}
+	tool := s.tools[index]
+	if len(arguments) == 0 || string(arguments) == "null" {
+		arguments = json.RawMessage("{}")
+	}
+
+	if s.options.Authorize != nil {
+		if err := s.options.Authorize(tool.Permission); err != nil {
+			return toolError(err), nil
+		}
+	}
+	result, err := tool.run(ctx, s.backend, arguments)
+	if err != nil {
+		return toolError(err), nil
+	}
+	text, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"content":           []map[string]any{{"type": "text", "text": string(text)}},
+		"structuredContent": result,
+		"isError":           false,
+	}, nil
+}
+
+func toolError(err error) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": err.Error()}},
+		"isError": true,
+	}
+}
+
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
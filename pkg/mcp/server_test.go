@@ -0,0 +1,213 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+type fakeBackend struct {
+	state types.MonitorState
+	sent  []string
+}
+
+func (b *fakeBackend) State(context.Context) (types.MonitorState, error) {
+	return b.state, nil
+}
+
+func (b *fakeBackend) SendAgentMessage(_ context.Context, team, agent, text string) error {
+	if team != "Docs Crew" {
+		return fmt.Errorf("team %q not found", team)
+	}
+	b.sent = append(b.sent, team+"/"+agent+": "+text)
+	return nil
+}
+
+func newFakeBackend() *fakeBackend {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &fakeBackend{state: types.MonitorState{Teams: []types.TeamInfo{
+		{
+			Name:     "Docs Crew",
+			Provider: "claude",
+			Members: []types.AgentInfo{
+				{Name: "writer", Status: "working", CurrentTask: "Guide", RecentEvents: []types.AgentEvent{
+					{Kind: "response", Text: "Drafted the deployment guide", Timestamp: now},
+					{Kind: "tool", Text: "Edit · guide.md", Timestamp: now.Add(-time.Minute)},
+				}},
+				{Name: "editor", Status: "idle", RecentEvents: []types.AgentEvent{
+					{Kind: "message", Text: "Please review the DEPLOYMENT section", Timestamp: now.Add(-2 * time.Minute)},
+				}},
+			},
+			Tasks: []types.TaskInfo{{ID: "1", Subject: "Guide", Status: "in_progress"}, {ID: "2", Subject: "FAQ", Status: "completed"}},
+		},
+		{Name: "Codex Crew", Provider: "codex", Members: []types.AgentInfo{{Name: "coder", Status: "idle", Provider: "codex"}}},
+	}}}
+}
+
+// exchange sends requests, one per line, and returns the responses by id.
+func exchange(t *testing.T, server *Server, requests ...string) map[string]map[string]any {
+	t.Helper()
+	var out strings.Builder
+	if err := server.Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	responses := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp map[string]any
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("bad response line %q: %v", line, err)
+		}
+		responses[fmt.Sprint(resp["id"])] = resp
+	}
+	return responses
+}
+
+func call(id int, tool string, arguments string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q,"arguments":%s}}`, id, tool, arguments)
+}
+
+func toolText(t *testing.T, resp map[string]any) (string, bool) {
+	t.Helper()
+	result, ok := resp["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected a result, got %v", resp)
+	}
+	content := result["content"].([]any)[0].(map[string]any)
+	return content["text"].(string), result["isError"] == true
+}
+
+func TestServeProtocol(t *testing.T) {
+	server := NewServer(newFakeBackend(), Options{Version: "test"})
+	responses := exchange(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"t","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"ping"}`,
+		`not json`,
+		call(5, "no_such_tool", `{}`),
+	)
+	if len(responses) != 6 {
+		t.Fatalf("expected no response to the notification, got %d responses", len(responses))
+	}
+
+	init := responses["1"]["result"].(map[string]any)
+	if init["protocolVersion"] != "2025-03-26" || init["serverInfo"].(map[string]any)["version"] != "test" {
+		t.Fatalf("unexpected initialize result %v", init)
+	}
+	var names []string
+	for _, tool := range responses["2"]["result"].(map[string]any)["tools"].([]any) {
+		names = append(names, tool.(map[string]any)["name"].(string))
+	}
+	if strings.Join(names, ",") != "list_teams,get_agent_status,get_team_tasks,search_transcripts,send_teammate_message" {
+		t.Fatalf("unexpected tools %v", names)
+	}
+	if code := responses["3"]["error"].(map[string]any)["code"]; code != float64(codeMethodNotFound) {
+		t.Fatalf("expected method not found, got %v", responses["3"])
+	}
+	if code := responses["<nil>"]["error"].(map[string]any)["code"]; code != float64(codeParseError) {
+		t.Fatalf("expected a parse error with a null id, got %v", responses["<nil>"])
+	}
+	if code := responses["5"]["error"].(map[string]any)["code"]; code != float64(codeInvalidParams) {
+		t.Fatalf("expected an unknown tool to be invalid params, got %v", responses["5"])
+	}
+}
+
+func TestTools(t *testing.T) {
+	backend := newFakeBackend()
+	server := NewServer(backend, Options{})
+	responses := exchange(t, server,
+		call(1, "list_teams", `{"provider":"claude"}`),
+		call(2, "get_agent_status", `{"team":"docs crew","agent":"writer","events":1}`),
+		call(3, "get_team_tasks", `{"team":"Docs Crew","status":"in_progress"}`),
+		call(4, "search_transcripts", `{"query":"deployment","limit":1}`),
+		call(5, "send_teammate_message", `{"team":"Docs Crew","agent":"editor","text":"Done"}`),
+		call(6, "get_agent_status", `{"team":"ghost"}`),
+		call(7, "search_transcripts", `{"query":" "}`),
+	)
+
+	var teams struct {
+		Teams []teamSummary `json:"teams"`
+	}
+	text, isError := toolText(t, responses["1"])
+	if isError || json.Unmarshal([]byte(text), &teams) != nil || len(teams.Teams) != 1 || teams.Teams[0].Working != 1 || teams.Teams[0].OpenTasks != 1 {
+		t.Fatalf("unexpected list_teams %s", text)
+	}
+
+	var status struct {
+		Agent agentStatus `json:"agent"`
+	}
+	text, _ = toolText(t, responses["2"])
+	if json.Unmarshal([]byte(text), &status) != nil || status.Agent.CurrentTask != "Guide" || len(status.Agent.RecentEvents) != 1 {
+		t.Fatalf("unexpected get_agent_status %s", text)
+	}
+
+	if text, _ := toolText(t, responses["3"]); !strings.Contains(text, `"subject": "Guide"`) || strings.Contains(text, "FAQ") {
+		t.Fatalf("unexpected get_team_tasks %s", text)
+	}
+
+	var search struct {
+		Total   int               `json:"total"`
+		Matches []transcriptMatch `json:"matches"`
+	}
+	text, _ = toolText(t, responses["4"])
+	if json.Unmarshal([]byte(text), &search) != nil || search.Total != 2 || len(search.Matches) != 1 || search.Matches[0].Agent != "writer" {
+		t.Fatalf("expected the newest of two case-insensitive matches, got %s", text)
+	}
+
+	if _, isError := toolText(t, responses["5"]); isError || len(backend.sent) != 1 || backend.sent[0] != "Docs Crew/editor: Done" {
+		t.Fatalf("unexpected send %v %v", responses["5"], backend.sent)
+	}
+	if text, isError := toolText(t, responses["6"]); !isError || !strings.Contains(text, "not found") {
+		t.Fatalf("expected a tool error for an unknown team, got %q", text)
+	}
+	if _, isError := toolText(t, responses["7"]); !isError {
+		t.Fatal("expected an empty query to fail")
+	}
+}
+
+func TestAuthorizeGuardsTools(t *testing.T) {
+	backend := newFakeBackend()
+	var checked []api.Permission
+	server := NewServer(backend, Options{Authorize: func(permission api.Permission) error {
+		checked = append(checked, permission)
+		if permission != api.PermissionRead {
+			return errors.New("login required")
+		}
+		return nil
+	}})
+	responses := exchange(t, server,
+		call(1, "list_teams", `{}`),
+		call(2, "send_teammate_message", `{"team":"Docs Crew","agent":"editor","text":"hi"}`),
+	)
+	if _, isError := toolText(t, responses["1"]); isError {
+		t.Fatal("expected reads to be allowed")
+	}
+	if text, isError := toolText(t, responses["2"]); !isError || text != "login required" || len(backend.sent) != 0 {
+		t.Fatalf("expected the message to be refused, got %q %v", text, backend.sent)
+	}
+	if len(checked) != 2 || checked[1] != api.PermissionMessage {
+		t.Fatalf("unexpected permission checks %v", checked)
+	}
+}
+
+func TestMatchSnippet(t *testing.T) {
+	text := strings.Repeat("a", 200) + " Needle " + strings.Repeat("b", 200)
+	snippet, ok := matchSnippet(text, "needle")
+	if !ok || !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "Needle") {
+		t.Fatalf("unexpected snippet %q", snippet)
+	}
+	if _, ok := matchSnippet("nothing here", "needle"); ok {
+		t.Fatal("expected no match")
+	}
+}
@@ -0,0 +1,426 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/api"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+const (
+	defaultStatusEvents = 5
+	defaultSearchLimit  = 20
+	maxSearchLimit      = 100
+	// maxToolText bounds long agent text in tool results.
+	maxToolText = 2000
+	// snippetContext is how much text search results keep around a match.
+	snippetContext = 120
+)
+
+// tool is one MCP tool and the HTTP API permission it needs.
+type tool struct {
+	Name        string
+	Title       string
+	Description string
+	Permission  api.Permission
+	InputSchema map[string]any
+	run         func(ctx context.Context, backend Backend, arguments json.RawMessage) (any, error)
+}
+
+func objectSchema(required []string, properties map[string]any) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringProperty(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+func integerProperty(description string, maximum int) map[string]any {
+	return map[string]any{"type": "integer", "description": description, "minimum": 0, "maximum": maximum}
+}
+
+func monitorTools() []tool {
+	return []tool{
+		{
+			Name:        "list_teams",
+			Title:       "List teams",
+			Description: "List agent teams with member statuses and task counts.",
+			Permission:  api.PermissionRead,
+			InputSchema: objectSchema(nil, map[string]any{
+				"provider": stringProperty("Only teams using this provider: claude, codex or openclaw"),
+				"status":   stringProperty("Only teams with a member in this status, e.g. working"),
+			}),
+			run: runListTeams,
+		},
+		{
+			Name:        "get_agent_status",
+			Title:       "Get agent status",
+			Description: "Show what one teammate, or every member of a team, is doing: status, current task, todos, latest output and recent events.",
+			Permission:  api.PermissionRead,
+			InputSchema: objectSchema([]string{"team"}, map[string]any{
+				"team":   stringProperty("Team name"),
+				"agent":  stringProperty("Agent name; omit for every member"),
+				"events": integerProperty("Recent events per agent, default 5", 50),
+			}),
+			run: runGetAgentStatus,
+		},
+		{
+			Name:        "get_team_tasks",
+			Title:       "Get team tasks",
+			Description: "List a team's tasks with status and owner.",
+			Permission:  api.PermissionRead,
+			InputSchema: objectSchema([]string{"team"}, map[string]any{
+				"team":   stringProperty("Team name"),
+				"status": stringProperty("Only tasks in this status: pending, in_progress or completed"),
+			}),
+			run: runGetTeamTasks,
+		},
+		{
+			Name:        "search_transcripts",
+			Title:       "Search transcripts",
+			Description: "Search teammates' recent messages, responses, thinking and tool calls for text (case-insensitive), newest first.",
+			Permission:  api.PermissionRead,
+			InputSchema: objectSchema([]string{"query"}, map[string]any{
+				"query": stringProperty("Text to find"),
+				"team":  stringProperty("Only this team"),
+				"agent": stringProperty("Only this agent"),
+				"limit": integerProperty("Maximum matches, default 20", maxSearchLimit),
+			}),
+			run: runSearchTranscripts,
+		},
+		{
+			Name:        "send_teammate_message",
+			Title:       "Send teammate message",
+			Description: "Send a message to a teammate's inbox.",
+			Permission:  api.PermissionMessage,
+			InputSchema: objectSchema([]string{"team", "agent", "text"}, map[string]any{
+				"team":  stringProperty("Team name"),
+				"agent": stringProperty("Recipient agent name"),
+				"text":  stringProperty("Message text"),
+			}),
+			run: runSendTeammateMessage,
+		},
+	}
+}
+
+func decodeArguments(raw json.RawMessage, out any) error {
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+type teamSummary struct {
+	Name          string          `json:"name"`
+	Provider      string          `json:"provider,omitempty"`
+	ManagedStatus string          `json:"managed_status,omitempty"`
+	Working       int             `json:"working"`
+	Tasks         int             `json:"tasks"`
+	OpenTasks     int             `json:"open_tasks"`
+	LastActivity  time.Time       `json:"last_activity,omitzero"`
+	Members       []memberSummary `json:"members"`
+}
+
+type memberSummary struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	CurrentTask string `json:"current_task,omitempty"`
+}
+
+func runListTeams(ctx context.Context, backend Backend, raw json.RawMessage) (any, error) {
+	var args struct {
+		Provider string `json:"provider"`
+		Status   string `json:"status"`
+	}
+	if err := decodeArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	state, err := backend.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := []teamSummary{}
+	for _, team := range state.Teams {
+		if args.Provider != "" && !teamUsesProvider(team, args.Provider) {
+			continue
+		}
+		summary := teamSummary{
+			Name:          team.Name,
+			Provider:      team.Provider,
+			ManagedStatus: team.ManagedStatus,
+			Tasks:         len(team.Tasks),
+			Members:       []memberSummary{},
+		}
+		statusMatch := args.Status == "" || strings.EqualFold(team.ManagedStatus, args.Status)
+		for _, member := range team.Members {
+			if member.Status == "working" {
+				summary.Working++
+			}
+			if strings.EqualFold(member.Status, args.Status) {
+				statusMatch = true
+			}
+			if member.LastActivity.After(summary.LastActivity) {
+				summary.LastActivity = member.LastActivity
+			}
+			summary.Members = append(summary.Members, memberSummary{Name: member.Name, Status: member.Status, CurrentTask: member.CurrentTask})
+		}
+		if !statusMatch {
+			continue
+		}
+		for _, task := range team.Tasks {
+			if task.Status != "completed" {
+				summary.OpenTasks++
+			}
+		}
+		teams = append(teams, summary)
+	}
+	return map[string]any{"teams": teams}, nil
+}
+
+func teamUsesProvider(team types.TeamInfo, provider string) bool {
+	if strings.EqualFold(team.Provider, provider) {
+		return true
+	}
+	for _, member := range team.Members {
+		if strings.EqualFold(member.Provider, provider) {
+			return true
+		}
+	}
+	return false
+}
+
+type agentStatus struct {
+	Name           string             `json:"name"`
+	Provider       string             `json:"provider,omitempty"`
+	Status         string             `json:"status"`
+	CurrentTask    string             `json:"current_task,omitempty"`
+	LastActivity   time.Time          `json:"last_activity,omitzero"`
+	LastTool       string             `json:"last_tool,omitempty"`
+	LatestMessage  string             `json:"latest_message,omitempty"`
+	LatestResponse string             `json:"latest_response,omitempty"`
+	Todos          []types.TodoItem   `json:"todos,omitempty"`
+	RecentEvents   []types.AgentEvent `json:"recent_events,omitempty"` // newest first
+}
+
+func runGetAgentStatus(ctx context.Context, backend Backend, raw json.RawMessage) (any, error) {
+	var args struct {
+		Team   string `json:"team"`
+		Agent  string `json:"agent"`
+		Events *int   `json:"events"`
+	}
+	if err := decodeArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	events := defaultStatusEvents
+	if args.Events != nil {
+		events = max(*args.Events, 0)
+	}
+	team, err := findTeam(ctx, backend, args.Team)
+	if err != nil {
+		return nil, err
+	}
+
+	if args.Agent != "" {
+		agent, ok := findAgent(team, args.Agent)
+		if !ok {
+			return nil, fmt.Errorf("agent %q not found in team %q", args.Agent, team.Name)
+		}
+		return map[string]any{"team": team.Name, "agent": newAgentStatus(agent, events)}, nil
+	}
+	agents := make([]agentStatus, 0, len(team.Members))
+	for _, agent := range team.Members {
+		agents = append(agents, newAgentStatus(agent, events))
+	}
+	return map[string]any{"team": team.Name, "agents": agents}, nil
+}
+
+func newAgentStatus(agent types.AgentInfo, events int) agentStatus {
+	status := agentStatus{
+		Name:           agent.Name,
+		Provider:       agent.Provider,
+		Status:         agent.Status,
+		CurrentTask:    agent.CurrentTask,
+		LastActivity:   agent.LastActivity,
+		LastTool:       strings.TrimSpace(agent.LastToolUse + " " + agent.LastToolDetail),
+		LatestMessage:  truncate(agent.LatestMessage, maxToolText),
+		LatestResponse: truncate(agent.LatestResponse, maxToolText),
+		Todos:          agent.Todos,
+	}
+	for _, event := range agent.RecentEvents[:min(events, len(agent.RecentEvents))] {
+		event.Text = truncate(event.Text, maxToolText)
+		status.RecentEvents = append(status.RecentEvents, event)
+	}
+	return status
+}
+
+func runGetTeamTasks(ctx context.Context, backend Backend, raw json.RawMessage) (any, error) {
+	var args struct {
+		Team   string `json:"team"`
+		Status string `json:"status"`
+	}
+	if err := decodeArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	team, err := findTeam(ctx, backend, args.Team)
+	if err != nil {
+		return nil, err
+	}
+	tasks := []types.TaskInfo{}
+	for _, task := range team.Tasks {
+		if args.Status == "" || strings.EqualFold(task.Status, args.Status) {
+			tasks = append(tasks, task)
+		}
+	}
+	return map[string]any{"team": team.Name, "tasks": tasks}, nil
+}
+
+type transcriptMatch struct {
+	Team      string    `json:"team"`
+	Agent     string    `json:"agent"`
+	Kind      string    `json:"kind"`
+	Timestamp time.Time `json:"timestamp,omitzero"`
+	Snippet   string    `json:"snippet"`
+}
+
+func runSearchTranscripts(ctx context.Context, backend Backend, raw json.RawMessage) (any, error) {
+	var args struct {
+		Query string `json:"query"`
+		Team  string `json:"team"`
+		Agent string `json:"agent"`
+		Limit int    `json:"limit"`
+	}
+	if err := decodeArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	query := strings.ToLower(strings.TrimSpace(args.Query))
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	state, err := backend.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matches := []transcriptMatch{}
+	for _, team := range state.Teams {
+		if args.Team != "" && !strings.EqualFold(team.Name, args.Team) {
+			continue
+		}
+		for _, agent := range team.Members {
+			if args.Agent != "" && !agentMatches(agent, args.Agent) {
+				continue
+			}
+			for _, event := range agent.RecentEvents {
+				if snippet, ok := matchSnippet(event.Text, query); ok {
+					matches = append(matches, transcriptMatch{
+						Team: team.Name, Agent: agent.Name, Kind: event.Kind, Timestamp: event.Timestamp, Snippet: snippet,
+					})
+				}
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Timestamp.After(matches[j].Timestamp)
+	})
+	total := len(matches)
+	matches = matches[:min(limit, total)]
+	return map[string]any{"query": args.Query, "total": total, "matches": matches}, nil
+}
+
+// matchSnippet finds query (already lower-cased) in text and returns the
+// surrounding text.
+func matchSnippet(text, query string) (string, bool) {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// Case mapping changed the length; fall back to the whole text.
+		if !strings.Contains(strings.ToLower(text), query) {
+			return "", false
+		}
+		return truncate(text, 2*snippetContext), true
+	}
+	index := strings.Index(string(lower), query)
+	if index < 0 {
+		return "", false
+	}
+	start := len([]rune(string(lower)[:index]))
+	end := start + len([]rune(query))
+	from, to := max(start-snippetContext, 0), min(end+snippetContext, len(runes))
+	snippet := strings.Join(strings.Fields(string(runes[from:to])), " ")
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(runes) {
+		snippet += "…"
+	}
+	return snippet, true
+}
+
+func runSendTeammateMessage(ctx context.Context, backend Backend, raw json.RawMessage) (any, error) {
+	var args struct {
+		Team  string `json:"team"`
+		Agent string `json:"agent"`
+		Text  string `json:"text"`
+	}
+	if err := decodeArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Team) == "" || strings.TrimSpace(args.Agent) == "" || strings.TrimSpace(args.Text) == "" {
+		return nil, fmt.Errorf("team, agent and text are required")
+	}
+	if err := backend.SendAgentMessage(ctx, args.Team, args.Agent, args.Text); err != nil {
+		return nil, err
+	}
+	return map[string]any{"sent": true, "team": args.Team, "agent": args.Agent}, nil
+}
+
+func findTeam(ctx context.Context, backend Backend, name string) (types.TeamInfo, error) {
+	if strings.TrimSpace(name) == "" {
+		return types.TeamInfo{}, fmt.Errorf("team is required")
+	}
+	state, err := backend.State(ctx)
+	if err != nil {
+		return types.TeamInfo{}, err
+	}
+	for _, team := range state.Teams {
+		if strings.EqualFold(team.Name, name) {
+			return team, nil
+		}
+	}
+	return types.TeamInfo{}, fmt.Errorf("team %q not found", name)
+}
+
+func findAgent(team types.TeamInfo, name string) (types.AgentInfo, bool) {
+	for _, agent := range team.Members {
+		if agentMatches(agent, name) {
+			return agent, true
+		}
+	}
+	return types.AgentInfo{}, false
+}
+
+func agentMatches(agent types.AgentInfo, name string) bool {
+	return strings.EqualFold(agent.Name, name) || agent.AgentID == name
+}
+
+func truncate(text string, limit int) string {
+	if runes := []rune(text); len(runes) > limit {
+		return string(runes[:limit-1]) + "…"
+	}
+	return text
+}
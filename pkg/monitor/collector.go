@@ -18,6 +18,7 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/liaoweijun/agent-team-monitor/pkg/narrative"
 	"github.com/liaoweijun/agent-team-monitor/pkg/parser"
+	"github.com/liaoweijun/agent-team-monitor/pkg/redact"
 	"github.com/liaoweijun/agent-team-monitor/pkg/types"
 )
 
@@ -38,34 +39,45 @@ var teamTokenStopWords = map[string]struct{}{
 	"user":      {},
 	"code":      {},
 }
-var exposeAbsolutePaths = readBoolEnv("ATM_EXPOSE_ABS_PATHS", false)
-var discoveryMetricsLoggingEnabled = readBoolEnv("ATM_DISCOVERY_METRICS", false)
 
-const projectDiscoveryMaxAge = 2 * time.Hour
-const codexSessionDiscoveryMaxAge = 8 * time.Hour
-const codexWorkingRecentThreshold = 2 * time.Minute
-const openClawSessionDiscoveryMaxAge = 8 * time.Hour
-const openClawWorkingRecentThreshold = 2 * time.Minute
 const discoveryMetricsLogInterval = 30 * time.Second
 const discoveryMetricsSlowThreshold = 500 * time.Millisecond
+const logOffsetsSaveInterval = time.Minute
 
 // CollectorOptions controls collector behavior.
 type CollectorOptions struct {
 	Provider ProviderMode
+	// Settings replaces DefaultSettings; its Provider, when empty, comes
+	// from Provider.
+	Settings *Settings
+	// Redactor masks secrets in agent text before state is published; nil
+	// publishes text as parsed.
+	Redactor *redact.Redactor
 }
 
 // Collector collects and aggregates monitoring data
 type Collector struct {
 	processMonitor          *ProcessMonitor
 	fsMonitor               *FileSystemMonitor
-	provider                ProviderMode
+	started                 bool
+	settings                Settings
+	containerRoots          []containerRoot
+	settingsMu              sync.RWMutex // Guards settings, containerRoots, fsMonitor, started and redactor
+	pollReset               chan struct{}
+	redactor                *redact.Redactor
 	state                   *types.MonitorState
 	stateMutex              sync.RWMutex
+	stateVersion            uint64                      // Counts collections; reported as MonitorState.Version
+	sessionPIDs             map[int32]string            // Claude session IDs by PID, from sessions/<pid>.json; guarded by stateMutex
+	containerSessionPIDs    map[string]map[int32]string // The same for container roots, by root label and PID inside the container
 	updateChan              chan struct{}
 	stopChan                chan struct{}
 	stopOnce                sync.Once
 	lastDiscoveryMetrics    parser.DiscoveryMetrics
 	lastDiscoveryMetricsLog time.Time
+	lastLogOffsetsSave      time.Time // Guarded by stateMutex
+	health                  collectorHealthTracker
+	watchdog                watchdog
 }
 
 // NewCollector creates a new data collector
@@ -75,11 +87,21 @@ func NewCollector() (*Collector, error) {
 
 // NewCollectorWithOptions creates a collector with explicit options.
 func NewCollectorWithOptions(options CollectorOptions) (*Collector, error) {
-	provider := normalizeProviderMode(options.Provider)
+	settings := DefaultSettings()
+	if options.Settings != nil {
+		settings = *options.Settings
+	}
+	if settings.Provider == "" {
+		settings.Provider = options.Provider
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
 
 	c := &Collector{
 		processMonitor: NewProcessMonitor(),
-		provider:       provider,
+		settings:       settings.normalized(),
+		redactor:       options.Redactor,
 		state: &types.MonitorState{
 			Teams:     []types.TeamInfo{},
 			Processes: []types.ProcessInfo{},
@@ -87,11 +109,33 @@ func NewCollectorWithOptions(options CollectorOptions) (*Collector, error) {
 		},
 		updateChan: make(chan struct{}, 1),
 		stopChan:   make(chan struct{}),
+		pollReset:  make(chan struct{}, 1),
 	}
 
-	// Create filesystem monitor with callback
-	fsMonitor, err := NewFileSystemMonitor(FileSystemMonitorOptions{
-		Provider: provider,
+	fsMonitor, err := c.newFileSystemMonitor(c.settings)
+	if err != nil {
+		return nil, err
+	}
+	c.fsMonitor = fsMonitor
+
+	if path := c.settings.LogOffsetsFile; path != "" {
+		if err := parser.LoadLogOffsets(path); err != nil {
+			log.Printf("Ignoring log offsets in %s: %v", path, err)
+		}
+		c.lastLogOffsetsSave = time.Now()
+	}
+
+	return c, nil
+}
+
+// newFileSystemMonitor creates a filesystem monitor for settings that
+// triggers a state update on every change.
+func (c *Collector) newFileSystemMonitor(settings Settings) (*FileSystemMonitor, error) {
+	return NewFileSystemMonitor(FileSystemMonitorOptions{
+		Provider:     settings.Provider,
+		ClaudeDirs:   rootDirs(settings.ClaudeRoots),
+		CodexDirs:    rootDirs(settings.CodexRoots),
+		OpenClawDirs: rootDirs(settings.OpenClawRoots),
 	}, func(event fsnotify.Event) {
 		select {
 		case <-c.stopChan:
@@ -106,18 +150,16 @@ func NewCollectorWithOptions(options CollectorOptions) (*Collector, error) {
 		default:
 		}
 	})
-	if err != nil {
-		return nil, err
-	}
-	c.fsMonitor = fsMonitor
-
-	return c, nil
 }
 
 // Start begins collecting data
 func (c *Collector) Start() error {
 	// Start filesystem monitoring
-	if err := c.fsMonitor.Start(); err != nil {
+	c.settingsMu.Lock()
+	err := c.fsMonitor.Start()
+	c.started = err == nil
+	c.settingsMu.Unlock()
+	if err != nil {
 		return err
 	}
 
@@ -133,15 +175,27 @@ func (c *Collector) Start() error {
 	return nil
 }
 
+// Refresh collects state once, for callers that read a snapshot without
+// starting the watchers.
+func (c *Collector) Refresh() {
+	c.updateState()
+}
+
 // periodicUpdate updates state periodically
 func (c *Collector) periodicUpdate() {
-	ticker := time.NewTicker(5 * time.Second)
+	interval := c.Settings().PollInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-c.stopChan:
 			return
+		case <-c.pollReset:
+			if next := c.Settings().PollInterval; next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
 		case <-ticker.C:
 			c.updateState()
 		}
@@ -171,47 +225,97 @@ func (c *Collector) updateState() {
 	c.stateMutex.Lock()
 	defer c.stateMutex.Unlock()
 
+	startedAt := time.Now()
+	defer func() {
+		c.health.recordUpdate(startedAt, time.Since(startedAt))
+	}()
+	settings := c.Settings()
+
 	// Collect process information
-	processes, err := c.processMonitor.FindProcesses(c.provider)
+	processes, err := c.processMonitor.FindProcesses(settings.Provider)
 	if err != nil {
+		c.health.recordParseError("processes")
 		log.Printf("Error finding monitored processes: %v", err)
 		processes = []types.ProcessInfo{}
 	}
+	if c.addContainerRoots(processes) {
+		settings = c.Settings()
+	}
 
-	homeDir, _ := os.UserHomeDir()
 	allTeams := make([]types.TeamInfo, 0)
+	c.sessionPIDs = map[int32]string{}
+	c.containerSessionPIDs = map[string]map[int32]string{}
 
-	if c.provider.IncludesClaude() {
-		claudeTeams := c.collectClaudeTeams(homeDir)
-		allTeams = append(allTeams, claudeTeams...)
+	if settings.Provider.IncludesClaude() {
+		allTeams = append(allTeams, collectRoots(settings.ClaudeRoots, c.collectClaudeTeams)...)
 	}
-	if c.provider.IncludesCodex() {
-		codexTeams := c.collectCodexTeams(homeDir)
-		allTeams = append(allTeams, codexTeams...)
+	if settings.Provider.IncludesCodex() {
+		allTeams = append(allTeams, collectRoots(settings.CodexRoots, c.collectCodexTeams)...)
 	}
-	if c.provider.IncludesOpenClaw() {
-		openClawTeams := c.collectOpenClawTeams(homeDir)
-		allTeams = append(allTeams, openClawTeams...)
+	if settings.Provider.IncludesOpenClaw() {
+		allTeams = append(allTeams, collectRoots(settings.OpenClawRoots, c.collectOpenClawTeams)...)
 	}
 
 	sort.SliceStable(allTeams, func(i, j int) bool {
 		return teamSortKey(allTeams[i]) < teamSortKey(allTeams[j])
 	})
 
+	linkContainerSessions(processes, c.containerSessionPIDs)
+	linkProcesses(processes, allTeams, c.sessionPIDs)
+	for _, job := range c.watchdog.inspect(time.Now(), settings.Watchdog, processes, allTeams) {
+		go c.applyRemedy(job)
+	}
+	c.redactState(allTeams, processes)
+
 	// Update state
 	c.state.Teams = allTeams
 	c.state.Processes = processes
 	c.state.UpdatedAt = time.Now()
+	c.stateVersion++
+
+	if settings.LogOffsetsFile != "" && time.Since(c.lastLogOffsetsSave) >= logOffsetsSaveInterval {
+		c.lastLogOffsetsSave = time.Now()
+		saveLogOffsets(settings.LogOffsetsFile)
+	}
 }
 
-func (c *Collector) collectClaudeTeams(homeDir string) []types.TeamInfo {
-	teamsDir := filepath.Join(homeDir, ".claude", "teams")
-	tasksDir := filepath.Join(homeDir, ".claude", "tasks")
-	projectsDir := filepath.Join(homeDir, ".claude", "projects")
-	sessionsDir := filepath.Join(homeDir, ".claude", "sessions")
+func saveLogOffsets(path string) {
+	if err := parser.SaveLogOffsets(path); err != nil {
+		log.Printf("Error saving log offsets to %s: %v", path, err)
+	}
+}
+
+// collectRoots collects every root on its own and tags the teams with the
+// root's label. Teams from a labeled root are renamed <team>@<label> so
+// same-named teams from different roots never merge.
+func collectRoots(roots []Root, collect func(dir string) []types.TeamInfo) []types.TeamInfo {
+	var teams []types.TeamInfo
+	for _, root := range roots {
+		rootTeams := collect(root.Dir)
+		if root.Label != "" {
+			for i := range rootTeams {
+				rootTeams[i].Root = root.Label
+				rootTeams[i].Name += "@" + root.Label
+				if rootTeams[i].SortKey != "" {
+					rootTeams[i].SortKey += "@" + root.Label
+				}
+			}
+		}
+		teams = append(teams, rootTeams...)
+	}
+	return teams
+}
+
+func (c *Collector) collectClaudeTeams(claudeDir string) []types.TeamInfo {
+	settings := c.Settings()
+	teamsDir := filepath.Join(claudeDir, "teams")
+	tasksDir := filepath.Join(claudeDir, "tasks")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	sessionsDir := filepath.Join(claudeDir, "sessions")
 
 	teams, err := parser.ScanTeams(teamsDir)
 	if err != nil {
+		c.health.recordParseError("teams")
 		log.Printf("Error scanning teams: %v", err)
 		teams = []types.TeamInfo{}
 	}
@@ -219,9 +323,10 @@ func (c *Collector) collectClaudeTeams(homeDir string) []types.TeamInfo {
 	teams = mergeInboxOnlyTeams(teams, teamsDir)
 
 	discoveryStart := time.Now()
-	discoveredTeams, err := parser.DiscoverProjectTeams(projectsDir, projectDiscoveryMaxAge)
+	discoveredTeams, err := parser.DiscoverProjectTeams(projectsDir, settings.ProjectDiscoveryMaxAge)
 	discoveryElapsed := time.Since(discoveryStart)
 	if err != nil {
+		c.health.recordParseError("project_discovery")
 		log.Printf("Error discovering teams from projects: %v", err)
 	} else {
 		teams = mergeDiscoveredProjectTeams(teams, discoveredTeams)
@@ -230,15 +335,34 @@ func (c *Collector) collectClaudeTeams(homeDir string) []types.TeamInfo {
 
 	standaloneSessions, err := parser.DiscoverClaudeSessions(sessionsDir, 0)
 	if err != nil {
+		c.health.recordParseError("claude_sessions")
 		log.Printf("Error discovering claude sessions: %v", err)
 	} else {
 		teams = mergeStandaloneClaudeSessions(teams, standaloneSessions)
+		sessionPIDs := c.sessionPIDs
+		if label, ok := c.containerRootForDir(claudeDir); ok {
+			// PIDs from inside a container must not match host processes.
+			sessionPIDs = map[int32]string{}
+			if c.containerSessionPIDs == nil {
+				c.containerSessionPIDs = map[string]map[int32]string{}
+			}
+			c.containerSessionPIDs[label] = sessionPIDs
+		} else if sessionPIDs == nil {
+			sessionPIDs = map[int32]string{}
+			c.sessionPIDs = sessionPIDs
+		}
+		for _, session := range standaloneSessions {
+			if session.PID > 0 && session.SessionID != "" {
+				sessionPIDs[session.PID] = session.SessionID
+			}
+		}
 	}
 
 	for i := range teams {
 		// Load visible tasks (excluding internal tasks)
 		tasks, err := parser.ScanTasks(tasksDir, teams[i].Name)
 		if err != nil {
+			c.health.recordParseError("tasks")
 			log.Printf("Error scanning tasks for team %s: %v", teams[i].Name, err)
 			continue
 		}
@@ -250,6 +374,7 @@ func (c *Collector) collectClaudeTeams(homeDir string) []types.TeamInfo {
 		// Load all tasks (including internal) for status calculation
 		allTasks, err := parser.ScanAllTasks(tasksDir, teams[i].Name)
 		if err != nil {
+			c.health.recordParseError("tasks")
 			log.Printf("Error scanning all tasks for team %s: %v", teams[i].Name, err)
 			allTasks = tasks // Fallback to visible tasks
 		}
@@ -269,13 +394,14 @@ func (c *Collector) collectClaudeTeams(homeDir string) []types.TeamInfo {
 		c.updateAgentCommandCapabilities(&teams[i], teamsDir)
 	}
 
-	return filterStaleTeams(teams, time.Hour, tasksDir)
+	return filterStaleTeams(teams, settings.StaleTeamThreshold, tasksDir)
 }
 
-func (c *Collector) collectCodexTeams(homeDir string) []types.TeamInfo {
-	sessionsDir := filepath.Join(homeDir, ".codex", "sessions")
-	discovered, err := parser.DiscoverCodexSessions(sessionsDir, codexSessionDiscoveryMaxAge)
+func (c *Collector) collectCodexTeams(codexDir string) []types.TeamInfo {
+	sessionsDir := filepath.Join(codexDir, "sessions")
+	discovered, err := parser.DiscoverCodexSessions(sessionsDir, c.Settings().CodexSessionMaxAge)
 	if err != nil {
+		c.health.recordParseError("codex_sessions")
 		log.Printf("Error discovering codex sessions: %v", err)
 		return []types.TeamInfo{}
 	}
@@ -283,17 +409,19 @@ func (c *Collector) collectCodexTeams(homeDir string) []types.TeamInfo {
 	return c.buildCodexTeams(discovered, time.Now())
 }
 
-func (c *Collector) collectOpenClawTeams(homeDir string) []types.TeamInfo {
-	agentsDir := filepath.Join(homeDir, ".openclaw", "agents")
-	discovered, err := parser.DiscoverOpenClawSessions(agentsDir, openClawSessionDiscoveryMaxAge)
+func (c *Collector) collectOpenClawTeams(stateDir string) []types.TeamInfo {
+	settings := c.Settings()
+	agentsDir := filepath.Join(stateDir, "agents")
+	discovered, err := parser.DiscoverOpenClawSessions(agentsDir, settings.OpenClawSessionMaxAge)
 	if err != nil {
+		c.health.recordParseError("openclaw_sessions")
 		log.Printf("Error discovering openclaw sessions: %v", err)
 		discovered = nil
 	}
 
-	stateDir := filepath.Join(homeDir, ".openclaw")
-	subagentRuns, runsErr := parser.DiscoverOpenClawSubagentRuns(stateDir, openClawSessionDiscoveryMaxAge)
+	subagentRuns, runsErr := parser.DiscoverOpenClawSubagentRuns(stateDir, settings.OpenClawSessionMaxAge)
 	if runsErr != nil {
+		c.health.recordParseError("openclaw_subagent_runs")
 		log.Printf("Error discovering openclaw subagent runs: %v", runsErr)
 	}
 
@@ -335,7 +463,7 @@ func (c *Collector) collectOpenClawTeams(homeDir string) []types.TeamInfo {
 		}
 
 		status := "idle"
-		if !lastActive.IsZero() && now.Sub(lastActive) <= openClawWorkingRecentThreshold {
+		if !lastActive.IsZero() && now.Sub(lastActive) <= settings.WorkingRecentThreshold {
 			status = "working"
 		}
 
@@ -395,7 +523,7 @@ func (c *Collector) collectOpenClawTeams(homeDir string) []types.TeamInfo {
 		status := "idle"
 		if !run.EndedAt.IsZero() {
 			status = "completed"
-		} else if !lastActive.IsZero() && now.Sub(lastActive) <= openClawWorkingRecentThreshold {
+		} else if !lastActive.IsZero() && now.Sub(lastActive) <= settings.WorkingRecentThreshold {
 			status = "working"
 		}
 
@@ -534,9 +662,10 @@ func (c *Collector) buildCodexTeams(discovered []parser.CodexSessionDiscovery, n
 		return []types.TeamInfo{}
 	}
 
+	workingRecent := c.Settings().WorkingRecentThreshold
 	envelopes := make([]codexSessionEnvelope, 0, len(discovered))
 	for _, session := range discovered {
-		envelopes = append(envelopes, buildCodexSessionEnvelope(session, now))
+		envelopes = append(envelopes, buildCodexSessionEnvelope(session, now, workingRecent))
 	}
 
 	unions := newCodexUnionFind(len(envelopes))
@@ -585,7 +714,7 @@ func (c *Collector) buildCodexTeams(discovered []parser.CodexSessionDiscovery, n
 	return teams
 }
 
-func buildCodexSessionEnvelope(session parser.CodexSessionDiscovery, now time.Time) codexSessionEnvelope {
+func buildCodexSessionEnvelope(session parser.CodexSessionDiscovery, now time.Time, workingRecent time.Duration) codexSessionEnvelope {
 	lastActive := session.LastActiveAt
 	if lastActive.IsZero() {
 		lastActive = session.StartedAt
@@ -600,7 +729,7 @@ func buildCodexSessionEnvelope(session parser.CodexSessionDiscovery, now time.Ti
 	}
 
 	status := "idle"
-	if !lastActive.IsZero() && now.Sub(lastActive) <= codexWorkingRecentThreshold {
+	if !lastActive.IsZero() && now.Sub(lastActive) <= workingRecent {
 		status = "working"
 	}
 
@@ -873,7 +1002,7 @@ func normalizeComparablePath(raw string) string {
 }
 
 func (c *Collector) logDiscoveryMetrics(elapsed time.Duration, discovered []parser.ProjectTeamDiscovery) {
-	if !discoveryMetricsLoggingEnabled {
+	if !c.Settings().DiscoveryMetrics {
 		return
 	}
 
@@ -1582,6 +1711,7 @@ func (c *Collector) loadAgentInboxes(team *types.TeamInfo, teamsDir string) {
 		agent := &team.Members[i]
 		message, err := parser.ParseInbox(teamsDir, inboxTeamName, agent.Name)
 		if err != nil {
+			c.health.recordParseError("inbox")
 			log.Printf("Error parsing inbox for %s: %v", agent.Name, err)
 			continue
 		}
@@ -1593,6 +1723,7 @@ func (c *Collector) loadAgentInboxes(team *types.TeamInfo, teamsDir string) {
 
 		messages, err := parser.ParseInboxMessages(teamsDir, inboxTeamName, agent.Name)
 		if err != nil {
+			c.health.recordParseError("inbox")
 			log.Printf("Error parsing inbox history for %s: %v", agent.Name, err)
 			continue
 		}
@@ -1708,8 +1839,11 @@ func (c *Collector) SendAgentMessage(teamName, agentName, text string) error {
 		return fmt.Errorf("missing inbox team target")
 	}
 
-	homeDir, _ := os.UserHomeDir()
-	teamsDir := filepath.Join(homeDir, ".claude", "teams")
+	claudeDir, _, err := c.claudeTeamRoot(teamSnapshot.Name)
+	if err != nil {
+		return err
+	}
+	teamsDir := filepath.Join(claudeDir, "teams")
 	inboxPath := filepath.Join(teamsDir, teamSnapshot.InboxTeamName, "inboxes", agentSnapshot.Name+".json")
 	if err := appendInboxMessage(inboxPath, "agent-team-monitor", text); err != nil {
 		return err
@@ -1764,8 +1898,7 @@ func appendInboxMessage(path, from, text string) error {
 
 // loadAgentActivities loads recent activities from agent jsonl logs
 func (c *Collector) loadAgentActivities(team *types.TeamInfo, projectsDir string) {
-	homeDir, _ := os.UserHomeDir()
-	todosDir := filepath.Join(homeDir, ".claude", "todos")
+	todosDir := filepath.Join(filepath.Dir(projectsDir), "todos")
 	leadLogPath, _ := parser.FindLeadSessionLogFile(projectsDir, team.LeadSessionID)
 
 	for i := range team.Members {
@@ -1790,6 +1923,7 @@ func (c *Collector) loadAgentActivities(team *types.TeamInfo, projectsDir string
 		// Parse agent activity
 		activity, err := parser.ParseAgentActivity(logPath)
 		if err != nil {
+			c.health.recordParseError("activity")
 			log.Printf("Error parsing activity for %s: %v", agent.Name, err)
 			continue
 		}
@@ -1809,6 +1943,7 @@ func (c *Collector) loadAgentActivities(team *types.TeamInfo, projectsDir string
 		if sessionID != "" {
 			todos, err := parser.LoadTodosForSession(todosDir, sessionID)
 			if err != nil {
+				c.health.recordParseError("todos")
 				log.Printf("Error loading todos for %s (session %s): %v", agent.Name, sessionID, err)
 			}
 			if len(todos) == 0 {
@@ -1931,10 +2066,22 @@ func (c *Collector) GetState() types.MonitorState {
 
 	stateCopy := types.MonitorState{
 		UpdatedAt: c.state.UpdatedAt,
+		Version:   c.stateVersion,
 		Processes: append([]types.ProcessInfo(nil), c.state.Processes...),
 		Teams:     make([]types.TeamInfo, len(c.state.Teams)),
 	}
 
+	exposeAbsolutePaths := c.Settings().ExposeAbsolutePaths
+	if !exposeAbsolutePaths {
+		for i := range stateCopy.Processes {
+			stateCopy.Processes[i].Cwd = sanitizeDisplayPath(stateCopy.Processes[i].Cwd)
+			if container := stateCopy.Processes[i].Container; container != nil && container.DataDir != "" {
+				containerCopy := *container
+				containerCopy.DataDir = sanitizeDisplayPath(containerCopy.DataDir)
+				stateCopy.Processes[i].Container = &containerCopy
+			}
+		}
+	}
 	for i, team := range c.state.Teams {
 		teamCopy := team
 		teamCopy.Tasks = append([]types.TaskInfo(nil), team.Tasks...)
@@ -1973,6 +2120,7 @@ func convertActivityEvents(events []parser.AgentActivityEvent) []types.AgentEven
 			Title:     event.Title,
 			Text:      event.Text,
 			Source:    "activity_log",
+			ToolID:    event.ToolID,
 			Timestamp: event.Timestamp,
 		})
 	}
@@ -1991,6 +2139,7 @@ func convertCodexEvents(events []parser.CodexSessionEvent) []types.AgentEvent {
 			Title:     event.Title,
 			Text:      event.Text,
 			Source:    "codex_session",
+			ToolID:    event.ToolID,
 			Timestamp: event.Timestamp,
 		})
 	}
@@ -2118,11 +2267,39 @@ func sanitizeDisplayPath(raw string) string {
 	return cleaned
 }
 
+// claudeTeamRoot returns the Claude root directory holding a published team
+// and the team's name inside it. Names not in the current state resolve to
+// the unlabeled root.
+func (c *Collector) claudeTeamRoot(teamName string) (string, string, error) {
+	label := ""
+	c.stateMutex.RLock()
+	for _, team := range c.state.Teams {
+		if team.Name == teamName {
+			label = team.Root
+			break
+		}
+	}
+	c.stateMutex.RUnlock()
+
+	for _, root := range c.Settings().ClaudeRoots {
+		if root.Label == label {
+			return root.Dir, strings.TrimSuffix(teamName, "@"+label), nil
+		}
+	}
+	if label == "" {
+		return "", "", fmt.Errorf("team %q not found", teamName)
+	}
+	return "", "", fmt.Errorf("root %q of team %q is no longer monitored", label, teamName)
+}
+
 // DeleteTeam removes a team's config and task directories.
 func (c *Collector) DeleteTeam(teamName string) error {
-	homeDir, _ := os.UserHomeDir()
-	teamsDir := filepath.Join(homeDir, ".claude", "teams", teamName)
-	tasksDir := filepath.Join(homeDir, ".claude", "tasks", teamName)
+	claudeDir, dirName, err := c.claudeTeamRoot(teamName)
+	if err != nil {
+		return err
+	}
+	teamsDir := filepath.Join(claudeDir, "teams", dirName)
+	tasksDir := filepath.Join(claudeDir, "tasks", dirName)
 
 	// Remove team config directory
 	if err := os.RemoveAll(teamsDir); err != nil && !os.IsNotExist(err) {
@@ -2149,7 +2326,12 @@ func (c *Collector) Stop() error {
 	var err error
 	c.stopOnce.Do(func() {
 		close(c.stopChan)
+		c.settingsMu.RLock()
+		defer c.settingsMu.RUnlock()
 		err = c.fsMonitor.Stop()
+		if c.settings.LogOffsetsFile != "" {
+			saveLogOffsets(c.settings.LogOffsetsFile)
+		}
 	})
 	return err
 }
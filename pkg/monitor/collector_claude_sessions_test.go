@@ -50,7 +50,7 @@ func TestCollectClaudeTeams_IncludesStandaloneSessionFromClaudeSessions(t *testi
 	}
 
 	collector := &Collector{}
-	teams := collector.collectClaudeTeams(root)
+	teams := collector.collectClaudeTeams(filepath.Join(root, ".claude"))
 	if len(teams) != 1 {
 		t.Fatalf("expected 1 team, got %d", len(teams))
 	}
@@ -0,0 +1,413 @@
+package monitor
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// procDir is where process information is read from.
+var procDir = "/proc"
+
+// Container ID patterns in cgroup paths, e.g. /docker/<id>,
+// /system.slice/docker-<id>.scope, /machine.slice/libpod-<id>.scope or
+// /kubepods/.../cri-containerd-<id>.scope, and in mount sources such as
+// /var/lib/docker/containers/<id>/hostname or
+// .../overlay-containers/<id>/userdata/hostname.
+var (
+	cgroupContainerPattern = regexp.MustCompile(`(?:^|/)(docker|libpod|cri-containerd|crio)[-/]([0-9a-f]{64})(?:\.scope)?(?:/|$)`)
+	cgroupIDPattern        = regexp.MustCompile(`/([0-9a-f]{64})(?:\.scope)?$`)
+	mountContainerPattern  = regexp.MustCompile(`/(containers|overlay-containers)/([0-9a-f]{64})/`)
+)
+
+var containerRuntimes = map[string]string{
+	"docker":             "docker",
+	"containers":         "docker",
+	"libpod":             "podman",
+	"overlay-containers": "podman",
+	"cri-containerd":     "containerd",
+	"crio":               "cri-o",
+}
+
+// mountEntry is one line of /proc/<pid>/mountinfo.
+type mountEntry struct {
+	device     string // major:minor
+	root       string // path within the device's filesystem
+	mountPoint string
+}
+
+// containerScan caches what is shared by every process of one scan.
+type containerScan struct {
+	self       string // container the monitor itself runs in, if any
+	hostMounts []mountEntry
+	names      map[string]string // container ID -> name
+}
+
+func newContainerScan() *containerScan {
+	scan := &containerScan{names: map[string]string{}}
+	if data, err := os.ReadFile(filepath.Join(procDir, "self", "cgroup")); err == nil {
+		scan.self, _ = parseCgroupContainer(string(data))
+	}
+	if data, err := os.ReadFile(filepath.Join(procDir, "self", "mountinfo")); err == nil {
+		scan.hostMounts = parseMountInfo(string(data))
+	}
+	return scan
+}
+
+// inspect reports the container pid runs in, or nil when it runs in the
+// same one as the monitor. For a provider data directory bind-mounted from
+// the host, DataDir is its host path.
+func (s *containerScan) inspect(pid int32, provider string, environ []string) *types.ContainerInfo {
+	procPath := filepath.Join(procDir, strconv.Itoa(int(pid)))
+	data, err := os.ReadFile(filepath.Join(procPath, "cgroup"))
+	if err != nil {
+		return nil
+	}
+	id, runtime := parseCgroupContainer(string(data))
+
+	var mounts []mountEntry
+	if data, err := os.ReadFile(filepath.Join(procPath, "mountinfo")); err == nil {
+		mounts = parseMountInfo(string(data))
+	}
+	var metadataPath string
+	if id == "" {
+		// With a private cgroup namespace the cgroup path is just "/", but
+		// the runtime's bind-mounted /etc/hostname still names the container.
+		for _, mount := range mounts {
+			if match := mountContainerPattern.FindStringSubmatch(mount.root); match != nil {
+				id, runtime = match[2], containerRuntimes[match[1]]
+				break
+			}
+		}
+	}
+	if id == "" || id == s.self {
+		return nil
+	}
+	for _, mount := range mounts {
+		if strings.Contains(mount.root, id) && (mount.mountPoint == "/etc/hostname" || mount.mountPoint == "/etc/hosts") {
+			metadataPath = hostPathOf(mount, s.hostMounts)
+			break
+		}
+	}
+
+	info := &types.ContainerInfo{ID: id, Runtime: runtime, Name: s.name(id, metadataPath)}
+	if data, err := os.ReadFile(filepath.Join(procPath, "status")); err == nil {
+		info.PID = namespacePID(string(data))
+	}
+	for _, dir := range containerDataDirs(provider, environ, mounts) {
+		if hostDir := boundHostPath(dir, mounts, s.hostMounts); hostDir != "" {
+			if stat, err := os.Stat(hostDir); err == nil && stat.IsDir() {
+				info.DataDir = hostDir
+				break
+			}
+		}
+	}
+	return info
+}
+
+// name looks the container's name up in the runtime's metadata next to
+// its hostname file: config.v2.json for Docker, containers.json for
+// Podman. It is empty when the metadata cannot be read, typically for
+// lack of permission.
+func (s *containerScan) name(id, hostnamePath string) string {
+	if name, ok := s.names[id]; ok {
+		return name
+	}
+	var name string
+	if hostnamePath != "" {
+		dir := filepath.Dir(hostnamePath)
+		if data, err := os.ReadFile(filepath.Join(dir, "config.v2.json")); err == nil {
+			var config struct{ Name string }
+			if json.Unmarshal(data, &config) == nil {
+				name = strings.TrimPrefix(config.Name, "/")
+			}
+		} else if filepath.Base(dir) == "userdata" {
+			if data, err := os.ReadFile(filepath.Join(dir, "..", "..", "containers.json")); err == nil {
+				var containers []struct {
+					ID    string   `json:"id"`
+					Names []string `json:"names"`
+				}
+				if json.Unmarshal(data, &containers) == nil {
+					for _, container := range containers {
+						if container.ID == id && len(container.Names) > 0 {
+							name = container.Names[0]
+						}
+					}
+				}
+			}
+		}
+	}
+	s.names[id] = name
+	return name
+}
+
+// parseCgroupContainer finds a container ID in /proc/<pid>/cgroup.
+func parseCgroupContainer(data string) (id, runtime string) {
+	for _, line := range strings.Split(data, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if match := cgroupContainerPattern.FindStringSubmatch(parts[2]); match != nil {
+			return match[2], containerRuntimes[match[1]]
+		}
+		if match := cgroupIDPattern.FindStringSubmatch(parts[2]); match != nil {
+			id = match[1]
+		}
+	}
+	return id, ""
+}
+
+// parseMountInfo reads /proc/<pid>/mountinfo; see proc(5).
+func parseMountInfo(data string) []mountEntry {
+	var mounts []mountEntry
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		separator := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				separator = i
+				break
+			}
+		}
+		if separator < 0 {
+			continue
+		}
+		mounts = append(mounts, mountEntry{
+			device:     fields[2],
+			root:       unescapeMountPath(fields[3]),
+			mountPoint: unescapeMountPath(fields[4]),
+		})
+	}
+	return mounts
+}
+
+// unescapeMountPath decodes the octal escapes mountinfo uses for spaces,
+// tabs, newlines and backslashes.
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if code, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// containerDataDirs lists where provider keeps its data inside the
+// container: the directory named by the environment, ~/.claude or
+// ~/.codex, and any mount point named like those.
+func containerDataDirs(provider string, environ []string, mounts []mountEntry) []string {
+	var override, base string
+	switch provider {
+	case "claude":
+		override, base = "CLAUDE_CONFIG_DIR", ".claude"
+	case "codex":
+		override, base = "CODEX_HOME", ".codex"
+	default:
+		return nil
+	}
+	var dirs []string
+	for _, entry := range environ {
+		key, value, _ := strings.Cut(entry, "=")
+		switch {
+		case key == override && filepath.IsAbs(value):
+			dirs = append([]string{filepath.Clean(value)}, dirs...)
+		case key == "HOME" && filepath.IsAbs(value):
+			dirs = append(dirs, filepath.Join(value, base))
+		}
+	}
+	for _, mount := range mounts {
+		if filepath.Base(mount.mountPoint) == base {
+			dirs = append(dirs, mount.mountPoint)
+		}
+	}
+	return dirs
+}
+
+// boundHostPath returns the host path of dir inside a container when dir
+// lies on a bind mount, and "" when it is part of the container's own
+// filesystem.
+func boundHostPath(dir string, mounts, hostMounts []mountEntry) string {
+	var best *mountEntry
+	for i := range mounts {
+		if pathWithin(dir, mounts[i].mountPoint) && (best == nil || len(mounts[i].mountPoint) > len(best.mountPoint)) {
+			best = &mounts[i]
+		}
+	}
+	if best == nil || best.mountPoint == "/" {
+		return ""
+	}
+	host := hostPathOf(*best, hostMounts)
+	if host == "" {
+		return ""
+	}
+	rel, _ := filepath.Rel(best.mountPoint, dir)
+	return filepath.Join(host, rel)
+}
+
+// hostPathOf maps the root of a container mount to the host path where
+// the same directory of the same device is mounted.
+func hostPathOf(mount mountEntry, hostMounts []mountEntry) string {
+	var best *mountEntry
+	for i := range hostMounts {
+		host := &hostMounts[i]
+		if host.device != mount.device || !pathWithin(mount.root, host.root) {
+			continue
+		}
+		if best == nil || len(host.root) > len(best.root) {
+			best = host
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	rel, _ := filepath.Rel(best.root, mount.root)
+	return filepath.Join(best.mountPoint, rel)
+}
+
+func pathWithin(path, dir string) bool {
+	return dir == "/" || path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// namespacePID reads the innermost PID from the NSpid line of
+// /proc/<pid>/status: the PID the process has inside its container.
+func namespacePID(status string) int32 {
+	for _, line := range strings.Split(status, "\n") {
+		if !strings.HasPrefix(line, "NSpid:") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "NSpid:"))
+		if len(fields) < 2 {
+			return 0
+		}
+		pid, _ := strconv.ParseInt(fields[len(fields)-1], 10, 32)
+		return int32(pid)
+	}
+	return 0
+}
+
+// containerRoot is a data root added for a container's bind-mounted data
+// directory.
+type containerRoot struct {
+	provider string
+	root     Root
+}
+
+// withContainerRoots returns s with the container roots appended, leaving
+// out those whose directory or label the configured roots already use.
+func (s Settings) withContainerRoots(roots []containerRoot) Settings {
+	for _, added := range roots {
+		var list *[]Root
+		switch added.provider {
+		case "claude":
+			list = &s.ClaudeRoots
+		case "codex":
+			list = &s.CodexRoots
+		default:
+			continue
+		}
+		if !slices.ContainsFunc(*list, func(r Root) bool { return r.Dir == added.root.Dir || r.Label == added.root.Label }) {
+			*list = append(slices.Clip(*list), added.root)
+		}
+	}
+	return s
+}
+
+// addContainerRoots watches the bind-mounted data directory of every
+// container process, and sets each process's Container.Root to the label
+// of the root holding its data. Roots stay until the collector stops, so
+// teams do not vanish between a container's sessions. It reports whether
+// a root was added.
+func (c *Collector) addContainerRoots(processes []types.ProcessInfo) bool {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+
+	added := false
+	for i := range processes {
+		container := processes[i].Container
+		if container == nil || container.DataDir == "" {
+			continue
+		}
+		settings := c.effectiveSettingsLocked()
+		var roots []Root
+		switch processes[i].Provider {
+		case "claude":
+			roots = settings.ClaudeRoots
+		case "codex":
+			roots = settings.CodexRoots
+		default:
+			continue
+		}
+		if j := slices.IndexFunc(roots, func(r Root) bool { return r.Dir == container.DataDir }); j >= 0 {
+			container.Root = roots[j].Label
+			continue
+		}
+
+		label := containerRootLabel(*container)
+		if slices.ContainsFunc(roots, func(r Root) bool { return r.Label == label }) {
+			label += "-" + container.ID[:12]
+		}
+		if slices.ContainsFunc(roots, func(r Root) bool { return r.Label == label }) {
+			continue
+		}
+		c.containerRoots = append(c.containerRoots, containerRoot{provider: processes[i].Provider, root: Root{Label: label, Dir: container.DataDir}})
+		container.Root = label
+		added = true
+		log.Printf("Watching %s data of container %s at %s as root %q", processes[i].Provider, firstNonEmpty(container.Name, container.ID[:12]), container.DataDir, label)
+	}
+	if added && c.fsMonitor != nil {
+		if err := c.replaceFileSystemMonitorLocked(c.effectiveSettingsLocked()); err != nil {
+			log.Printf("Error watching container data roots: %v", err)
+		}
+	}
+	return added
+}
+
+// containerRootForDir returns the label of the container root at dir.
+func (c *Collector) containerRootForDir(dir string) (string, bool) {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+	for _, added := range c.containerRoots {
+		if added.root.Dir == dir {
+			return added.root.Label, true
+		}
+	}
+	return "", false
+}
+
+// containerRootLabel names a container's root after the container, which
+// Docker and Podman names already suit, or after its short ID.
+func containerRootLabel(container types.ContainerInfo) string {
+	if rootLabelPattern.MatchString(container.Name) {
+		return container.Name
+	}
+	return container.ID[:12]
+}
+
+// linkContainerSessions fills in the session of container processes from
+// the sessions/<pid>.json files of their data root, which name PIDs as
+// seen inside the container.
+func linkContainerSessions(processes []types.ProcessInfo, sessionPIDs map[string]map[int32]string) {
+	for i := range processes {
+		container := processes[i].Container
+		if processes[i].SessionID == "" && container != nil && container.Root != "" && container.PID > 0 {
+			processes[i].SessionID = sessionPIDs[container.Root][container.PID]
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+const testContainerID = "3f4e8c2a9b1d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f"
+
+func TestParseCgroupContainer(t *testing.T) {
+	cases := []struct {
+		cgroup, id, runtime string
+	}{
+		{"12:memory:/docker/" + testContainerID + "\n1:name=systemd:/docker/" + testContainerID, testContainerID, "docker"},
+		{"0::/system.slice/docker-" + testContainerID + ".scope", testContainerID, "docker"},
+		{"0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + testContainerID + ".scope/container", testContainerID, "podman"},
+		{"0::/kubepods.slice/kubepods-burstable.slice/cri-containerd-" + testContainerID + ".scope", testContainerID, "containerd"},
+		{"0::/kubepods/burstable/pod1234/" + testContainerID, testContainerID, ""},
+		{"0::/user.slice/user-1000.slice/session-2.scope", "", ""},
+		{"0::/", "", ""},
+	}
+	for _, c := range cases {
+		if id, runtime := parseCgroupContainer(c.cgroup); id != c.id || runtime != c.runtime {
+			t.Errorf("parseCgroupContainer(%q) = %q, %q; want %q, %q", c.cgroup, id, runtime, c.id, c.runtime)
+		}
+	}
+}
+
+func TestContainerScanFindsBindMountedData(t *testing.T) {
+	host := t.TempDir()
+	dataDir := filepath.Join(host, "home dir", ".claude")
+	metadataDir := filepath.Join(host, "docker", "containers", testContainerID)
+	for _, dir := range []string{dataDir, metadataDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(metadataDir, "config.v2.json"), []byte(`{"Name":"/devbox"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	escaped := strings.ReplaceAll(host, " ", `\040`)
+	fakeProc := t.TempDir()
+	files := map[string]string{
+		"self/cgroup":    "0::/user.slice/user-1000.slice/session-2.scope\n",
+		"self/mountinfo": "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n",
+		"4242/cgroup":    "0::/\n",
+		"4242/status":    "Name:\tclaude\nNSpid:\t4242\t17\n",
+		"4242/mountinfo": strings.Join([]string{
+			"600 500 0:61 / / rw,relatime - overlay overlay rw",
+			"601 600 8:1 " + escaped + "/docker/containers/" + testContainerID + "/hostname /etc/hostname rw - ext4 /dev/sda1 rw",
+			`602 600 8:1 ` + escaped + `/home\040dir/.claude /root/.claude rw - ext4 /dev/sda1 rw`,
+		}, "\n"),
+	}
+	for name, content := range files {
+		path := filepath.Join(fakeProc, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := procDir
+	procDir = fakeProc
+	t.Cleanup(func() { procDir = previous })
+
+	info := newContainerScan().inspect(4242, "claude", []string{"HOME=/root"})
+	want := types.ContainerInfo{ID: testContainerID, Name: "devbox", Runtime: "docker", PID: 17, DataDir: dataDir}
+	if info == nil || *info != want {
+		t.Fatalf("got %+v, want %+v", info, want)
+	}
+	if info := newContainerScan().inspect(4242, "codex", []string{"HOME=/root"}); info == nil || info.DataDir != "" {
+		t.Fatalf("expected no codex data for a container that only mounts .claude, got %+v", info)
+	}
+}
+
+func TestCollectorAddsContainerRoots(t *testing.T) {
+	collector := &Collector{settings: Settings{ClaudeRoots: []Root{{Dir: "/home/me/.claude"}, {Label: "devbox", Dir: "/srv/devbox/.claude"}}}}
+	container := func(name, dataDir string, pid int32) *types.ContainerInfo {
+		return &types.ContainerInfo{ID: testContainerID, Name: name, PID: pid, DataDir: dataDir}
+	}
+	processes := []types.ProcessInfo{
+		{PID: 1, Provider: "claude", Container: container("devbox", "/var/lib/devbox/.claude", 7)},
+		{PID: 2, Provider: "claude", Container: container("devbox", "/var/lib/devbox/.claude", 8)},
+		{PID: 3, Provider: "claude", Container: container("shared", "/home/me/.claude", 9)},
+		{PID: 4, Provider: "claude"},
+	}
+	if !collector.addContainerRoots(processes) {
+		t.Fatal("expected a root to be added")
+	}
+
+	label := "devbox-" + testContainerID[:12]
+	roots := collector.Settings().ClaudeRoots
+	if len(roots) != 3 || roots[2] != (Root{Label: label, Dir: "/var/lib/devbox/.claude"}) {
+		t.Fatalf("expected one container root beside the configured ones, got %+v", roots)
+	}
+	if processes[0].Container.Root != label || processes[1].Container.Root != label || processes[2].Container.Root != "" {
+		t.Fatalf("unexpected root labels %q %q %q", processes[0].Container.Root, processes[1].Container.Root, processes[2].Container.Root)
+	}
+	if collector.addContainerRoots(processes) {
+		t.Fatal("expected a known data directory not to be added twice")
+	}
+	if err := collector.settings.withContainerRoots(collector.containerRoots).Validate(); err != nil {
+		t.Fatalf("expected valid roots, got %v", err)
+	}
+
+	linkContainerSessions(processes, map[string]map[int32]string{label: {7: "session-7"}})
+	if processes[0].SessionID != "session-7" || processes[1].SessionID != "" {
+		t.Fatalf("expected sessions by the PID inside the container, got %+v", processes[:2])
+	}
+	linkProcesses(processes, nil, map[int32]string{1: "host-session", 2: "host-session", 4: "host-session"})
+	if processes[1].SessionID != "" || processes[3].SessionID != "host-session" {
+		t.Fatal("expected host session files to match host processes only")
+	}
+}
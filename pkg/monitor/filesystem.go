@@ -1,6 +1,8 @@
 package monitor
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -15,22 +17,42 @@ var projectsSessionIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9
 // FileSystemMonitorOptions controls filesystem watcher behavior.
 type FileSystemMonitorOptions struct {
 	Provider ProviderMode
+	// ClaudeDirs, CodexDirs and OpenClawDirs are watched independently.
+	// Empty lists mean ~/.claude, ~/.codex and ~/.openclaw.
+	ClaudeDirs   []string
+	CodexDirs    []string
+	OpenClawDirs []string
 }
 
 // FileSystemMonitor monitors Claude/Codex/OpenClaw runtime directories.
 type FileSystemMonitor struct {
-	watcher                *fsnotify.Watcher
-	provider               ProviderMode
-	claudeDir              string
-	teamsDir               string
-	tasksDir               string
-	projectsDir            string
-	codexDir               string
-	codexSessionsDir       string
-	openClawDir            string
-	openClawAgentsDir      string
-	openClawSubdirsWatched bool
-	onChange               func(event fsnotify.Event)
+	watcher       *fsnotify.Watcher
+	provider      ProviderMode
+	claudeRoots   []claudeWatchRoot
+	codexRoots    []codexWatchRoot
+	openClawRoots []openClawWatchRoot
+	onChange      func(event fsnotify.Event)
+}
+
+// claudeWatchRoot is one watched Claude data directory.
+type claudeWatchRoot struct {
+	dir         string
+	teamsDir    string
+	tasksDir    string
+	projectsDir string
+}
+
+// codexWatchRoot is one watched Codex data directory.
+type codexWatchRoot struct {
+	dir         string
+	sessionsDir string
+}
+
+// openClawWatchRoot is one watched OpenClaw state directory.
+type openClawWatchRoot struct {
+	dir            string
+	agentsDir      string
+	subdirsWatched bool
 }
 
 // NewFileSystemMonitor creates a new filesystem monitor
@@ -41,7 +63,7 @@ func NewFileSystemMonitor(options FileSystemMonitorOptions, onChange func(event
 	}
 
 	homeDir, err := os.UserHomeDir()
-	if err != nil {
+	if err != nil && (len(options.ClaudeDirs) == 0 || len(options.CodexDirs) == 0 || len(options.OpenClawDirs) == 0) {
 		return nil, err
 	}
 
@@ -54,23 +76,40 @@ func NewFileSystemMonitor(options FileSystemMonitorOptions, onChange func(event
 	}
 
 	if provider.IncludesClaude() {
-		fsm.claudeDir = filepath.Join(homeDir, ".claude")
-		fsm.teamsDir = filepath.Join(fsm.claudeDir, "teams")
-		fsm.tasksDir = filepath.Join(fsm.claudeDir, "tasks")
-		fsm.projectsDir = filepath.Join(fsm.claudeDir, "projects")
+		for _, dir := range dirsOrDefault(options.ClaudeDirs, filepath.Join(homeDir, ".claude")) {
+			fsm.claudeRoots = append(fsm.claudeRoots, claudeWatchRoot{
+				dir:         dir,
+				teamsDir:    filepath.Join(dir, "teams"),
+				tasksDir:    filepath.Join(dir, "tasks"),
+				projectsDir: filepath.Join(dir, "projects"),
+			})
+		}
 	}
 	if provider.IncludesCodex() {
-		fsm.codexDir = filepath.Join(homeDir, ".codex")
-		fsm.codexSessionsDir = filepath.Join(fsm.codexDir, "sessions")
+		for _, dir := range dirsOrDefault(options.CodexDirs, filepath.Join(homeDir, ".codex")) {
+			fsm.codexRoots = append(fsm.codexRoots, codexWatchRoot{dir: dir, sessionsDir: filepath.Join(dir, "sessions")})
+		}
 	}
 	if provider.IncludesOpenClaw() {
-		fsm.openClawDir = filepath.Join(homeDir, ".openclaw")
-		fsm.openClawAgentsDir = filepath.Join(fsm.openClawDir, "agents")
+		for _, dir := range dirsOrDefault(options.OpenClawDirs, filepath.Join(homeDir, ".openclaw")) {
+			fsm.openClawRoots = append(fsm.openClawRoots, openClawWatchRoot{dir: dir, agentsDir: filepath.Join(dir, "agents")})
+		}
 	}
 
 	return fsm, nil
 }
 
+func dirsOrDefault(dirs []string, fallback string) []string {
+	if len(dirs) == 0 {
+		return []string{fallback}
+	}
+	result := make([]string, len(dirs))
+	for i, dir := range dirs {
+		result[i] = filepath.Clean(dir)
+	}
+	return result
+}
+
 // Start begins monitoring the filesystem
 func (fsm *FileSystemMonitor) Start() error {
 	// Watch root directories and existing subdirectories.
@@ -83,65 +122,82 @@ func (fsm *FileSystemMonitor) Start() error {
 	return nil
 }
 
+// ensureRootsWatched watches every root independently: a root that cannot
+// be watched is logged and skipped, and only failing every root is an
+// error.
 func (fsm *FileSystemMonitor) ensureRootsWatched() error {
-	if fsm.provider.IncludesClaude() {
-		if err := os.MkdirAll(fsm.teamsDir, 0755); err != nil {
-			return err
-		}
-		if err := os.MkdirAll(fsm.tasksDir, 0755); err != nil {
-			return err
+	var errs []error
+	for i := range fsm.claudeRoots {
+		if err := fsm.ensureClaudeRootWatched(&fsm.claudeRoots[i]); err != nil {
+			errs = append(errs, fmt.Errorf("claude root %s: %w", fsm.claudeRoots[i].dir, err))
 		}
-		if err := os.MkdirAll(fsm.projectsDir, 0755); err != nil {
-			return err
-		}
-
-		if err := fsm.addWatch(fsm.claudeDir); err != nil {
-			return err
-		}
-		if err := fsm.addWatch(fsm.teamsDir); err != nil {
-			return err
-		}
-		if err := fsm.addWatch(fsm.tasksDir); err != nil {
-			return err
+	}
+	for i := range fsm.codexRoots {
+		if err := fsm.ensureCodexRootWatched(&fsm.codexRoots[i]); err != nil {
+			errs = append(errs, fmt.Errorf("codex root %s: %w", fsm.codexRoots[i].dir, err))
 		}
-		if err := fsm.addWatch(fsm.projectsDir); err != nil {
-			return err
+	}
+	for i := range fsm.openClawRoots {
+		if err := fsm.ensureOpenClawRootWatched(&fsm.openClawRoots[i]); err != nil {
+			errs = append(errs, fmt.Errorf("openclaw root %s: %w", fsm.openClawRoots[i].dir, err))
 		}
+	}
 
-		fsm.watchSubdirectories(fsm.teamsDir)
-		fsm.watchSubdirectories(fsm.tasksDir)
-		fsm.watchProjectsSubdirectories()
+	if len(errs) > 0 && len(errs) == len(fsm.claudeRoots)+len(fsm.codexRoots)+len(fsm.openClawRoots) {
+		return errors.Join(errs...)
+	}
+	for _, err := range errs {
+		log.Printf("Failed to watch %v", err)
 	}
+	return nil
+}
 
-	if fsm.provider.IncludesCodex() {
-		if err := os.MkdirAll(fsm.codexSessionsDir, 0755); err != nil {
-			return err
-		}
-		if err := fsm.addWatch(fsm.codexDir); err != nil {
+func (fsm *FileSystemMonitor) ensureClaudeRootWatched(root *claudeWatchRoot) error {
+	for _, dir := range []string{root.teamsDir, root.tasksDir, root.projectsDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
 			return err
 		}
-		if err := fsm.addWatch(fsm.codexSessionsDir); err != nil {
+	}
+	for _, dir := range []string{root.dir, root.teamsDir, root.tasksDir, root.projectsDir} {
+		if err := fsm.addWatch(dir); err != nil {
 			return err
 		}
-		fsm.watchSubdirectories(fsm.codexSessionsDir)
 	}
 
-	if fsm.provider.IncludesOpenClaw() {
-		if err := os.MkdirAll(fsm.openClawAgentsDir, 0755); err != nil {
-			return err
-		}
-		if err := fsm.addWatch(fsm.openClawDir); err != nil {
-			return err
-		}
-		if err := fsm.addWatch(fsm.openClawAgentsDir); err != nil {
-			return err
-		}
-		if !fsm.openClawSubdirsWatched {
-			fsm.watchSubdirectories(fsm.openClawDir)
-			fsm.openClawSubdirsWatched = true
-		}
+	fsm.watchSubdirectories(root.teamsDir)
+	fsm.watchSubdirectories(root.tasksDir)
+	fsm.watchProjectsSubtree(root.projectsDir)
+	return nil
+}
+
+func (fsm *FileSystemMonitor) ensureCodexRootWatched(root *codexWatchRoot) error {
+	if err := os.MkdirAll(root.sessionsDir, 0755); err != nil {
+		return err
+	}
+	if err := fsm.addWatch(root.dir); err != nil {
+		return err
+	}
+	if err := fsm.addWatch(root.sessionsDir); err != nil {
+		return err
 	}
+	fsm.watchSubdirectories(root.sessionsDir)
+	return nil
+}
 
+func (fsm *FileSystemMonitor) ensureOpenClawRootWatched(root *openClawWatchRoot) error {
+	if err := os.MkdirAll(root.agentsDir, 0755); err != nil {
+		return err
+	}
+	if err := fsm.addWatch(root.dir); err != nil {
+		return err
+	}
+	if err := fsm.addWatch(root.agentsDir); err != nil {
+		return err
+	}
+	if !root.subdirsWatched {
+		fsm.watchSubdirectories(root.dir)
+		root.subdirsWatched = true
+	}
 	return nil
 }
 
@@ -177,15 +233,12 @@ func (fsm *FileSystemMonitor) watchSubdirectories(dir string) {
 	})
 }
 
-// watchProjectsSubdirectories adds focused watchers for ~/.claude/projects:
+// watchProjectsSubtree adds focused watchers under a Claude projects
+// directory:
 // - projects root
 // - each project directory (level 1)
 // - each session directory (level 2, UUID-like)
 // - each subagents directory (level 3, named "subagents")
-func (fsm *FileSystemMonitor) watchProjectsSubdirectories() {
-	fsm.watchProjectsSubtree(fsm.projectsDir)
-}
-
 func (fsm *FileSystemMonitor) watchProjectsSubtree(root string) {
 	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -205,12 +258,23 @@ func (fsm *FileSystemMonitor) watchProjectsSubtree(root string) {
 	})
 }
 
+// shouldWatchProjectsDir reports whether path is worth watching inside the
+// projects directory of any Claude root.
 func (fsm *FileSystemMonitor) shouldWatchProjectsDir(path string) bool {
-	if strings.TrimSpace(fsm.projectsDir) == "" {
+	for _, root := range fsm.claudeRoots {
+		if isWatchedProjectsDir(root.projectsDir, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWatchedProjectsDir(projectsDir, path string) bool {
+	if strings.TrimSpace(projectsDir) == "" {
 		return false
 	}
 
-	cleanProjects := filepath.Clean(fsm.projectsDir)
+	cleanProjects := filepath.Clean(projectsDir)
 	cleanPath := filepath.Clean(path)
 
 	if cleanPath == cleanProjects {
@@ -292,17 +356,27 @@ func (fsm *FileSystemMonitor) Stop() error {
 	return fsm.watcher.Close()
 }
 
-// GetTeamsDir returns the teams directory path
+// GetTeamsDir returns the teams directory of the first Claude root.
 func (fsm *FileSystemMonitor) GetTeamsDir() string {
-	return fsm.teamsDir
+	if len(fsm.claudeRoots) == 0 {
+		return ""
+	}
+	return fsm.claudeRoots[0].teamsDir
 }
 
-// GetTasksDir returns the tasks directory path
+// GetTasksDir returns the tasks directory of the first Claude root.
 func (fsm *FileSystemMonitor) GetTasksDir() string {
-	return fsm.tasksDir
+	if len(fsm.claudeRoots) == 0 {
+		return ""
+	}
+	return fsm.claudeRoots[0].tasksDir
 }
 
-// GetCodexSessionsDir returns the codex sessions directory path.
+// GetCodexSessionsDir returns the sessions directory of the first Codex
+// root.
 func (fsm *FileSystemMonitor) GetCodexSessionsDir() string {
-	return fsm.codexSessionsDir
+	if len(fsm.codexRoots) == 0 {
+		return ""
+	}
+	return fsm.codexRoots[0].sessionsDir
 }
@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// CollectorHealth summarizes how the collector's update loop is doing.
+type CollectorHealth struct {
+	Updates            int64            `json:"updates"`
+	LastUpdateAt       time.Time        `json:"last_update_at"`
+	LastUpdateDuration time.Duration    `json:"last_update_duration"`
+	TotalUpdateTime    time.Duration    `json:"total_update_time"`
+	ParseErrors        map[string]int64 `json:"parse_errors"`
+}
+
+// ParseErrorSources returns the parse error sources in stable order.
+func (h CollectorHealth) ParseErrorSources() []string {
+	sources := make([]string, 0, len(h.ParseErrors))
+	for source := range h.ParseErrors {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+type collectorHealthTracker struct {
+	mu     sync.Mutex
+	health CollectorHealth
+}
+
+func (t *collectorHealthTracker) recordUpdate(startedAt time.Time, elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.health.Updates++
+	t.health.LastUpdateAt = startedAt
+	t.health.LastUpdateDuration = elapsed
+	t.health.TotalUpdateTime += elapsed
+}
+
+func (t *collectorHealthTracker) recordParseError(source string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.health.ParseErrors == nil {
+		t.health.ParseErrors = make(map[string]int64)
+	}
+	t.health.ParseErrors[source]++
+}
+
+func (t *collectorHealthTracker) snapshot() CollectorHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snapshot := t.health
+	snapshot.ParseErrors = make(map[string]int64, len(t.health.ParseErrors))
+	for source, count := range t.health.ParseErrors {
+		snapshot.ParseErrors[source] = count
+	}
+	return snapshot
+}
+
+// Health returns a snapshot of update timings and parse error counters.
+func (c *Collector) Health() CollectorHealth {
+	if c == nil {
+		return CollectorHealth{ParseErrors: map[string]int64{}}
+	}
+	return c.health.snapshot()
+}
@@ -43,12 +43,17 @@ func TestOpenClawWatcherPaths(t *testing.T) {
 		t.Fatalf("NewFileSystemMonitor error: %v", err)
 	}
 
-	if filepath.Base(fsm.openClawAgentsDir) != "agents" {
-		t.Fatalf("unexpected openclaw agents dir: %s", fsm.openClawAgentsDir)
+	if len(fsm.openClawRoots) != 1 {
+		t.Fatalf("expected one openclaw root, got %d", len(fsm.openClawRoots))
 	}
+	root := fsm.openClawRoots[0]
 
-	if filepath.Base(fsm.openClawDir) != ".openclaw" {
-		t.Fatalf("unexpected openclaw root dir: %s", fsm.openClawDir)
+	if filepath.Base(root.agentsDir) != "agents" {
+		t.Fatalf("unexpected openclaw agents dir: %s", root.agentsDir)
+	}
+
+	if filepath.Base(root.dir) != ".openclaw" {
+		t.Fatalf("unexpected openclaw root dir: %s", root.dir)
 	}
 }
 
@@ -84,7 +89,7 @@ func TestCollectOpenClawTeams_IncludesSubagentRunsWithoutSessions(t *testing.T)
 	}
 
 	collector := &Collector{}
-	teams := collector.collectOpenClawTeams(root)
+	teams := collector.collectOpenClawTeams(filepath.Join(root, ".openclaw"))
 	if len(teams) != 1 {
 		t.Fatalf("expected 1 team, got %d", len(teams))
 	}
@@ -1,8 +1,10 @@
 package monitor
 
 import (
+	"math"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/liaoweijun/agent-team-monitor/pkg/types"
@@ -10,22 +12,48 @@ import (
 )
 
 // ProcessMonitor monitors Claude Code processes
-type ProcessMonitor struct{}
+type ProcessMonitor struct {
+	mu      sync.Mutex
+	samples map[int32]cpuSample // CPU time at the previous scan, for CPU %
+}
+
+// cpuSample is a process's total CPU time when last scanned. created tells
+// a reused PID apart.
+type cpuSample struct {
+	created int64
+	seconds float64
+	at      time.Time
+}
+
+const (
+	// maxChildProcesses bounds the descendants listed per process.
+	maxChildProcesses = 32
+	// maxChildDepth bounds how deep the child tree goes.
+	maxChildDepth = 4
+)
 
 // NewProcessMonitor creates a new process monitor
 func NewProcessMonitor() *ProcessMonitor {
-	return &ProcessMonitor{}
+	return &ProcessMonitor{samples: map[int32]cpuSample{}}
 }
 
-// FindProcesses finds all running Claude/Codex/OpenClaw processes.
+// FindProcesses finds all running Claude/Codex/OpenClaw processes with
+// their working directory, managed team environment, session ID, resource
+// use and child process tree.
 func (pm *ProcessMonitor) FindProcesses(provider ProviderMode) ([]types.ProcessInfo, error) {
 	processes, err := process.Processes()
 	if err != nil {
 		return nil, err
 	}
 
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
 	mode := normalizeProviderMode(provider)
+	scan := newProcessScan(pm, processes)
+	containers := newContainerScan()
 	var monitored []types.ProcessInfo
+	var matched []*process.Process
 
 	for _, p := range processes {
 		cmdline, err := p.Cmdline()
@@ -49,13 +77,30 @@ func (pm *ProcessMonitor) FindProcesses(provider ProviderMode) ([]types.ProcessI
 		createTime, _ := p.CreateTime()
 		startedAt := time.Unix(createTime/1000, 0)
 
-		monitored = append(monitored, types.ProcessInfo{
+		info := types.ProcessInfo{
 			PID:       p.Pid,
 			Command:   cmdline,
 			StartedAt: startedAt,
 			Provider:  matchedProvider,
-		})
+			SessionID: sessionIDFromCommand(cmdline),
+		}
+		info.Cwd, _ = p.Cwd()
+		environ, _ := p.Environ()
+		applyManagedEnv(&info, environ)
+		info.Container = containers.inspect(p.Pid, matchedProvider, environ)
+		if threads, err := p.NumThreads(); err == nil {
+			info.Threads = threads
+		}
+		info.CPUPercent, info.RSSBytes = scan.usage(p)
+		monitored = append(monitored, info)
+		matched = append(matched, p)
+	}
+
+	for i := range monitored {
+		budget := maxChildProcesses
+		monitored[i].Children = scan.tree(matched[i].Pid, 1, &budget)
 	}
+	pm.samples = scan.samples
 
 	sort.SliceStable(monitored, func(i, j int) bool {
 		return monitored[i].StartedAt.After(monitored[j].StartedAt)
@@ -64,6 +109,155 @@ func (pm *ProcessMonitor) FindProcesses(provider ProviderMode) ([]types.ProcessI
 	return monitored, nil
 }
 
+// processScan holds one pass over the process table.
+type processScan struct {
+	monitor  *ProcessMonitor
+	byPID    map[int32]*process.Process
+	children map[int32][]int32
+	now      time.Time
+	samples  map[int32]cpuSample // kept for the next scan
+}
+
+func newProcessScan(pm *ProcessMonitor, processes []*process.Process) *processScan {
+	scan := &processScan{
+		monitor:  pm,
+		byPID:    make(map[int32]*process.Process, len(processes)),
+		children: map[int32][]int32{},
+		now:      time.Now(),
+		samples:  map[int32]cpuSample{},
+	}
+	for _, p := range processes {
+		scan.byPID[p.Pid] = p
+		if ppid, err := p.Ppid(); err == nil && ppid != p.Pid {
+			scan.children[ppid] = append(scan.children[ppid], p.Pid)
+		}
+	}
+	return scan
+}
+
+// tree lists pid's descendants, spending at most *budget entries.
+func (s *processScan) tree(pid int32, depth int, budget *int) []types.ChildProcess {
+	childPIDs := append([]int32(nil), s.children[pid]...)
+	sort.Slice(childPIDs, func(i, j int) bool { return childPIDs[i] < childPIDs[j] })
+
+	var result []types.ChildProcess
+	for _, childPID := range childPIDs {
+		if *budget <= 0 {
+			break
+		}
+		p, ok := s.byPID[childPID]
+		if !ok {
+			continue
+		}
+		*budget--
+		child := types.ChildProcess{PID: childPID}
+		if cmdline, err := p.Cmdline(); err == nil && cmdline != "" {
+			child.Command = cmdline
+		} else if name, err := p.Name(); err == nil {
+			child.Command = name
+		}
+		child.CPUPercent, child.RSSBytes = s.usage(p)
+		if depth < maxChildDepth {
+			child.Children = s.tree(childPID, depth+1, budget)
+		}
+		result = append(result, child)
+	}
+	return result
+}
+
+// usage returns p's CPU % since the previous scan, or over its lifetime on
+// first sight, and its resident memory.
+func (s *processScan) usage(p *process.Process) (float64, uint64) {
+	var rss uint64
+	if memory, err := p.MemoryInfo(); err == nil {
+		rss = memory.RSS
+	}
+
+	times, err := p.Times()
+	if err != nil {
+		return 0, rss
+	}
+	created, _ := p.CreateTime()
+	sample := cpuSample{created: created, seconds: times.User + times.System, at: s.now}
+	s.samples[p.Pid] = sample
+
+	var percent float64
+	if previous, ok := s.monitor.samples[p.Pid]; ok && previous.created == created && s.now.After(previous.at) {
+		percent = (sample.seconds - previous.seconds) / s.now.Sub(previous.at).Seconds() * 100
+	} else if created > 0 {
+		if elapsed := s.now.Sub(time.UnixMilli(created)).Seconds(); elapsed > 0 {
+			percent = sample.seconds / elapsed * 100
+		}
+	}
+	return math.Round(math.Max(percent, 0)*10) / 10, rss
+}
+
+// applyManagedEnv reads the variables managed.Manager starts agents with.
+func applyManagedEnv(info *types.ProcessInfo, environ []string) {
+	var agentID, agentName string
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "ATM_MANAGED_TEAM_ID":
+			info.ManagedTeamID = value
+		case "ATM_MANAGED_AGENT_ID":
+			agentID = value
+			info.ManagedAgentID = value
+		case "ATM_MANAGED_AGENT_NAME":
+			agentName = value
+		}
+	}
+	if info.ManagedTeamID != "" {
+		info.Agent = firstNonEmpty(agentName, agentID)
+		info.LinkedBy = processLinkManagedEnv
+	}
+}
+
+// sessionIDFromCommand finds a session ID passed on the command line, as in
+// "claude --resume <id>", "claude --session-id <id>" or "codex resume <id>".
+func sessionIDFromCommand(cmdline string) string {
+	fields := strings.Fields(cmdline)
+	for i, field := range fields {
+		if flag, value, ok := strings.Cut(field, "="); ok {
+			if isSessionFlag(flag) && looksLikeSessionID(value) {
+				return value
+			}
+			continue
+		}
+		if i+1 >= len(fields) {
+			break
+		}
+		if (isSessionFlag(field) || field == "resume") && looksLikeSessionID(fields[i+1]) {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
+func isSessionFlag(flag string) bool {
+	switch flag {
+	case "--resume", "-r", "--session-id", "--session":
+		return true
+	}
+	return false
+}
+
+// looksLikeSessionID accepts UUID-style IDs, leaving out flags and prompts.
+func looksLikeSessionID(value string) bool {
+	if len(value) < 8 || strings.HasPrefix(value, "-") {
+		return false
+	}
+	for _, r := range value {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
 // FindClaudeProcesses keeps backward compatibility with older callsites.
 func (pm *ProcessMonitor) FindClaudeProcesses() ([]types.ProcessInfo, error) {
 	return pm.FindProcesses(ProviderClaude)
@@ -147,3 +341,89 @@ func isOpenClawProcess(cmdLower string) bool {
 
 	return false
 }
+
+// How a process was tied to its team, reported in ProcessInfo.LinkedBy.
+const (
+	processLinkManagedEnv = "managed_env"
+	processLinkSession    = "session"
+	processLinkCwd        = "cwd"
+)
+
+// linkProcesses ties each process to the team and agent it runs, trusting
+// in turn the managed team environment, a session ID from the command line
+// or Claude's sessions/<pid>.json, and a working directory shared with
+// exactly one team. Managed teams are resolved by the API, which knows
+// their names.
+func linkProcesses(processes []types.ProcessInfo, teams []types.TeamInfo, sessionPIDs map[int32]string) {
+	for i := range processes {
+		proc := &processes[i]
+		if proc.SessionID == "" && proc.Container == nil {
+			proc.SessionID = sessionPIDs[proc.PID]
+		}
+		if proc.LinkedBy == processLinkManagedEnv {
+			continue
+		}
+		if proc.SessionID != "" && linkProcessBySession(proc, teams) {
+			continue
+		}
+		linkProcessByCwd(proc, teams)
+	}
+}
+
+func linkProcessBySession(proc *types.ProcessInfo, teams []types.TeamInfo) bool {
+	for _, team := range teams {
+		for _, agent := range team.Members {
+			if agent.AgentID == proc.SessionID {
+				proc.Team, proc.Agent, proc.LinkedBy = team.Name, types.AgentKey(agent), processLinkSession
+				return true
+			}
+		}
+	}
+	for _, team := range teams {
+		if team.LeadSessionID == proc.SessionID {
+			proc.Team, proc.LinkedBy = team.Name, processLinkSession
+			return true
+		}
+	}
+	return false
+}
+
+func linkProcessByCwd(proc *types.ProcessInfo, teams []types.TeamInfo) {
+	cwd := normalizeComparablePath(proc.Cwd)
+	if cwd == "" {
+		return
+	}
+
+	var team, agent string
+	agents := 0
+	for _, candidate := range teams {
+		if proc.Provider != "" && candidate.Provider != "" && candidate.Provider != proc.Provider {
+			continue
+		}
+		if proc.Container != nil && proc.Container.Root != "" && candidate.Root != proc.Container.Root {
+			continue // Paths inside a container only mean something for its own data.
+		}
+		matched := normalizeComparablePath(candidate.ProjectCwd) == cwd
+		for _, member := range candidate.Members {
+			if normalizeComparablePath(member.Cwd) == cwd {
+				matched = true
+				agent = types.AgentKey(member)
+				agents++
+			}
+		}
+		if !matched {
+			continue
+		}
+		if team != "" && team != candidate.Name {
+			return // Shared by several teams: ambiguous.
+		}
+		team = candidate.Name
+	}
+	if team == "" {
+		return
+	}
+	proc.Team, proc.LinkedBy = team, processLinkCwd
+	if agents == 1 {
+		proc.Agent = agent
+	}
+}
@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+func TestSessionIDFromCommand(t *testing.T) {
+	const id = "4f0c2b51-8d7e-4a39-9a51-0b7c2e6d9f13"
+	cases := map[string]string{
+		"claude --resume " + id:                  id,
+		"node /usr/lib/claude -r " + id:          id,
+		"claude --session-id=" + id + " -p hi":   id,
+		"codex resume " + id:                     id,
+		"claude --resume --model opus":           "",
+		"claude -p 'resume the deployment plan'": "",
+		"claude":                                 "",
+	}
+	for cmdline, want := range cases {
+		if got := sessionIDFromCommand(cmdline); got != want {
+			t.Errorf("sessionIDFromCommand(%q) = %q, want %q", cmdline, got, want)
+		}
+	}
+}
+
+func TestLinkProcesses(t *testing.T) {
+	teams := []types.TeamInfo{
+		{
+			Name:          "alpha",
+			Provider:      "claude",
+			ProjectCwd:    "/work/alpha",
+			LeadSessionID: "aaaaaaaa-0000",
+			Members: []types.AgentInfo{
+				{Name: "lead", AgentID: "lead@alpha", Cwd: "/work/alpha"},
+				{Name: "tester", AgentID: "bbbbbbbb-1111", Cwd: "/work/alpha/tests"},
+			},
+		},
+		{Name: "codex-shared", Provider: "codex", ProjectCwd: "/work/shared", Members: []types.AgentInfo{{Name: "one", Cwd: "/work/shared"}}},
+		{Name: "codex-shared-2", Provider: "codex", ProjectCwd: "/work/shared", Members: []types.AgentInfo{{Name: "two", Cwd: "/work/shared"}}},
+	}
+
+	processes := []types.ProcessInfo{
+		{PID: 1, Provider: "claude", SessionID: "bbbbbbbb-1111"},
+		{PID: 2, Provider: "claude"},
+		{PID: 3, Provider: "claude", Cwd: "/work/alpha/"},
+		{PID: 4, Provider: "codex", Cwd: "/work/shared"},
+		{PID: 5, Provider: "claude", Cwd: "/work/alpha"},
+	}
+	applyManagedEnv(&processes[4], []string{"PATH=/bin", "ATM_MANAGED_TEAM_ID=team-1", "ATM_MANAGED_AGENT_ID=agent-1", "ATM_MANAGED_AGENT_NAME=lead"})
+	linkProcesses(processes, teams, map[int32]string{2: "aaaaaaaa-0000"})
+
+	want := []struct{ team, agent, by string }{
+		{"alpha", "tester", processLinkSession},
+		{"alpha", "", processLinkSession},
+		{"alpha", "lead", processLinkCwd},
+		{"", "", ""},
+		{"", "lead", processLinkManagedEnv},
+	}
+	for i, w := range want {
+		got := processes[i]
+		if got.Team != w.team || got.Agent != w.agent || got.LinkedBy != w.by {
+			t.Errorf("process %d: got team %q agent %q by %q, want %+v", got.PID, got.Team, got.Agent, got.LinkedBy, w)
+		}
+	}
+	if processes[4].ManagedTeamID != "team-1" || processes[4].ManagedAgentID != "agent-1" {
+		t.Fatalf("expected the managed IDs from the environment, got %+v", processes[4])
+	}
+}
+
+func TestProcessScanListsChildren(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Skipf("cannot start a child process: %v", err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	processes, err := process.Processes()
+	if err != nil {
+		t.Skipf("cannot list processes: %v", err)
+	}
+	monitor := NewProcessMonitor()
+	budget := maxChildProcesses
+	children := newProcessScan(monitor, processes).tree(int32(os.Getpid()), 1, &budget)
+
+	for _, c := range children {
+		if c.PID == int32(child.Process.Pid) {
+			if c.Command == "" || c.RSSBytes == 0 {
+				t.Fatalf("expected the child's command and memory, got %+v", c)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected pid %d among the children, got %+v", child.Process.Pid, children)
+}
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"github.com/liaoweijun/agent-team-monitor/pkg/redact"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// SetRedactor switches the redactor that masks secrets in agent text and
+// collects again; nil publishes text as parsed.
+func (c *Collector) SetRedactor(redactor *redact.Redactor) {
+	c.settingsMu.Lock()
+	c.redactor = redactor
+	c.settingsMu.Unlock()
+	c.updateState()
+}
+
+// redactState masks secrets in freshly collected teams and processes so
+// that neither GetState nor anything built on it sees them. Counts land on
+// each agent's Redactions.
+func (c *Collector) redactState(teams []types.TeamInfo, processes []types.ProcessInfo) {
+	c.settingsMu.RLock()
+	redactor := c.redactor
+	c.settingsMu.RUnlock()
+	if redactor == nil {
+		return
+	}
+
+	for i := range teams {
+		team := &teams[i]
+		team.Tasks = append([]types.TaskInfo(nil), team.Tasks...)
+		team.Members = append([]types.AgentInfo(nil), team.Members...)
+		for j := range team.Tasks {
+			task := &team.Tasks[j]
+			redactor.Apply(&task.Subject)
+			redactor.Apply(&task.Description)
+		}
+		for j := range team.Members {
+			redactAgent(redactor, &team.Members[j])
+		}
+	}
+	for i := range processes {
+		redactor.Apply(&processes[i].Command)
+		redactChildren(redactor, processes[i].Children)
+	}
+}
+
+func redactChildren(redactor *redact.Redactor, children []types.ChildProcess) {
+	for i := range children {
+		redactor.Apply(&children[i].Command)
+		redactChildren(redactor, children[i].Children)
+	}
+}
+
+func redactAgent(redactor *redact.Redactor, agent *types.AgentInfo) {
+	fields := []*string{
+		&agent.CurrentTask,
+		&agent.LatestMessage,
+		&agent.MessageSummary,
+		&agent.LatestResponse,
+		&agent.LastThinking,
+		&agent.LastToolDetail,
+	}
+	count := 0
+	for _, field := range fields {
+		count += redactor.Apply(field)
+	}
+	// Events may share their backing array with parser caches, so copy
+	// before rewriting.
+	if len(agent.RecentEvents) > 0 {
+		agent.RecentEvents = append([]types.AgentEvent(nil), agent.RecentEvents...)
+		for k := range agent.RecentEvents {
+			count += redactor.Apply(&agent.RecentEvents[k].Title)
+			count += redactor.Apply(&agent.RecentEvents[k].Text)
+		}
+	}
+	if len(agent.Todos) > 0 {
+		agent.Todos = append([]types.TodoItem(nil), agent.Todos...)
+		for k := range agent.Todos {
+			count += redactor.Apply(&agent.Todos[k].Content)
+			count += redactor.Apply(&agent.Todos[k].ActiveForm)
+		}
+	}
+	agent.Redactions = count
+}
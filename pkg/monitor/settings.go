@@ -0,0 +1,275 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Collector defaults, used for zero Settings fields.
+const (
+	DefaultPollInterval           = 5 * time.Second
+	DefaultStaleTeamThreshold     = time.Hour
+	DefaultProjectDiscoveryMaxAge = 2 * time.Hour
+	DefaultCodexSessionMaxAge     = 8 * time.Hour
+	DefaultOpenClawSessionMaxAge  = 8 * time.Hour
+	DefaultWorkingRecentThreshold = 2 * time.Minute
+)
+
+// Settings are the collector options that can change while it runs, for
+// example from a reloaded config file. Zero durations and empty directories
+// take the defaults.
+type Settings struct {
+	Provider ProviderMode
+	// ClaudeRoots, CodexRoots and OpenClawRoots list the data directories
+	// to scan. Empty lists mean $CLAUDE_CONFIG_DIR or ~/.claude,
+	// $CODEX_HOME or ~/.codex, and ~/.openclaw.
+	ClaudeRoots   []Root
+	CodexRoots    []Root
+	OpenClawRoots []Root
+	// PollInterval is how often state is collected when no file changes.
+	PollInterval time.Duration
+	// StaleTeamThreshold hides Claude teams whose members have all been
+	// inactive for longer.
+	StaleTeamThreshold time.Duration
+	// ProjectDiscoveryMaxAge, CodexSessionMaxAge and OpenClawSessionMaxAge
+	// bound how old a session log may be and still be discovered.
+	ProjectDiscoveryMaxAge time.Duration
+	CodexSessionMaxAge     time.Duration
+	OpenClawSessionMaxAge  time.Duration
+	// WorkingRecentThreshold is how recently a Codex or OpenClaw session
+	// must have been active to count as working.
+	WorkingRecentThreshold time.Duration
+	// ExposeAbsolutePaths publishes working directories as they are instead
+	// of shortening them to ~/... or the folder name.
+	ExposeAbsolutePaths bool
+	// DiscoveryMetrics logs project discovery timings and cache hit rates.
+	DiscoveryMetrics bool
+	// Watchdog flags hung agents and picks the remedy for managed runs.
+	Watchdog WatchdogSettings
+	// LogOffsetsFile, when set, saves how far each session log was read
+	// and what was parsed from it, so a restart does not read the logs
+	// again. It is loaded at startup only.
+	LogOffsetsFile string
+}
+
+// Root is one provider data directory, such as another account's ~/.claude
+// or a devcontainer volume.
+type Root struct {
+	// Label tags the teams found in Dir. Teams from a labeled root are
+	// named <team>@<label> so they stay apart from same-named teams in
+	// other roots; at most one root per provider may be unlabeled.
+	Label string
+	Dir   string
+}
+
+var rootLabelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateRoots checks that every root has a directory and that labels are
+// well formed and unique.
+func ValidateRoots(roots []Root) error {
+	var errs []error
+	seen := make(map[string]struct{}, len(roots))
+	for _, root := range roots {
+		if strings.TrimSpace(root.Dir) == "" {
+			errs = append(errs, fmt.Errorf("root %q has no directory", root.Label))
+		}
+		if root.Label != "" && !rootLabelPattern.MatchString(root.Label) {
+			errs = append(errs, fmt.Errorf("root label %q may only contain letters, digits, '.', '_' and '-'", root.Label))
+		}
+		if _, ok := seen[root.Label]; ok {
+			if root.Label == "" {
+				errs = append(errs, errors.New("only one root may be unlabeled"))
+			} else {
+				errs = append(errs, fmt.Errorf("root label %q is used twice", root.Label))
+			}
+		}
+		seen[root.Label] = struct{}{}
+	}
+	return errors.Join(errs...)
+}
+
+// HasRootLabel reports whether a root of any provider is labeled label.
+func (s Settings) HasRootLabel(label string) bool {
+	for _, roots := range [][]Root{s.ClaudeRoots, s.CodexRoots, s.OpenClawRoots} {
+		for _, root := range roots {
+			if root.Label != "" && strings.TrimSpace(root.Label) == label {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate checks the root lists of every provider.
+func (s Settings) Validate() error {
+	var errs []error
+	for _, roots := range []struct {
+		provider string
+		list     []Root
+	}{
+		{"claude", s.ClaudeRoots},
+		{"codex", s.CodexRoots},
+		{"openclaw", s.OpenClawRoots},
+	} {
+		if err := ValidateRoots(roots.list); err != nil {
+			errs = append(errs, fmt.Errorf("%s roots: %w", roots.provider, err))
+		}
+	}
+	if _, err := ParseRemedy(s.Watchdog.Remedy); err != nil {
+		errs = append(errs, fmt.Errorf("watchdog: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// DefaultSettings returns the defaults with ATM_EXPOSE_ABS_PATHS,
+// ATM_DISCOVERY_METRICS, ATM_WATCHDOG_REMEDY and ATM_LOG_OFFSETS_FILE
+// applied.
+func DefaultSettings() Settings {
+	remedy, err := ParseRemedy(os.Getenv("ATM_WATCHDOG_REMEDY"))
+	if err != nil {
+		log.Printf("Ignoring ATM_WATCHDOG_REMEDY: %v", err)
+	}
+	return Settings{
+		ExposeAbsolutePaths: readBoolEnv("ATM_EXPOSE_ABS_PATHS", false),
+		DiscoveryMetrics:    readBoolEnv("ATM_DISCOVERY_METRICS", false),
+		Watchdog:            WatchdogSettings{Remedy: remedy},
+		LogOffsetsFile:      strings.TrimSpace(os.Getenv("ATM_LOG_OFFSETS_FILE")),
+	}.normalized()
+}
+
+// normalized fills zero fields with the defaults.
+func (s Settings) normalized() Settings {
+	s.Provider = normalizeProviderMode(s.Provider)
+	homeDir, _ := os.UserHomeDir()
+	s.ClaudeRoots = rootsOrDefault(s.ClaudeRoots, envDirOrDefault("CLAUDE_CONFIG_DIR", filepath.Join(homeDir, ".claude")))
+	s.CodexRoots = rootsOrDefault(s.CodexRoots, envDirOrDefault("CODEX_HOME", filepath.Join(homeDir, ".codex")))
+	s.OpenClawRoots = rootsOrDefault(s.OpenClawRoots, filepath.Join(homeDir, ".openclaw"))
+	s.PollInterval = durationOrDefault(s.PollInterval, DefaultPollInterval)
+	s.StaleTeamThreshold = durationOrDefault(s.StaleTeamThreshold, DefaultStaleTeamThreshold)
+	s.ProjectDiscoveryMaxAge = durationOrDefault(s.ProjectDiscoveryMaxAge, DefaultProjectDiscoveryMaxAge)
+	s.CodexSessionMaxAge = durationOrDefault(s.CodexSessionMaxAge, DefaultCodexSessionMaxAge)
+	s.OpenClawSessionMaxAge = durationOrDefault(s.OpenClawSessionMaxAge, DefaultOpenClawSessionMaxAge)
+	s.WorkingRecentThreshold = durationOrDefault(s.WorkingRecentThreshold, DefaultWorkingRecentThreshold)
+	s.Watchdog = s.Watchdog.normalized()
+	return s
+}
+
+// watchesSameFiles reports whether both settings need the same filesystem
+// watchers.
+func (s Settings) watchesSameFiles(other Settings) bool {
+	return s.Provider == other.Provider &&
+		sameRootDirs(s.ClaudeRoots, other.ClaudeRoots) &&
+		sameRootDirs(s.CodexRoots, other.CodexRoots) &&
+		sameRootDirs(s.OpenClawRoots, other.OpenClawRoots)
+}
+
+func sameRootDirs(a, b []Root) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Dir != b[i].Dir {
+			return false
+		}
+	}
+	return true
+}
+
+// rootsOrDefault returns a cleaned copy of roots, or a single unlabeled
+// root at fallback.
+func rootsOrDefault(roots []Root, fallback string) []Root {
+	if len(roots) == 0 {
+		return []Root{{Dir: fallback}}
+	}
+	result := make([]Root, len(roots))
+	for i, root := range roots {
+		result[i] = Root{Label: strings.TrimSpace(root.Label), Dir: filepath.Clean(root.Dir)}
+	}
+	return result
+}
+
+func rootDirs(roots []Root) []string {
+	dirs := make([]string, len(roots))
+	for i, root := range roots {
+		dirs[i] = root.Dir
+	}
+	return dirs
+}
+
+func envDirOrDefault(name, fallback string) string {
+	if dir := strings.TrimSpace(os.Getenv(name)); dir != "" {
+		return filepath.Clean(dir)
+	}
+	return fallback
+}
+
+func durationOrDefault(value, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// Settings returns the settings the collector runs with, including the
+// roots added for containers' data directories.
+func (c *Collector) Settings() Settings {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+	return c.effectiveSettingsLocked()
+}
+
+func (c *Collector) effectiveSettingsLocked() Settings {
+	return c.settings.normalized().withContainerRoots(c.containerRoots)
+}
+
+// ApplySettings switches a running collector to new settings and collects
+// again. Invalid roots are rejected. A changed provider or data directory
+// replaces the filesystem watchers.
+func (c *Collector) ApplySettings(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	settings = settings.normalized()
+
+	c.settingsMu.Lock()
+	effective := settings.withContainerRoots(c.containerRoots)
+	if c.fsMonitor != nil && !effective.watchesSameFiles(c.effectiveSettingsLocked()) {
+		if err := c.replaceFileSystemMonitorLocked(effective); err != nil {
+			c.settingsMu.Unlock()
+			return err
+		}
+	}
+	c.settings = settings
+	c.settingsMu.Unlock()
+
+	select {
+	case c.pollReset <- struct{}{}:
+	default:
+	}
+	c.updateState()
+	return nil
+}
+
+// replaceFileSystemMonitorLocked switches to watchers for settings,
+// keeping the current ones if the new ones fail to start.
+func (c *Collector) replaceFileSystemMonitorLocked(settings Settings) error {
+	fsMonitor, err := c.newFileSystemMonitor(settings)
+	if err != nil {
+		return err
+	}
+	if c.started {
+		if err := fsMonitor.Start(); err != nil {
+			_ = fsMonitor.Stop()
+			return err
+		}
+	}
+	_ = c.fsMonitor.Stop()
+	c.fsMonitor = fsMonitor
+	return nil
+}
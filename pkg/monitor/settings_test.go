@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeStandaloneClaudeSession writes a recently active Claude CLI session
+// under claudeDir.
+func writeStandaloneClaudeSession(t *testing.T, claudeDir string) {
+	t.Helper()
+	sessionID := "4f0c2b51-8d7e-4a39-9a51-0b7c2e6d9f13"
+	sessionsDir := filepath.Join(claudeDir, "sessions")
+	projectsDir := filepath.Join(claudeDir, "projects", "-home-test-work-settings")
+	for _, dir := range []string{sessionsDir, projectsDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sessionJSON := `{"pid":4242,"sessionId":"` + sessionID + `","cwd":"/home/test/work/settings","startedAt":1775665224316,"kind":"interactive","entrypoint":"cli"}`
+	if err := os.WriteFile(filepath.Join(sessionsDir, "4242.json"), []byte(sessionJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mustWriteJSONL(t, filepath.Join(projectsDir, sessionID+".jsonl"), []any{
+		map[string]any{
+			"type":      "assistant",
+			"timestamp": time.Now().Add(-10 * time.Second).UTC().Format(time.RFC3339),
+			"sessionId": sessionID,
+			"cwd":       "/home/test/work/settings",
+			"message": map[string]any{
+				"role":    "assistant",
+				"content": []any{map[string]any{"type": "text", "text": "Checking the settings"}},
+			},
+		},
+	})
+}
+
+func TestApplySettingsSwitchesDataDirectory(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), ".claude")
+	populated := filepath.Join(t.TempDir(), ".claude")
+	writeStandaloneClaudeSession(t, populated)
+
+	collector, err := NewCollectorWithOptions(CollectorOptions{Settings: &Settings{Provider: ProviderClaude, ClaudeRoots: []Root{{Dir: empty}}}})
+	if err != nil {
+		t.Fatalf("NewCollectorWithOptions: %v", err)
+	}
+	defer collector.Stop()
+	collector.Refresh()
+	if teams := collector.GetState().Teams; len(teams) != 0 {
+		t.Fatalf("expected no teams in the empty directory, got %d", len(teams))
+	}
+
+	if err := collector.ApplySettings(Settings{Provider: ProviderClaude, ClaudeRoots: []Root{{Dir: populated}}, ExposeAbsolutePaths: true}); err != nil {
+		t.Fatalf("ApplySettings: %v", err)
+	}
+	state := collector.GetState()
+	if len(state.Teams) != 1 || state.Teams[0].ProjectCwd != "/home/test/work/settings" {
+		t.Fatalf("expected the session from the new directory with its absolute cwd, got %+v", state.Teams)
+	}
+	if dir := collector.fsMonitor.claudeRoots[0].dir; dir != populated {
+		t.Fatalf("expected the watchers to move to %s, got %s", populated, dir)
+	}
+	if settings := collector.Settings(); settings.PollInterval != DefaultPollInterval || settings.StaleTeamThreshold != DefaultStaleTeamThreshold {
+		t.Fatalf("expected zero durations to take the defaults, got %+v", settings)
+	}
+}
+
+func TestLabeledRootsKeepSameNamedTeamsApart(t *testing.T) {
+	home := filepath.Join(t.TempDir(), ".claude")
+	volume := filepath.Join(t.TempDir(), ".claude")
+	writeStandaloneClaudeSession(t, home)
+	writeStandaloneClaudeSession(t, volume)
+
+	collector, err := NewCollectorWithOptions(CollectorOptions{Settings: &Settings{
+		Provider:    ProviderClaude,
+		ClaudeRoots: []Root{{Dir: home}, {Label: "devbox", Dir: volume}},
+	}})
+	if err != nil {
+		t.Fatalf("NewCollectorWithOptions: %v", err)
+	}
+	defer collector.Stop()
+	collector.Refresh()
+
+	teams := collector.GetState().Teams
+	if len(teams) != 2 {
+		t.Fatalf("expected one team per root, got %+v", teams)
+	}
+	byRoot := map[string]string{}
+	for _, team := range teams {
+		byRoot[team.Root] = team.Name
+	}
+	if byRoot["devbox"] != byRoot[""]+"@devbox" {
+		t.Fatalf("expected the labeled root's team to carry its label, got %v", byRoot)
+	}
+
+	dir, name, err := collector.claudeTeamRoot(byRoot["devbox"])
+	if err != nil || dir != volume || name != byRoot[""] {
+		t.Fatalf("expected %s in %s, got %q in %q (%v)", byRoot[""], volume, name, dir, err)
+	}
+}
+
+func TestDefaultRootsHonorEnvironment(t *testing.T) {
+	t.Setenv("CLAUDE_CONFIG_DIR", "/srv/claude")
+	t.Setenv("CODEX_HOME", "/srv/codex")
+
+	settings := Settings{}.normalized()
+	if settings.ClaudeRoots[0].Dir != "/srv/claude" || settings.CodexRoots[0].Dir != "/srv/codex" {
+		t.Fatalf("expected the environment roots, got %+v %+v", settings.ClaudeRoots, settings.CodexRoots)
+	}
+}
+
+func TestValidateRootsRejectsAmbiguousLabels(t *testing.T) {
+	if err := ValidateRoots([]Root{{Dir: "/a"}, {Dir: "/b"}}); err == nil {
+		t.Fatal("expected two unlabeled roots to fail")
+	}
+	if err := ValidateRoots([]Root{{Label: "box", Dir: "/a"}, {Label: "box", Dir: "/b"}}); err == nil {
+		t.Fatal("expected a repeated label to fail")
+	}
+	if err := ValidateRoots([]Root{{Label: "a/b", Dir: "/a"}}); err == nil {
+		t.Fatal("expected a label with a slash to fail")
+	}
+	if _, err := NewCollectorWithOptions(CollectorOptions{Settings: &Settings{ClaudeRoots: []Root{{Label: "box"}}}}); err == nil {
+		t.Fatal("expected a root without a directory to fail")
+	}
+}
@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Signals that can be sent to an agent process.
+const (
+	SignalInterrupt = "SIGINT"
+	SignalTerminate = "SIGTERM"
+	SignalKill      = "SIGKILL"
+)
+
+const (
+	// DefaultSignalTimeout is how long SignalProcesses waits for exits.
+	DefaultSignalTimeout = 5 * time.Second
+	// MaxSignalTimeout keeps a waiting request inside the web server's
+	// write timeout.
+	MaxSignalTimeout = 8 * time.Second
+
+	signalPollInterval = 100 * time.Millisecond
+)
+
+// ErrProcessNotMonitored is returned for a PID missing from the process
+// list, so that only agent processes can be signalled.
+var ErrProcessNotMonitored = errors.New("process is not a monitored agent process")
+
+var signalsByName = map[string]syscall.Signal{
+	SignalInterrupt: syscall.SIGINT,
+	SignalTerminate: syscall.SIGTERM,
+	SignalKill:      syscall.SIGKILL,
+}
+
+// ParseSignal accepts SIGTERM, TERM or term and returns the canonical name.
+func ParseSignal(name string) (string, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if _, ok := signalsByName[name]; !ok {
+		return "", fmt.Errorf("unsupported signal %q (use SIGINT, SIGTERM or SIGKILL)", name)
+	}
+	return name, nil
+}
+
+// SignalProcesses sends signal to each PID and waits up to timeout for them
+// to exit. Every PID must be in the current process list; otherwise nothing
+// is sent. A PID whose process was replaced since the last scan counts as
+// exited rather than being signalled.
+func (c *Collector) SignalProcesses(pids []int32, signal string, timeout time.Duration) ([]types.SignalResult, error) {
+	signal, err := ParseSignal(signal)
+	if err != nil {
+		return nil, err
+	}
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("no processes to signal")
+	}
+	if timeout <= 0 {
+		timeout = DefaultSignalTimeout
+	}
+	timeout = min(timeout, MaxSignalTimeout)
+
+	targets := make([]types.ProcessInfo, 0, len(pids))
+	c.stateMutex.RLock()
+	for _, pid := range pids {
+		i := slices.IndexFunc(c.state.Processes, func(p types.ProcessInfo) bool { return p.PID == pid })
+		if i < 0 || int(pid) == os.Getpid() {
+			c.stateMutex.RUnlock()
+			return nil, fmt.Errorf("%w: %d", ErrProcessNotMonitored, pid)
+		}
+		targets = append(targets, c.state.Processes[i])
+	}
+	c.stateMutex.RUnlock()
+
+	results := make([]types.SignalResult, len(targets))
+	for i, target := range targets {
+		results[i] = types.SignalResult{PID: target.PID, Command: target.Command, Team: target.Team, Signal: signal}
+		if !processAlive(target) {
+			results[i].Exited = true
+			continue
+		}
+		err := sendSignal(target.PID, signalsByName[signal])
+		switch {
+		case errors.Is(err, os.ErrProcessDone):
+			results[i].Exited = true
+		case err != nil:
+			results[i].Error = err.Error()
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		waiting := false
+		for i := range results {
+			if results[i].Exited || results[i].Error != "" {
+				continue
+			}
+			if results[i].Exited = !processAlive(targets[i]); !results[i].Exited {
+				waiting = true
+			}
+		}
+		if !waiting || !time.Now().Before(deadline) {
+			break
+		}
+		time.Sleep(signalPollInterval)
+	}
+
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			log.Printf("Signal %s to pid %d failed: %s", signal, result.PID, result.Error)
+		case result.Exited:
+			log.Printf("Signal %s: pid %d exited", signal, result.PID)
+		default:
+			log.Printf("Signal %s: pid %d still running after %s", signal, result.PID, timeout)
+		}
+	}
+
+	select {
+	case c.updateChan <- struct{}{}:
+	default:
+	}
+	return results, nil
+}
+
+// SignalTeam signals every process linked to team.
+func (c *Collector) SignalTeam(team, signal string, timeout time.Duration) ([]types.SignalResult, error) {
+	c.stateMutex.RLock()
+	var pids []int32
+	for _, p := range c.state.Processes {
+		if p.Team == team {
+			pids = append(pids, p.PID)
+		}
+	}
+	c.stateMutex.RUnlock()
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("%w: none linked to team %q", ErrProcessNotMonitored, team)
+	}
+	return c.SignalProcesses(pids, signal, timeout)
+}
+
+// SummarizeSignalResults condenses results into one line, e.g. for an
+// audit entry: "SIGTERM 101 exited, 102 running".
+func SummarizeSignalResults(results []types.SignalResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+	parts := make([]string, len(results))
+	for i, result := range results {
+		outcome := "running"
+		switch {
+		case result.Error != "":
+			outcome = "failed: " + result.Error
+		case result.Exited:
+			outcome = "exited"
+		}
+		parts[i] = fmt.Sprintf("%d %s", result.PID, outcome)
+	}
+	return results[0].Signal + " " + strings.Join(parts, ", ")
+}
+
+func sendSignal(pid int32, signal syscall.Signal) error {
+	p, err := os.FindProcess(int(pid))
+	if err != nil {
+		return err
+	}
+	return p.Signal(signal)
+}
+
+// processAlive reports whether target still runs, treating zombies and a
+// reused PID as gone.
+func processAlive(target types.ProcessInfo) bool {
+	p, err := process.NewProcess(target.PID)
+	if err != nil {
+		return false
+	}
+	if created, err := p.CreateTime(); err == nil && created/1000 != target.StartedAt.Unix() {
+		return false
+	}
+	if status, err := p.Status(); err == nil && slices.Contains(status, process.Zombie) {
+		return false
+	}
+	return true
+}
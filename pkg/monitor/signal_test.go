@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+func startSignalTarget(t *testing.T) types.ProcessInfo {
+	t.Helper()
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start a child process: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	p, err := process.NewProcess(int32(cmd.Process.Pid))
+	if err != nil {
+		t.Skipf("cannot inspect the child process: %v", err)
+	}
+	created, err := p.CreateTime()
+	if err != nil {
+		t.Skipf("cannot read the child's start time: %v", err)
+	}
+	return types.ProcessInfo{PID: p.Pid, Command: "sleep 30", Team: "alpha", StartedAt: time.Unix(created/1000, 0)}
+}
+
+func TestSignalTeamWaitsForExit(t *testing.T) {
+	target := startSignalTarget(t)
+	collector := &Collector{state: &types.MonitorState{Processes: []types.ProcessInfo{target}}}
+
+	results, err := collector.SignalTeam("alpha", "term", 2*time.Second)
+	if err != nil {
+		t.Fatalf("SignalTeam: %v", err)
+	}
+	if len(results) != 1 || results[0].PID != target.PID || results[0].Signal != SignalTerminate || !results[0].Exited {
+		t.Fatalf("expected the process to exit, got %+v", results)
+	}
+	if got := SummarizeSignalResults(results); got != "SIGTERM "+strconv.Itoa(int(target.PID))+" exited" {
+		t.Fatalf("unexpected summary %q", got)
+	}
+}
+
+func TestSignalProcessesRefusesUnmonitoredPIDs(t *testing.T) {
+	target := startSignalTarget(t)
+	collector := &Collector{state: &types.MonitorState{Processes: []types.ProcessInfo{{PID: 1, Team: "alpha"}}}}
+
+	if _, err := collector.SignalProcesses([]int32{1, target.PID}, SignalKill, time.Second); !errors.Is(err, ErrProcessNotMonitored) {
+		t.Fatalf("expected an unmonitored PID to be refused, got %v", err)
+	}
+	if !processAlive(target) {
+		t.Fatal("expected nothing to be signalled when one PID is refused")
+	}
+	if _, err := collector.SignalTeam("beta", SignalKill, time.Second); !errors.Is(err, ErrProcessNotMonitored) {
+		t.Fatalf("expected a team without processes to be refused, got %v", err)
+	}
+	if _, err := collector.SignalProcesses([]int32{1}, "SIGHUP", time.Second); err == nil {
+		t.Fatal("expected an unsupported signal to be refused")
+	}
+}
+
+func TestProcessAliveTreatsReusedPIDAsGone(t *testing.T) {
+	target := startSignalTarget(t)
+	target.StartedAt = target.StartedAt.Add(-time.Hour)
+	if processAlive(target) {
+		t.Fatal("expected a different start time to mean the agent is gone")
+	}
+}
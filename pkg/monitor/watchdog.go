@@ -0,0 +1,277 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// Remedies the watchdog can apply to a hung managed agent.
+const (
+	RemedyNone      = "none"
+	RemedyNudge     = "nudge"
+	RemedyInterrupt = "interrupt"
+	RemedyRestart   = "restart"
+)
+
+// Watchdog defaults, used for zero WatchdogSettings fields.
+const (
+	DefaultHungAfter      = 30 * time.Minute
+	DefaultIdleCPUPercent = 1.0
+	DefaultRemedyCooldown = 30 * time.Minute
+	DefaultNudgeText      = "Are you still working? Please continue the current task, or reply with what is blocking you."
+)
+
+const (
+	// watchdogQuietWindow is how long CPU must have stayed idle while
+	// observed, so one quiet sample right after startup flags nothing.
+	watchdogQuietWindow = time.Minute
+	// restartStopTimeout is how long a restart waits for the old process
+	// to exit before killing it.
+	restartStopTimeout = 10 * time.Second
+)
+
+// WatchdogSettings tune hung agent detection. An agent process is hung when
+// it is alive, its CPU usage including children stays below IdleCPUPercent,
+// and its log has not been written for HungAfter.
+type WatchdogSettings struct {
+	HungAfter      time.Duration
+	IdleCPUPercent float64
+	// Remedy is applied to hung managed runs: none, nudge (send NudgeText),
+	// interrupt (press Esc) or restart. At most one remedy per run is
+	// applied within Cooldown.
+	Remedy    string
+	NudgeText string
+	Cooldown  time.Duration
+}
+
+// ParseRemedy accepts none, nudge, interrupt or restart; empty means none.
+func ParseRemedy(name string) (string, error) {
+	switch remedy := strings.ToLower(strings.TrimSpace(name)); remedy {
+	case "", RemedyNone:
+		return RemedyNone, nil
+	case RemedyNudge, RemedyInterrupt, RemedyRestart:
+		return remedy, nil
+	default:
+		return "", fmt.Errorf("unknown remedy %q (use none, nudge, interrupt or restart)", name)
+	}
+}
+
+func (s WatchdogSettings) normalized() WatchdogSettings {
+	s.HungAfter = durationOrDefault(s.HungAfter, DefaultHungAfter)
+	if s.IdleCPUPercent <= 0 {
+		s.IdleCPUPercent = DefaultIdleCPUPercent
+	}
+	if remedy, err := ParseRemedy(s.Remedy); err == nil {
+		s.Remedy = remedy
+	}
+	if strings.TrimSpace(s.NudgeText) == "" {
+		s.NudgeText = DefaultNudgeText
+	}
+	s.Cooldown = durationOrDefault(s.Cooldown, DefaultRemedyCooldown)
+	return s
+}
+
+// ManagedRemedies controls managed runs; *managed.Manager implements it.
+type ManagedRemedies interface {
+	SendMessageToAgent(teamID, agentID, text string) error
+	InterruptAgent(teamID, agentID string) error
+	RestartAgent(teamID, agentID string, timeout time.Duration) (managed.RunState, error)
+}
+
+// SetManagedRemedies lets the watchdog apply its remedy to hung managed
+// runs. Without it hung agents are only flagged.
+func (c *Collector) SetManagedRemedies(remedies ManagedRemedies) {
+	c.watchdog.mu.Lock()
+	c.watchdog.remedies = remedies
+	c.watchdog.mu.Unlock()
+}
+
+// watchdog remembers what it saw of each agent process between scans.
+type watchdog struct {
+	mu        sync.Mutex
+	remedies  ManagedRemedies
+	processes map[int32]*watchedProcess
+	applied   map[string]*appliedRemedy // By managed team and agent ID
+}
+
+type watchedProcess struct {
+	startedAt    time.Time // Tells a reused PID apart
+	idleSince    time.Time
+	flaggedSince time.Time
+}
+
+type appliedRemedy struct {
+	remedy string
+	at     time.Time
+	err    string
+}
+
+// remedyJob is a remedy to apply outside the state lock.
+type remedyJob struct {
+	remedy, teamID, agentID, nudgeText string
+}
+
+// inspect flags hung processes and agents in place and returns the
+// remedies that are due.
+func (w *watchdog) inspect(now time.Time, settings WatchdogSettings, processes []types.ProcessInfo, teams []types.TeamInfo) []remedyJob {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.processes == nil {
+		w.processes = map[int32]*watchedProcess{}
+		w.applied = map[string]*appliedRemedy{}
+	}
+
+	var jobs []remedyJob
+	seen := make(map[int32]struct{}, len(processes))
+	for i := range processes {
+		proc := &processes[i]
+		seen[proc.PID] = struct{}{}
+		watched := w.processes[proc.PID]
+		if watched == nil || !watched.startedAt.Equal(proc.StartedAt) {
+			watched = &watchedProcess{startedAt: proc.StartedAt, idleSince: now}
+			w.processes[proc.PID] = watched
+		}
+
+		cpu := proc.CPUPercent + childrenCPU(proc.Children)
+		if cpu >= settings.IdleCPUPercent {
+			watched.idleSince = now
+		}
+		agent, lastLog := processLogSource(*proc, teams)
+		hung := !lastLog.IsZero() &&
+			now.Sub(watched.idleSince) >= watchdogQuietWindow &&
+			now.Sub(lastLog) >= settings.HungAfter &&
+			now.Sub(proc.StartedAt) >= settings.HungAfter &&
+			(agent == nil || agent.Status != "completed")
+		if !hung {
+			watched.flaggedSince = time.Time{}
+			continue
+		}
+		if watched.flaggedSince.IsZero() {
+			watched.flaggedSince = now
+			log.Printf("Watchdog: pid %d (%s) looks hung: log quiet since %s, cpu %.1f%% since %s",
+				proc.PID, firstNonEmpty(proc.Agent, proc.Team, proc.Command), lastLog.Format(time.RFC3339), cpu, watched.idleSince.Format(time.RFC3339))
+		}
+
+		evidence := &types.HungEvidence{
+			PID:          proc.PID,
+			Since:        watched.flaggedSince,
+			CPUPercent:   cpu,
+			CPUIdleSince: watched.idleSince,
+			LastLogWrite: lastLog,
+		}
+		if proc.ManagedTeamID != "" && proc.ManagedAgentID != "" {
+			key := proc.ManagedTeamID + "/" + proc.ManagedAgentID
+			applied := w.applied[key]
+			if settings.Remedy != RemedyNone && w.remedies != nil && (applied == nil || now.Sub(applied.at) >= settings.Cooldown) {
+				applied = &appliedRemedy{remedy: settings.Remedy, at: now}
+				w.applied[key] = applied
+				jobs = append(jobs, remedyJob{remedy: settings.Remedy, teamID: proc.ManagedTeamID, agentID: proc.ManagedAgentID, nudgeText: settings.NudgeText})
+			}
+			if applied != nil {
+				evidence.Remedy, evidence.RemedyAt, evidence.RemedyError = applied.remedy, applied.at, applied.err
+			}
+		}
+
+		proc.Hung = evidence
+		if agent != nil {
+			agentEvidence := *evidence
+			agent.Status = "hung"
+			agent.Hung = &agentEvidence
+		}
+	}
+
+	for pid := range w.processes {
+		if _, ok := seen[pid]; !ok {
+			delete(w.processes, pid)
+		}
+	}
+	return jobs
+}
+
+// applyRemedy runs job and collects again so the outcome shows.
+func (c *Collector) applyRemedy(job remedyJob) {
+	c.watchdog.apply(job)
+	select {
+	case c.updateChan <- struct{}{}:
+	default:
+	}
+}
+
+// apply runs job and records its outcome for the next scan.
+func (w *watchdog) apply(job remedyJob) {
+	w.mu.Lock()
+	remedies := w.remedies
+	w.mu.Unlock()
+	if remedies == nil {
+		return
+	}
+
+	var err error
+	switch job.remedy {
+	case RemedyNudge:
+		err = remedies.SendMessageToAgent(job.teamID, job.agentID, job.nudgeText)
+	case RemedyInterrupt:
+		err = remedies.InterruptAgent(job.teamID, job.agentID)
+	case RemedyRestart:
+		_, err = remedies.RestartAgent(job.teamID, job.agentID, restartStopTimeout)
+	}
+	if err != nil {
+		log.Printf("Watchdog: %s of managed agent %s/%s failed: %v", job.remedy, job.teamID, job.agentID, err)
+		w.mu.Lock()
+		if applied := w.applied[job.teamID+"/"+job.agentID]; applied != nil {
+			applied.err = err.Error()
+		}
+		w.mu.Unlock()
+		return
+	}
+	log.Printf("Watchdog: applied %s to managed agent %s/%s", job.remedy, job.teamID, job.agentID)
+}
+
+// processLogSource finds the log that shows proc making progress: its
+// agent's, or with only a team known, the latest of the team's members.
+// Managed processes are matched to the logs by session or working
+// directory. The agent is nil unless proc maps to exactly one member.
+func processLogSource(proc types.ProcessInfo, teams []types.TeamInfo) (*types.AgentInfo, time.Time) {
+	if proc.LinkedBy == processLinkManagedEnv {
+		proc.Team, proc.Agent = "", ""
+		if proc.SessionID == "" || !linkProcessBySession(&proc, teams) {
+			linkProcessByCwd(&proc, teams)
+		}
+	}
+	if proc.Team == "" {
+		return nil, time.Time{}
+	}
+	for i := range teams {
+		if teams[i].Name != proc.Team {
+			continue
+		}
+		var latest time.Time
+		for j := range teams[i].Members {
+			member := &teams[i].Members[j]
+			if proc.Agent != "" && types.AgentKey(*member) == proc.Agent {
+				return member, member.LastActiveTime
+			}
+			if member.LastActiveTime.After(latest) {
+				latest = member.LastActiveTime
+			}
+		}
+		if proc.Agent == "" {
+			return nil, latest
+		}
+	}
+	return nil, time.Time{}
+}
+
+func childrenCPU(children []types.ChildProcess) float64 {
+	var total float64
+	for _, child := range children {
+		total += child.CPUPercent + childrenCPU(child.Children)
+	}
+	return total
+}
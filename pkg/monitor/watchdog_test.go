@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/managed"
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+type fakeRemedies struct {
+	calls []string
+}
+
+func (f *fakeRemedies) SendMessageToAgent(teamID, agentID, text string) error {
+	f.calls = append(f.calls, "nudge "+teamID+"/"+agentID+": "+text)
+	return nil
+}
+
+func (f *fakeRemedies) InterruptAgent(teamID, agentID string) error {
+	f.calls = append(f.calls, "interrupt "+teamID+"/"+agentID)
+	return nil
+}
+
+func (f *fakeRemedies) RestartAgent(teamID, agentID string, timeout time.Duration) (managed.RunState, error) {
+	f.calls = append(f.calls, "restart "+teamID+"/"+agentID)
+	return managed.RunState{}, nil
+}
+
+func watchdogFixture(start time.Time) ([]types.ProcessInfo, []types.TeamInfo) {
+	teams := []types.TeamInfo{{
+		Name:       "alpha",
+		Provider:   "claude",
+		ProjectCwd: "/work/alpha",
+		Members: []types.AgentInfo{
+			{Name: "lead", Status: "idle", Cwd: "/work/alpha", LastActiveTime: start.Add(-time.Hour)},
+			{Name: "tester", Status: "working", Cwd: "/work/alpha/tests", LastActiveTime: start},
+		},
+	}}
+	processes := []types.ProcessInfo{
+		{PID: 10, Provider: "claude", Team: "alpha", Agent: "lead", StartedAt: start.Add(-2 * time.Hour)},
+		{PID: 11, Provider: "claude", Team: "alpha", Agent: "tester", StartedAt: start.Add(-2 * time.Hour)},
+		{PID: 12, Provider: "claude", StartedAt: start.Add(-2 * time.Hour)},
+	}
+	return processes, teams
+}
+
+func TestWatchdogFlagsIdleAgentsWithStaleLogs(t *testing.T) {
+	start := time.Now()
+	settings := WatchdogSettings{}.normalized()
+	w := &watchdog{}
+
+	processes, teams := watchdogFixture(start)
+	w.inspect(start, settings, processes, teams)
+	if processes[0].Hung != nil {
+		t.Fatal("expected no flag before CPU has been idle for the quiet window")
+	}
+
+	later := start.Add(2 * time.Minute)
+	processes, teams = watchdogFixture(start)
+	processes[0].Children = []types.ChildProcess{{PID: 20, CPUPercent: 0.2}}
+	w.inspect(later, settings, processes, teams)
+
+	evidence := processes[0].Hung
+	if evidence == nil || evidence.PID != 10 || !evidence.Since.Equal(later) || !evidence.CPUIdleSince.Equal(start) ||
+		!evidence.LastLogWrite.Equal(start.Add(-time.Hour)) || evidence.CPUPercent != 0.2 {
+		t.Fatalf("expected evidence for the stale lead, got %+v", evidence)
+	}
+	if lead := teams[0].Members[0]; lead.Status != "hung" || lead.Hung == nil || lead.Hung.PID != 10 {
+		t.Fatalf("expected the lead to be hung, got %+v", lead)
+	}
+	if processes[1].Hung != nil || teams[0].Members[1].Status != "working" {
+		t.Fatal("expected the agent with a fresh log to stay as it is")
+	}
+	if processes[2].Hung != nil {
+		t.Fatal("expected a process without a log to be left alone")
+	}
+
+	processes, teams = watchdogFixture(start)
+	processes[0].CPUPercent = 35
+	w.inspect(later.Add(time.Minute), settings, processes, teams)
+	if processes[0].Hung != nil || teams[0].Members[0].Status != "idle" {
+		t.Fatal("expected CPU use to clear the flag")
+	}
+}
+
+func TestWatchdogAppliesRemedyToManagedRunsWithCooldown(t *testing.T) {
+	start := time.Now()
+	remedies := &fakeRemedies{}
+	w := &watchdog{remedies: remedies}
+	settings := WatchdogSettings{Remedy: RemedyNudge, NudgeText: "still there?", Cooldown: 10 * time.Minute}.normalized()
+
+	scan := func(at time.Time) []types.ProcessInfo {
+		processes, teams := watchdogFixture(start)
+		processes[0].ManagedTeamID, processes[0].ManagedAgentID = "team-1", "lead-1"
+		processes[0].Team, processes[0].Agent, processes[0].LinkedBy = "", "lead", processLinkManagedEnv
+		processes[0].Cwd = "/work/alpha"
+		for _, job := range w.inspect(at, settings, processes, teams) {
+			w.apply(job)
+		}
+		return processes
+	}
+
+	scan(start)
+	processes := scan(start.Add(2 * time.Minute))
+	if len(remedies.calls) != 1 || remedies.calls[0] != "nudge team-1/lead-1: still there?" {
+		t.Fatalf("expected one nudge, got %q", remedies.calls)
+	}
+	if hung := processes[0].Hung; hung == nil || hung.Remedy != RemedyNudge || hung.RemedyError != "" {
+		t.Fatalf("expected the nudge in the evidence, got %+v", hung)
+	}
+
+	scan(start.Add(5 * time.Minute))
+	if len(remedies.calls) != 1 {
+		t.Fatalf("expected the cooldown to hold back a second remedy, got %q", remedies.calls)
+	}
+	scan(start.Add(13 * time.Minute))
+	if len(remedies.calls) != 2 {
+		t.Fatalf("expected a second remedy after the cooldown, got %q", remedies.calls)
+	}
+
+	settings.Remedy = RemedyNone
+	scan(start.Add(30 * time.Minute))
+	if len(remedies.calls) != 2 {
+		t.Fatalf("expected no remedy when none is configured, got %q", remedies.calls)
+	}
+}
@@ -59,6 +59,7 @@ type AgentActivityEvent struct {
 	Kind      string
 	Title     string
 	Text      string
+	ToolID    string // tool_use id, shared by the matching tool_result
 	Timestamp time.Time
 }
 
@@ -85,53 +86,32 @@ type activityRecord struct {
 const activityLogTailLines = 200
 const activityLogEventLimit = 24
 
-// ParseAgentActivity parses the agent's jsonl log file and extracts recent activity
+// activityTails follows the logs read by ParseAgentActivity.
+var activityTails = newLogTailer(activityLogTailLines, false, parseActivityLines, mergeAgentActivity)
+
+// ParseAgentActivity parses the agent's jsonl log file and extracts recent activity.
+// Lines parsed by an earlier call are not parsed again.
 func ParseAgentActivity(logPath string) (*AgentActivity, error) {
-	file, err := os.Open(logPath)
+	activity, err := activityTails.read(logPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil // No log file is not an error
 		}
 		return nil, err
 	}
-	defer file.Close()
-
-	activity := &AgentActivity{}
-	scanner := newLargeScanner(file)
-
-	// Use a ring buffer to keep only the last N lines in memory
-	const tailSize = activityLogTailLines
-	ring := make([]string, tailSize)
-	ringIdx := 0
-	totalLines := 0
-	for scanner.Scan() {
-		ring[ringIdx%tailSize] = scanner.Text()
-		ringIdx++
-		totalLines++
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	// Determine how many tail lines we have
-	count := totalLines
-	if count > tailSize {
-		count = tailSize
-	}
+	return &activity, nil
+}
 
+// parseActivityLines extracts the activity recorded in lines, oldest first.
+func parseActivityLines(lines [][]byte) AgentActivity {
+	activity := AgentActivity{}
 	recentEvents := make([]AgentActivityEvent, 0, activityLogEventLimit)
 	pendingToolResults := make(map[string]int)
 
 	// Process lines in reverse to get most recent activity first
-	for k := 0; k < count; k++ {
-		idx := (ringIdx - 1 - k) % tailSize
-		if idx < 0 {
-			idx += tailSize
-		}
-
+	for k := len(lines) - 1; k >= 0; k-- {
 		var entry ActivityLog
-		if err := json.Unmarshal([]byte(ring[idx]), &entry); err != nil {
+		if err := json.Unmarshal(lines[k], &entry); err != nil {
 			continue
 		}
 
@@ -190,8 +170,42 @@ func ParseAgentActivity(logPath string) (*AgentActivity, error) {
 	}
 
 	activity.RecentEvents = dedupeActivityEvents(recentEvents, activityLogEventLimit)
+	return activity
+}
 
-	return activity, nil
+// mergeAgentActivity folds the activity of newer lines into that of older
+// ones. A tool result whose call is among the older events is terminal
+// output when the call was a terminal command.
+func mergeAgentActivity(newer, older AgentActivity) AgentActivity {
+	merged := older
+	if newer.LastThinking != "" {
+		merged.LastThinking = newer.LastThinking
+	}
+	if newer.LastToolUse != "" {
+		merged.LastToolUse, merged.LastToolDetail = newer.LastToolUse, newer.LastToolDetail
+	}
+	if newer.LastResponse != "" {
+		merged.LastResponse = newer.LastResponse
+	}
+	if newer.LastActiveTime.After(merged.LastActiveTime) {
+		merged.LastActiveTime = newer.LastActiveTime
+	}
+
+	terminalCalls := make(map[string]struct{})
+	for _, event := range older.RecentEvents {
+		if event.Kind == "terminal" && event.ToolID != "" {
+			terminalCalls[event.ToolID] = struct{}{}
+		}
+	}
+	events := make([]AgentActivityEvent, 0, len(newer.RecentEvents)+len(older.RecentEvents))
+	for _, event := range newer.RecentEvents {
+		if _, ok := terminalCalls[event.ToolID]; ok && event.Kind == "tool_result" {
+			event.Kind, event.Title = "terminal_output", "终端输出"
+		}
+		events = append(events, event)
+	}
+	merged.RecentEvents = dedupeActivityEvents(append(events, older.RecentEvents...), activityLogEventLimit)
+	return merged
 }
 
 func normalizeActivityText(text string) string {
@@ -287,6 +301,7 @@ func appendActivityEvents(events []AgentActivityEvent, recordType string, conten
 				Kind:      kind,
 				Title:     title,
 				Text:      text,
+				ToolID:    item.ID,
 				Timestamp: timestamp,
 			})
 			if item.ID != "" {
@@ -305,6 +320,7 @@ func appendActivityEvents(events []AgentActivityEvent, recordType string, conten
 				Kind:      kind,
 				Title:     title,
 				Text:      text,
+				ToolID:    item.ToolUseID,
 				Timestamp: timestamp,
 			})
 			if item.ToolUseID != "" {
@@ -197,10 +197,10 @@ func TestParseAgentActivity_ClassifiesTerminalAndToolResultEvents(t *testing.T)
 	foundTerminalOutput := false
 	for _, event := range activity.RecentEvents {
 		if event.Kind == "terminal" && strings.Contains(event.Text, "git status --short") {
-			foundTerminal = true
+			foundTerminal = event.ToolID == "call-bash-1"
 		}
 		if event.Kind == "terminal_output" && strings.Contains(event.Text, "web/static/js/app.js") {
-			foundTerminalOutput = true
+			foundTerminalOutput = event.ToolID == "call-bash-1"
 		}
 	}
 
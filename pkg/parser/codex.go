@@ -15,6 +15,9 @@ var codexSessionIDPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]
 
 const codexSessionTailLines = 240
 
+// codexSessionTails follows the session logs read by DiscoverCodexSessions.
+var codexSessionTails = newLogTailer(codexSessionTailLines, true, parseCodexSessionLines, mergeCodexSessions)
+
 // CodexSessionDiscovery is summarized runtime activity inferred from one codex session log.
 type CodexSessionDiscovery struct {
 	SessionID        string
@@ -38,6 +41,7 @@ type CodexSessionEvent struct {
 	Kind      string
 	Title     string
 	Text      string
+	ToolID    string // function call_id, shared by the matching output
 	Timestamp time.Time
 }
 
@@ -123,7 +127,7 @@ func DiscoverCodexSessions(sessionsDir string, maxAge time.Duration) ([]CodexSes
 			continue
 		}
 
-		session, err := inspectCodexSessionLog(sessionPath)
+		session, err := codexSessionTails.read(sessionPath)
 		if err != nil {
 			continue
 		}
@@ -155,63 +159,31 @@ func DiscoverCodexSessions(sessionsDir string, maxAge time.Duration) ([]CodexSes
 	return discovered, nil
 }
 
-func inspectCodexSessionLog(logPath string) (CodexSessionDiscovery, error) {
-	file, err := os.Open(logPath)
-	if err != nil {
-		return CodexSessionDiscovery{}, err
-	}
-	defer file.Close()
-
+// parseCodexSessionLines summarizes lines of a codex session log, oldest
+// first. A session_meta first line wins over later ones.
+func parseCodexSessionLines(lines [][]byte) CodexSessionDiscovery {
 	result := CodexSessionDiscovery{}
-	ring := make([]string, codexSessionTailLines)
-	ringIdx := 0
-	totalLines := 0
-
-	scanner := newLargeScanner(file)
-	firstTimestamp := time.Time{}
-	for scanner.Scan() {
-		line := scanner.Text()
-		if totalLines == 0 {
-			var firstEntry codexLogEntry
-			if err := json.Unmarshal([]byte(line), &firstEntry); err == nil {
-				if ts := parseCodexTimestamp(firstEntry.Timestamp); !ts.IsZero() {
-					firstTimestamp = ts
-				}
-				if firstEntry.Type == "session_meta" {
-					applyCodexSessionMeta(&result, firstEntry.Payload)
-				}
-			}
-		}
-
-		ring[ringIdx%codexSessionTailLines] = line
-		ringIdx++
-		totalLines++
-	}
-	if err := scanner.Err(); err != nil {
-		return CodexSessionDiscovery{}, err
+	if len(lines) == 0 {
+		return result
 	}
 
-	if result.StartedAt.IsZero() && !firstTimestamp.IsZero() {
-		result.StartedAt = firstTimestamp
-	}
-
-	count := totalLines
-	if count > codexSessionTailLines {
-		count = codexSessionTailLines
+	var firstEntry codexLogEntry
+	if err := json.Unmarshal(lines[0], &firstEntry); err == nil {
+		if firstEntry.Type == "session_meta" {
+			applyCodexSessionMeta(&result, firstEntry.Payload)
+		}
+		if ts := parseCodexTimestamp(firstEntry.Timestamp); result.StartedAt.IsZero() && !ts.IsZero() {
+			result.StartedAt = ts
+		}
 	}
 
 	recentEvents := make([]CodexSessionEvent, 0, 16)
 	pendingToolOutputIdx := make(map[string]int)
 	seenToolOutputCalls := make(map[string]struct{})
 
-	for k := 0; k < count; k++ {
-		idx := (ringIdx - 1 - k) % codexSessionTailLines
-		if idx < 0 {
-			idx += codexSessionTailLines
-		}
-
+	for k := len(lines) - 1; k >= 0; k-- {
 		var entry codexLogEntry
-		if err := json.Unmarshal([]byte(ring[idx]), &entry); err != nil {
+		if err := json.Unmarshal(lines[k], &entry); err != nil {
 			continue
 		}
 
@@ -303,6 +275,7 @@ func inspectCodexSessionLog(logPath string) (CodexSessionDiscovery, error) {
 					Kind:      kind,
 					Title:     title,
 					Text:      text,
+					ToolID:    payload.CallID,
 					Timestamp: ts,
 				})
 			}
@@ -326,6 +299,7 @@ func inspectCodexSessionLog(logPath string) (CodexSessionDiscovery, error) {
 						Kind:      kind,
 						Title:     title,
 						Text:      text,
+						ToolID:    payload.CallID,
 						Timestamp: ts,
 					})
 				}
@@ -346,6 +320,7 @@ func inspectCodexSessionLog(logPath string) (CodexSessionDiscovery, error) {
 						Kind:      kind,
 						Title:     title,
 						Text:      text,
+						ToolID:    payload.CallID,
 						Timestamp: ts,
 					})
 					if payload.CallID != "" {
@@ -387,7 +362,62 @@ func inspectCodexSessionLog(logPath string) (CodexSessionDiscovery, error) {
 
 	result.RecentEvents = dedupeCodexEvents(recentEvents, 24)
 
-	return result, nil
+	return result
+}
+
+// mergeCodexSessions folds the summary of newer lines into that of older
+// ones. Session metadata keeps its first value. A call keeps only its
+// newest result, which is terminal output when the call was a terminal
+// command.
+func mergeCodexSessions(newer, older CodexSessionDiscovery) CodexSessionDiscovery {
+	merged := older
+	merged.SessionID = firstNonEmpty(older.SessionID, newer.SessionID)
+	merged.Cwd = firstNonEmpty(older.Cwd, newer.Cwd)
+	merged.DisplayName = firstNonEmpty(older.DisplayName, newer.DisplayName)
+	merged.AgentRole = firstNonEmpty(older.AgentRole, newer.AgentRole)
+	if merged.StartedAt.IsZero() {
+		merged.StartedAt = newer.StartedAt
+	}
+	if newer.LastActiveAt.After(merged.LastActiveAt) {
+		merged.LastActiveAt = newer.LastActiveAt
+	}
+	merged.LastUserMessage = firstNonEmpty(newer.LastUserMessage, older.LastUserMessage)
+	merged.LastAgentMessage = firstNonEmpty(newer.LastAgentMessage, older.LastAgentMessage)
+	merged.FullAgentMessage = firstNonEmpty(newer.FullAgentMessage, older.FullAgentMessage)
+	merged.LastReasoning = firstNonEmpty(newer.LastReasoning, older.LastReasoning)
+	if newer.LastToolUse != "" {
+		merged.LastToolUse, merged.LastToolDetail = newer.LastToolUse, newer.LastToolDetail
+	}
+
+	terminalCalls := make(map[string]struct{})
+	for _, event := range older.RecentEvents {
+		if event.Kind == "terminal" && event.ToolID != "" {
+			terminalCalls[event.ToolID] = struct{}{}
+		}
+	}
+	resultCalls := make(map[string]struct{})
+	events := make([]CodexSessionEvent, 0, len(newer.RecentEvents)+len(older.RecentEvents))
+	for _, event := range newer.RecentEvents {
+		if isCodexToolResult(event) {
+			if _, ok := terminalCalls[event.ToolID]; ok && event.Kind == "tool_result" {
+				event.Kind, event.Title = "terminal_output", "终端输出"
+			}
+			resultCalls[event.ToolID] = struct{}{}
+		}
+		events = append(events, event)
+	}
+	for _, event := range older.RecentEvents {
+		if _, ok := resultCalls[event.ToolID]; ok && isCodexToolResult(event) {
+			continue
+		}
+		events = append(events, event)
+	}
+	merged.RecentEvents = dedupeCodexEvents(events, 24)
+	return merged
+}
+
+func isCodexToolResult(event CodexSessionEvent) bool {
+	return event.ToolID != "" && (event.Kind == "tool_result" || event.Kind == "terminal_output")
 }
 
 func applyCodexSessionMeta(target *CodexSessionDiscovery, payload json.RawMessage) {
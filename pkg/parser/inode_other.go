@@ -0,0 +1,11 @@
+//go:build !unix
+
+package parser
+
+import "os"
+
+// fileInode returns 0 where inodes are not available, so a replaced file
+// is only noticed when it is smaller than the offset read up to.
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package parser
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode tells a replaced file apart from the one it replaced.
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
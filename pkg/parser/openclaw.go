@@ -12,6 +12,9 @@ import (
 
 const openClawSessionTailLines = 320
 
+// openClawSessionTails follows the transcripts read by DiscoverOpenClawSessions.
+var openClawSessionTails = newLogTailer(openClawSessionTailLines, false, parseOpenClawSessionLines, mergeOpenClawSessions)
+
 var openClawSessionIDPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
 
 // OpenClawSessionDiscovery is summarized runtime activity inferred from one OpenClaw session.
@@ -267,7 +270,7 @@ func buildOpenClawSessionFromPath(session OpenClawSessionDiscovery, sessionPath
 		return OpenClawSessionDiscovery{}, false
 	}
 
-	inspected, err := inspectOpenClawSessionLog(sessionPath)
+	inspected, err := openClawSessionTails.read(sessionPath)
 	if err != nil {
 		return OpenClawSessionDiscovery{}, false
 	}
@@ -299,44 +302,15 @@ func buildOpenClawSessionFromPath(session OpenClawSessionDiscovery, sessionPath
 	return session, true
 }
 
-func inspectOpenClawSessionLog(logPath string) (OpenClawSessionDiscovery, error) {
-	file, err := os.Open(logPath)
-	if err != nil {
-		return OpenClawSessionDiscovery{}, err
-	}
-	defer file.Close()
-
+// parseOpenClawSessionLines summarizes lines of an OpenClaw transcript,
+// oldest first.
+func parseOpenClawSessionLines(lines [][]byte) OpenClawSessionDiscovery {
 	result := OpenClawSessionDiscovery{}
-	ring := make([]string, openClawSessionTailLines)
-	ringIdx := 0
-	totalLines := 0
-
-	scanner := newLargeScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		ring[ringIdx%openClawSessionTailLines] = line
-		ringIdx++
-		totalLines++
-	}
-	if err := scanner.Err(); err != nil {
-		return OpenClawSessionDiscovery{}, err
-	}
-
-	count := totalLines
-	if count > openClawSessionTailLines {
-		count = openClawSessionTailLines
-	}
-
 	recentEvents := make([]OpenClawSessionEvent, 0, 10)
 
-	for k := 0; k < count; k++ {
-		idx := (ringIdx - 1 - k) % openClawSessionTailLines
-		if idx < 0 {
-			idx += openClawSessionTailLines
-		}
-
+	for k := len(lines) - 1; k >= 0; k-- {
 		var envelope openClawTranscriptEnvelope
-		if err := json.Unmarshal([]byte(ring[idx]), &envelope); err != nil {
+		if err := json.Unmarshal(lines[k], &envelope); err != nil {
 			continue
 		}
 		if envelope.Message == nil {
@@ -425,7 +399,29 @@ func inspectOpenClawSessionLog(logPath string) (OpenClawSessionDiscovery, error)
 	}
 
 	result.RecentEvents = dedupeOpenClawEvents(recentEvents, 24)
-	return result, nil
+	return result
+}
+
+// mergeOpenClawSessions folds the summary of newer lines into that of older
+// ones.
+func mergeOpenClawSessions(newer, older OpenClawSessionDiscovery) OpenClawSessionDiscovery {
+	merged := older
+	if merged.StartedAt.IsZero() || (!newer.StartedAt.IsZero() && newer.StartedAt.Before(merged.StartedAt)) {
+		merged.StartedAt = newer.StartedAt
+	}
+	if newer.LastActiveAt.After(merged.LastActiveAt) {
+		merged.LastActiveAt = newer.LastActiveAt
+	}
+	merged.LastUserMessage = firstNonEmpty(newer.LastUserMessage, older.LastUserMessage)
+	merged.LastAgentMessage = firstNonEmpty(newer.LastAgentMessage, older.LastAgentMessage)
+	merged.FullAgentMessage = firstNonEmpty(newer.FullAgentMessage, older.FullAgentMessage)
+	merged.LastReasoning = firstNonEmpty(newer.LastReasoning, older.LastReasoning)
+	if newer.LastToolUse != "" {
+		merged.LastToolUse, merged.LastToolDetail = newer.LastToolUse, newer.LastToolDetail
+	}
+	events := append(append(make([]OpenClawSessionEvent, 0, len(newer.RecentEvents)+len(older.RecentEvents)), newer.RecentEvents...), older.RecentEvents...)
+	merged.RecentEvents = dedupeOpenClawEvents(events, 24)
+	return merged
 }
 
 func resolveOpenClawSessionPath(sessionsDir, sessionID string, entry openClawSessionIndexEntry) string {
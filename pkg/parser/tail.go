@@ -0,0 +1,288 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// logTailBlockSize is how much a tail read reads per step back from
+	// the end of a file.
+	logTailBlockSize = 64 * 1024
+	// logTailSkipBytes is how much may be appended between two reads before
+	// the appended bytes are tail read instead of parsed in full.
+	logTailSkipBytes = 8 * 1024 * 1024
+	logTailMaxFiles  = 4000
+	logOffsetsFormat = 1
+)
+
+// logTailer follows append-only JSONL logs. For each file it remembers the
+// inode, the size and the offset up to which lines were parsed, along with
+// the state derived from them, so a read only parses the lines appended
+// since the previous one and merges them into that state. A new file, or
+// one that was truncated or replaced, is read from its last lines instead,
+// found by seeking back from the end.
+type logTailer[S any] struct {
+	lines int
+	// head makes a tail read also parse the file's first line, as the
+	// oldest state.
+	head bool
+	// parse derives state from complete lines, oldest first.
+	parse func(lines [][]byte) S
+	// merge folds the state of newer lines into the state of older ones.
+	merge func(newer, older S) S
+
+	mu    sync.Mutex
+	files map[string]*tailedLog[S]
+}
+
+// tailedLog is what a logTailer keeps of one file. The exported fields are
+// saved by SaveLogOffsets.
+type tailedLog[S any] struct {
+	Inode   uint64 `json:"inode,omitempty"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+	// Offset is just past the last complete line; State covers the lines
+	// before it.
+	Offset int64 `json:"offset"`
+	State  S     `json:"state"`
+
+	// current adds a trailing line without a newline to State; it answers
+	// reads while the file stays the same.
+	current S
+	ready   bool
+}
+
+func newLogTailer[S any](lines int, head bool, parse func([][]byte) S, merge func(newer, older S) S) *logTailer[S] {
+	return &logTailer[S]{lines: lines, head: head, parse: parse, merge: merge, files: map[string]*tailedLog[S]{}}
+}
+
+// read returns the state of the file at path, parsing what was appended
+// since the last read.
+func (t *logTailer[S]) read(path string) (S, error) {
+	var zero S
+	info, err := os.Stat(path)
+	if err != nil {
+		return zero, err
+	}
+	inode := fileInode(info)
+	size := info.Size()
+	modTime := info.ModTime().UnixNano()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tailed := t.files[path]
+	if tailed != nil && tailed.ready && tailed.Inode == inode && tailed.Size == size && tailed.ModTime == modTime {
+		return tailed.current, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return zero, err
+	}
+	defer file.Close()
+
+	resume := tailed != nil && tailed.Inode == inode && size >= tailed.Offset
+	next := &tailedLog[S]{Inode: inode, Size: size, ModTime: modTime}
+	start := int64(0)
+	if resume {
+		start = tailed.Offset
+	}
+	if !resume || size-start > logTailSkipBytes {
+		tailStart, err := lastLinesStart(file, size, t.lines)
+		if err != nil {
+			return zero, err
+		}
+		start = max(start, tailStart)
+	}
+
+	lines, partial, err := readLines(file, start, size)
+	if err != nil {
+		return zero, err
+	}
+	next.Offset = start
+	for _, line := range lines {
+		next.Offset += int64(len(line)) + 1
+	}
+
+	next.State = t.parse(lines)
+	switch {
+	case resume:
+		next.State = t.merge(next.State, tailed.State)
+	case start > 0 && t.head:
+		if first, err := readFirstLine(file, size); err == nil && first != nil {
+			next.State = t.merge(next.State, t.parse([][]byte{first}))
+		}
+	}
+	next.current = next.State
+	if len(bytes.TrimSpace(partial)) > 0 {
+		next.current = t.merge(t.parse([][]byte{partial}), next.State)
+	}
+	next.ready = true
+
+	if tailed == nil && len(t.files) >= logTailMaxFiles {
+		t.files = map[string]*tailedLog[S]{}
+	}
+	t.files[path] = next
+	return next.current, nil
+}
+
+// saved returns the files worth saving: those read up to a line end.
+func (t *logTailer[S]) saved() map[string]*tailedLog[S] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	files := make(map[string]*tailedLog[S], len(t.files))
+	for path, tailed := range t.files {
+		if tailed.ready && tailed.Offset > 0 {
+			files[path] = tailed
+		}
+	}
+	return files
+}
+
+// restore adds saved files that have not been read yet. Their next read
+// resumes at the saved offset when the file is still the same one.
+func (t *logTailer[S]) restore(files map[string]*tailedLog[S]) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for path, tailed := range files {
+		if _, ok := t.files[path]; ok || tailed == nil || len(t.files) >= logTailMaxFiles {
+			continue
+		}
+		if tailed.Size == tailed.Offset {
+			tailed.current, tailed.ready = tailed.State, true
+		}
+		t.files[path] = tailed
+	}
+}
+
+// lastLinesStart returns the offset of the first of the last n complete
+// lines before size, or 0 when the file has no more.
+func lastLinesStart(file *os.File, size int64, n int) (int64, error) {
+	buf := make([]byte, logTailBlockSize)
+	newlines := 0
+	for end := size; end > 0; {
+		start := max(end-logTailBlockSize, 0)
+		block := buf[:end-start]
+		if _, err := file.ReadAt(block, start); err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		for i := len(block) - 1; i >= 0; i-- {
+			if block[i] != '\n' {
+				continue
+			}
+			// Past the newline that ends the line before the last n.
+			if newlines++; newlines > n {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// readLines reads the complete lines between start and end and the partial
+// line after them.
+func readLines(file *os.File, start, end int64) (lines [][]byte, partial []byte, err error) {
+	reader := bufio.NewReaderSize(io.NewSectionReader(file, start, end-start), 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return lines, line, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		lines = append(lines, line[:len(line)-1])
+	}
+}
+
+func readFirstLine(file *os.File, size int64) ([]byte, error) {
+	reader := bufio.NewReaderSize(io.NewSectionReader(file, 0, size), 64*1024)
+	line, err := reader.ReadBytes('\n')
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return line[:len(line)-1], nil
+}
+
+// logOffsets is the file written by SaveLogOffsets.
+type logOffsets struct {
+	Version  int                                             `json:"version"`
+	Activity map[string]*tailedLog[AgentActivity]            `json:"activity,omitempty"`
+	Codex    map[string]*tailedLog[CodexSessionDiscovery]    `json:"codex,omitempty"`
+	OpenClaw map[string]*tailedLog[OpenClawSessionDiscovery] `json:"openclaw,omitempty"`
+}
+
+// SaveLogOffsets writes where each followed log was read up to, with the
+// activity parsed from it, so LoadLogOffsets can spare a restarted monitor
+// from reading the logs again.
+func SaveLogOffsets(path string) error {
+	data, err := json.Marshal(logOffsets{
+		Version:  logOffsetsFormat,
+		Activity: activityTails.saved(),
+		Codex:    codexSessionTails.saved(),
+		OpenClaw: openClawSessionTails.saved(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".log-offsets-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadLogOffsets reads a file written by SaveLogOffsets. A missing file or
+// one in an older format is not an error.
+func LoadLogOffsets(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var offsets logOffsets
+	if err := json.Unmarshal(data, &offsets); err != nil {
+		return err
+	}
+	if offsets.Version != logOffsetsFormat {
+		return nil
+	}
+	activityTails.restore(offsets.Activity)
+	codexSessionTails.restore(offsets.Codex)
+	openClawSessionTails.restore(offsets.OpenClaw)
+	return nil
+}
+
+// firstNonEmpty returns the first value that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
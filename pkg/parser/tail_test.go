@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineTailer records the lines it is handed to parse.
+func lineTailer(limit int, head bool, parsed *[]string) *logTailer[[]string] {
+	return newLogTailer(limit, head, func(lines [][]byte) []string {
+		var state []string
+		for _, line := range lines {
+			*parsed = append(*parsed, string(line))
+			state = append(state, string(line))
+		}
+		return state
+	}, func(newer, older []string) []string {
+		return append(append([]string(nil), older...), newer...)
+	})
+}
+
+func appendToFile(t *testing.T, path, text string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogTailerParsesOnlyAppendedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	appendToFile(t, path, "l1\nl2\nl3\nl4\nl5\n")
+	var parsed []string
+	tailer := lineTailer(3, true, &parsed)
+
+	state, err := tailer.read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(state, ","); got != "l1,l3,l4,l5" {
+		t.Fatalf("expected the first line and the last three, got %s", got)
+	}
+
+	parsed = nil
+	appendToFile(t, path, "l6\nl7\npart")
+	state, _ = tailer.read(path)
+	if got := strings.Join(state, ","); got != "l1,l3,l4,l5,l6,l7,part" {
+		t.Fatalf("unexpected state %s", got)
+	}
+	if got := strings.Join(parsed, ","); got != "l6,l7,part" {
+		t.Fatalf("expected only appended lines to be parsed, got %s", got)
+	}
+
+	parsed = nil
+	if _, err := tailer.read(path); err != nil || len(parsed) != 0 {
+		t.Fatalf("expected an unchanged file not to be parsed, got %q, %v", parsed, err)
+	}
+	appendToFile(t, path, "ial\n")
+	state, _ = tailer.read(path)
+	if got := strings.Join(state, ","); got != "l1,l3,l4,l5,l6,l7,partial" {
+		t.Fatalf("expected the completed line once, got %s", got)
+	}
+}
+
+func TestLogTailerStartsOverOnTruncationAndRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "session.jsonl")
+	appendToFile(t, path, "a1\na2\na3\na4\n")
+	var parsed []string
+	tailer := lineTailer(2, false, &parsed)
+	if _, err := tailer.read(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("b1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := tailer.read(path); strings.Join(state, ",") != "b1" {
+		t.Fatalf("expected a truncated file to be read again, got %q", state)
+	}
+
+	rotated := filepath.Join(dir, "rotated.jsonl")
+	if err := os.WriteFile(rotated, []byte("c1\nc2\nc3\nc4\nc5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(rotated, path); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := tailer.read(path); strings.Join(state, ",") != "c4,c5" {
+		t.Fatalf("expected the tail of the replacing file, got %q", state)
+	}
+}
+
+func TestParseAgentActivity_PairsToolResultAcrossReads(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "agent-tail.jsonl")
+	call := logEntry("assistant", "2026-04-10T09:10:00Z", "aa1234", "session-1", "/workspace/project", map[string]any{
+		"role": "assistant",
+		"content": []any{
+			map[string]any{"type": "text", "text": "先看一下状态。"},
+			map[string]any{"type": "tool_use", "id": "call-1", "name": "Bash", "input": map[string]any{"command": "git status --short"}},
+		},
+	})
+	result := logEntry("user", "2026-04-10T09:10:01Z", "aa1234", "session-1", "/workspace/project", map[string]any{
+		"role":    "user",
+		"content": []any{map[string]any{"type": "tool_result", "tool_use_id": "call-1", "content": " M README.md"}},
+	})
+	mustWriteJSONL(t, logPath, []any{call})
+	if _, err := ParseAgentActivity(logPath); err != nil {
+		t.Fatal(err)
+	}
+
+	fullPath := filepath.Join(t.TempDir(), "agent-full.jsonl")
+	mustWriteJSONL(t, fullPath, []any{call, result})
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	incremental, err := ParseAgentActivity(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := ParseAgentActivity(fullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(*incremental) != fmt.Sprint(*full) {
+		t.Fatalf("incremental activity differs from a full read:\n%+v\n%+v", *incremental, *full)
+	}
+	if len(incremental.RecentEvents) == 0 || incremental.RecentEvents[0].Kind != "terminal_output" {
+		t.Fatalf("expected the appended result to be terminal output, got %+v", incremental.RecentEvents)
+	}
+}
+
+func TestDiscoverCodexSessions_KeepsSessionMetaOfLongLogs(t *testing.T) {
+	root := t.TempDir()
+	sessionsDir := filepath.Join(root, "sessions", "2026", "03", "22")
+	if err := os.MkdirAll(sessionsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sessionID := "019d11a7-145a-7be3-bf0d-8939469bc2a3"
+	logPath := filepath.Join(sessionsDir, "rollout-2026-03-22T02-27-35-"+sessionID+".jsonl")
+	lines := []string{`{"timestamp":"2026-03-21T18:27:35.654Z","type":"session_meta","payload":{"id":"` + sessionID + `","timestamp":"2026-03-21T18:27:35.645Z","cwd":"/home/test/work/demo","agent_nickname":"Gauss","agent_role":"explorer"}}`}
+	for i := 0; i < 2*codexSessionTailLines; i++ {
+		lines = append(lines, `{"timestamp":"2026-03-21T18:28:00.000Z","type":"event_msg","payload":{"type":"agent_message","message":"step `+fmt.Sprint(i)+`"}}`)
+	}
+	if err := os.WriteFile(logPath, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := DiscoverCodexSessions(filepath.Join(root, "sessions"), 24*time.Hour)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("expected one session, got %d, %v", len(sessions), err)
+	}
+	session := sessions[0]
+	if session.SessionID != sessionID || session.Cwd != "/home/test/work/demo" || session.DisplayName != "Gauss (explorer)" ||
+		!session.StartedAt.Equal(time.Date(2026, 3, 21, 18, 27, 35, 645000000, time.UTC)) {
+		t.Fatalf("expected the metadata from the first line, got %+v", session)
+	}
+	if want := fmt.Sprint("step ", 2*codexSessionTailLines-1); session.LastAgentMessage != want {
+		t.Fatalf("expected the last message %q, got %q", want, session.LastAgentMessage)
+	}
+}
+
+func TestLogOffsetsResumeAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "agent.jsonl")
+	reply := func(timestamp, text string) any {
+		return logEntry("assistant", timestamp, "aa1234", "session-1", "/workspace/project", map[string]any{
+			"role":    "assistant",
+			"content": []any{map[string]any{"type": "text", "text": text}},
+		})
+	}
+	mustWriteJSONL(t, logPath, []any{reply("2026-04-10T09:10:00Z", "第一步完成。")})
+	if _, err := ParseAgentActivity(logPath); err != nil {
+		t.Fatal(err)
+	}
+	offsetsPath := filepath.Join(dir, "state", "log-offsets.json")
+	if err := SaveLogOffsets(offsetsPath); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := activityTails.files
+	activityTails.files = map[string]*tailedLog[AgentActivity]{}
+	t.Cleanup(func() { activityTails.files = previous })
+	if err := LoadLogOffsets(offsetsPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Blank out the line that was read before the restart: a resumed read
+	// keeps what was parsed from it.
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blank := []byte(strings.Repeat(" ", len(data)-1) + "\n")
+	if err := os.WriteFile(logPath, blank, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(logPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	activity, err := ParseAgentActivity(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity.LastResponse != "第一步完成。" {
+		t.Fatalf("expected the saved activity, got %+v", activity)
+	}
+
+	if err := LoadLogOffsets(filepath.Join(dir, "missing.json")); err != nil {
+		t.Fatalf("expected a missing offsets file to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	configPathEnv = "ATM_REDACT_CONFIG"
+
+	defaultConfigFileName = "redact.json"
+)
+
+// Config tunes the redactor. The zero value enables every built-in
+// detector.
+type Config struct {
+	// Disabled turns redaction off entirely.
+	Disabled bool `json:"disabled,omitempty"`
+	// DisableEntropy skips the high-entropy string detector, which is the
+	// one most likely to hide harmless identifiers.
+	DisableEntropy bool `json:"disable_entropy,omitempty"`
+	// Patterns are extra user-defined detectors.
+	Patterns []PatternConfig `json:"patterns,omitempty"`
+}
+
+// PatternConfig is a user-defined detector. When the regex has capture
+// groups only the first group is replaced, so "password=(\S+)" keeps the
+// key visible.
+type PatternConfig struct {
+	Name  string `json:"name" yaml:"name"`
+	Regex string `json:"regex" yaml:"regex"`
+}
+
+// DefaultConfigPath returns the redaction config location, honoring ATM_REDACT_CONFIG.
+func DefaultConfigPath() (string, error) {
+	if custom := strings.TrimSpace(os.Getenv(configPathEnv)); custom != "" {
+		return custom, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".agent-team-monitor", defaultConfigFileName), nil
+}
+
+// LoadConfigFromEnv loads the redaction config from DefaultConfigPath.
+// A missing file yields the built-in detectors only.
+func LoadConfigFromEnv() (Config, error) {
+	path, err := DefaultConfigPath()
+	if err != nil {
+		return Config{}, err
+	}
+	return LoadConfig(path)
+}
+
+// LoadConfig reads a redaction config file.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return Config{}, nil
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse redact config %s: %w", path, err)
+	}
+	if _, err := New(cfg); err != nil {
+		return Config{}, fmt.Errorf("redact config %s: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,166 @@
+// Package redact masks credentials in agent transcripts before they are
+// shown or served.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Placeholder formats the text left in place of a secret.
+func Placeholder(rule string) string {
+	return "[REDACTED:" + rule + "]"
+}
+
+type rule struct {
+	name    string
+	pattern *regexp.Regexp
+	// group selects the submatch to replace; 0 replaces the whole match.
+	group int
+	// check, when set, must accept the secret before it is replaced.
+	check func(string) bool
+}
+
+// builtinRules run in order, most specific first, so a key inside an
+// assignment is reported under its own name.
+var builtinRules = []rule{
+	{name: "private_key", pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|\z)`)},
+	{name: "aws_access_key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "github_token", pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{name: "anthropic_key", pattern: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{name: "openai_key", pattern: regexp.MustCompile(`\bsk-(?:proj-|svcacct-|admin-)?[A-Za-z0-9_-]{20,}`)},
+	{name: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	// .env lines and config assignments: keep the key, hide the value.
+	{name: "secret_assignment", group: 1, pattern: regexp.MustCompile(
+		`(?i)\b[A-Z0-9_.-]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY|CREDENTIALS?)[A-Z0-9_.-]*["']?\s*[:=]\s*["']?([^\s"'\[]{6,})`)},
+}
+
+var entropyRule = rule{
+	name:    "high_entropy",
+	pattern: regexp.MustCompile(`[A-Za-z0-9+_=-]{32,}`),
+	check:   looksRandom,
+}
+
+// Redactor replaces secrets in text. A nil Redactor leaves text unchanged.
+type Redactor struct {
+	rules []rule
+}
+
+// New builds a redactor from cfg, or returns nil when redaction is
+// disabled.
+func New(cfg Config) (*Redactor, error) {
+	if cfg.Disabled {
+		return nil, nil
+	}
+	rules := append([]rule(nil), builtinRules...)
+	for i, pattern := range cfg.Patterns {
+		name := strings.TrimSpace(pattern.Name)
+		if name == "" {
+			name = fmt.Sprintf("custom_%d", i+1)
+		}
+		if strings.TrimSpace(pattern.Regex) == "" {
+			return nil, fmt.Errorf("pattern %q has no regex", name)
+		}
+		compiled, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", name, err)
+		}
+		r := rule{name: name, pattern: compiled}
+		if compiled.NumSubexp() > 0 {
+			r.group = 1
+		}
+		rules = append(rules, r)
+	}
+	if !cfg.DisableEntropy {
+		rules = append(rules, entropyRule)
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// Redact returns text with every detected secret replaced by a
+// placeholder, and how many were replaced.
+func (r *Redactor) Redact(text string) (string, int) {
+	if r == nil || text == "" {
+		return text, 0
+	}
+	total := 0
+	for _, rl := range r.rules {
+		var count int
+		text, count = rl.apply(text)
+		total += count
+	}
+	return text, total
+}
+
+// Apply redacts *s in place and returns the number of replacements.
+func (r *Redactor) Apply(s *string) int {
+	redacted, count := r.Redact(*s)
+	if count > 0 {
+		*s = redacted
+	}
+	return count
+}
+
+func (rl rule) apply(text string) (string, int) {
+	matches := rl.pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text, 0
+	}
+
+	var b strings.Builder
+	last, count := 0, 0
+	placeholder := Placeholder(rl.name)
+	for _, m := range matches {
+		start, end := m[2*rl.group], m[2*rl.group+1]
+		if start < 0 || start < last {
+			continue
+		}
+		secret := text[start:end]
+		if strings.HasPrefix(secret, "[REDACTED:") || (rl.check != nil && !rl.check(secret)) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(placeholder)
+		last = end
+		count++
+	}
+	if count == 0 {
+		return text, 0
+	}
+	b.WriteString(text[last:])
+	return b.String(), count
+}
+
+// looksRandom accepts mixed-case alphanumeric strings with high Shannon
+// entropy. Hex digests and identifiers made of words fall below it.
+func looksRandom(s string) bool {
+	var upper, lower, digit bool
+	freq := make(map[rune]int)
+	for _, c := range s {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		}
+		freq[c]++
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+	return shannonEntropy(freq, len(s)) >= 4.0
+}
+
+func shannonEntropy(freq map[rune]int, n int) float64 {
+	entropy := 0.0
+	for _, count := range freq {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,382 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format is a report output format.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatJSON     Format = "json"
+)
+
+// maxEventText bounds event text in Markdown and HTML; JSON keeps it whole.
+const maxEventText = 300
+
+// ParseFormat accepts markdown (or md), html and json.
+func ParseFormat(raw string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "markdown", "md":
+		return FormatMarkdown, nil
+	case "html":
+		return FormatHTML, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown report format %q (want markdown, html or json)", raw)
+	}
+}
+
+// Render writes report in format.
+func Render(w io.Writer, format Format, report Report) error {
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(w, report)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, report)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+func renderMarkdown(w io.Writer, report Report) error {
+	var b strings.Builder
+	b.WriteString("# Agent Team Report\n\n")
+	fmt.Fprintf(&b, "%s\n", summaryLine(report))
+
+	for _, team := range report.Teams {
+		fmt.Fprintf(&b, "\n## %s\n\n", team.Name)
+		if details := teamDetails(team); details != "" {
+			fmt.Fprintf(&b, "- %s\n", details)
+		}
+		if team.ProjectCwd != "" {
+			fmt.Fprintf(&b, "- Project: `%s`\n", team.ProjectCwd)
+		}
+		fmt.Fprintf(&b, "- %s\n", timingLine(team.Timing, report.GeneratedAt))
+		fmt.Fprintf(&b, "- Members: %s · Tasks: %s\n", formatCounts(team.StatusCounts), formatCounts(team.TaskCounts))
+
+		b.WriteString("\n### Members\n\n")
+		b.WriteString("| Agent | Status | Current task | Last active | Last tool |\n|---|---|---|---|---|\n")
+		for _, member := range team.Members {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+				markdownCell(member.Name), markdownCell(member.Status), markdownCell(member.CurrentTask),
+				formatTime(member.LastActivity), markdownCell(member.LastTool))
+		}
+
+		if len(team.Tasks) > 0 {
+			b.WriteString("\n### Tasks\n\n")
+			b.WriteString("| ID | Subject | Status | Owner | Duration |\n|---|---|---|---|---|\n")
+			for _, task := range team.Tasks {
+				fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+					markdownCell(task.ID), markdownCell(task.Subject), markdownCell(task.Status),
+					markdownCell(task.Owner), FormatDuration(task.Duration))
+			}
+		}
+
+		if hasTodos(team) {
+			b.WriteString("\n### Todos\n")
+			for _, member := range team.Members {
+				if len(member.Todos) == 0 {
+					continue
+				}
+				fmt.Fprintf(&b, "\n**%s**\n\n", member.Name)
+				for _, todo := range member.Todos {
+					fmt.Fprintf(&b, "- %s %s%s\n", todoBox(todo.Status), oneLine(todo.Content, 0), todoSuffix(todo.Status))
+				}
+			}
+		}
+
+		if len(team.TouchedFiles) > 0 {
+			b.WriteString("\n### Touched files\n\n")
+			b.WriteString("| File | Action | Agents | Calls |\n|---|---|---|---|\n")
+			for _, file := range team.TouchedFiles {
+				fmt.Fprintf(&b, "| `%s` | %s | %s | %d |\n",
+					strings.ReplaceAll(file.Path, "|", `\|`), file.Action, markdownCell(strings.Join(file.Agents, ", ")), file.Count)
+			}
+		}
+
+		if hasEvents(team) {
+			b.WriteString("\n### Recent events\n")
+			for _, member := range team.Members {
+				if len(member.Events) == 0 {
+					continue
+				}
+				fmt.Fprintf(&b, "\n**%s**\n\n", member.Name)
+				for _, event := range member.Events {
+					fmt.Fprintf(&b, "- `%s` **%s** %s\n", formatClock(event.Timestamp), event.Kind, oneLine(event.Text, maxEventText))
+				}
+			}
+		}
+	}
+	if len(report.Teams) == 0 {
+		b.WriteString("\nNo teams.\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func summaryLine(report Report) string {
+	parts := []string{"Generated " + formatTime(report.GeneratedAt)}
+	if report.StateVersion > 0 {
+		parts = append(parts, fmt.Sprintf("state version %d", report.StateVersion))
+	}
+	if !report.StateTime.IsZero() {
+		parts = append(parts, "collected "+formatTime(report.StateTime))
+	}
+	parts = append(parts, countNoun(len(report.Teams), "team"))
+	return strings.Join(parts, " · ")
+}
+
+func countNoun(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func teamDetails(team TeamReport) string {
+	var parts []string
+	if team.Provider != "" {
+		parts = append(parts, "Provider: "+team.Provider)
+	}
+	if team.ManagedStatus != "" {
+		parts = append(parts, "Managed: "+team.ManagedStatus)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func timingLine(timing Timing, now time.Time) string {
+	if timing.StartedAt.IsZero() {
+		return "No recorded activity"
+	}
+	line := "Started " + formatTime(timing.StartedAt)
+	if !timing.LastActivity.IsZero() {
+		line += fmt.Sprintf(" · last activity %s (%s ago) · active for %s",
+			formatTime(timing.LastActivity), FormatDuration(timing.Idle), FormatDuration(timing.Elapsed))
+	}
+	return line
+}
+
+// formatCounts lists counts by descending size, e.g. "2 working, 1 idle".
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[key], firstNonEmpty(key, "unknown")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func hasTodos(team TeamReport) bool {
+	for _, member := range team.Members {
+		if len(member.Todos) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func hasEvents(team TeamReport) bool {
+	for _, member := range team.Members {
+		if len(member.Events) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func todoBox(status string) string {
+	if status == "completed" {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+func todoSuffix(status string) string {
+	if status == "in_progress" {
+		return " _(in progress)_"
+	}
+	return ""
+}
+
+// oneLine collapses whitespace and, with limit > 0, truncates to limit
+// runes.
+func oneLine(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); limit > 0 && len(runes) > limit {
+		return string(runes[:limit-1]) + "…"
+	}
+	return text
+}
+
+func markdownCell(text string) string {
+	text = oneLine(text, 0)
+	if text == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func formatClock(t time.Time) string {
+	if t.IsZero() {
+		return "--:--:--"
+	}
+	return t.Local().Format("15:04:05")
+}
+
+// FormatDuration prints d compactly: 45s, 12m, 3h05m or 2d4h.
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "-"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time":      formatTime,
+	"clock":     formatClock,
+	"duration":  FormatDuration,
+	"counts":    formatCounts,
+	"oneLine":   oneLine,
+	"join":      strings.Join,
+	"summary":   summaryLine,
+	"details":   teamDetails,
+	"timing":    timingLine,
+	"hasTodos":  hasTodos,
+	"hasEvents": hasEvents,
+	"eventText": func(text string) string {
+		return oneLine(text, maxEventText)
+	},
+	"dash": func(text string) string {
+		return firstNonEmpty(oneLine(text, 0), "-")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Agent Team Report</title>
+<style>
+body { font: 14px/1.5 -apple-system, "Segoe UI", "PingFang SC", sans-serif; color: #1f2328; margin: 2rem auto; max-width: 1100px; padding: 0 1rem; }
+h1 { margin-bottom: .2rem; }
+h2 { border-bottom: 1px solid #d0d7de; padding-bottom: .3rem; margin-top: 2.5rem; }
+h3 { margin-top: 1.5rem; }
+.muted { color: #656d76; }
+table { border-collapse: collapse; width: 100%; margin: .5rem 0; }
+th, td { border: 1px solid #d0d7de; padding: .35rem .6rem; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+code { font: 12px ui-monospace, SFMono-Regular, Menlo, monospace; background: #f6f8fa; padding: .1rem .3rem; border-radius: 4px; }
+.status { display: inline-block; padding: 0 .5rem; border-radius: 1rem; font-size: 12px; background: #eaeef2; }
+.status-working, .status-in_progress, .status-running { background: #ddf4ff; color: #0969da; }
+.status-completed { background: #dafbe1; color: #1a7f37; }
+.status-error, .status-failed { background: #ffebe9; color: #cf222e; }
+ul.events { list-style: none; padding-left: 0; }
+ul.events li { padding: .15rem 0; border-bottom: 1px dashed #eaeef2; }
+.kind { font-weight: 600; margin-right: .4rem; }
+</style>
+</head>
+<body>
+<h1>Agent Team Report</h1>
+<p class="muted">{{summary .}}</p>
+{{- $now := .GeneratedAt}}
+{{- range .Teams}}
+<h2>{{.Name}}</h2>
+<ul>
+{{- with details .}}<li>{{.}}</li>{{end}}
+{{- with .ProjectCwd}}<li>Project: <code>{{.}}</code></li>{{end}}
+<li>{{timing .Timing $now}}</li>
+<li>Members: {{counts .StatusCounts}} · Tasks: {{counts .TaskCounts}}</li>
+</ul>
+<h3>Members</h3>
+<table>
+<tr><th>Agent</th><th>Status</th><th>Current task</th><th>Last active</th><th>Last tool</th></tr>
+{{- range .Members}}
+<tr><td>{{.Name}}</td><td><span class="status status-{{.Status}}">{{dash .Status}}</span></td><td>{{dash .CurrentTask}}</td><td>{{time .LastActivity}}</td><td>{{dash .LastTool}}</td></tr>
+{{- end}}
+</table>
+{{- if .Tasks}}
+<h3>Tasks</h3>
+<table>
+<tr><th>ID</th><th>Subject</th><th>Status</th><th>Owner</th><th>Duration</th></tr>
+{{- range .Tasks}}
+<tr><td>{{.ID}}</td><td>{{dash .Subject}}</td><td><span class="status status-{{.Status}}">{{dash .Status}}</span></td><td>{{dash .Owner}}</td><td>{{duration .Duration}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if hasTodos .}}
+<h3>Todos</h3>
+{{- range .Members}}{{if .Todos}}
+<p><strong>{{.Name}}</strong></p>
+<ul>
+{{- range .Todos}}
+<li>{{if eq .Status "completed"}}☑{{else}}☐{{end}} {{oneLine .Content 0}}{{if eq .Status "in_progress"}} <em>(in progress)</em>{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}{{end}}
+{{- end}}
+{{- if .TouchedFiles}}
+<h3>Touched files</h3>
+<table>
+<tr><th>File</th><th>Action</th><th>Agents</th><th>Calls</th></tr>
+{{- range .TouchedFiles}}
+<tr><td><code>{{.Path}}</code></td><td>{{.Action}}</td><td>{{join .Agents ", "}}</td><td>{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if hasEvents .}}
+<h3>Recent events</h3>
+{{- range .Members}}{{if .Events}}
+<p><strong>{{.Name}}</strong></p>
+<ul class="events">
+{{- range .Events}}
+<li><code>{{clock .Timestamp}}</code> <span class="kind">{{.Kind}}</span>{{eventText .Text}}</li>
+{{- end}}
+</ul>
+{{- end}}{{end}}
+{{- end}}
+{{- else}}
+<p>No teams.</p>
+{{- end}}
+</body>
+</html>
+`))
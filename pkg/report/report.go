@@ -0,0 +1,278 @@
+// Package report renders a monitor state snapshot as a self-contained team
+// report for stand-ups, pull requests and post-mortems.
+package report
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// DefaultEvents is how many recent events each member lists when
+// Options.Events is zero.
+const DefaultEvents = 10
+
+// Options selects what Build includes.
+type Options struct {
+	// Team limits the report to one team (case-insensitive); empty means
+	// every team.
+	Team string
+	// Events caps each member's recent events; 0 means DefaultEvents and a
+	// negative value omits them.
+	Events int
+	// Now is the reference time for durations; zero means time.Now.
+	Now time.Time
+}
+
+// Report is one rendered snapshot.
+type Report struct {
+	GeneratedAt  time.Time    `json:"generated_at"`
+	StateVersion uint64       `json:"state_version,omitempty"`
+	StateTime    time.Time    `json:"state_time,omitempty"`
+	Teams        []TeamReport `json:"teams"`
+}
+
+// TeamReport is one team's section.
+type TeamReport struct {
+	Name          string         `json:"name"`
+	Provider      string         `json:"provider,omitempty"`
+	ManagedStatus string         `json:"managed_status,omitempty"`
+	ProjectCwd    string         `json:"project_cwd,omitempty"`
+	Timing        Timing         `json:"timing"`
+	StatusCounts  map[string]int `json:"status_counts"`
+	TaskCounts    map[string]int `json:"task_counts"`
+	Members       []MemberReport `json:"members"`
+	Tasks         []TaskReport   `json:"tasks"`
+	TouchedFiles  []TouchedFile  `json:"touched_files"`
+}
+
+// Timing spans a team's first to last observed activity.
+type Timing struct {
+	StartedAt    time.Time     `json:"started_at,omitempty"`
+	LastActivity time.Time     `json:"last_activity,omitempty"`
+	Elapsed      time.Duration `json:"elapsed_ns"`
+	Idle         time.Duration `json:"idle_ns"`
+}
+
+// MemberReport is one agent's row and detail.
+type MemberReport struct {
+	Name         string             `json:"name"`
+	Provider     string             `json:"provider,omitempty"`
+	Status       string             `json:"status"`
+	CurrentTask  string             `json:"current_task,omitempty"`
+	LastActivity time.Time          `json:"last_activity,omitempty"`
+	LastTool     string             `json:"last_tool,omitempty"`
+	Todos        []types.TodoItem   `json:"todos,omitempty"`
+	Events       []types.AgentEvent `json:"recent_events,omitempty"` // oldest first
+}
+
+// TaskReport is a task with how long it has been open or took.
+type TaskReport struct {
+	types.TaskInfo
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// TouchedFile is a file agents read or changed, as seen in recent tool
+// calls.
+type TouchedFile struct {
+	Path        string    `json:"path"`
+	Action      string    `json:"action"` // edited or read
+	Agents      []string  `json:"agents"`
+	Count       int       `json:"count"`
+	LastTouched time.Time `json:"last_touched,omitempty"`
+}
+
+// Build turns a state snapshot into a report.
+func Build(state types.MonitorState, options Options) (Report, error) {
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	events := options.Events
+	if events == 0 {
+		events = DefaultEvents
+	}
+
+	report := Report{
+		GeneratedAt:  now,
+		StateVersion: state.Version,
+		StateTime:    state.UpdatedAt,
+		Teams:        []TeamReport{},
+	}
+	for _, team := range state.Teams {
+		if options.Team != "" && !strings.EqualFold(team.Name, options.Team) {
+			continue
+		}
+		report.Teams = append(report.Teams, buildTeam(team, events, now))
+	}
+	if options.Team != "" && len(report.Teams) == 0 {
+		return Report{}, fmt.Errorf("team %q not found", options.Team)
+	}
+	return report, nil
+}
+
+func buildTeam(team types.TeamInfo, events int, now time.Time) TeamReport {
+	section := TeamReport{
+		Name:          team.Name,
+		Provider:      team.Provider,
+		ManagedStatus: team.ManagedStatus,
+		ProjectCwd:    team.ProjectCwd,
+		StatusCounts:  map[string]int{},
+		TaskCounts:    map[string]int{},
+		Members:       []MemberReport{},
+		Tasks:         []TaskReport{},
+	}
+
+	timing := Timing{StartedAt: team.CreatedAt}
+	observe := func(t time.Time) {
+		if t.IsZero() {
+			return
+		}
+		if timing.StartedAt.IsZero() || t.Before(timing.StartedAt) {
+			timing.StartedAt = t
+		}
+		if t.After(timing.LastActivity) {
+			timing.LastActivity = t
+		}
+	}
+
+	touched := map[string]*TouchedFile{}
+	for _, agent := range team.Members {
+		section.StatusCounts[agent.Status]++
+		observe(agent.JoinedAt)
+		observe(agent.LastActivity)
+		observe(agent.LastActiveTime)
+
+		member := MemberReport{
+			Name:         agent.Name,
+			Provider:     firstNonEmpty(agent.Provider, team.Provider),
+			Status:       agent.Status,
+			CurrentTask:  agent.CurrentTask,
+			LastActivity: latest(agent.LastActivity, agent.LastActiveTime),
+			LastTool:     strings.TrimSpace(agent.LastToolUse + " " + agent.LastToolDetail),
+			Todos:        agent.Todos,
+		}
+		// RecentEvents are newest first; reports read oldest first.
+		for i := len(agent.RecentEvents) - 1; i >= 0; i-- {
+			event := agent.RecentEvents[i]
+			observe(event.Timestamp)
+			recordTouchedFile(touched, agent.Name, event)
+			member.Events = append(member.Events, event)
+		}
+		if events < 0 {
+			member.Events = nil
+		} else if len(member.Events) > events {
+			member.Events = member.Events[len(member.Events)-events:]
+		}
+		section.Members = append(section.Members, member)
+	}
+
+	for _, task := range team.Tasks {
+		section.TaskCounts[task.Status]++
+		observe(task.CreatedAt)
+		observe(task.UpdatedAt)
+		section.Tasks = append(section.Tasks, TaskReport{TaskInfo: task, Duration: taskDuration(task, now)})
+	}
+
+	if !timing.StartedAt.IsZero() && !timing.LastActivity.IsZero() {
+		timing.Elapsed = timing.LastActivity.Sub(timing.StartedAt)
+		timing.Idle = max(now.Sub(timing.LastActivity), 0)
+	}
+	section.Timing = timing
+
+	section.TouchedFiles = make([]TouchedFile, 0, len(touched))
+	for _, file := range touched {
+		sort.Strings(file.Agents)
+		section.TouchedFiles = append(section.TouchedFiles, *file)
+	}
+	sort.Slice(section.TouchedFiles, func(i, j int) bool {
+		a, b := section.TouchedFiles[i], section.TouchedFiles[j]
+		if a.Action != b.Action {
+			return a.Action == "edited"
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Path < b.Path
+	})
+	return section
+}
+
+// taskDuration is creation to completion for finished tasks and creation
+// to now for open ones.
+func taskDuration(task types.TaskInfo, now time.Time) time.Duration {
+	if task.CreatedAt.IsZero() {
+		return 0
+	}
+	end := now
+	if task.Status == "completed" && !task.UpdatedAt.IsZero() {
+		end = task.UpdatedAt
+	}
+	return max(end.Sub(task.CreatedAt), 0)
+}
+
+var (
+	editTools = []string{"edit", "multiedit", "write", "notebookedit", "apply_patch", "create_file", "str_replace_editor"}
+	readTools = []string{"read", "read_file", "view", "open_file"}
+)
+
+// recordTouchedFile picks file paths out of "Tool · detail" tool calls;
+// Claude details are base names, Codex details are key=value.
+func recordTouchedFile(touched map[string]*TouchedFile, agent string, event types.AgentEvent) {
+	if event.Kind != "tool" {
+		return
+	}
+	tool, detail, found := strings.Cut(event.Text, " · ")
+	if !found {
+		return
+	}
+	action := ""
+	switch name := strings.ToLower(strings.TrimSpace(tool)); {
+	case slices.Contains(editTools, name):
+		action = "edited"
+	case slices.Contains(readTools, name):
+		action = "read"
+	default:
+		return
+	}
+	detail = strings.TrimSpace(detail)
+	for _, prefix := range []string{"file_path=", "path="} {
+		detail = strings.TrimPrefix(detail, prefix)
+	}
+	// Free-form details such as patch bodies are not paths.
+	if detail == "" || strings.ContainsAny(detail, " \t\n") {
+		return
+	}
+
+	key := action + "\x00" + detail
+	file := touched[key]
+	if file == nil {
+		file = &TouchedFile{Path: detail, Action: action}
+		touched[key] = file
+	}
+	file.Count++
+	if !slices.Contains(file.Agents, agent) {
+		file.Agents = append(file.Agents, agent)
+	}
+	file.LastTouched = latest(file.LastTouched, event.Timestamp)
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func reportFixture(now time.Time) types.MonitorState {
+	return types.MonitorState{
+		Version:   7,
+		UpdatedAt: now,
+		Teams: []types.TeamInfo{
+			{
+				Name:      "Release Crew",
+				Provider:  "claude",
+				CreatedAt: now.Add(-2 * time.Hour),
+				Members: []types.AgentInfo{
+					{
+						Name:         "lead",
+						Status:       "working",
+						CurrentTask:  "Ship | v2",
+						LastActivity: now.Add(-5 * time.Minute),
+						LastToolUse:  "Edit",
+						Todos: []types.TodoItem{
+							{Content: "Write notes", Status: "completed"},
+							{Content: "Tag release", Status: "in_progress"},
+						},
+						// Newest first, as the collector publishes them.
+						RecentEvents: []types.AgentEvent{
+							{Kind: "tool", Text: "Edit · main.go", Timestamp: now.Add(-5 * time.Minute)},
+							{Kind: "response", Text: "<script>alert(1)</script>", Timestamp: now.Add(-10 * time.Minute)},
+							{Kind: "tool", Text: "Edit · main.go", Timestamp: now.Add(-20 * time.Minute)},
+							{Kind: "tool", Text: "apply_patch · *** Begin Patch", Timestamp: now.Add(-25 * time.Minute)},
+						},
+					},
+					{
+						Name:   "reviewer",
+						Status: "idle",
+						RecentEvents: []types.AgentEvent{
+							{Kind: "tool", Text: "read_file · path=docs/guide.md", Timestamp: now.Add(-30 * time.Minute)},
+						},
+					},
+				},
+				Tasks: []types.TaskInfo{
+					{ID: "1", Subject: "Changelog", Status: "completed", Owner: "lead", CreatedAt: now.Add(-90 * time.Minute), UpdatedAt: now.Add(-30 * time.Minute)},
+					{ID: "2", Subject: "Tag", Status: "in_progress", Owner: "lead", CreatedAt: now.Add(-15 * time.Minute), UpdatedAt: now.Add(-15 * time.Minute)},
+				},
+			},
+			{Name: "Other Crew", Members: []types.AgentInfo{{Name: "solo", Status: "idle"}}},
+		},
+	}
+}
+
+func TestBuildSummarizesTeams(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	report, err := Build(reportFixture(now), Options{Team: "release crew", Events: 2, Now: now})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(report.Teams) != 1 || report.StateVersion != 7 {
+		t.Fatalf("expected only the requested team, got %+v", report)
+	}
+	team := report.Teams[0]
+	if team.StatusCounts["working"] != 1 || team.StatusCounts["idle"] != 1 || team.TaskCounts["completed"] != 1 {
+		t.Fatalf("unexpected counts %v %v", team.StatusCounts, team.TaskCounts)
+	}
+	if team.Timing.StartedAt != now.Add(-2*time.Hour) || team.Timing.LastActivity != now.Add(-5*time.Minute) || team.Timing.Idle != 5*time.Minute {
+		t.Fatalf("unexpected timing %+v", team.Timing)
+	}
+	if team.Tasks[0].Duration != time.Hour || team.Tasks[1].Duration != 15*time.Minute {
+		t.Fatalf("expected completed tasks to stop the clock, got %v %v", team.Tasks[0].Duration, team.Tasks[1].Duration)
+	}
+
+	lead := team.Members[0]
+	if len(lead.Events) != 2 || lead.Events[1].Text != "Edit · main.go" || lead.LastTool != "Edit" {
+		t.Fatalf("expected the two newest events oldest first, got %+v", lead.Events)
+	}
+	// Files come from every event, not just the ones listed.
+	if len(team.TouchedFiles) != 2 {
+		t.Fatalf("unexpected touched files %+v", team.TouchedFiles)
+	}
+	if edited := team.TouchedFiles[0]; edited.Path != "main.go" || edited.Action != "edited" || edited.Count != 2 {
+		t.Fatalf("unexpected edited file %+v", edited)
+	}
+	if read := team.TouchedFiles[1]; read.Path != "docs/guide.md" || read.Action != "read" || read.Agents[0] != "reviewer" {
+		t.Fatalf("unexpected read file %+v", read)
+	}
+
+	if _, err := Build(reportFixture(now), Options{Team: "ghost"}); err == nil {
+		t.Fatal("expected an unknown team to fail")
+	}
+	all, _ := Build(reportFixture(now), Options{Events: -1, Now: now})
+	if len(all.Teams) != 2 || all.Teams[0].Members[0].Events != nil {
+		t.Fatalf("expected every team without events, got %+v", all.Teams)
+	}
+}
+
+func TestRenderFormats(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	report, err := Build(reportFixture(now), Options{Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var markdown bytes.Buffer
+	if err := Render(&markdown, FormatMarkdown, report); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"## Release Crew",
+		"| lead | working | Ship \\| v2 |",
+		"| 1 | Changelog | completed | lead | 1h00m |",
+		"- [x] Write notes",
+		"- [ ] Tag release _(in progress)_",
+		"| `main.go` | edited | lead | 2 |",
+		"- Members: 1 idle, 1 working",
+	} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("markdown is missing %q:\n%s", want, markdown.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := Render(&html, FormatHTML, report); err != nil {
+		t.Fatal(err)
+	}
+	page := html.String()
+	if strings.Contains(page, "<script>") || !strings.Contains(page, "&lt;script&gt;") {
+		t.Fatal("expected event text to be escaped")
+	}
+	for _, external := range []string{"src=", "href=", "<link", "@import", "url("} {
+		if strings.Contains(page, external) {
+			t.Errorf("expected a self-contained page, found %q", external)
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := Render(&encoded, FormatJSON, report); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(encoded.Bytes(), &decoded); err != nil || len(decoded.Teams) != 2 || decoded.Teams[0].Tasks[0].Subject != "Changelog" {
+		t.Fatalf("unexpected JSON round trip %+v (%v)", decoded, err)
+	}
+
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Fatal("expected an unknown format to fail")
+	}
+	if format, _ := ParseFormat("MD"); format != FormatMarkdown {
+		t.Fatalf("expected md to mean markdown, got %q", format)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                           "-",
+		45 * time.Second:            "45s",
+		12 * time.Minute:            "12m",
+		3*time.Hour + 5*time.Minute: "3h05m",
+		52 * time.Hour:              "2d4h",
+	} {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package trace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+const (
+	scopeName   = "github.com/liaoweijun/agent-team-monitor/pkg/trace"
+	serviceName = "agent-team-monitor"
+
+	// maxAttributeBytes keeps long tool output from bloating the export.
+	maxAttributeBytes = 1024
+)
+
+// BuildTraces converts the monitor state into one trace per team: the team is
+// the root span, each agent is a child span, and tool calls are leaf spans
+// whose duration runs from the tool_use to the tool_result with the same id.
+// Other timeline entries (thinking, responses, messages) become span events
+// on the agent span. IDs are derived from team, agent and tool identifiers,
+// so exporting the same state twice yields the same spans.
+func BuildTraces(state types.MonitorState) ExportRequest {
+	request := ExportRequest{ResourceSpans: []ResourceSpans{}}
+	for _, team := range state.Teams {
+		spans := buildTeamSpans(team)
+		if len(spans) == 0 {
+			continue
+		}
+		request.ResourceSpans = append(request.ResourceSpans, ResourceSpans{
+			Resource: Resource{Attributes: []KeyValue{
+				stringAttr("service.name", serviceName),
+				stringAttr("atm.provider", firstNonEmpty(team.Provider, "claude")),
+			}},
+			ScopeSpans: []ScopeSpans{{
+				Scope: Scope{Name: scopeName},
+				Spans: spans,
+			}},
+		})
+	}
+	return request
+}
+
+func buildTeamSpans(team types.TeamInfo) []Span {
+	traceID := hashID(32, "team", teamKey(team))
+	rootID := hashID(16, traceID, "team")
+
+	var (
+		spans     []Span
+		teamStart = team.CreatedAt
+		teamEnd   = team.CreatedAt
+	)
+	for _, agent := range team.Members {
+		agentSpans := buildAgentSpans(traceID, rootID, team, agent)
+		if len(agentSpans) == 0 {
+			continue
+		}
+		spans = append(spans, agentSpans...)
+		teamStart = earliest(teamStart, parseNanos(agentSpans[0].StartTimeUnixNano))
+		teamEnd = latest(teamEnd, parseNanos(agentSpans[0].EndTimeUnixNano))
+	}
+	if teamStart.IsZero() {
+		return nil
+	}
+
+	root := Span{
+		TraceID:           traceID,
+		SpanID:            rootID,
+		Name:              firstNonEmpty(team.Name, "team"),
+		Kind:              SpanKindInternal,
+		StartTimeUnixNano: formatNanos(teamStart),
+		EndTimeUnixNano:   formatNanos(teamEnd),
+		Attributes: []KeyValue{
+			stringAttr("atm.span", "team"),
+			stringAttr("atm.team", team.Name),
+			stringAttr("atm.provider", firstNonEmpty(team.Provider, "claude")),
+			boolAttr("atm.managed", team.Managed),
+		},
+	}
+	if team.ManagedStatus != "" {
+		root.Attributes = append(root.Attributes, stringAttr("atm.managed_status", team.ManagedStatus))
+	}
+	if team.ManagedStatus == "failed" {
+		root.Status = Status{Code: StatusCodeError, Message: team.LastError}
+	}
+
+	return append([]Span{root}, spans...)
+}
+
+// buildAgentSpans returns the agent span followed by its tool spans.
+func buildAgentSpans(traceID, parentID string, team types.TeamInfo, agent types.AgentInfo) []Span {
+	agentID := hashID(16, traceID, "agent", firstNonEmpty(agent.AgentID, agent.Name))
+
+	events := append([]types.AgentEvent(nil), agent.RecentEvents...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+
+	results := make(map[string]types.AgentEvent)
+	for _, event := range events {
+		if isToolResult(event.Kind) && event.ToolID != "" {
+			if _, ok := results[event.ToolID]; !ok {
+				results[event.ToolID] = event
+			}
+		}
+	}
+
+	start := agent.JoinedAt
+	end := latest(agent.LastActiveTime, agent.LastActivity)
+	var (
+		toolSpans  []Span
+		spanEvents []SpanEvent
+		paired     = make(map[string]bool)
+	)
+	for index, event := range events {
+		if event.Timestamp.IsZero() {
+			continue
+		}
+		start = earliest(start, event.Timestamp)
+		end = latest(end, event.Timestamp)
+
+		switch {
+		case isToolCall(event.Kind):
+			span := buildToolSpan(traceID, agentID, index, event, results)
+			if event.ToolID != "" {
+				if _, ok := results[event.ToolID]; ok {
+					paired[event.ToolID] = true
+				}
+			}
+			end = latest(end, parseNanos(span.EndTimeUnixNano))
+			toolSpans = append(toolSpans, span)
+		case isToolResult(event.Kind) && event.ToolID != "":
+			// Folded into the matching tool span below unless its call has
+			// already scrolled out of the recent timeline.
+			continue
+		default:
+			spanEvents = append(spanEvents, buildSpanEvent(event))
+		}
+	}
+	for _, event := range events {
+		if isToolResult(event.Kind) && event.ToolID != "" && !paired[event.ToolID] && !event.Timestamp.IsZero() {
+			spanEvents = append(spanEvents, buildSpanEvent(event))
+		}
+	}
+	sort.SliceStable(spanEvents, func(i, j int) bool {
+		return spanEvents[i].TimeUnixNano < spanEvents[j].TimeUnixNano
+	})
+
+	if start.IsZero() {
+		start = end
+	}
+	if start.IsZero() {
+		return nil
+	}
+	if end.Before(start) {
+		end = start
+	}
+
+	span := Span{
+		TraceID:           traceID,
+		SpanID:            agentID,
+		ParentSpanID:      parentID,
+		Name:              firstNonEmpty(agent.Name, agent.AgentID, "agent"),
+		Kind:              SpanKindInternal,
+		StartTimeUnixNano: formatNanos(start),
+		EndTimeUnixNano:   formatNanos(end),
+		Attributes: []KeyValue{
+			stringAttr("atm.span", "agent"),
+			stringAttr("atm.team", team.Name),
+			stringAttr("atm.agent", agent.Name),
+			stringAttr("atm.provider", firstNonEmpty(agent.Provider, team.Provider, "claude")),
+			stringAttr("atm.agent.status", agent.Status),
+		},
+		Events: spanEvents,
+	}
+	if agent.AgentID != "" {
+		span.Attributes = append(span.Attributes, stringAttr("atm.agent_id", agent.AgentID))
+	}
+	if agent.AgentType != "" {
+		span.Attributes = append(span.Attributes, stringAttr("atm.agent.type", agent.AgentType))
+	}
+	if agent.ManagedStatus != "" {
+		span.Attributes = append(span.Attributes, stringAttr("atm.agent.managed_status", agent.ManagedStatus))
+	}
+	if agent.ManagedStatus == "failed" {
+		span.Status = Status{Code: StatusCodeError, Message: firstNonEmpty(agent.LastThinking, team.LastError)}
+	}
+
+	return append([]Span{span}, toolSpans...)
+}
+
+func buildToolSpan(traceID, parentID string, index int, call types.AgentEvent, results map[string]types.AgentEvent) Span {
+	toolName, detail := splitToolText(call.Text)
+	spanKey := call.ToolID
+	if spanKey == "" {
+		spanKey = "index:" + strconv.Itoa(index) + ":" + call.Timestamp.Format(time.RFC3339Nano)
+	}
+
+	span := Span{
+		TraceID:           traceID,
+		SpanID:            hashID(16, parentID, "tool", spanKey),
+		ParentSpanID:      parentID,
+		Name:              firstNonEmpty(toolName, call.Title, "tool"),
+		Kind:              SpanKindInternal,
+		StartTimeUnixNano: formatNanos(call.Timestamp),
+		EndTimeUnixNano:   formatNanos(call.Timestamp),
+		Attributes: []KeyValue{
+			stringAttr("atm.span", "tool"),
+			stringAttr("atm.tool.name", toolName),
+			stringAttr("atm.tool.kind", call.Kind),
+		},
+	}
+	if detail != "" {
+		span.Attributes = append(span.Attributes, stringAttr("atm.tool.detail", truncate(detail)))
+	}
+	if call.ToolID != "" {
+		span.Attributes = append(span.Attributes, stringAttr("atm.tool.id", call.ToolID))
+	}
+
+	result, ok := results[call.ToolID]
+	if call.ToolID == "" || !ok {
+		span.Attributes = append(span.Attributes, boolAttr("atm.tool.pending", true))
+		return span
+	}
+	if result.Timestamp.After(call.Timestamp) {
+		span.EndTimeUnixNano = formatNanos(result.Timestamp)
+	}
+	span.Attributes = append(span.Attributes, stringAttr("atm.tool.output", truncate(result.Text)))
+	return span
+}
+
+func buildSpanEvent(event types.AgentEvent) SpanEvent {
+	attributes := []KeyValue{stringAttr("atm.event.text", truncate(event.Text))}
+	if event.Title != "" {
+		attributes = append(attributes, stringAttr("atm.event.title", event.Title))
+	}
+	if event.Source != "" {
+		attributes = append(attributes, stringAttr("atm.event.source", event.Source))
+	}
+	return SpanEvent{
+		TimeUnixNano: formatNanos(event.Timestamp),
+		Name:         firstNonEmpty(event.Kind, "event"),
+		Attributes:   attributes,
+	}
+}
+
+func isToolCall(kind string) bool {
+	return kind == "tool" || kind == "terminal"
+}
+
+func isToolResult(kind string) bool {
+	return kind == "tool_result" || kind == "terminal_output"
+}
+
+// splitToolText undoes the parser's "Tool · detail" event formatting.
+func splitToolText(text string) (string, string) {
+	name, detail, found := strings.Cut(text, " · ")
+	if !found {
+		return strings.TrimSpace(text), ""
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(detail)
+}
+
+func teamKey(team types.TeamInfo) string {
+	if team.ManagedTeamID != "" {
+		return "managed:" + team.ManagedTeamID
+	}
+	return firstNonEmpty(team.Provider, "claude") + "::" + team.Name
+}
+
+// hashID returns a stable hex ID of the given length (32 for traces, 16 for spans).
+func hashID(length int, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])[:length]
+}
+
+func formatNanos(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func parseNanos(value string) time.Time {
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func truncate(text string) string {
+	if len(text) <= maxAttributeBytes {
+		return text
+	}
+	cut := maxAttributeBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "..."
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
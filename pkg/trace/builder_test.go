@@ -0,0 +1,93 @@
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func sampleState(base time.Time) types.MonitorState {
+	return types.MonitorState{Teams: []types.TeamInfo{{
+		Name:      "alpha",
+		Provider:  "claude",
+		CreatedAt: base,
+		Members: []types.AgentInfo{{
+			Name:     "dev",
+			AgentID:  "dev@alpha",
+			Status:   "working",
+			JoinedAt: base.Add(time.Second),
+			RecentEvents: []types.AgentEvent{
+				{Kind: "response", Text: "done", Timestamp: base.Add(9 * time.Second)},
+				{Kind: "terminal_output", Text: "ok", ToolID: "toolu_1", Timestamp: base.Add(7 * time.Second)},
+				{Kind: "terminal", Text: "Bash · go test ./...", ToolID: "toolu_1", Timestamp: base.Add(4 * time.Second)},
+				{Kind: "tool", Text: "Read · main.go", ToolID: "toolu_2", Timestamp: base.Add(8 * time.Second)},
+				{Kind: "thinking", Text: "plan", Timestamp: base.Add(2 * time.Second)},
+			},
+		}},
+	}}}
+}
+
+func TestBuildTracesNestsTeamAgentAndToolSpans(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	spans := BuildTraces(sampleState(base)).Spans()
+	if len(spans) != 4 {
+		t.Fatalf("expected team, agent and two tool spans, got %d", len(spans))
+	}
+
+	team, agent, bash, read := spans[0], spans[1], spans[2], spans[3]
+	if team.ParentSpanID != "" || agent.ParentSpanID != team.SpanID || bash.ParentSpanID != agent.SpanID || read.ParentSpanID != agent.SpanID {
+		t.Fatalf("unexpected span hierarchy: %+v", spans)
+	}
+	for _, span := range spans {
+		if span.TraceID != team.TraceID || len(span.TraceID) != 32 || len(span.SpanID) != 16 {
+			t.Fatalf("unexpected ids on span %+v", span)
+		}
+	}
+
+	if bash.Name != "Bash" || parseNanos(bash.EndTimeUnixNano).Sub(parseNanos(bash.StartTimeUnixNano)) != 3*time.Second {
+		t.Fatalf("expected paired Bash span lasting 3s, got %+v", bash)
+	}
+	if output, _ := bash.Attribute("atm.tool.output"); output != "ok" {
+		t.Fatalf("expected tool output attribute, got %q", output)
+	}
+	if read.StartTimeUnixNano != read.EndTimeUnixNano {
+		t.Fatalf("expected unpaired Read span to have zero duration, got %+v", read)
+	}
+
+	if len(agent.Events) != 2 || agent.Events[0].Name != "thinking" || agent.Events[1].Name != "response" {
+		t.Fatalf("unexpected agent span events: %+v", agent.Events)
+	}
+	if !parseNanos(team.StartTimeUnixNano).Equal(base) || !parseNanos(team.EndTimeUnixNano).Equal(base.Add(9*time.Second)) {
+		t.Fatalf("unexpected team span bounds: %s-%s", team.StartTimeUnixNano, team.EndTimeUnixNano)
+	}
+}
+
+func TestBuildTracesIsDeterministicAndMarksFailures(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	state := sampleState(base)
+	state.Teams[0].ManagedStatus = "failed"
+	state.Teams[0].LastError = "exec: claude not found"
+	state.Teams[0].Members[0].ManagedStatus = "failed"
+
+	first := BuildTraces(state).Spans()
+	second := BuildTraces(state).Spans()
+	for i := range first {
+		if first[i].SpanID != second[i].SpanID {
+			t.Fatalf("expected stable span ids, got %s and %s", first[i].SpanID, second[i].SpanID)
+		}
+	}
+	if first[0].Status.Code != StatusCodeError || first[0].Status.Message != "exec: claude not found" {
+		t.Fatalf("expected failed team span, got %+v", first[0].Status)
+	}
+	if first[1].Status.Code != StatusCodeError {
+		t.Fatalf("expected failed agent span, got %+v", first[1].Status)
+	}
+}
+
+func TestBuildTracesSkipsTeamsWithoutTimestamps(t *testing.T) {
+	request := BuildTraces(types.MonitorState{Teams: []types.TeamInfo{{Name: "empty"}}})
+	if len(request.ResourceSpans) != 0 {
+		t.Fatalf("expected no spans, got %+v", request)
+	}
+}
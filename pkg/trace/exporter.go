@@ -0,0 +1,445 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+const (
+	endpointEnv = "ATM_OTLP_ENDPOINT"
+	headersEnv  = "ATM_OTLP_HEADERS"
+	fileEnv     = "ATM_TRACE_FILE"
+	intervalEnv = "ATM_TRACE_INTERVAL"
+
+	defaultExportInterval = 30 * time.Second
+	defaultRequestTimeout = 10 * time.Second
+	tracesPath            = "/v1/traces"
+)
+
+// Config selects where traces go. Endpoint and FilePath may both be set.
+type Config struct {
+	Endpoint string            // OTLP/HTTP base URL or full /v1/traces URL
+	Headers  map[string]string // extra request headers, e.g. Authorization
+	FilePath string            // local OTLP/JSON snapshot file
+	Interval time.Duration
+}
+
+// Enabled reports whether any trace sink is configured.
+func (c Config) Enabled() bool {
+	return strings.TrimSpace(c.Endpoint) != "" || strings.TrimSpace(c.FilePath) != ""
+}
+
+// LoadConfigFromEnv reads ATM_OTLP_ENDPOINT, ATM_OTLP_HEADERS (k=v,k2=v2),
+// ATM_TRACE_FILE and ATM_TRACE_INTERVAL.
+func LoadConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Endpoint: strings.TrimSpace(os.Getenv(endpointEnv)),
+		FilePath: strings.TrimSpace(os.Getenv(fileEnv)),
+		Interval: defaultExportInterval,
+	}
+
+	if raw := strings.TrimSpace(os.Getenv(headersEnv)); raw != "" {
+		cfg.Headers = make(map[string]string)
+		for _, pair := range strings.Split(raw, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return Config{}, fmt.Errorf("invalid %s entry %q, expected key=value", headersEnv, pair)
+			}
+			cfg.Headers[key] = strings.TrimSpace(value)
+		}
+	}
+
+	if raw := strings.TrimSpace(os.Getenv(intervalEnv)); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval <= 0 {
+			return Config{}, fmt.Errorf("invalid %s %q", intervalEnv, raw)
+		}
+		cfg.Interval = interval
+	}
+
+	return cfg, nil
+}
+
+// Exporter periodically converts monitor state into traces and ships them.
+type Exporter struct {
+	endpoint string
+	headers  map[string]string
+	filePath string
+	interval time.Duration
+	client   *http.Client
+
+	mu sync.Mutex
+	exportState
+}
+
+// exportState is what the exporter remembers between exports.
+type exportState struct {
+	// sent holds the spanId of every span in the latest export that was
+	// already sent, so spans that left the state are forgotten.
+	sent map[string]bool
+	// open holds the spans still running, as last built, by spanId.
+	open map[string]heldSpan
+	// segments holds how much of each running span was already sent.
+	segments map[string]segment
+}
+
+// segment records the parts of a running span sent so far: how many, and
+// where the last one ended.
+type segment struct {
+	count int
+	end   time.Time
+}
+
+// heldSpan is a running span with the resource it is sent under.
+type heldSpan struct {
+	resource Resource
+	scope    Scope
+	span     Span
+}
+
+// NewExporter validates cfg and returns an exporter.
+func NewExporter(cfg Config) (*Exporter, error) {
+	endpoint, err := normalizeEndpoint(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultExportInterval
+	}
+	return &Exporter{
+		endpoint: endpoint,
+		headers:  cfg.Headers,
+		filePath: strings.TrimSpace(cfg.FilePath),
+		interval: interval,
+		client:   &http.Client{Timeout: defaultRequestTimeout},
+		exportState: exportState{
+			sent:     make(map[string]bool),
+			open:     make(map[string]heldSpan),
+			segments: make(map[string]segment),
+		},
+	}, nil
+}
+
+func normalizeEndpoint(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: must be an http(s) URL", raw)
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = tracesPath
+	}
+	return parsed.String(), nil
+}
+
+// Run exports on every interval until ctx is cancelled, then sends the
+// spans still running as they were last built.
+func (e *Exporter) Run(ctx context.Context, source func() types.MonitorState) {
+	if e == nil || source == nil {
+		return
+	}
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Export(ctx, source()); err != nil && ctx.Err() == nil {
+			log.Printf("Error exporting traces: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
+			if err := e.Flush(flushCtx); err != nil {
+				log.Printf("Error exporting traces: %v", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Export writes the full trace snapshot to the file sink and sends each
+// span to the OTLP endpoint once, after it ended. Team and agent spans, and
+// tool calls still waiting for their result, run for as long as they are
+// in the state. They are sent in segments: whenever a span goes out, its
+// running parents go with it, covering the time since their previous
+// segment, so a monitor that runs for days keeps its traces connected.
+// What remains of a running span is sent when it leaves the state, ending
+// at the last time it was seen.
+func (e *Exporter) Export(ctx context.Context, state types.MonitorState) error {
+	request := BuildTraces(state)
+
+	if e.filePath != "" {
+		if err := writeSnapshot(e.filePath, request); err != nil {
+			return fmt.Errorf("write trace file: %w", err)
+		}
+	}
+
+	if e.endpoint == "" {
+		return nil
+	}
+
+	e.mu.Lock()
+	due, next := e.dueSpans(request)
+	e.mu.Unlock()
+	return e.send(ctx, due, next)
+}
+
+// Flush sends what remains of every span still running, as it was last
+// built.
+func (e *Exporter) Flush(ctx context.Context) error {
+	if e == nil || e.endpoint == "" {
+		return nil
+	}
+	e.mu.Lock()
+	due, next := e.dueSpans(ExportRequest{})
+	e.mu.Unlock()
+	return e.send(ctx, due, next)
+}
+
+// send posts due and, once it was accepted, records what was sent and
+// what still runs.
+func (e *Exporter) send(ctx context.Context, due ExportRequest, next exportState) error {
+	if len(due.ResourceSpans) > 0 {
+		if err := e.post(ctx, due); err != nil {
+			return err
+		}
+	}
+	e.mu.Lock()
+	e.exportState = next
+	e.mu.Unlock()
+	return nil
+}
+
+// dueSpans picks the spans of request that ended and were not sent yet,
+// the running spans that are no longer in request, and a segment of each
+// running parent of those. It returns them with the state after this
+// export. The caller holds mu.
+func (e *Exporter) dueSpans(request ExportRequest) (ExportRequest, exportState) {
+	plan := exportPlan{
+		prev: e.exportState,
+		next: exportState{
+			sent:     make(map[string]bool),
+			open:     make(map[string]heldSpan),
+			segments: make(map[string]segment),
+		},
+		due:      make(map[string]heldSpan),
+		segments: make(map[string]string),
+	}
+
+	var ended []heldSpan
+	for _, resource := range request.ResourceSpans {
+		scope := resource.ScopeSpans[0].Scope
+		for _, span := range resource.ScopeSpans[0].Spans {
+			held := heldSpan{resource: resource.Resource, scope: scope, span: span}
+			switch {
+			case e.sent[span.SpanID]:
+				plan.next.sent[span.SpanID] = true
+			case spanEnded(span):
+				plan.next.sent[span.SpanID] = true
+				ended = append(ended, held)
+			default:
+				plan.next.open[span.SpanID] = held
+			}
+		}
+	}
+
+	for _, held := range ended {
+		plan.add(held)
+	}
+	for spanID := range e.open {
+		if _, running := plan.next.open[spanID]; !running && !plan.next.sent[spanID] {
+			plan.segment(spanID)
+		}
+	}
+	for spanID := range plan.next.open {
+		if _, sent := plan.segments[spanID]; !sent {
+			if previous, ok := e.segments[spanID]; ok {
+				plan.next.segments[spanID] = previous
+			}
+		}
+	}
+	return heldRequest(plan.due), plan.next
+}
+
+// exportPlan collects the spans of one export.
+type exportPlan struct {
+	prev, next exportState
+	due        map[string]heldSpan // by the spanId sent
+	segments   map[string]string   // spanId of a held span to the one of its segment
+}
+
+// add queues held, after pointing it at the segment of its parent when the
+// parent is still held.
+func (p *exportPlan) add(held heldSpan) {
+	parentID := held.span.ParentSpanID
+	if _, ok := p.lookup(parentID); ok {
+		held.span.ParentSpanID = p.segment(parentID)
+	}
+	p.due[held.span.SpanID] = held
+	p.widen(held.span.ParentSpanID, parseNanos(held.span.StartTimeUnixNano))
+}
+
+// segment queues the part of a held span not sent yet and returns its
+// spanId. The first segment keeps the span's own id and start; later ones
+// start where the previous one ended and carry only the events since.
+func (p *exportPlan) segment(spanID string) string {
+	if segmentID, ok := p.segments[spanID]; ok {
+		return segmentID
+	}
+	held, _ := p.lookup(spanID)
+	previous := p.prev.segments[spanID]
+	span := held.span
+	if previous.count > 0 {
+		span.SpanID = hashID(16, spanID, "segment", strconv.Itoa(previous.count))
+		span.StartTimeUnixNano = formatNanos(previous.end)
+		if parseNanos(span.EndTimeUnixNano).Before(previous.end) {
+			span.EndTimeUnixNano = span.StartTimeUnixNano
+		}
+		span.Attributes = append(append([]KeyValue(nil), span.Attributes...), boolAttr("atm.span.continued", true))
+		span.Events = eventsAfter(span.Events, previous.end)
+	}
+	p.segments[spanID] = span.SpanID
+	if _, running := p.next.open[spanID]; running {
+		p.next.segments[spanID] = segment{count: previous.count + 1, end: parseNanos(span.EndTimeUnixNano)}
+	}
+	held.span = span
+	p.add(held)
+	return span.SpanID
+}
+
+// lookup finds a span held before or during this export.
+func (p *exportPlan) lookup(spanID string) (heldSpan, bool) {
+	if held, ok := p.next.open[spanID]; ok {
+		return held, true
+	}
+	held, ok := p.prev.open[spanID]
+	return held, ok
+}
+
+// widen moves the start of a queued segment, and of its own parents, back
+// to cover a child that started before it.
+func (p *exportPlan) widen(spanID string, start time.Time) {
+	held, ok := p.due[spanID]
+	if !ok || start.IsZero() || !start.Before(parseNanos(held.span.StartTimeUnixNano)) {
+		return
+	}
+	held.span.StartTimeUnixNano = formatNanos(start)
+	p.due[spanID] = held
+	p.widen(held.span.ParentSpanID, start)
+}
+
+func eventsAfter(events []SpanEvent, after time.Time) []SpanEvent {
+	var result []SpanEvent
+	for _, event := range events {
+		if parseNanos(event.TimeUnixNano).After(after) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// heldRequest groups held spans by trace, which is one resource per team.
+func heldRequest(held map[string]heldSpan) ExportRequest {
+	request := ExportRequest{}
+	byTrace := make(map[string]int)
+	spanIDs := make([]string, 0, len(held))
+	for spanID := range held {
+		spanIDs = append(spanIDs, spanID)
+	}
+	sort.Strings(spanIDs)
+	for _, spanID := range spanIDs {
+		item := held[spanID]
+		index, ok := byTrace[item.span.TraceID]
+		if !ok {
+			index = len(request.ResourceSpans)
+			byTrace[item.span.TraceID] = index
+			request.ResourceSpans = append(request.ResourceSpans, ResourceSpans{
+				Resource:   item.resource,
+				ScopeSpans: []ScopeSpans{{Scope: item.scope}},
+			})
+		}
+		scope := &request.ResourceSpans[index].ScopeSpans[0]
+		scope.Spans = append(scope.Spans, item.span)
+	}
+	return request
+}
+
+// spanEnded reports whether span can no longer change: a tool call with
+// its result. Team and agent spans grow for as long as they are active.
+func spanEnded(span Span) bool {
+	if kind, _ := span.Attribute("atm.span"); kind != "tool" {
+		return false
+	}
+	for _, attr := range span.Attributes {
+		if attr.Key == "atm.tool.pending" && attr.Value.BoolValue != nil && *attr.Value.BoolValue {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Exporter) post(ctx context.Context, request ExportRequest) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("encode traces: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send traces: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("send traces: %s returned %s", e.endpoint, resp.Status)
+	}
+	return nil
+}
+
+func writeSnapshot(path string, request ExportRequest) error {
+	payload, err := json.MarshalIndent(request, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(payload, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
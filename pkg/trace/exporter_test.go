@@ -0,0 +1,200 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func TestExporterSendsEachSpanOnceItEnded(t *testing.T) {
+	var received []ExportRequest
+	var authHeader string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		authHeader = r.Header.Get("Authorization")
+		var request ExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		received = append(received, request)
+	}))
+	defer collector.Close()
+
+	exporter, err := NewExporter(Config{Endpoint: collector.URL, Headers: map[string]string{"Authorization": "Bearer t"}})
+	if err != nil {
+		t.Fatalf("NewExporter: %v", err)
+	}
+	spanNames := func(request ExportRequest) []string {
+		var names []string
+		for _, span := range request.Spans() {
+			names = append(names, span.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	base := time.Unix(1700000000, 0)
+	state := sampleState(base)
+	if err := exporter.Export(context.Background(), state); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if err := exporter.Export(context.Background(), state); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(received) != 1 || fmt.Sprint(spanNames(received[0])) != "[Bash alpha dev]" || authHeader != "Bearer t" {
+		t.Fatalf("expected the finished tool call once with its parents, got %d requests", len(received))
+	}
+
+	agent := &state.Teams[0].Members[0]
+	agent.RecentEvents = append(agent.RecentEvents, types.AgentEvent{Kind: "thinking", Text: "more", Timestamp: base.Add(20 * time.Second)})
+	if err := exporter.Export(context.Background(), state); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected running team and agent spans to be held, got %v", spanNames(received[len(received)-1]))
+	}
+
+	agent.RecentEvents = append(agent.RecentEvents, types.AgentEvent{Kind: "tool_result", Text: "package main", ToolID: "toolu_2", Timestamp: base.Add(21 * time.Second)})
+	if err := exporter.Export(context.Background(), state); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(received) != 2 || fmt.Sprint(spanNames(received[1])) != "[Read alpha dev]" {
+		t.Fatalf("expected the Read call once it finished, with its parents, got %d requests", len(received))
+	}
+	for _, span := range received[1].Spans() {
+		if span.Name == "dev" {
+			if continued := span.Attributes[len(span.Attributes)-1]; continued.Key != "atm.span.continued" {
+				t.Fatalf("expected a continued agent segment, got %+v", span.Attributes)
+			}
+			if len(span.Events) != 1 || span.Events[0].Name != "thinking" || !parseNanos(span.StartTimeUnixNano).Equal(base.Add(8*time.Second)) {
+				t.Fatalf("expected the segment to cover the Read call and carry only new events, got %+v", span)
+			}
+		}
+	}
+
+	if err := exporter.Export(context.Background(), types.MonitorState{}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(received) != 3 || fmt.Sprint(spanNames(received[2])) != "[alpha dev]" {
+		t.Fatalf("expected the team and agent spans once the team left, got %d requests", len(received))
+	}
+	for _, span := range received[2].Spans() {
+		if end := parseNanos(span.EndTimeUnixNano); !end.Equal(base.Add(21 * time.Second)) {
+			t.Fatalf("expected %s to end when last seen, got %s", span.Name, end)
+		}
+	}
+	if len(exporter.sent) != 0 || len(exporter.open) != 0 || len(exporter.segments) != 0 {
+		t.Fatalf("expected nothing to be remembered, got %d sent, %d open and %d segments", len(exporter.sent), len(exporter.open), len(exporter.segments))
+	}
+
+	seen := make(map[string]bool)
+	for _, request := range received {
+		ids := make(map[string]bool)
+		for _, span := range request.Spans() {
+			if seen[span.SpanID] {
+				t.Fatalf("expected %s to be sent once, got it again", span.Name)
+			}
+			seen[span.SpanID] = true
+			ids[span.SpanID] = true
+		}
+		for _, span := range request.Spans() {
+			if span.ParentSpanID != "" && !ids[span.ParentSpanID] {
+				t.Fatalf("expected the parent of %s in the same request", span.Name)
+			}
+		}
+	}
+}
+
+func TestExporterFlushesRunningSpans(t *testing.T) {
+	var received []ExportRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ExportRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		received = append(received, request)
+	}))
+	defer collector.Close()
+	exporter, err := NewExporter(Config{Endpoint: collector.URL})
+	if err != nil {
+		t.Fatalf("NewExporter: %v", err)
+	}
+
+	if err := exporter.Export(context.Background(), sampleState(time.Unix(1700000000, 0))); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(exporter.sent) != 1 || len(exporter.open) != 3 {
+		t.Fatalf("expected the finished tool call sent and the rest running, got %d sent and %d open", len(exporter.sent), len(exporter.open))
+	}
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(received) != 2 || len(received[1].Spans()) != 3 || len(received[1].ResourceSpans) != 1 {
+		t.Fatalf("expected the running spans in one resource, got %+v", received)
+	}
+	if err := exporter.Flush(context.Background()); err != nil || len(received) != 2 {
+		t.Fatalf("expected a second flush to send nothing, got %d requests (%v)", len(received), err)
+	}
+}
+
+func TestExporterWritesSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "atm.json")
+	exporter, err := NewExporter(Config{FilePath: path})
+	if err != nil {
+		t.Fatalf("NewExporter: %v", err)
+	}
+	if err := exporter.Export(context.Background(), sampleState(time.Unix(1700000000, 0))); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	var request ExportRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+	if len(request.Spans()) != 4 {
+		t.Fatalf("expected 4 spans in snapshot, got %d", len(request.Spans()))
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("ATM_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("ATM_OTLP_HEADERS", "Authorization=Bearer x, X-Scope-OrgID=team")
+	t.Setenv("ATM_TRACE_INTERVAL", "5s")
+
+	cfg, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigFromEnv: %v", err)
+	}
+	if !cfg.Enabled() || cfg.Interval != 5*time.Second || cfg.Headers["X-Scope-OrgID"] != "team" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	exporter, err := NewExporter(cfg)
+	if err != nil {
+		t.Fatalf("NewExporter: %v", err)
+	}
+	if exporter.endpoint != "http://localhost:4318/v1/traces" {
+		t.Fatalf("expected /v1/traces to be appended, got %q", exporter.endpoint)
+	}
+
+	t.Setenv("ATM_OTLP_HEADERS", "broken")
+	if _, err := LoadConfigFromEnv(); err == nil {
+		t.Fatal("expected invalid headers error")
+	}
+	if _, err := NewExporter(Config{Endpoint: "localhost:4318"}); err == nil {
+		t.Fatal("expected invalid endpoint error")
+	}
+}
@@ -0,0 +1,113 @@
+package trace
+
+// The types below mirror the OTLP/JSON encoding of
+// opentelemetry.proto.collector.trace.v1.ExportTraceServiceRequest. Only the
+// fields the exporter fills in are modelled; trace and span IDs are hex
+// strings and timestamps are decimal nanosecond strings, as the OTLP/HTTP
+// JSON mapping requires.
+
+// SpanKindInternal is SPAN_KIND_INTERNAL; every agent span is in-process work.
+const SpanKindInternal = 1
+
+// Span status codes.
+const (
+	StatusCodeUnset = 0
+	StatusCodeOK    = 1
+	StatusCodeError = 2
+)
+
+// ExportRequest is the body POSTed to an OTLP/HTTP /v1/traces endpoint.
+type ExportRequest struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans groups the spans emitted for one resource (one team).
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Resource carries attributes shared by every span in a ResourceSpans.
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// ScopeSpans groups spans produced by one instrumentation scope.
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+// Scope identifies the instrumentation library.
+type Scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Span is a single OTLP span.
+type Span struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []KeyValue  `json:"attributes,omitempty"`
+	Events            []SpanEvent `json:"events,omitempty"`
+	Status            Status      `json:"status"`
+}
+
+// SpanEvent is a timestamped annotation on a span.
+type SpanEvent struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []KeyValue `json:"attributes,omitempty"`
+}
+
+// Status is the span outcome.
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// KeyValue is an attribute.
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue holds one attribute value.
+type AnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+func stringAttr(key, value string) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{StringValue: &value}}
+}
+
+func boolAttr(key string, value bool) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{BoolValue: &value}}
+}
+
+// Attribute returns the string value of key, if present.
+func (s Span) Attribute(key string) (string, bool) {
+	for _, attr := range s.Attributes {
+		if attr.Key == key && attr.Value.StringValue != nil {
+			return *attr.Value.StringValue, true
+		}
+	}
+	return "", false
+}
+
+// Spans flattens every span in the request.
+func (r ExportRequest) Spans() []Span {
+	var spans []Span
+	for _, resource := range r.ResourceSpans {
+		for _, scope := range resource.ScopeSpans {
+			spans = append(spans, scope.Spans...)
+		}
+	}
+	return spans
+}
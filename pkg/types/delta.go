@@ -0,0 +1,151 @@
+package types
+
+import "time"
+
+// StateDelta lists what changed in a MonitorState after version Since.
+// Teams carry only their own fields; members and tasks travel as
+// AgentChange and TaskChange entries and are ordered by the team's
+// MemberOrder and TaskOrder.
+type StateDelta struct {
+	Version   uint64        `json:"version"`
+	Since     uint64        `json:"since"`
+	Reset     bool          `json:"reset,omitempty"` // Since was unknown; State holds everything
+	State     *MonitorState `json:"state,omitempty"`
+	TeamOrder []string      `json:"team_order,omitempty"`
+	Teams     []TeamChange  `json:"teams,omitempty"`
+	Agents    []AgentChange `json:"agents,omitempty"`
+	Tasks     []TaskChange  `json:"tasks,omitempty"`
+	Removed   []Tombstone   `json:"removed,omitempty"`
+	Processes []ProcessInfo `json:"processes"`       // null when unchanged
+	Nodes     []NodeInfo    `json:"nodes,omitempty"` // omitted when unchanged
+	UpdatedAt time.Time     `json:"updated_at"`
+	Kiosk     *KioskInfo    `json:"kiosk,omitempty"`
+}
+
+// TeamChange is a team whose own fields, membership or task list changed.
+// Members and Tasks are always empty.
+type TeamChange struct {
+	TeamInfo
+	MemberOrder []string `json:"member_order"`
+	TaskOrder   []string `json:"task_order"`
+}
+
+// AgentChange is an added or updated team member.
+type AgentChange struct {
+	Team string `json:"team"`
+	AgentInfo
+}
+
+// TaskChange is an added or updated task.
+type TaskChange struct {
+	Team string `json:"team"`
+	TaskInfo
+}
+
+// Tombstone records a team, agent or task that disappeared.
+type Tombstone struct {
+	Kind    string `json:"kind"` // team, agent, task
+	Team    string `json:"team"`
+	ID      string `json:"id,omitempty"` // Agent key or task ID
+	Version uint64 `json:"version"`
+}
+
+// AgentKey identifies a member within its team in deltas: its name, or
+// its agent ID when unnamed.
+func AgentKey(agent AgentInfo) string {
+	if agent.Name != "" {
+		return agent.Name
+	}
+	return agent.AgentID
+}
+
+// ApplyDelta returns state advanced by delta. A reset delta replaces it
+// outright.
+func (s MonitorState) ApplyDelta(delta StateDelta) MonitorState {
+	if delta.Reset && delta.State != nil {
+		next := *delta.State
+		next.Version = delta.Version
+		return next
+	}
+
+	teams := make(map[string]TeamInfo, len(s.Teams))
+	agents := map[string]AgentInfo{}
+	tasks := map[string]TaskInfo{}
+	memberOrder := map[string][]string{}
+	taskOrder := map[string][]string{}
+	for _, team := range s.Teams {
+		teams[team.Name] = team
+		for _, agent := range team.Members {
+			key := AgentKey(agent)
+			agents[team.Name+"\x00"+key] = agent
+			memberOrder[team.Name] = append(memberOrder[team.Name], key)
+		}
+		for _, task := range team.Tasks {
+			tasks[team.Name+"\x00"+task.ID] = task
+			taskOrder[team.Name] = append(taskOrder[team.Name], task.ID)
+		}
+	}
+
+	for _, removed := range delta.Removed {
+		switch removed.Kind {
+		case "team":
+			delete(teams, removed.Team)
+		case "agent":
+			delete(agents, removed.Team+"\x00"+removed.ID)
+		case "task":
+			delete(tasks, removed.Team+"\x00"+removed.ID)
+		}
+	}
+	for _, change := range delta.Teams {
+		teams[change.Name] = change.TeamInfo
+		memberOrder[change.Name] = change.MemberOrder
+		taskOrder[change.Name] = change.TaskOrder
+	}
+	for _, change := range delta.Agents {
+		agents[change.Team+"\x00"+AgentKey(change.AgentInfo)] = change.AgentInfo
+	}
+	for _, change := range delta.Tasks {
+		tasks[change.Team+"\x00"+change.ID] = change.TaskInfo
+	}
+
+	order := delta.TeamOrder
+	if order == nil {
+		for _, team := range s.Teams {
+			order = append(order, team.Name)
+		}
+	}
+	next := MonitorState{
+		Teams:     make([]TeamInfo, 0, len(order)),
+		Processes: s.Processes,
+		UpdatedAt: delta.UpdatedAt,
+		Version:   delta.Version,
+		Kiosk:     delta.Kiosk,
+	}
+	if delta.Processes != nil {
+		next.Processes = delta.Processes
+	}
+	next.Nodes = s.Nodes
+	if delta.Nodes != nil {
+		next.Nodes = delta.Nodes
+	}
+	for _, name := range order {
+		team, ok := teams[name]
+		if !ok {
+			continue
+		}
+		team.Members = make([]AgentInfo, 0, len(memberOrder[name]))
+		for _, key := range memberOrder[name] {
+			if agent, ok := agents[name+"\x00"+key]; ok {
+				team.Members = append(team.Members, agent)
+			}
+		}
+		team.Tasks = make([]TaskInfo, 0, len(taskOrder[name]))
+		for _, id := range taskOrder[name] {
+			if task, ok := tasks[name+"\x00"+id]; ok {
+				team.Tasks = append(team.Tasks, task)
+			}
+		}
+		next.Teams = append(next.Teams, team)
+	}
+	return next
+}
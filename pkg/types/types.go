@@ -6,6 +6,8 @@ import "time"
 type TeamInfo struct {
 	Name          string      `json:"name"`
 	Provider      string      `json:"provider,omitempty"`     // claude, codex, openclaw
+	Root          string      `json:"root,omitempty"`         // label of the data root it came from
+	Host          string      `json:"host,omitempty"`         // federated node it runs on; empty when local
 	ControlMode   string      `json:"control_mode,omitempty"` // managed, imported
 	Managed       bool        `json:"managed,omitempty"`
 	ManagedTeamID string      `json:"managed_team_id,omitempty"`
@@ -29,6 +31,7 @@ type AgentEvent struct {
 	Title     string    `json:"title,omitempty"`     // Short UI label
 	Text      string    `json:"text"`                // Full display text
 	Source    string    `json:"source,omitempty"`    // inbox, activity_log, codex_session, openclaw_session
+	ToolID    string    `json:"tool_id,omitempty"`   // Pairs a tool call with its result
 	Timestamp time.Time `json:"timestamp,omitempty"` // Event time
 }
 
@@ -38,7 +41,8 @@ type AgentInfo struct {
 	Provider        string    `json:"provider,omitempty"` // claude, codex, openclaw
 	AgentID         string    `json:"agent_id"`
 	AgentType       string    `json:"agent_type"`
-	Status          string    `json:"status"` // idle, working, completed
+	Status          string    `json:"status"`                   // idle, working, completed, hung
+	ManagedStatus   string    `json:"managed_status,omitempty"` // Run status of a managed agent: running, exited, failed...
 	CurrentTask     string    `json:"current_task,omitempty"`
 	JoinedAt        time.Time `json:"joined_at,omitempty"`
 	RoleEmoji       string    `json:"role_emoji,omitempty"`       // Office persona emoji
@@ -50,14 +54,16 @@ type AgentInfo struct {
 	LatestResponse  string    `json:"latest_response,omitempty"` // Latest full outbound response text
 	LastMessageTime time.Time `json:"last_message_time,omitempty"`
 	// Activity tracking from jsonl logs
-	LastThinking      string       `json:"last_thinking,omitempty"`    // Latest thinking/reasoning
-	LastToolUse       string       `json:"last_tool_use,omitempty"`    // Latest tool name (Read, Edit, Bash, etc.)
-	LastToolDetail    string       `json:"last_tool_detail,omitempty"` // Tool usage details
-	LastActiveTime    time.Time    `json:"last_active_time,omitempty"` // Last activity timestamp from logs
-	RecentEvents      []AgentEvent `json:"recent_events,omitempty"`    // Full recent timeline for Web UI
-	SessionEntrypoint string       `json:"session_entrypoint,omitempty"`
-	CommandTransport  string       `json:"command_transport,omitempty"` // claude_inbox
-	CommandReason     string       `json:"command_reason,omitempty"`
+	LastThinking      string        `json:"last_thinking,omitempty"`    // Latest thinking/reasoning
+	LastToolUse       string        `json:"last_tool_use,omitempty"`    // Latest tool name (Read, Edit, Bash, etc.)
+	LastToolDetail    string        `json:"last_tool_detail,omitempty"` // Tool usage details
+	LastActiveTime    time.Time     `json:"last_active_time,omitempty"` // Last activity timestamp from logs
+	RecentEvents      []AgentEvent  `json:"recent_events,omitempty"`    // Full recent timeline for Web UI
+	SessionEntrypoint string        `json:"session_entrypoint,omitempty"`
+	CommandTransport  string        `json:"command_transport,omitempty"` // claude_inbox
+	CommandReason     string        `json:"command_reason,omitempty"`
+	Redactions        int           `json:"redactions,omitempty"` // Secrets masked in this agent's text
+	Hung              *HungEvidence `json:"hung,omitempty"`       // Set with status hung by the watchdog
 	// TodoWrite items from ~/.claude/todos/
 	Todos []TodoItem `json:"todos,omitempty"`
 }
@@ -82,11 +88,70 @@ type TaskInfo struct {
 
 // ProcessInfo represents a Claude Code process
 type ProcessInfo struct {
-	PID       int32     `json:"pid"`
-	Command   string    `json:"command"`
-	Team      string    `json:"team,omitempty"`
-	Provider  string    `json:"provider,omitempty"` // claude, codex, openclaw
-	StartedAt time.Time `json:"started_at"`
+	PID            int32          `json:"pid"`
+	Command        string         `json:"command"`
+	Team           string         `json:"team,omitempty"`
+	Agent          string         `json:"agent,omitempty"`            // Team member running it, by AgentKey
+	ManagedTeamID  string         `json:"managed_team_id,omitempty"`  // From ATM_MANAGED_TEAM_ID
+	ManagedAgentID string         `json:"managed_agent_id,omitempty"` // From ATM_MANAGED_AGENT_ID
+	SessionID      string         `json:"session_id,omitempty"`
+	LinkedBy       string         `json:"linked_by,omitempty"` // managed_env, session, cwd
+	Cwd            string         `json:"cwd,omitempty"`
+	Provider       string         `json:"provider,omitempty"` // claude, codex, openclaw
+	Host           string         `json:"host,omitempty"`     // federated node it runs on; empty when local
+	Container      *ContainerInfo `json:"container,omitempty"`
+	StartedAt      time.Time      `json:"started_at"`
+	CPUPercent     float64        `json:"cpu_percent"` // Since the previous scan, 100 per busy core
+	RSSBytes       uint64         `json:"rss_bytes"`
+	Threads        int32          `json:"threads"`
+	Children       []ChildProcess `json:"children,omitempty"`
+	Hung           *HungEvidence  `json:"hung,omitempty"`
+}
+
+// HungEvidence explains why the watchdog considers an agent process hung:
+// alive, idle and not writing its log.
+type HungEvidence struct {
+	PID          int32     `json:"pid"`
+	Since        time.Time `json:"since"`          // First flagged
+	CPUPercent   float64   `json:"cpu_percent"`    // Process and children, last scan
+	CPUIdleSince time.Time `json:"cpu_idle_since"` // Below the idle threshold since
+	LastLogWrite time.Time `json:"last_log_write"`
+	Remedy       string    `json:"remedy,omitempty"` // nudge, interrupt or restart, once applied
+	RemedyAt     time.Time `json:"remedy_at,omitempty"`
+	RemedyError  string    `json:"remedy_error,omitempty"`
+}
+
+// ContainerInfo identifies the Docker, Podman or other container a process
+// runs in.
+type ContainerInfo struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`    // From the runtime's metadata, when readable
+	Runtime string `json:"runtime,omitempty"` // docker, podman, containerd, cri-o
+	PID     int32  `json:"pid,omitempty"`     // Inside the container
+	// DataDir is the host path of the process's bind-mounted .claude or
+	// .codex directory, and Root the label of the data root watching it.
+	DataDir string `json:"data_dir,omitempty"`
+	Root    string `json:"root,omitempty"`
+}
+
+// ChildProcess is a process spawned by a monitored process, such as a shell
+// or a test runner, with its own descendants.
+type ChildProcess struct {
+	PID        int32          `json:"pid"`
+	Command    string         `json:"command"`
+	CPUPercent float64        `json:"cpu_percent"`
+	RSSBytes   uint64         `json:"rss_bytes"`
+	Children   []ChildProcess `json:"children,omitempty"`
+}
+
+// SignalResult is the outcome of signalling one agent process.
+type SignalResult struct {
+	PID     int32  `json:"pid"`
+	Command string `json:"command,omitempty"`
+	Team    string `json:"team,omitempty"`
+	Signal  string `json:"signal"`
+	Exited  bool   `json:"exited"`          // gone before the wait timed out
+	Error   string `json:"error,omitempty"` // the signal could not be sent
 }
 
 // MonitorState represents the overall monitoring state
@@ -94,4 +159,42 @@ type MonitorState struct {
 	Teams     []TeamInfo    `json:"teams"`
 	Processes []ProcessInfo `json:"processes"`
 	UpdatedAt time.Time     `json:"updated_at"`
+	Version   uint64        `json:"version,omitempty"` // Grows with every change; see StateDelta
+	Kiosk     *KioskInfo    `json:"kiosk,omitempty"`   // Set when served in read-only mode
+	Nodes     []NodeInfo    `json:"nodes,omitempty"`   // Federated nodes, set on a hub
+}
+
+// NodeInfo is a monitor that pushes its state to a hub.
+type NodeInfo struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url,omitempty"`
+	Online    bool      `json:"online"`
+	LastSeen  time.Time `json:"last_seen"`
+	Teams     int       `json:"teams"`
+	Processes int       `json:"processes"`
+}
+
+// NodeReport is what a federation agent pushes to its hub: the changes
+// since the version the hub acknowledged last, and how to reach the node.
+type NodeReport struct {
+	Node   string     `json:"node"`
+	URL    string     `json:"url"`
+	Secret string     `json:"secret"` // Bearer credential the hub presents to URL
+	Delta  StateDelta `json:"delta"`
+}
+
+// NodeAck answers a NodeReport. Reset asks the agent for a full state
+// because the hub does not hold the version the delta starts from.
+type NodeAck struct {
+	Version uint64 `json:"version"`
+	Reset   bool   `json:"reset,omitempty"`
+}
+
+// KioskInfo tells read-only dashboards which team to spotlight.
+type KioskInfo struct {
+	Privacy        string    `json:"privacy"`                  // full, standard, strict
+	FocusTeam      string    `json:"focus_team,omitempty"`     // Team currently in rotation
+	FocusIndex     int       `json:"focus_index"`              // Index of FocusTeam in Teams
+	RotateSeconds  int       `json:"rotate_seconds,omitempty"` // 0 when rotation is off
+	NextRotationAt time.Time `json:"next_rotation_at,omitempty"`
 }
@@ -48,6 +48,10 @@ var (
 	statusCompletedStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("#888888"))
 
+	statusHungStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF4444")).
+			Bold(true)
+
 	officeSectionStyle = lipgloss.NewStyle().
 				Underline(true).
 				Foreground(lipgloss.Color("#A88CFF"))
@@ -92,6 +96,9 @@ type model struct {
 	height         int
 	providerFilter string
 	hideIdleAgents bool
+	processCursor  int
+	pendingSignal  string // awaiting y/Y confirmation
+	notice         string // outcome of the last signal
 }
 
 type providerStats struct {
@@ -107,6 +114,11 @@ type providerStats struct {
 
 type tickMsg time.Time
 
+type signalResultMsg struct {
+	results []types.SignalResult
+	err     error
+}
+
 func tickCmd() tea.Cmd {
 	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
 		return tickMsg(t)
@@ -129,9 +141,22 @@ func (m model) Init() tea.Cmd {
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.pendingSignal != "" {
+			return m.confirmSignal(msg.String())
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "up", "k":
+			m.processCursor--
+		case "down", "j":
+			m.processCursor++
+		case "x":
+			m.pendingSignal = monitor.SignalInterrupt
+		case "t":
+			m.pendingSignal = monitor.SignalTerminate
+		case "K":
+			m.pendingSignal = monitor.SignalKill
 		case "r":
 			// Manual refresh
 			m.state = m.collector.GetState()
@@ -155,8 +180,50 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Update state periodically
 		m.state = m.collector.GetState()
 		return m, tickCmd()
+
+	case signalResultMsg:
+		if msg.err != nil {
+			m.notice = "发送信号失败: " + msg.err.Error()
+		} else {
+			m.notice = monitor.SummarizeSignalResults(msg.results)
+		}
+	}
+
+	_, processes, _ := m.filteredState()
+	m.processCursor = max(0, min(m.processCursor, len(processes)-1))
+	if len(processes) == 0 {
+		m.pendingSignal = ""
 	}
+	return m, nil
+}
 
+// confirmSignal handles the key after a signal key: y signals the selected
+// process, Y every process linked to its team, anything else cancels.
+func (m model) confirmSignal(key string) (tea.Model, tea.Cmd) {
+	signal := m.pendingSignal
+	m.pendingSignal = ""
+	_, processes, _ := m.filteredState()
+	if m.processCursor >= len(processes) {
+		return m, nil
+	}
+	proc := processes[m.processCursor]
+
+	collector := m.collector
+	switch {
+	case key == "y":
+		m.notice = fmt.Sprintf("正在向进程 %d 发送 %s…", proc.PID, signal)
+		return m, func() tea.Msg {
+			results, err := collector.SignalProcesses([]int32{proc.PID}, signal, monitor.DefaultSignalTimeout)
+			return signalResultMsg{results: results, err: err}
+		}
+	case key == "Y" && proc.Team != "":
+		m.notice = fmt.Sprintf("正在向团队 %s 的进程发送 %s…", proc.Team, signal)
+		return m, func() tea.Msg {
+			results, err := collector.SignalTeam(proc.Team, signal, monitor.DefaultSignalTimeout)
+			return signalResultMsg{results: results, err: err}
+		}
+	}
+	m.notice = "已取消"
 	return m, nil
 }
 
@@ -182,14 +249,22 @@ func (m model) View() string {
 	if len(processes) == 0 {
 		b.WriteString(processStyle.Render("  未检测到代理进程\n"))
 	} else {
-		for _, proc := range processes {
-			uptime := time.Since(proc.StartedAt).Round(time.Second)
-			provider := detectProcessProvider(proc)
-			procInfo := fmt.Sprintf("  进程 ID: %d | 来源: %s | 运行时间: %s", proc.PID, provider, uptime)
-			b.WriteString(processStyle.Render(procInfo))
-			b.WriteString("\n")
+		for i, proc := range processes {
+			b.WriteString(m.renderProcess(proc, i == m.processCursor))
 		}
 	}
+	if m.pendingSignal != "" && m.processCursor < len(processes) {
+		proc := processes[m.processCursor]
+		prompt := fmt.Sprintf("  向进程 %d 发送 %s？按 'y' 确认", proc.PID, m.pendingSignal)
+		if proc.Team != "" {
+			prompt += fmt.Sprintf(" | 按 'Y' 发送给团队 %s 的全部进程", proc.Team)
+		}
+		b.WriteString(statusWorkingStyle.Render(prompt + " | 其他键取消"))
+		b.WriteString("\n")
+	} else if m.notice != "" {
+		b.WriteString(agentMetaStyle.Render(m.notice))
+		b.WriteString("\n")
+	}
 	b.WriteString("\n")
 
 	// Teams section
@@ -208,7 +283,7 @@ func (m model) View() string {
 
 	// Help
 	b.WriteString("\n")
-	help := lipgloss.NewStyle().Faint(true).Render("按 '1/2/3/4' 切换筛选 | 按 'i' 切换空闲隐藏 | 按 'r' 刷新 | 按 'q' 退出")
+	help := lipgloss.NewStyle().Faint(true).Render("按 '1/2/3/4' 切换筛选 | 按 'i' 切换空闲隐藏 | 按 '↑/↓' 选择进程，'x/t/K' 发送 SIGINT/SIGTERM/SIGKILL | 按 'r' 刷新 | 按 'q' 退出")
 	b.WriteString(help)
 
 	return b.String()
@@ -406,6 +481,93 @@ func detectProcessProvider(proc types.ProcessInfo) string {
 	return "unknown"
 }
 
+// maxProcessChildLines keeps a busy agent's subprocesses from pushing the
+// teams off screen.
+const maxProcessChildLines = 6
+
+func (m model) renderProcess(proc types.ProcessInfo, selected bool) string {
+	var b strings.Builder
+	uptime := time.Since(proc.StartedAt).Round(time.Second)
+	provider := detectProcessProvider(proc)
+	marker := " "
+	if selected {
+		marker = "▶"
+	}
+	procInfo := fmt.Sprintf("%s 进程 ID: %d | 来源: %s | 运行时间: %s | CPU: %.1f%% | 内存: %s | 线程: %d",
+		marker, proc.PID, provider, uptime, proc.CPUPercent, formatBytes(proc.RSSBytes), proc.Threads)
+	if proc.Team != "" {
+		owner := proc.Team
+		if proc.Agent != "" {
+			owner += " / " + proc.Agent
+		}
+		procInfo += " | 团队: " + owner
+	}
+	if proc.Container != nil {
+		procInfo += " | 容器: " + containerLabel(proc.Container)
+	}
+	b.WriteString(processStyle.Render(procInfo))
+	b.WriteString("\n")
+	if proc.Hung != nil {
+		b.WriteString(statusHungStyle.MarginLeft(4).Render(formatHungEvidence(proc.Hung)))
+		b.WriteString("\n")
+	}
+
+	lines := 0
+	var walk func(children []types.ChildProcess, depth int)
+	walk = func(children []types.ChildProcess, depth int) {
+		for _, child := range children {
+			if lines == maxProcessChildLines {
+				return
+			}
+			lines++
+			line := fmt.Sprintf("%s└ %d %s · CPU %.1f%% · %s",
+				strings.Repeat("  ", depth+1), child.PID, narrative.NormalizeDialogText(child.Command, 40), child.CPUPercent, formatBytes(child.RSSBytes))
+			b.WriteString(agentMetaStyle.Render(line))
+			b.WriteString("\n")
+			walk(child.Children, depth+1)
+		}
+	}
+	walk(proc.Children, 1)
+	if total := countChildProcesses(proc.Children); total > lines {
+		b.WriteString(agentMetaStyle.Render(fmt.Sprintf("    … 另有 %d 个子进程", total-lines)))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// containerLabel names a container, with its runtime when known.
+func containerLabel(container *types.ContainerInfo) string {
+	label := container.Name
+	if label == "" {
+		label = container.ID[:min(12, len(container.ID))]
+	}
+	if container.Runtime != "" {
+		label += " (" + container.Runtime + ")"
+	}
+	return label
+}
+
+func countChildProcesses(children []types.ChildProcess) int {
+	n := len(children)
+	for _, child := range children {
+		n += countChildProcesses(child.Children)
+	}
+	return n
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func (m model) renderAgentDesk(agent types.AgentInfo, tasks []types.TaskInfo) string {
 	var b strings.Builder
 
@@ -417,6 +579,10 @@ func (m model) renderAgentDesk(agent types.AgentInfo, tasks []types.TaskInfo) st
 	)
 	b.WriteString(agentStyle.Render(header))
 	b.WriteString("\n")
+	if agent.Hung != nil {
+		b.WriteString(statusHungStyle.MarginLeft(4).Render(formatHungEvidence(agent.Hung)))
+		b.WriteString("\n")
+	}
 
 	dialogues := m.agentDialogues(agent, tasks)
 	for i, dialogue := range dialogues {
@@ -512,11 +678,32 @@ func (m model) formatStatus(status string) string {
 		return statusIdleStyle.Render("空闲")
 	case "completed":
 		return statusCompletedStyle.Render("已完成")
+	case "hung":
+		return statusHungStyle.Render("疑似卡住")
 	default:
 		return status
 	}
 }
 
+var hungRemedyLabels = map[string]string{
+	monitor.RemedyNudge:     "发送提醒",
+	monitor.RemedyInterrupt: "中断当前回合",
+	monitor.RemedyRestart:   "重启",
+}
+
+// formatHungEvidence explains a watchdog flag in one line.
+func formatHungEvidence(hung *types.HungEvidence) string {
+	line := fmt.Sprintf("⚠ 疑似卡住: 日志 %s 未写入 · CPU %.1f%%, 已空闲 %s",
+		time.Since(hung.LastLogWrite).Round(time.Minute), hung.CPUPercent, time.Since(hung.CPUIdleSince).Round(time.Minute))
+	switch {
+	case hung.RemedyError != "":
+		line += fmt.Sprintf(" · %s失败: %s", hungRemedyLabels[hung.Remedy], hung.RemedyError)
+	case hung.Remedy != "":
+		line += fmt.Sprintf(" · %s 前已%s", time.Since(hung.RemedyAt).Round(time.Second), hungRemedyLabels[hung.Remedy])
+	}
+	return line
+}
+
 func (m model) formatTaskStatus(status string) string {
 	switch status {
 	case "in_progress":
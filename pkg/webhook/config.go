@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	configPathEnv = "ATM_WEBHOOKS_CONFIG"
+
+	defaultConfigFileName     = "webhooks.json"
+	defaultDeadLetterFileName = "webhooks-dead-letter.jsonl"
+	defaultMaxAttempts        = 4
+	defaultInitialBackoff     = time.Second
+	defaultMaxBackoff         = 30 * time.Second
+	defaultRequestTimeout     = 10 * time.Second
+)
+
+// Config describes every outbound webhook endpoint and where failed
+// deliveries are parked once all retries are exhausted.
+type Config struct {
+	DeadLetterPath string           `json:"dead_letter_path,omitempty" yaml:"dead_letter_path"`
+	Endpoints      []EndpointConfig `json:"endpoints" yaml:"endpoints"`
+}
+
+// EndpointConfig describes a single webhook receiver.
+type EndpointConfig struct {
+	Name           string            `json:"name" yaml:"name"`
+	URL            string            `json:"url" yaml:"url"`
+	Events         []string          `json:"events,omitempty" yaml:"events"` // empty means all events
+	Format         string            `json:"format,omitempty" yaml:"format"` // json, slack, feishu, dingtalk
+	Template       string            `json:"template,omitempty" yaml:"template"`
+	Secret         string            `json:"secret,omitempty" yaml:"secret"`
+	Headers        map[string]string `json:"headers,omitempty" yaml:"headers"`
+	MaxAttempts    int               `json:"max_attempts,omitempty" yaml:"max_attempts"`
+	InitialBackoff string            `json:"initial_backoff,omitempty" yaml:"initial_backoff"`
+	MaxBackoff     string            `json:"max_backoff,omitempty" yaml:"max_backoff"`
+	Timeout        string            `json:"timeout,omitempty" yaml:"timeout"`
+}
+
+// Endpoint is a validated EndpointConfig ready for delivery.
+type Endpoint struct {
+	Name           string
+	URL            string
+	Format         string
+	Secret         string
+	Headers        map[string]string
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+
+	events   map[EventType]struct{}
+	template *template.Template
+}
+
+// Accepts reports whether the endpoint subscribed to the given event type.
+func (e Endpoint) Accepts(eventType EventType) bool {
+	if len(e.events) == 0 {
+		return true
+	}
+	_, ok := e.events[eventType]
+	return ok
+}
+
+// DefaultConfigPath returns the webhook config location, honoring ATM_WEBHOOKS_CONFIG.
+func DefaultConfigPath() (string, error) {
+	if custom := strings.TrimSpace(os.Getenv(configPathEnv)); custom != "" {
+		return custom, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".agent-team-monitor", defaultConfigFileName), nil
+}
+
+// LoadConfigFromEnv loads the webhook config from DefaultConfigPath.
+// A missing file yields an empty config so webhooks stay opt-in.
+func LoadConfigFromEnv() (Config, error) {
+	path, err := DefaultConfigPath()
+	if err != nil {
+		return Config{}, err
+	}
+	return LoadConfig(path)
+}
+
+// LoadConfig reads a webhook config file.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return Config{}, nil
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse webhook config %s: %w", path, err)
+	}
+	if strings.TrimSpace(cfg.DeadLetterPath) == "" {
+		cfg.DeadLetterPath = filepath.Join(filepath.Dir(path), defaultDeadLetterFileName)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports the first endpoint that cannot be delivered to.
+func (c Config) Validate() error {
+	_, err := c.endpoints()
+	return err
+}
+
+func (c Config) endpoints() ([]Endpoint, error) {
+	result := make([]Endpoint, 0, len(c.Endpoints))
+	for i, raw := range c.Endpoints {
+		endpoint, err := raw.compile()
+		if err != nil {
+			name := firstNonEmpty(raw.Name, fmt.Sprintf("#%d", i+1))
+			return nil, fmt.Errorf("webhook endpoint %s: %w", name, err)
+		}
+		result = append(result, endpoint)
+	}
+	return result, nil
+}
+
+func (c EndpointConfig) compile() (Endpoint, error) {
+	target := strings.TrimSpace(c.URL)
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return Endpoint{}, fmt.Errorf("invalid url %q", c.URL)
+	}
+
+	format := strings.ToLower(strings.TrimSpace(c.Format))
+	switch format {
+	case "":
+		format = FormatJSON
+	case FormatJSON, FormatSlack, FormatFeishu, FormatDingTalk:
+	default:
+		return Endpoint{}, fmt.Errorf("unsupported format %q (expected: json, slack, feishu, dingtalk)", c.Format)
+	}
+
+	endpoint := Endpoint{
+		Name:           firstNonEmpty(c.Name, parsed.Host),
+		URL:            target,
+		Format:         format,
+		Secret:         c.Secret,
+		Headers:        c.Headers,
+		MaxAttempts:    c.MaxAttempts,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		Timeout:        defaultRequestTimeout,
+		events:         make(map[EventType]struct{}, len(c.Events)),
+	}
+	if endpoint.MaxAttempts <= 0 {
+		endpoint.MaxAttempts = defaultMaxAttempts
+	}
+
+	for _, field := range []struct {
+		raw    string
+		target *time.Duration
+		name   string
+	}{
+		{c.InitialBackoff, &endpoint.InitialBackoff, "initial_backoff"},
+		{c.MaxBackoff, &endpoint.MaxBackoff, "max_backoff"},
+		{c.Timeout, &endpoint.Timeout, "timeout"},
+	} {
+		if strings.TrimSpace(field.raw) == "" {
+			continue
+		}
+		value, err := time.ParseDuration(strings.TrimSpace(field.raw))
+		if err != nil || value < 0 {
+			return Endpoint{}, fmt.Errorf("invalid %s %q", field.name, field.raw)
+		}
+		*field.target = value
+	}
+
+	for _, raw := range c.Events {
+		eventType, err := ParseEventType(raw)
+		if err != nil {
+			return Endpoint{}, err
+		}
+		endpoint.events[eventType] = struct{}{}
+	}
+
+	if strings.TrimSpace(c.Template) != "" {
+		tmpl, err := template.New(endpoint.Name).Funcs(templateFuncs).Parse(c.Template)
+		if err != nil {
+			return Endpoint{}, fmt.Errorf("parse template: %w", err)
+		}
+		endpoint.template = tmpl
+	}
+
+	return endpoint, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
@@ -0,0 +1,305 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const dispatcherQueueSize = 256
+
+// DeadLetter is a delivery that exhausted all retries.
+type DeadLetter struct {
+	Endpoint string    `json:"endpoint"`
+	URL      string    `json:"url"`
+	Event    Event     `json:"event"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+type delivery struct {
+	endpoint Endpoint
+	event    Event
+}
+
+// Dispatcher delivers events to every subscribed endpoint in the background.
+// Each endpoint has its own queue and worker, so an endpoint that is slow or
+// backing off between retries does not hold up deliveries to the others.
+type Dispatcher struct {
+	endpoints      []Endpoint
+	deadLetterPath string
+	client         *http.Client
+	now            func() time.Time
+	sleep          func(context.Context, time.Duration) bool
+
+	queues    []chan delivery // one per endpoint, in the same order
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	deadLetterMu sync.Mutex
+}
+
+// NewDispatcher validates the config and starts a delivery worker per
+// endpoint.
+func NewDispatcher(cfg Config) (*Dispatcher, error) {
+	endpoints, err := cfg.endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		endpoints:      endpoints,
+		deadLetterPath: cfg.DeadLetterPath,
+		client:         &http.Client{},
+		now:            time.Now,
+		sleep:          sleepContext,
+		queues:         make([]chan delivery, len(endpoints)),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	for i := range d.queues {
+		d.queues[i] = make(chan delivery, dispatcherQueueSize)
+		d.wg.Add(1)
+		go d.run(d.queues[i])
+	}
+	return d, nil
+}
+
+// Endpoints returns the number of configured endpoints.
+func (d *Dispatcher) Endpoints() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.endpoints)
+}
+
+// Publish queues an event for every endpoint subscribed to it.
+func (d *Dispatcher) Publish(event Event) {
+	if d == nil {
+		return
+	}
+	for i, endpoint := range d.endpoints {
+		if !endpoint.Accepts(event.Type) {
+			continue
+		}
+		select {
+		case <-d.ctx.Done():
+			return
+		case d.queues[i] <- delivery{endpoint: endpoint, event: event}:
+		default:
+			d.writeDeadLetter(endpoint, event, 0, fmt.Errorf("delivery queue full"))
+		}
+	}
+}
+
+// Close stops the workers. Queued deliveries that have not been attempted
+// yet are moved to the dead-letter file so they are not silently lost.
+func (d *Dispatcher) Close() error {
+	if d == nil {
+		return nil
+	}
+	d.closeOnce.Do(func() {
+		d.cancel()
+		d.wg.Wait()
+		for _, queue := range d.queues {
+			d.drain(queue)
+		}
+	})
+	return nil
+}
+
+func (d *Dispatcher) drain(queue chan delivery) {
+	for {
+		select {
+		case pending := <-queue:
+			d.writeDeadLetter(pending.endpoint, pending.event, 0, fmt.Errorf("dispatcher stopped"))
+		default:
+			return
+		}
+	}
+}
+
+// run delivers one endpoint's queue in order.
+func (d *Dispatcher) run(queue chan delivery) {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case item := <-queue:
+			if err := d.Deliver(d.ctx, item.endpoint, item.event); err != nil {
+				log.Printf("Webhook %s delivery of %s failed: %v", item.endpoint.Name, item.event.Type, err)
+			}
+		}
+	}
+}
+
+// Deliver sends one event to one endpoint, retrying with exponential
+// backoff. Exhausted deliveries are appended to the dead-letter file.
+func (d *Dispatcher) Deliver(ctx context.Context, endpoint Endpoint, event Event) error {
+	backoff := endpoint.InitialBackoff
+	var lastErr error
+	attempts := 0
+
+	for attempts < endpoint.MaxAttempts {
+		attempts++
+		lastErr = d.attempt(ctx, endpoint, event)
+		if lastErr == nil {
+			return nil
+		}
+		if attempts >= endpoint.MaxAttempts {
+			break
+		}
+		if !d.sleep(ctx, backoff) {
+			break
+		}
+		backoff *= 2
+		if endpoint.MaxBackoff > 0 && backoff > endpoint.MaxBackoff {
+			backoff = endpoint.MaxBackoff
+		}
+	}
+
+	d.writeDeadLetter(endpoint, event, attempts, lastErr)
+	return lastErr
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, endpoint Endpoint, event Event) error {
+	now := d.now()
+	body, err := RenderPayload(endpoint, event, now)
+	if err != nil {
+		return err
+	}
+
+	reqCtx := ctx
+	if endpoint.Timeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, endpoint.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, RequestURL(endpoint, now), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "agent-team-monitor-webhook")
+	req.Header.Set("X-ATM-Event", string(event.Type))
+	req.Header.Set("X-ATM-Delivery", event.ID)
+	req.Header.Set("X-ATM-Timestamp", timestamp)
+	if endpoint.Secret != "" {
+		req.Header.Set("X-ATM-Signature", Signature(endpoint.Secret, timestamp, body))
+	}
+	for key, value := range endpoint.Headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) writeDeadLetter(endpoint Endpoint, event Event, attempts int, cause error) {
+	if d.deadLetterPath == "" {
+		return
+	}
+
+	record := DeadLetter{
+		Endpoint: endpoint.Name,
+		URL:      endpoint.URL,
+		Event:    event,
+		Attempts: attempts,
+		FailedAt: d.now(),
+	}
+	if cause != nil {
+		record.Error = cause.Error()
+	}
+
+	d.deadLetterMu.Lock()
+	defer d.deadLetterMu.Unlock()
+	if err := appendJSONLine(d.deadLetterPath, record); err != nil {
+		log.Printf("Error writing webhook dead letter: %v", err)
+	}
+}
+
+// ReadDeadLetters loads every record from a dead-letter file.
+func ReadDeadLetters(path string) ([]DeadLetter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []DeadLetter
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record DeadLetter
+		if err := json.Unmarshal(line, &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func appendJSONLine(path string, payload interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) bool {
+	if duration <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,272 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestDispatcher(t *testing.T, cfg Config) *Dispatcher {
+	t.Helper()
+	if cfg.DeadLetterPath == "" {
+		cfg.DeadLetterPath = filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	}
+	dispatcher, err := NewDispatcher(cfg)
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+	dispatcher.sleep = func(ctx context.Context, _ time.Duration) bool { return ctx.Err() == nil }
+	t.Cleanup(func() { _ = dispatcher.Close() })
+	return dispatcher
+}
+
+func TestDeliverSignsJSONPayload(t *testing.T) {
+	var (
+		gotBody      []byte
+		gotSignature string
+		gotTimestamp string
+		gotEvent     string
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get("X-ATM-Signature")
+		gotTimestamp = r.Header.Get("X-ATM-Timestamp")
+		gotEvent = r.Header.Get("X-ATM-Event")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t, Config{Endpoints: []EndpointConfig{{Name: "ci", URL: receiver.URL, Secret: "s3cret"}}})
+	event := Event{ID: "evt-1", Type: EventTaskCompleted, Team: "alpha", Title: "任务已完成"}
+
+	if err := dispatcher.Deliver(context.Background(), dispatcher.endpoints[0], event); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	if gotEvent != string(EventTaskCompleted) {
+		t.Fatalf("expected event header, got %q", gotEvent)
+	}
+	if want := Signature("s3cret", gotTimestamp, gotBody); gotSignature != want {
+		t.Fatalf("signature mismatch: got %q want %q", gotSignature, want)
+	}
+
+	var decoded Event
+	if err := json.Unmarshal(gotBody, &decoded); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if decoded.Team != "alpha" || decoded.Type != EventTaskCompleted {
+		t.Fatalf("unexpected payload: %+v", decoded)
+	}
+}
+
+func TestDeliverRetriesThenSucceeds(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t, Config{Endpoints: []EndpointConfig{{URL: receiver.URL, MaxAttempts: 5}}})
+	if err := dispatcher.Deliver(context.Background(), dispatcher.endpoints[0], Event{Type: EventTeamStarted}); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls.Load())
+	}
+
+	records, err := ReadDeadLetters(dispatcher.deadLetterPath)
+	if err != nil {
+		t.Fatalf("ReadDeadLetters: %v", err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("expected no dead letters, got %+v", records)
+	}
+}
+
+func TestDeliverWritesDeadLetterAfterExhaustingRetries(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t, Config{Endpoints: []EndpointConfig{{Name: "chat", URL: receiver.URL, MaxAttempts: 2}}})
+	err := dispatcher.Deliver(context.Background(), dispatcher.endpoints[0], Event{ID: "evt-9", Type: EventManagedRunFailed})
+	if err == nil {
+		t.Fatal("expected delivery error")
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 attempts, got %d", calls.Load())
+	}
+
+	records, err := ReadDeadLetters(dispatcher.deadLetterPath)
+	if err != nil {
+		t.Fatalf("ReadDeadLetters: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(records))
+	}
+	if records[0].Endpoint != "chat" || records[0].Event.ID != "evt-9" || records[0].Attempts != 2 {
+		t.Fatalf("unexpected dead letter: %+v", records[0])
+	}
+}
+
+func TestPublishFiltersBySubscribedEvents(t *testing.T) {
+	received := make(chan string, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-ATM-Event")
+	}))
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t, Config{Endpoints: []EndpointConfig{{URL: receiver.URL, Events: []string{"task.completed"}}}})
+	dispatcher.Publish(Event{Type: EventTeamStarted})
+	dispatcher.Publish(Event{Type: EventTaskCompleted})
+
+	select {
+	case got := <-received:
+		if got != string(EventTaskCompleted) {
+			t.Fatalf("expected only task.completed, got %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+
+	select {
+	case got := <-received:
+		t.Fatalf("unexpected extra delivery %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPublishDoesNotWaitForSlowEndpoints(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	received := make(chan string, 4)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-ATM-Delivery")
+	}))
+	defer fast.Close()
+
+	dispatcher := newTestDispatcher(t, Config{Endpoints: []EndpointConfig{{Name: "slow", URL: slow.URL}, {Name: "fast", URL: fast.URL}}})
+	dispatcher.Publish(Event{ID: "first", Type: EventTaskCompleted})
+	dispatcher.Publish(Event{ID: "second", Type: EventTaskCompleted})
+
+	for _, want := range []string{"first", "second"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("expected delivery %q, got %q", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q while the slow endpoint hangs", want)
+		}
+	}
+}
+
+func TestRenderPayloadFormats(t *testing.T) {
+	event := Event{Type: EventTaskCompleted, Title: "任务已完成", Message: "alpha 已完成 T-1", Team: "alpha"}
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name   string
+		config EndpointConfig
+		check  func(t *testing.T, body map[string]interface{})
+	}{
+		{
+			name:   "slack",
+			config: EndpointConfig{URL: "https://hooks.example.com/a", Format: "slack"},
+			check: func(t *testing.T, body map[string]interface{}) {
+				if text, _ := body["text"].(string); !strings.Contains(text, "alpha 已完成 T-1") {
+					t.Fatalf("unexpected slack body: %#v", body)
+				}
+			},
+		},
+		{
+			name:   "feishu signed",
+			config: EndpointConfig{URL: "https://open.feishu.cn/hook", Format: "feishu", Secret: "k"},
+			check: func(t *testing.T, body map[string]interface{}) {
+				if body["msg_type"] != "text" || body["sign"] == "" || body["timestamp"] != "1700000000" {
+					t.Fatalf("unexpected feishu body: %#v", body)
+				}
+			},
+		},
+		{
+			name:   "dingtalk",
+			config: EndpointConfig{URL: "https://oapi.dingtalk.com/robot/send?access_token=x", Format: "dingtalk"},
+			check: func(t *testing.T, body map[string]interface{}) {
+				if body["msgtype"] != "text" {
+					t.Fatalf("unexpected dingtalk body: %#v", body)
+				}
+			},
+		},
+		{
+			name:   "custom template",
+			config: EndpointConfig{URL: "https://hooks.example.com/b", Template: `{"summary": {{json .Message}}, "kind": "{{.Type}}"}`},
+			check: func(t *testing.T, body map[string]interface{}) {
+				if body["summary"] != "alpha 已完成 T-1" || body["kind"] != "task.completed" {
+					t.Fatalf("unexpected templated body: %#v", body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, err := tt.config.compile()
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			payload, err := RenderPayload(endpoint, event, now)
+			if err != nil {
+				t.Fatalf("RenderPayload: %v", err)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(payload, &body); err != nil {
+				t.Fatalf("payload is not JSON: %v (%s)", err, payload)
+			}
+			tt.check(t, body)
+		})
+	}
+}
+
+func TestRequestURLSignsDingTalk(t *testing.T) {
+	endpoint, err := EndpointConfig{URL: "https://oapi.dingtalk.com/robot/send?access_token=x", Format: "dingtalk", Secret: "SEC"}.compile()
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	got := RequestURL(endpoint, time.UnixMilli(1700000000123))
+	if !strings.Contains(got, "&timestamp=1700000000123&sign=") {
+		t.Fatalf("expected signed dingtalk url, got %q", got)
+	}
+}
+
+func TestLoadConfigRejectsInvalidEndpoints(t *testing.T) {
+	tests := []EndpointConfig{
+		{URL: "ftp://example.com"},
+		{URL: "https://example.com", Format: "teams"},
+		{URL: "https://example.com", Events: []string{"nope"}},
+		{URL: "https://example.com", InitialBackoff: "soon"},
+		{URL: "https://example.com", Template: "{{"},
+	}
+	for _, endpoint := range tests {
+		if _, err := endpoint.compile(); err == nil {
+			t.Fatalf("expected compile error for %+v", endpoint)
+		}
+	}
+}
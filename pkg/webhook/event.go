@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+// EventType identifies a monitor event that can be delivered to webhooks.
+type EventType string
+
+const (
+	EventTaskCompleted    EventType = "task.completed"
+	EventAgentErrored     EventType = "agent.errored"
+	EventAgentHung        EventType = "agent.hung"
+	EventTeamStarted      EventType = "team.started"
+	EventTeamFinished     EventType = "team.finished"
+	EventManagedRunFailed EventType = "managed.run_failed"
+)
+
+var allEventTypes = []EventType{
+	EventTaskCompleted,
+	EventAgentErrored,
+	EventAgentHung,
+	EventTeamStarted,
+	EventTeamFinished,
+	EventManagedRunFailed,
+}
+
+// ParseEventType parses an event name from config.
+func ParseEventType(raw string) (EventType, error) {
+	value := EventType(strings.ToLower(strings.TrimSpace(raw)))
+	for _, known := range allEventTypes {
+		if value == known {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("unknown webhook event %q", raw)
+}
+
+// Event is the JSON payload delivered for the default json format.
+type Event struct {
+	ID          string    `json:"id"`
+	Type        EventType `json:"type"`
+	Time        time.Time `json:"time"`
+	Title       string    `json:"title"`
+	Message     string    `json:"message"`
+	Team        string    `json:"team,omitempty"`
+	Provider    string    `json:"provider,omitempty"`
+	Agent       string    `json:"agent,omitempty"`
+	TaskID      string    `json:"task_id,omitempty"`
+	TaskSubject string    `json:"task_subject,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+func newEvent(eventType EventType, now time.Time) Event {
+	return Event{
+		ID:   newEventID(),
+		Type: eventType,
+		Time: now,
+	}
+}
+
+func newEventID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// DiffStates compares two monitor snapshots and returns the events that
+// happened between them.
+func DiffStates(prev, next types.MonitorState, now time.Time) []Event {
+	prevTeams := indexTeams(prev.Teams)
+	nextTeams := indexTeams(next.Teams)
+
+	var events []Event
+	for _, team := range next.Teams {
+		before, existed := prevTeams[teamKey(team)]
+		if !existed {
+			events = append(events, teamEvent(EventTeamStarted, team, now, "团队已启动", fmt.Sprintf("团队 %s 已开始运行", team.Name)))
+			continue
+		}
+
+		events = append(events, diffTasks(before, team, now)...)
+		events = append(events, diffAgents(before, team, now)...)
+		events = append(events, diffManagedRun(before, team, now)...)
+
+		if allTasksCompleted(team) && !allTasksCompleted(before) {
+			events = append(events, teamEvent(EventTeamFinished, team, now, "团队任务已全部完成", fmt.Sprintf("团队 %s 的 %d 个任务已全部完成", team.Name, len(team.Tasks))))
+		}
+	}
+
+	for _, team := range prev.Teams {
+		if _, ok := nextTeams[teamKey(team)]; ok {
+			continue
+		}
+		events = append(events, teamEvent(EventTeamFinished, team, now, "团队已结束", fmt.Sprintf("团队 %s 已不再活动", team.Name)))
+	}
+
+	return events
+}
+
+func diffTasks(before, after types.TeamInfo, now time.Time) []Event {
+	previous := make(map[string]types.TaskInfo, len(before.Tasks))
+	for _, task := range before.Tasks {
+		previous[taskKey(task)] = task
+	}
+
+	var events []Event
+	for _, task := range after.Tasks {
+		old, ok := previous[taskKey(task)]
+		if !ok || isCompleted(old.Status) || !isCompleted(task.Status) {
+			continue
+		}
+
+		event := teamEvent(EventTaskCompleted, after, now, "任务已完成", "")
+		event.TaskID = task.ID
+		event.TaskSubject = task.Subject
+		event.Agent = task.Owner
+		event.Status = task.Status
+		label := firstNonEmpty(task.Subject, task.ID)
+		if strings.TrimSpace(task.Owner) != "" {
+			event.Message = fmt.Sprintf("%s / %s 已完成 %s", after.Name, task.Owner, label)
+		} else {
+			event.Message = fmt.Sprintf("%s 已完成 %s", after.Name, label)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func diffAgents(before, after types.TeamInfo, now time.Time) []Event {
+	previous := make(map[string]types.AgentInfo, len(before.Members))
+	for _, agent := range before.Members {
+		previous[agent.Name] = agent
+	}
+
+	var events []Event
+	for _, agent := range after.Members {
+		old, ok := previous[agent.Name]
+		if ok && old.Status != "hung" && agent.Status == "hung" && agent.Hung != nil {
+			event := teamEvent(EventAgentHung, after, now, "成员疑似卡住", fmt.Sprintf("%s / %s 的进程 %d 仍在运行，但日志自 %s 起未写入", after.Name, agent.Name, agent.Hung.PID, agent.Hung.LastLogWrite.Format("15:04")))
+			event.Agent = agent.Name
+			event.Provider = firstNonEmpty(agent.Provider, after.Provider)
+			event.Status = agent.Status
+			events = append(events, event)
+			continue
+		}
+		if !ok || isFailedRun(old) || !isFailedRun(agent) {
+			continue
+		}
+
+		event := teamEvent(EventAgentErrored, after, now, "成员运行出错", fmt.Sprintf("%s / %s 的受管运行失败", after.Name, agent.Name))
+		event.Agent = agent.Name
+		event.Provider = firstNonEmpty(agent.Provider, after.Provider)
+		event.Status = agent.ManagedStatus
+		event.Error = agent.LastThinking
+		events = append(events, event)
+	}
+	return events
+}
+
+func diffManagedRun(before, after types.TeamInfo, now time.Time) []Event {
+	if !after.Managed {
+		return nil
+	}
+
+	var events []Event
+	wasRunning := isRunningManagedStatus(before.ManagedStatus)
+	isRunning := isRunningManagedStatus(after.ManagedStatus)
+	if !wasRunning && isRunning {
+		event := teamEvent(EventTeamStarted, after, now, "受管团队已启动", fmt.Sprintf("受管团队 %s 已启动", after.Name))
+		event.Status = after.ManagedStatus
+		events = append(events, event)
+	}
+
+	failed := after.ManagedStatus == "failed" || (after.LastError != "" && after.LastError != before.LastError)
+	if failed && (before.ManagedStatus != after.ManagedStatus || before.LastError != after.LastError) {
+		event := teamEvent(EventManagedRunFailed, after, now, "受管运行失败", fmt.Sprintf("受管团队 %s 运行失败: %s", after.Name, firstNonEmpty(after.LastError, after.ManagedStatus)))
+		event.Status = after.ManagedStatus
+		event.Error = after.LastError
+		events = append(events, event)
+	} else if wasRunning && !isRunning {
+		event := teamEvent(EventTeamFinished, after, now, "受管团队已停止", fmt.Sprintf("受管团队 %s 已停止", after.Name))
+		event.Status = after.ManagedStatus
+		events = append(events, event)
+	}
+	return events
+}
+
+func teamEvent(eventType EventType, team types.TeamInfo, now time.Time, title, message string) Event {
+	event := newEvent(eventType, now)
+	event.Title = title
+	event.Message = message
+	event.Team = team.Name
+	event.Provider = team.Provider
+	return event
+}
+
+func indexTeams(teams []types.TeamInfo) map[string]types.TeamInfo {
+	result := make(map[string]types.TeamInfo, len(teams))
+	for _, team := range teams {
+		result[teamKey(team)] = team
+	}
+	return result
+}
+
+func teamKey(team types.TeamInfo) string {
+	if team.ManagedTeamID != "" {
+		return "managed:" + team.ManagedTeamID
+	}
+	return team.Provider + "::" + team.Name
+}
+
+func taskKey(task types.TaskInfo) string {
+	if id := strings.TrimSpace(task.ID); id != "" {
+		return id
+	}
+	return strings.TrimSpace(task.Subject)
+}
+
+func allTasksCompleted(team types.TeamInfo) bool {
+	if len(team.Tasks) == 0 {
+		return false
+	}
+	for _, task := range team.Tasks {
+		if !isCompleted(task.Status) {
+			return false
+		}
+	}
+	return true
+}
+
+func isCompleted(status string) bool {
+	return strings.ToLower(strings.TrimSpace(status)) == "completed"
+}
+
+// isFailedRun reports whether a managed agent's last run failed; agents
+// outside managed teams never carry a run status.
+func isFailedRun(agent types.AgentInfo) bool {
+	return agent.ManagedStatus == "failed"
+}
+
+func isRunningManagedStatus(status string) bool {
+	return status == "running" || status == "running_detached"
+}
@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+func eventTypes(events []Event) []EventType {
+	result := make([]EventType, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Type)
+	}
+	return result
+}
+
+func TestDiffStatesDetectsTaskCompletionAndTeamFinish(t *testing.T) {
+	now := time.Now()
+	prev := types.MonitorState{Teams: []types.TeamInfo{{
+		Name:     "alpha",
+		Provider: "claude",
+		Tasks: []types.TaskInfo{
+			{ID: "1", Subject: "build", Status: "completed"},
+			{ID: "2", Subject: "test", Status: "in_progress", Owner: "tester"},
+		},
+	}}}
+	next := types.MonitorState{Teams: []types.TeamInfo{{
+		Name:     "alpha",
+		Provider: "claude",
+		Tasks: []types.TaskInfo{
+			{ID: "1", Subject: "build", Status: "completed"},
+			{ID: "2", Subject: "test", Status: "completed", Owner: "tester"},
+		},
+	}}}
+
+	events := DiffStates(prev, next, now)
+	got := eventTypes(events)
+	if len(got) != 2 || got[0] != EventTaskCompleted || got[1] != EventTeamFinished {
+		t.Fatalf("unexpected events: %v", got)
+	}
+	if events[0].TaskID != "2" || events[0].Agent != "tester" {
+		t.Fatalf("unexpected task event: %+v", events[0])
+	}
+}
+
+func TestDiffStatesDetectsTeamLifecycle(t *testing.T) {
+	now := time.Now()
+	alpha := types.TeamInfo{Name: "alpha", Provider: "claude"}
+	beta := types.TeamInfo{Name: "beta", Provider: "codex"}
+
+	events := DiffStates(types.MonitorState{Teams: []types.TeamInfo{alpha}}, types.MonitorState{Teams: []types.TeamInfo{beta}}, now)
+	got := eventTypes(events)
+	if len(got) != 2 || got[0] != EventTeamStarted || got[1] != EventTeamFinished {
+		t.Fatalf("unexpected events: %v", got)
+	}
+	if events[0].Team != "beta" || events[1].Team != "alpha" {
+		t.Fatalf("unexpected teams: %+v", events)
+	}
+}
+
+func TestDiffStatesDetectsAgentAndManagedFailures(t *testing.T) {
+	now := time.Now()
+	prev := types.MonitorState{Teams: []types.TeamInfo{{
+		Name:          "managed",
+		Managed:       true,
+		ManagedTeamID: "m-1",
+		ManagedStatus: "running",
+		Members:       []types.AgentInfo{{Name: "lead", Status: "working"}},
+	}}}
+	next := types.MonitorState{Teams: []types.TeamInfo{{
+		Name:          "managed",
+		Managed:       true,
+		ManagedTeamID: "m-1",
+		ManagedStatus: "failed",
+		LastError:     "exec: claude not found",
+		Members:       []types.AgentInfo{{Name: "lead", Status: "idle", ManagedStatus: "failed", LastThinking: "exec: claude not found"}},
+	}}}
+
+	events := DiffStates(prev, next, now)
+	got := eventTypes(events)
+	if len(got) != 2 || got[0] != EventAgentErrored || got[1] != EventManagedRunFailed {
+		t.Fatalf("unexpected events: %v", got)
+	}
+	if events[1].Error != "exec: claude not found" {
+		t.Fatalf("expected managed error, got %+v", events[1])
+	}
+
+	if repeated := DiffStates(next, next, now); len(repeated) != 0 {
+		t.Fatalf("expected no events for unchanged state, got %v", eventTypes(repeated))
+	}
+}
+
+func TestDiffStatesDetectsHungAgents(t *testing.T) {
+	now := time.Now()
+	prev := types.MonitorState{Teams: []types.TeamInfo{{
+		Name:    "alpha",
+		Members: []types.AgentInfo{{Name: "lead", Status: "idle"}},
+	}}}
+	next := types.MonitorState{Teams: []types.TeamInfo{{
+		Name:    "alpha",
+		Members: []types.AgentInfo{{Name: "lead", Status: "hung", Hung: &types.HungEvidence{PID: 42, LastLogWrite: now.Add(-time.Hour)}}},
+	}}}
+
+	events := DiffStates(prev, next, now)
+	if got := eventTypes(events); len(got) != 1 || got[0] != EventAgentHung || events[0].Agent != "lead" || !strings.Contains(events[0].Message, "42") {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if repeated := DiffStates(next, next, now); len(repeated) != 0 {
+		t.Fatalf("expected one event per hang, got %v", eventTypes(repeated))
+	}
+}
+
+func TestWatcherPrimesBeforePublishing(t *testing.T) {
+	states := []types.MonitorState{
+		{Teams: []types.TeamInfo{{Name: "alpha"}}},
+		{Teams: []types.TeamInfo{{Name: "alpha"}, {Name: "beta"}}},
+	}
+	index := 0
+	watcher := NewWatcher(func() types.MonitorState {
+		state := states[index]
+		if index < len(states)-1 {
+			index++
+		}
+		return state
+	}, &Dispatcher{})
+
+	if events := watcher.Poll(time.Now()); len(events) != 0 {
+		t.Fatalf("expected priming poll to publish nothing, got %v", eventTypes(events))
+	}
+	events := watcher.Poll(time.Now())
+	if len(events) != 1 || events[0].Type != EventTeamStarted || events[0].Team != "beta" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
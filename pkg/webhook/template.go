@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatSlack    = "slack"
+	FormatFeishu   = "feishu"
+	FormatDingTalk = "dingtalk"
+)
+
+var templateFuncs = template.FuncMap{
+	// json renders a value as a JSON literal so templates can embed
+	// arbitrary text without breaking the body.
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// RenderPayload renders the request body for an endpoint. Custom templates
+// win over the built-in formats.
+func RenderPayload(endpoint Endpoint, event Event, now time.Time) ([]byte, error) {
+	if endpoint.template != nil {
+		var buf bytes.Buffer
+		if err := endpoint.template.Execute(&buf, event); err != nil {
+			return nil, fmt.Errorf("render template: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	switch endpoint.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]interface{}{
+			"text": summaryText(event),
+		})
+	case FormatFeishu:
+		body := map[string]interface{}{
+			"msg_type": "text",
+			"content": map[string]string{
+				"text": summaryText(event),
+			},
+		}
+		if endpoint.Secret != "" {
+			timestamp := strconv.FormatInt(now.Unix(), 10)
+			body["timestamp"] = timestamp
+			body["sign"] = feishuSignature(endpoint.Secret, timestamp)
+		}
+		return json.Marshal(body)
+	case FormatDingTalk:
+		return json.Marshal(map[string]interface{}{
+			"msgtype": "text",
+			"text": map[string]string{
+				"content": summaryText(event),
+			},
+		})
+	default:
+		return json.Marshal(event)
+	}
+}
+
+// RequestURL returns the delivery URL, adding query-string signatures for
+// receivers that expect them (DingTalk).
+func RequestURL(endpoint Endpoint, now time.Time) string {
+	if endpoint.Format != FormatDingTalk || endpoint.Secret == "" {
+		return endpoint.URL
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	separator := "?"
+	if strings.Contains(endpoint.URL, "?") {
+		separator = "&"
+	}
+	return endpoint.URL + separator + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(dingTalkSignature(endpoint.Secret, timestamp))
+}
+
+// Signature returns the hex-encoded HMAC-SHA256 of timestamp + "." + body,
+// sent as X-ATM-Signature so receivers can verify authenticity.
+func Signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+func feishuSignature(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func dingTalkSignature(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func summaryText(event Event) string {
+	title := firstNonEmpty(event.Title, string(event.Type))
+	message := strings.TrimSpace(event.Message)
+	if message == "" {
+		return "[Agent Team Monitor] " + title
+	}
+	return "[Agent Team Monitor] " + title + "\n" + message
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/liaoweijun/agent-team-monitor/pkg/types"
+)
+
+const defaultWatchInterval = 5 * time.Second
+
+// StateSource returns the latest monitor snapshot.
+type StateSource func() types.MonitorState
+
+// Watcher polls a state source and publishes the differences as events.
+type Watcher struct {
+	source     StateSource
+	dispatcher *Dispatcher
+	interval   time.Duration
+	last       types.MonitorState
+	primed     bool
+}
+
+// NewWatcher creates a watcher that publishes to dispatcher.
+func NewWatcher(source StateSource, dispatcher *Dispatcher) *Watcher {
+	return &Watcher{
+		source:     source,
+		dispatcher: dispatcher,
+		interval:   defaultWatchInterval,
+	}
+}
+
+// Run polls until ctx is cancelled. The first snapshot only primes the
+// baseline so pre-existing teams do not fire team.started on startup.
+func (w *Watcher) Run(ctx context.Context) {
+	if w == nil || w.source == nil || w.dispatcher == nil {
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.Poll(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.Poll(now)
+		}
+	}
+}
+
+// Poll takes one snapshot and publishes the events since the previous one.
+func (w *Watcher) Poll(now time.Time) []Event {
+	next := w.source()
+	if !w.primed {
+		w.last = next
+		w.primed = true
+		return nil
+	}
+
+	events := DiffStates(w.last, next, now)
+	w.last = next
+	for _, event := range events {
+		w.dispatcher.Publish(event)
+	}
+	return events
+}
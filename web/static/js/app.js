@@ -1,9 +1,10 @@
-import { getDesktopPreferences, initDesktopUI, isDesktopMode, setDesktopAdminAccess, setDesktopPreferences } from './desktop-ui.js';
+import { getDesktopPreferences, initDesktopUI, isDesktopMode, loginDesktopAdmin, logoutDesktopAdmin, setDesktopAdminAccess, setDesktopPreferences } from './desktop-ui.js';
 
 // API Configuration
 const API_BASE_URL = window.location.origin;
 const API_ENDPOINTS = {
     state: `${API_BASE_URL}/api/state`,
+    stateDelta: `${API_BASE_URL}/api/state/delta`,
     teams: `${API_BASE_URL}/api/teams`,
     managedTeams: `${API_BASE_URL}/api/managed/teams`,
     agentMessage: `${API_BASE_URL}/api/agents/message`,
@@ -11,6 +12,7 @@ const API_ENDPOINTS = {
     authLogin: `${API_BASE_URL}/api/auth/login`,
     authLogout: `${API_BASE_URL}/api/auth/logout`,
     processes: `${API_BASE_URL}/api/processes`,
+    v1: `${API_BASE_URL}/api/v1`,
     health: `${API_BASE_URL}/api/health`
 };
 const DESKTOP_BRIDGE = window.AgentMonitorDesktopBridge || null;
@@ -30,6 +32,7 @@ const TEAM_OVERVIEW_KEY = '__team__';
 const CONTROL_FEED_LIMIT = 48;
 const STATE_LABELS = {
     working: '工作中',
+    hung: '疑似卡住',
     busy: '忙碌中',
     idle: '空闲',
     completed: '已完成',
@@ -87,6 +90,8 @@ let latestManagedTeams = [];
 let selectedTeamName = null;
 let selectedAgentKey = null;
 let deferredTeamsRender = null;
+let kioskFocusTeam = null; // 只读模式下服务端轮播的团队
+let syncedState = null; // 按版本增量同步的后端状态
 let controlComposerIsComposing = false;
 const controlComposerDrafts = {};
 const controlComposerFeedback = {};
@@ -144,11 +149,43 @@ document.addEventListener('DOMContentLoaded', async () => {
             renderFilteredUI();
         }
     });
-    setDesktopAdminAccess(isAdminAuthenticated());
+    document.body.classList.toggle('read-only', Boolean(adminAuthState.read_only));
+    setDesktopAdminAccess(hasPermission('admin'));
 });
 
-function isAdminAuthenticated() {
-    return adminAuthState.configured === true && adminAuthState.authenticated === true;
+// csrfHeaders adds the session's CSRF token, which the server requires on
+// every state-changing /api request.
+function csrfHeaders(headers = {}) {
+    if (adminAuthState.csrf_token) {
+        return { ...headers, 'X-CSRF-Token': adminAuthState.csrf_token };
+    }
+    return headers;
+}
+
+// hasPermission mirrors the server's role checks so controls the current
+// user cannot use are disabled up front. "admin" implies every permission.
+function hasPermission(permission) {
+    if (adminAuthState.configured !== true) {
+        return false;
+    }
+    const granted = Array.isArray(adminAuthState.permissions) ? adminAuthState.permissions : [];
+    if (permission === 'teams:delete' && granted.includes('tasks:write')) {
+        return true;
+    }
+    return granted.includes(permission) || granted.includes('admin');
+}
+
+function formatRoleLabel(role) {
+    switch (role) {
+        case 'admin':
+            return '管理员';
+        case 'operator':
+            return '操作员';
+        case 'viewer':
+            return '只读';
+        default:
+            return role || '未知';
+    }
 }
 
 async function refreshAuthStatus() {
@@ -176,11 +213,13 @@ function applyAdminAccessState() {
 
     if (indicator) {
         indicator.classList.remove('authenticated', 'unconfigured');
-        if (!adminAuthState.configured) {
+        if (adminAuthState.read_only) {
+            indicator.textContent = '只读模式';
+        } else if (!adminAuthState.configured) {
             indicator.textContent = '管理：未配置';
             indicator.classList.add('unconfigured');
         } else if (adminAuthState.authenticated) {
-            indicator.textContent = '管理：已登录';
+            indicator.textContent = `管理：${adminAuthState.username || '已登录'}（${formatRoleLabel(adminAuthState.role)}）`;
             indicator.classList.add('authenticated');
         } else {
             indicator.textContent = '管理：未登录';
@@ -188,6 +227,7 @@ function applyAdminAccessState() {
     }
 
     if (button) {
+        button.hidden = Boolean(adminAuthState.read_only);
         if (!adminAuthState.configured) {
             button.textContent = '未配置登录';
             button.disabled = true;
@@ -200,7 +240,7 @@ function applyAdminAccessState() {
         }
     }
 
-    setDesktopAdminAccess(isAdminAuthenticated());
+    setDesktopAdminAccess(hasPermission('admin'));
 
     if (previousState) {
         renderFilteredUI();
@@ -224,10 +264,11 @@ function initAuthControls() {
 
             if (adminAuthState.authenticated) {
                 try {
-                    const response = await fetch(API_ENDPOINTS.authLogout, { method: 'POST' });
+                    const response = await fetch(API_ENDPOINTS.authLogout, { method: 'POST', headers: csrfHeaders() });
                     if (!response.ok) {
                         throw new Error(`HTTP ${response.status}`);
                     }
+                    await logoutDesktopAdmin();
                     await refreshAuthStatus();
                 } catch (error) {
                     console.error('Failed to logout admin:', error);
@@ -285,6 +326,8 @@ function initAuthControls() {
                     const message = await response.text();
                     throw new Error(message.trim() || '登录失败');
                 }
+                // Desktop bridge calls carry no cookie, so the bridge keeps its own session.
+                await loginDesktopAdmin(authUsername.value.trim(), authPassword.value);
 
                 closeAuthModal();
                 authPassword.value = '';
@@ -756,8 +799,9 @@ async function fetchData() {
                 managedTeams = await managedResponse.json();
             }
         } else {
+            const since = syncedState?.version || 0;
             const [response, managedResponse] = await Promise.all([
-                fetch(`${API_ENDPOINTS.state}?_ts=${Date.now()}`, {
+                fetch(`${API_ENDPOINTS.stateDelta}?since=${since}&_ts=${Date.now()}`, {
                     cache: 'no-store',
                     headers: {
                         'Cache-Control': 'no-cache'
@@ -770,7 +814,8 @@ async function fetchData() {
             if (!response.ok) {
                 throw new Error(`HTTP error! status: ${response.status}`);
             }
-            data = await response.json();
+            syncedState = applyStateDelta(syncedState, await response.json());
+            data = syncedState;
             if (managedResponse.ok) {
                 managedTeams = await managedResponse.json();
             }
@@ -784,13 +829,79 @@ async function fetchData() {
     }
 }
 
+// 将 /api/state/delta 的增量合并进上一次的状态，与 types.MonitorState.ApplyDelta 一致
+function applyStateDelta(state, delta) {
+    if (delta.reset || !state) {
+        return { ...(delta.state || { teams: [], processes: [] }), version: delta.version };
+    }
+
+    const key = (...parts) => parts.join('\u0000');
+    const agentKey = (agent) => agent.name || agent.agent_id;
+    const teams = new Map();
+    const agents = new Map();
+    const tasks = new Map();
+    const memberOrder = new Map();
+    const taskOrder = new Map();
+    for (const team of state.teams || []) {
+        teams.set(team.name, team);
+        memberOrder.set(team.name, (team.members || []).map(agentKey));
+        taskOrder.set(team.name, (team.tasks || []).map((task) => task.id));
+        (team.members || []).forEach((agent) => agents.set(key(team.name, agentKey(agent)), agent));
+        (team.tasks || []).forEach((task) => tasks.set(key(team.name, task.id), task));
+    }
+
+    for (const removed of delta.removed || []) {
+        if (removed.kind === 'team') teams.delete(removed.team);
+        if (removed.kind === 'agent') agents.delete(key(removed.team, removed.id));
+        if (removed.kind === 'task') tasks.delete(key(removed.team, removed.id));
+    }
+    for (const change of delta.teams || []) {
+        const { member_order: members, task_order: taskIDs, ...team } = change;
+        teams.set(team.name, team);
+        memberOrder.set(team.name, members || []);
+        taskOrder.set(team.name, taskIDs || []);
+    }
+    for (const { team, ...agent } of delta.agents || []) {
+        agents.set(key(team, agentKey(agent)), agent);
+    }
+    for (const { team, ...task } of delta.tasks || []) {
+        tasks.set(key(team, task.id), task);
+    }
+
+    const order = delta.team_order || (state.teams || []).map((team) => team.name);
+    return {
+        teams: order.filter((name) => teams.has(name)).map((name) => ({
+            ...teams.get(name),
+            members: (memberOrder.get(name) || []).map((id) => agents.get(key(name, id))).filter(Boolean),
+            tasks: (taskOrder.get(name) || []).map((id) => tasks.get(key(name, id))).filter(Boolean)
+        })),
+        processes: delta.processes ?? state.processes,
+        nodes: delta.nodes ?? state.nodes,
+        updated_at: delta.updated_at,
+        version: delta.version,
+        kiosk: delta.kiosk
+    };
+}
+
 // Update UI with new data (智能更新，只更新变化的部分)
 function updateUI(data, managedTeams = []) {
     latestRawState = data;
     latestManagedTeams = Array.isArray(managedTeams) ? managedTeams : [];
+    followKioskFocus(data?.kiosk);
     renderFilteredUI();
 }
 
+// 只读模式下跟随服务端轮播的团队；轮播切换前保留观众自己的选择
+function followKioskFocus(kiosk) {
+    const focusTeam = kiosk?.focus_team || null;
+    if (!focusTeam || focusTeam === kioskFocusTeam) {
+        return;
+    }
+    kioskFocusTeam = focusTeam;
+    selectedTeamName = focusTeam;
+    selectedAgentKey = null;
+}
+
 function initViewFilters() {
     const chips = document.querySelectorAll('.filter-chip');
     chips.forEach((chip) => {
@@ -843,6 +954,7 @@ function renderFilteredUI() {
 
     updateManagedTeams(latestManagedTeams);
     updateProviderFilterStats(latestRawState);
+    updateNodeStrip(latestRawState.nodes || []);
 
     const filtered = buildFilteredState(latestRawState);
     updateLastUpdateTime(filtered.updated_at || latestRawState.updated_at);
@@ -869,8 +981,8 @@ function initManagedTeamControls() {
 
     if (openButton) {
         openButton.addEventListener('click', () => {
-            if (!isAdminAuthenticated()) {
-                setManagedTeamFeedback('管理员登录后才能创建团队');
+            if (!hasPermission('managed:control')) {
+                setManagedTeamFeedback('登录具备权限的账号后才能创建团队');
                 return;
             }
             openManagedTeamModal();
@@ -896,8 +1008,8 @@ function initManagedTeamControls() {
 
     form.addEventListener('submit', async (event) => {
         event.preventDefault();
-        if (!isAdminAuthenticated()) {
-            setManagedTeamFeedback('管理员登录后才能创建团队');
+        if (!hasPermission('managed:control')) {
+            setManagedTeamFeedback('登录具备权限的账号后才能创建团队');
             return;
         }
 
@@ -919,9 +1031,7 @@ function initManagedTeamControls() {
         try {
             const response = await fetch(API_ENDPOINTS.managedTeams, {
                 method: 'POST',
-                headers: {
-                    'Content-Type': 'application/json'
-                },
+                headers: csrfHeaders({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({
                     name: nameInput.value.trim(),
                     provider: 'claude',
@@ -939,7 +1049,8 @@ function initManagedTeamControls() {
 
             if (autostartToggle && autostartToggle.checked && created?.id) {
                 const startResponse = await fetch(`${API_ENDPOINTS.managedTeams}/${encodeURIComponent(created.id)}/start`, {
-                    method: 'POST'
+                    method: 'POST',
+                    headers: csrfHeaders()
                 });
                 if (!startResponse.ok) {
                     const message = await startResponse.text();
@@ -950,9 +1061,7 @@ function initManagedTeamControls() {
                 if (initialTask) {
                     const messageResponse = await fetch(`${API_ENDPOINTS.managedTeams}/${encodeURIComponent(created.id)}/message`, {
                         method: 'POST',
-                        headers: {
-                            'Content-Type': 'application/json'
-                        },
+                        headers: csrfHeaders({ 'Content-Type': 'application/json' }),
                         body: JSON.stringify({ text: initialTask })
                     });
                     if (!messageResponse.ok) {
@@ -988,8 +1097,8 @@ function initManagedTeamControls() {
         if (!actionButton) {
             return;
         }
-        if (!isAdminAuthenticated()) {
-            setManagedTeamFeedback('管理员登录后才能管理自托管团队');
+        if (!hasPermission('managed:control')) {
+            setManagedTeamFeedback('登录具备权限的账号后才能管理自托管团队');
             return;
         }
 
@@ -1009,7 +1118,7 @@ function initManagedTeamControls() {
         actionButton.disabled = true;
         setManagedActionFeedback(feedbackNode, `${label} ${actionText}中...`);
         try {
-            const response = await fetch(endpoint, { method: 'POST' });
+            const response = await fetch(endpoint, { method: 'POST', headers: csrfHeaders() });
             if (!response.ok) {
                 const message = await response.text();
                 throw new Error(message.trim() || `${action} 失败`);
@@ -1029,8 +1138,8 @@ function initManagedTeamControls() {
             return;
         }
         event.preventDefault();
-        if (!isAdminAuthenticated()) {
-            setManagedTeamFeedback('管理员登录后才能下发任务');
+        if (!hasPermission('message')) {
+            setManagedTeamFeedback('登录具备权限的账号后才能下发任务');
             return;
         }
 
@@ -1047,9 +1156,7 @@ function initManagedTeamControls() {
         try {
             const response = await fetch(`${API_ENDPOINTS.managedTeams}/${encodeURIComponent(teamID)}/message`, {
                 method: 'POST',
-                headers: {
-                    'Content-Type': 'application/json'
-                },
+                headers: csrfHeaders({ 'Content-Type': 'application/json' }),
                 body: JSON.stringify({ text, agent_id: agentID || undefined })
             });
             if (!response.ok) {
@@ -1090,9 +1197,7 @@ async function sendAgentMessageRequest({ teamName = '', agentName = '', transpor
     if (transport === 'managed_pty' && managedTeamID) {
         const response = await fetch(`${API_ENDPOINTS.managedTeams}/${encodeURIComponent(managedTeamID)}/message`, {
             method: 'POST',
-            headers: {
-                'Content-Type': 'application/json'
-            },
+            headers: csrfHeaders({ 'Content-Type': 'application/json' }),
             body: JSON.stringify({ text: normalizedText, agent_id: managedAgentID || undefined })
         });
         if (!response.ok) {
@@ -1118,9 +1223,7 @@ async function sendAgentMessageRequest({ teamName = '', agentName = '', transpor
 
     const response = await fetch(API_ENDPOINTS.agentMessage, {
         method: 'POST',
-        headers: {
-            'Content-Type': 'application/json'
-        },
+        headers: csrfHeaders({ 'Content-Type': 'application/json' }),
         body: JSON.stringify({
             team_name: teamName,
             agent_name: agentName,
@@ -1147,7 +1250,7 @@ function updateManagedTeams(teams) {
     const permissionSelect = document.getElementById('managed-team-permission');
     const autostartToggle = document.getElementById('managed-team-autostart');
     const initialTaskInput = document.getElementById('managed-team-initial-task');
-    const controlsEnabled = isAdminAuthenticated();
+    const controlsEnabled = hasPermission('managed:control');
 
     [openButton, submitButton, nameInput, workspaceInput, modelInput, permissionSelect, autostartToggle, initialTaskInput].forEach((element) => {
         if (element) {
@@ -1163,7 +1266,7 @@ function renderManagedTeamCard(item) {
     const statusLabel = formatManagedRunStatus(status);
     const running = status === 'running' || status === 'running_detached';
     const controllable = Boolean(run && run.controllable);
-    const disabled = !isAdminAuthenticated();
+    const disabled = !hasPermission('managed:control');
     const workspace = spec.workspace || '';
     const model = (spec.agents && spec.agents[0] && spec.agents[0].model) || '';
 
@@ -1288,7 +1391,7 @@ function renderTeamActions(team, provider, canDelete) {
         const members = Array.isArray(team.members) ? team.members : [];
         const runningCount = countManagedRunningMembers(members);
         const controllableCount = countManagedControllableMembers(members);
-        const disabled = !isAdminAuthenticated();
+        const disabled = !hasPermission('managed:control');
         return `
             <div class="managed-team-actions">
                 <button class="team-delete-btn start" type="button" data-managed-action="start" data-managed-team-id="${escapeHtml(team.managed_team_id || '')}" data-managed-label="${escapeHtml(team.name || '受管团队')} 全部成员" ${members.length === 0 || runningCount >= members.length || disabled ? 'disabled' : ''}>全部启动</button>
@@ -1297,11 +1400,17 @@ function renderTeamActions(team, provider, canDelete) {
         `;
     }
 
+    const linkedProcesses = (latestRawState?.processes || []).filter(process => process.team === team.name).length;
+    const signalAction = linkedProcesses > 0 && hasPermission('managed:control')
+        ? `<button class="team-delete-btn stop" type="button" onclick="signalProcesses('team', '${escapeHtml(team.name)}', 'SIGTERM')" title="向团队的 ${linkedProcesses} 个进程发送 SIGTERM">终止进程 (${linkedProcesses})</button>`
+        : '';
+
     if (canDelete) {
-        return `<button class="team-delete-btn danger" onclick="deleteTeam('${escapeHtml(team.name)}')" title="清理团队"><svg width="12" height="12" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><path d="M3 6h18"/><path d="M19 6v14c0 1-1 2-2 2H7c-1 0-2-1-2-2V6"/><path d="M8 6V4c0-1 1-2 2-2h4c0 1 2 1 2 2v2"/></svg> 清理</button>`;
+        const deleteButton = `<button class="team-delete-btn danger" onclick="deleteTeam('${escapeHtml(team.name)}')" title="清理团队"><svg width="12" height="12" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><path d="M3 6h18"/><path d="M19 6v14c0 1-1 2-2 2H7c-1 0-2-1-2-2V6"/><path d="M8 6V4c0-1 1-2 2-2h4c0 1 2 1 2 2v2"/></svg> 清理</button>`;
+        return signalAction ? `<div class="managed-team-actions">${signalAction}${deleteButton}</div>` : deleteButton;
     }
 
-    return '';
+    return signalAction;
 }
 
 function buildFilteredState(rawState) {
@@ -1395,7 +1504,7 @@ function shouldShowDashboardAgent(agent, teamTasks = []) {
     }
 
     const status = String(agent.status || 'idle').toLowerCase();
-    if (status === 'working' || status === 'busy') {
+    if (status === 'working' || status === 'busy' || status === 'hung') {
         return true;
     }
 
@@ -1596,6 +1705,22 @@ function updateConnectionStatus(connected) {
     }
 }
 
+// Update the federation node strip; hidden unless this is a hub.
+function updateNodeStrip(nodes) {
+    const strip = document.getElementById('node-strip');
+    if (!strip) {
+        return;
+    }
+    strip.hidden = nodes.length === 0;
+    strip.innerHTML = nodes.map(node => `
+        <span class="node-chip ${node.online ? 'online' : 'offline'}" title="${escapeHtml(node.url || '')}">
+            <span class="node-chip-dot"></span>
+            ${escapeHtml(node.name)}
+            <span class="node-chip-meta">${node.online ? `${node.teams || 0} 个团队 · ${node.processes || 0} 个进程` : `离线 · ${escapeHtml(formatRelativeTime(node.last_seen))}`}</span>
+        </span>
+    `).join('');
+}
+
 // Update processes section
 function updateProcesses(processes) {
     const container = document.getElementById('processes-container');
@@ -1624,12 +1749,86 @@ function renderProcess(process) {
                 <span class="process-pid">进程 ${process.pid}</span>
                 <span class="process-uptime">${provider}</span>
                 <span class="process-uptime">${uptime}</span>
+                ${process.host ? `<span class="process-uptime">节点 ${escapeHtml(process.host)}</span>` : ''}
+                ${process.container ? `<span class="process-uptime" title="${escapeHtml(process.container.id)}">容器 ${escapeHtml(formatContainerLabel(process.container))}</span>` : ''}
+                ${process.team ? `<span class="process-owner">${escapeHtml(process.team)}${process.agent ? ` / ${escapeHtml(process.agent)}` : ''}</span>` : ''}
+                <span class="process-uptime">CPU ${(process.cpu_percent || 0).toFixed(1)}%</span>
+                <span class="process-uptime">${formatBytes(process.rss_bytes)}</span>
+                ${process.threads ? `<span class="process-uptime">${process.threads} 线程</span>` : ''}
             </div>
             ${process.command ? `<div class="process-cmd">${escapeHtml(process.command)}</div>` : ''}
+            ${renderHungEvidence(process.hung)}
+            ${renderProcessSignalButtons(process)}
+            ${process.children?.length ? `<ul class="process-children">${renderChildProcesses(process.children)}</ul>` : ''}
         </div>
     `;
 }
 
+function formatContainerLabel(container) {
+    const name = container.name || String(container.id || '').slice(0, 12);
+    return container.runtime ? `${name} (${container.runtime})` : name;
+}
+
+const HUNG_REMEDY_LABELS = {
+    nudge: '发送提醒',
+    interrupt: '中断当前回合',
+    restart: '重启',
+};
+
+// The watchdog flags a process that is alive, idle and not writing its log.
+function formatHungEvidence(hung) {
+    const parts = [
+        `日志最后写入 ${formatRelativeTime(hung.last_log_write) || '未知'}`,
+        `CPU ${(hung.cpu_percent || 0).toFixed(1)}%，${formatRelativeTime(hung.cpu_idle_since)}起空闲`,
+    ];
+    if (hung.remedy) {
+        const remedy = HUNG_REMEDY_LABELS[hung.remedy] || hung.remedy;
+        parts.push(hung.remedy_error ? `${remedy}失败：${hung.remedy_error}` : `${formatRelativeTime(hung.remedy_at)}已${remedy}`);
+    }
+    return parts.join(' · ');
+}
+
+function renderHungEvidence(hung) {
+    if (!hung) {
+        return '';
+    }
+    return `<div class="hung-evidence">⚠ 疑似卡住：${escapeHtml(formatHungEvidence(hung))}</div>`;
+}
+
+const PROCESS_SIGNALS = [
+    { signal: 'SIGINT', label: '中断' },
+    { signal: 'SIGTERM', label: '终止' },
+    { signal: 'SIGKILL', label: '强制结束' },
+];
+
+// A hub addresses a node's process as <pid>@<node>.
+function processSignalTarget(process) {
+    return process.host ? `${process.pid}@${process.host}` : String(process.pid);
+}
+
+function renderProcessSignalButtons(process) {
+    if (!hasPermission('managed:control')) {
+        return '';
+    }
+    const target = escapeHtml(processSignalTarget(process));
+    return `
+        <div class="process-actions">
+            ${PROCESS_SIGNALS.map(({ signal, label }) => `<button class="team-delete-btn ${signal === 'SIGINT' ? 'stop' : 'danger'}" type="button" onclick="signalProcesses('process', '${target}', '${signal}')" title="发送 ${signal}">${label}</button>`).join('')}
+        </div>
+    `;
+}
+
+function renderChildProcesses(children) {
+    return children.map(child => `
+        <li>
+            <span class="process-pid">${child.pid}</span>
+            <span class="process-cmd">${escapeHtml(child.command || '')}</span>
+            <span class="process-uptime">CPU ${(child.cpu_percent || 0).toFixed(1)}% · ${formatBytes(child.rss_bytes)}</span>
+            ${child.children?.length ? `<ul class="process-children">${renderChildProcesses(child.children)}</ul>` : ''}
+        </li>
+    `).join('');
+}
+
 // Update teams section
 function updateTeams(teams) {
     const container = document.getElementById('teams-container');
@@ -1777,6 +1976,7 @@ function renderControlSidebarTeam(team, context) {
                 <div class="control-team-card-top">
                     <span class="control-provider-pill">${escapeHtml(providerLabel)}</span>
                     ${team.managed ? '<span class="control-provider-pill managed">受管</span>' : '<span class="control-provider-pill imported">导入</span>'}
+                    ${team.host ? `<span class="control-provider-pill subtle">${escapeHtml(team.host)}</span>` : ''}
                 </div>
                 <div class="control-team-card-name">${escapeHtml(team.name)}</div>
                 <div class="control-team-card-meta">${members.length} 个成员 · ${pendingTaskCount} 项待处理</div>
@@ -1857,9 +2057,9 @@ function renderControlHero(context) {
     const { team, agent, provider, tasks, members, teamTasks } = context;
     const providerLabel = providerDisplayName(provider);
     const statusClass = agent ? String(agent?.status || 'idle').toLowerCase() : '';
-    const canDelete = !team.managed && provider !== 'codex' && isAdminAuthenticated();
+    const canDelete = !team.managed && provider !== 'codex' && hasPermission('teams:delete');
     const actionMarkup = agent && isManagedAgent(team, agent)
-        ? renderManagedAgentActionButtons(team, agent, isAdminAuthenticated())
+        ? renderManagedAgentActionButtons(team, agent, hasPermission('managed:control'))
         : renderTeamActions(team, provider, canDelete);
 
     return `
@@ -2265,12 +2465,12 @@ function buildControlComposerConfig(context) {
         };
     }
 
-    if (!isAdminAuthenticated()) {
+    if (!hasPermission('message')) {
         return {
             selectionKey,
             targetLabel: agent ? agent.name : team.name,
             channelLabel: '只读',
-            hint: '管理员登录后才能发送指令。',
+            hint: '登录具备权限的账号后才能发送指令。',
             placeholder: '当前不可发送',
             buttonLabel: '发送',
             enabled: false,
@@ -2411,8 +2611,8 @@ async function submitControlComposer(form) {
         return;
     }
 
-    if (!isAdminAuthenticated()) {
-        controlComposerFeedback[selectionKey] = '管理员登录后才能发送指令';
+    if (!hasPermission('message')) {
+        controlComposerFeedback[selectionKey] = '登录具备权限的账号后才能发送指令';
         rerenderControlWorkspace();
         return;
     }
@@ -2579,11 +2779,12 @@ function renderTeam(team) {
     const managedRunningCount = team.managed ? countManagedRunningMembers(members) : 0;
     const managedControllableCount = team.managed ? countManagedControllableMembers(members) : 0;
     const teamId = `team-${encodeURIComponent(team.name)}`;
-    const canDelete = !team.managed && provider !== 'codex' && isAdminAuthenticated();
+    const canDelete = !team.managed && provider !== 'codex' && hasPermission('teams:delete');
     const providerBadge = provider !== 'unknown' ? `<span class="agent-type">[${escapeHtml(providerDisplayName(provider))}]</span>` : '';
     const controlBadge = team.managed
         ? `<span class="agent-type">[受管:${escapeHtml(formatManagedRunStatus(team.managed_status || ''))}]</span>`
         : `<span class="agent-type">[导入]</span>`;
+    const hostBadge = team.host ? `<span class="agent-type">[节点:${escapeHtml(team.host)}]</span>` : '';
     const headerAction = renderTeamActions(team, provider, canDelete);
     const summaryBar = team.managed ? `
                 <div class="team-summary-item">
@@ -2628,7 +2829,7 @@ function renderTeam(team) {
         <div class="team-card" id="${teamId}">
             <div class="team-header">
                 <div class="team-header-left">
-                    <div class="team-name">${escapeHtml(team.name)} ${providerBadge} ${controlBadge}</div>
+                    <div class="team-name">${escapeHtml(team.name)} ${providerBadge} ${controlBadge} ${hostBadge}</div>
                     <div class="team-created">创建时间: ${createdDate}</div>
                     ${projectCwd ? `<div class="team-cwd">工作目录: ${escapeHtml(projectCwd)}</div>` : ''}
                     ${team.log_path ? `<div class="team-cwd">日志: ${escapeHtml(team.log_path)}</div>` : ''}
@@ -2649,10 +2850,59 @@ function renderTeam(team) {
     `;
 }
 
+// Send a signal to one process (kind 'process', target pid or pid@node) or
+// to every process linked to a team (kind 'team').
+async function signalProcesses(kind, target, signal) {
+    if (!hasPermission('managed:control')) {
+        alert('登录具备权限的账号后才能向进程发送信号');
+        return;
+    }
+
+    const subject = kind === 'team' ? `团队「${target}」的全部进程` : `进程 ${target}`;
+    if (!confirm(`确定要向${subject}发送 ${signal} 吗？`)) {
+        return;
+    }
+
+    try {
+        let results;
+        if (IS_DESKTOP_MODE && DESKTOP_BRIDGE) {
+            results = kind === 'team'
+                ? await DESKTOP_BRIDGE.signalDesktopTeam(target, signal)
+                : await DESKTOP_BRIDGE.signalDesktopProcess(Number(target), signal);
+        } else {
+            const path = kind === 'team' ? `teams/${encodeURIComponent(target)}` : `processes/${encodeURIComponent(target)}`;
+            const response = await fetch(`${API_ENDPOINTS.v1}/${path}/signals`, {
+                method: 'POST',
+                headers: csrfHeaders({ 'Content-Type': 'application/json' }),
+                body: JSON.stringify({ signal }),
+            });
+            const body = await response.json().catch(() => ({}));
+            if (!response.ok) {
+                throw new Error(body.error?.message || `HTTP error! status: ${response.status}`);
+            }
+            results = body.data || [];
+        }
+        alert(formatSignalResults(results));
+        await fetchData();
+    } catch (error) {
+        console.error('Error signalling processes:', error);
+        alert(`发送信号失败: ${error.message}`);
+    }
+}
+
+function formatSignalResults(results) {
+    return (results || []).map(result => {
+        if (result.error) {
+            return `进程 ${result.pid}: ${result.signal} 发送失败（${result.error}）`;
+        }
+        return `进程 ${result.pid}: ${result.exited ? '已退出' : `${result.signal} 已发送，仍在运行`}`;
+    }).join('\n') || '没有进程收到信号';
+}
+
 // Delete a team
 async function deleteTeam(teamName) {
-    if (!isAdminAuthenticated()) {
-        alert('管理员登录后才能清理团队');
+    if (!hasPermission('teams:delete')) {
+        alert('登录具备权限的账号后才能清理团队');
         return;
     }
 
@@ -2666,6 +2916,7 @@ async function deleteTeam(teamName) {
         } else {
             const response = await fetch(`${API_ENDPOINTS.teams}/${encodeURIComponent(teamName)}`, {
                 method: 'DELETE',
+                headers: csrfHeaders(),
             });
             if (!response.ok) {
                 throw new Error(`HTTP error! status: ${response.status}`);
@@ -2933,7 +3184,7 @@ function renderManagedAgentControls(team, agent) {
     }
 
     const stateTone = managedAgentControlTone(agent);
-    const controlsEnabled = isAdminAuthenticated();
+    const controlsEnabled = hasPermission('managed:control');
 
     return `
         <div class="managed-agent-controls ${escapeHtml(stateTone)}">
@@ -2995,6 +3246,9 @@ function buildBroadcastDetailKey(team) {
 }
 
 function buildAgentPrimarySignal(agent, tasks) {
+    if (agent.hung) {
+        return { kind: 'hung', label: '疑似卡住', value: truncateMultiline(formatHungEvidence(agent.hung), 44) };
+    }
     if (agent.current_task) {
         return { kind: 'task', label: '当前任务', value: truncateMultiline(agent.current_task, 44) };
     }
@@ -3050,6 +3304,10 @@ function buildAgentMetaSummary(agent, tasks, moving) {
         parts.push(`${agent.todos.length} 条待办`);
     }
 
+    if (agent.redactions > 0) {
+        parts.push(`已遮蔽 ${agent.redactions} 处密钥`);
+    }
+
     return parts.join(' · ');
 }
 
@@ -3451,9 +3709,9 @@ function initAgentDetailModal() {
         const feedback = form.querySelector('[data-agent-message-feedback]');
         const text = textarea ? textarea.value.trim() : '';
 
-        if (!isAdminAuthenticated()) {
+        if (!hasPermission('message')) {
             if (feedback) {
-                feedback.textContent = '管理员登录后才能发送消息';
+                feedback.textContent = '登录具备权限的账号后才能发送消息';
             }
             return;
         }
@@ -3610,6 +3868,7 @@ function renderAgentDetailModalContent(team, agent, tasks, isBroadcast) {
                 <span class="agent-activity ${moving ? 'active' : 'idle'}">${escapeHtml(motionLabel)}</span>
             </div>
         </div>
+        ${renderHungEvidence(agent.hung)}
         ${renderAgentCommandComposer(team, agent)}
         ${renderAgentWithTasks(agent, tasks)}
     `;
@@ -3621,14 +3880,14 @@ function renderAgentCommandComposer(team, agent) {
     }
 
     if (isManagedAgent(team, agent)) {
-        const controlsEnabled = isAdminAuthenticated();
+        const controlsEnabled = hasPermission('message');
         const controllable = isManagedAgentControllable(agent);
         const stateTone = managedAgentControlTone(agent);
         let controlHint = '当前受管会话的启动、停止与发消息入口已统一到这里。';
         if (!adminAuthState.configured) {
             controlHint = '未配置管理员账号密码，当前仅允许浏览受管会话状态。';
         } else if (!controlsEnabled) {
-            controlHint = '管理员登录后才能启动、停止或向受管会话发消息。';
+            controlHint = '登录具备权限的账号后才能启动、停止或向受管会话发消息。';
         } else if (controllable) {
             controlHint = '当前直连受管终端，可直接下发任务。';
         } else if (isManagedAgentRunning(agent)) {
@@ -3685,11 +3944,11 @@ function renderAgentCommandComposer(team, agent) {
         `;
     }
 
-    if (!isAdminAuthenticated()) {
+    if (!hasPermission('message')) {
         return `
             <div class="agent-command-panel unsupported">
                 <div class="agent-panel-title">直接发消息</div>
-                <div class="agent-command-hint">管理员登录后才能发送消息或修改设置。</div>
+                <div class="agent-command-hint">登录具备权限的账号后才能发送消息或修改设置。</div>
             </div>
         `;
     }
@@ -3792,6 +4051,17 @@ function formatTaskStatus(status) {
 }
 
 // Escape HTML to prevent XSS
+function formatBytes(bytes) {
+    const units = ['B', 'KiB', 'MiB', 'GiB', 'TiB'];
+    let value = bytes || 0;
+    let unit = 0;
+    while (value >= 1024 && unit < units.length - 1) {
+        value /= 1024;
+        unit++;
+    }
+    return unit === 0 ? `${value} B` : `${value.toFixed(1)} ${units[unit]}`;
+}
+
 function escapeHtml(text) {
     const div = document.createElement('div');
     div.textContent = text;
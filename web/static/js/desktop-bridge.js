@@ -54,6 +54,42 @@ async function sendDesktopAgentMessage(teamName, agentName, text) {
     return await window.atmDesktopSendAgentMessage(teamName, agentName, text);
 }
 
+async function signalDesktopProcess(pid, signal) {
+    if (!isDesktopModeEnabled() || typeof window.atmDesktopSignalProcess !== 'function') {
+        throw new Error('desktop signal bridge unavailable');
+    }
+
+    return await window.atmDesktopSignalProcess(pid, signal);
+}
+
+async function signalDesktopTeam(teamName, signal) {
+    if (!isDesktopModeEnabled() || typeof window.atmDesktopSignalTeam !== 'function') {
+        throw new Error('desktop signal bridge unavailable');
+    }
+
+    return await window.atmDesktopSignalTeam(teamName, signal);
+}
+
+async function loginDesktopAdmin(username, password) {
+    if (!isDesktopModeEnabled()) {
+        throw new Error('desktop bridge unavailable');
+    }
+
+    if (typeof window.atmDesktopLogin !== 'function') {
+        throw new Error('desktop login bridge unavailable');
+    }
+
+    return await window.atmDesktopLogin(username, password);
+}
+
+async function logoutDesktopAdmin() {
+    if (!isDesktopModeEnabled() || typeof window.atmDesktopLogout !== 'function') {
+        return;
+    }
+
+    await window.atmDesktopLogout();
+}
+
 async function getDesktopContext() {
     if (!isDesktopModeEnabled()) {
         throw new Error('desktop bridge unavailable');
@@ -201,6 +237,10 @@ window.AgentMonitorDesktopBridge = {
     fetchDesktopState,
     deleteDesktopTeam,
     sendDesktopAgentMessage,
+    signalDesktopProcess,
+    signalDesktopTeam,
+    loginDesktopAdmin,
+    logoutDesktopAdmin,
     getDesktopContext,
     quitDesktopApp,
     navigateDesktopApp,
@@ -93,7 +93,7 @@ async function loadDesktopPreferences() {
 
 async function saveDesktopPreferences(nextPrefs) {
     if (!desktopAdminAccessEnabled) {
-        throw new Error('管理员登录后才能修改桌面设置');
+        throw new Error('登录具备权限的账号后才能修改桌面设置');
     }
 
     const normalized = normalizePreferences(nextPrefs);
@@ -121,7 +121,7 @@ export function setDesktopAdminAccess(enabled) {
     const settingsButton = document.getElementById('desktop-settings-button');
     if (settingsButton) {
         settingsButton.disabled = !desktopAdminAccessEnabled;
-        settingsButton.title = desktopAdminAccessEnabled ? '' : '管理员登录后才能修改设置';
+        settingsButton.title = desktopAdminAccessEnabled ? '' : '登录具备权限的账号后才能修改设置';
     }
 
     [
@@ -542,6 +542,22 @@ export async function initDesktopUI(options = {}) {
     return { ...desktopPreferencesCache };
 }
 
+export async function loginDesktopAdmin(username, password) {
+    if (!IS_DESKTOP_MODE || !DESKTOP_BRIDGE?.loginDesktopAdmin) {
+        return null;
+    }
+
+    return await DESKTOP_BRIDGE.loginDesktopAdmin(username, password);
+}
+
+export async function logoutDesktopAdmin() {
+    if (!IS_DESKTOP_MODE || !DESKTOP_BRIDGE?.logoutDesktopAdmin) {
+        return;
+    }
+
+    await DESKTOP_BRIDGE.logoutDesktopAdmin();
+}
+
 export function isDesktopMode() {
     return IS_DESKTOP_MODE;
 }